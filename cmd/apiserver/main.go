@@ -14,6 +14,7 @@ import (
 	"github.com/woxQAQ/gim/pkg/constants"
 	"github.com/woxQAQ/gim/pkg/db"
 	"github.com/woxQAQ/gim/pkg/logger"
+	"github.com/woxQAQ/gim/pkg/snowflake"
 )
 
 func init() {
@@ -23,18 +24,91 @@ func init() {
 	viper.SetDefault(constants.LogLevel, "info")
 	viper.SetDefault(constants.LogFilePath, "")
 	viper.SetDefault(constants.ApiPath, ":8081")
-	viper.SetDefault(constants.DBPath, "gim.db")
+	viper.SetDefault(constants.DBDriver, "sqlite")
+	viper.SetDefault(constants.DBDSN, "gim.db")
+	viper.SetDefault(constants.DBMaxOpenConns, 0)
+	viper.SetDefault(constants.DBMaxIdleConns, 0)
+	viper.SetDefault(constants.DBConnMaxLifetime, "0s")
+	viper.SetDefault(constants.DBConnMaxIdleTime, "0s")
+	viper.SetDefault(constants.DBAutoMigrate, true)
+	viper.SetDefault(constants.JWTSecret, "")
+	viper.SetDefault(constants.JWTPrivateKeyFile, "")
+	viper.SetDefault(constants.JWTPublicKeyFile, "")
+	viper.SetDefault(constants.JWTIssuer, "gim")
+	viper.SetDefault(constants.JWTTTL, "15m")
+	viper.SetDefault(constants.RefreshTokenTTL, "720h")
+	viper.SetDefault(constants.SessionCleanupInterval, "1h")
+	viper.SetDefault(constants.AuditHashChain, false)
+	viper.SetDefault(constants.LoginMaxFailures, 5)
+	viper.SetDefault(constants.LoginIPMaxFailures, 20)
+	viper.SetDefault(constants.LoginFailureWindow, "15m")
+	viper.SetDefault(constants.LoginLockoutDuration, "15m")
+	viper.SetDefault(constants.LoginDelayBase, "1s")
+	viper.SetDefault(constants.LoginMaxDelay, "30s")
+	viper.SetDefault(constants.LoginCleanupInterval, "10m")
+	viper.SetDefault(constants.RegisterPerIP, 10)
+	viper.SetDefault(constants.CaptchaAfterFailures, 3)
+	viper.SetDefault(constants.CaptchaStaticToken, "")
+	viper.SetDefault(constants.MailDriver, "")
+	viper.SetDefault(constants.MailFrom, "gim <noreply@localhost>")
+	viper.SetDefault(constants.MailDir, "data/mail")
+	viper.SetDefault(constants.SMTPAddr, "")
+	viper.SetDefault(constants.SMTPUsername, "")
+	viper.SetDefault(constants.SMTPPassword, "")
+	viper.SetDefault(constants.MailLinkBaseURL, "")
+	viper.SetDefault(constants.EmailTokenSecret, "")
+	viper.SetDefault(constants.EmailVerifyTTL, "24h")
+	viper.SetDefault(constants.PasswordResetTTL, "1h")
+	viper.SetDefault(constants.RequireVerifiedEmail, false)
+	viper.SetDefault(constants.GatewayURL, "http://127.0.0.1:8080")
+	viper.SetDefault(constants.GatewayInternalToken, "")
+	viper.SetDefault(constants.GatewayTimeout, "5s")
+	viper.SetDefault(constants.NoticeSchedulerInterval, "5s")
+	viper.SetDefault(constants.RetentionInterval, "1h")
+	viper.SetDefault(constants.RetentionBatchSize, 500)
+	viper.SetDefault(constants.RetentionBatchPause, "50ms")
+	viper.SetDefault(constants.AttachmentDir, "")
+	viper.SetDefault(constants.ArchiveDir, "")
+	viper.SetDefault(constants.DataRequestInterval, "10s")
+	viper.SetDefault(constants.DataExportDir, "data/exports")
+	viper.SetDefault(constants.DataExportTTL, "168h")
+	viper.SetDefault(constants.CacheDriver, "")
+	viper.SetDefault(constants.CacheTTL, "5m")
+	viper.SetDefault(constants.CacheSize, 10000)
+	viper.SetDefault(constants.CacheRedisAddr, "127.0.0.1:6379")
+	viper.SetDefault(constants.CacheRedisPassword, "")
+	viper.SetDefault(constants.CacheRedisDB, 0)
+	viper.SetDefault(constants.CachePrefix, "gim:")
+	viper.SetDefault(constants.NodeID, 2)
+
+	// 允许通过同名环境变量覆盖配置
+	viper.AutomaticEnv()
 }
 
 func main() {
 	// 初始化日志系统
 	l := config.SetupLogger()
 
+	// 初始化ID生成器，节点号需要与网关区分
+	if err := snowflake.InitGenerator(viper.GetInt64(constants.NodeID)); err != nil {
+		l.Error("初始化ID生成器失败", logger.Error(err))
+		os.Exit(1)
+	}
+
 	// 初始化数据库连接
 	config.SetupDatabase(l)
 	server := config.SetupApiServer(l)
 
-	config.Register(server, db.GetDB())
+	svcs := config.Register(server, db.GetDB(), l)
+
+	// 启动后台任务
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go svcs.Notice.Run(ctx, viper.GetDuration(constants.NoticeSchedulerInterval))
+	go svcs.Retention.Run(ctx, viper.GetDuration(constants.RetentionInterval))
+	go svcs.DataRequest.Run(ctx, viper.GetDuration(constants.DataRequestInterval))
+	go svcs.Session.Run(ctx, viper.GetDuration(constants.SessionCleanupInterval))
+	go svcs.LoginGuard.Run(ctx, viper.GetDuration(constants.LoginCleanupInterval))
 
 	// 启动服务器
 	go func() {
@@ -52,6 +126,7 @@ func main() {
 
 	// 优雅关闭
 	l.Info("正在关闭服务...")
+	cancel()
 	if err := server.Shutdown(context.Background()); err != nil {
 		l.Error("关闭服务器失败", logger.Error(err))
 	}
@@ -0,0 +1,9 @@
+package main
+
+import (
+	"github.com/woxQAQ/gim/internal/gimctl/cmd"
+)
+
+func main() {
+	cmd.Execute()
+}
@@ -12,25 +12,85 @@ import (
 
 	"go.uber.org/zap"
 
+	"github.com/woxQAQ/gim/internal/apiserver/stores"
+	"github.com/woxQAQ/gim/internal/audit"
+	"github.com/woxQAQ/gim/internal/migrations"
+	"github.com/woxQAQ/gim/internal/search"
+	"github.com/woxQAQ/gim/internal/webhook"
 	"github.com/woxQAQ/gim/internal/wsgateway"
 	"github.com/woxQAQ/gim/internal/wsgateway/console"
+	"github.com/woxQAQ/gim/internal/wsgateway/filter"
+	"github.com/woxQAQ/gim/internal/wsgateway/handler"
+	"github.com/woxQAQ/gim/internal/wsgateway/intercept"
+	"github.com/woxQAQ/gim/pkg/auth"
+	"github.com/woxQAQ/gim/pkg/cache"
 	"github.com/woxQAQ/gim/pkg/db"
 	"github.com/woxQAQ/gim/pkg/logger"
 	"github.com/woxQAQ/gim/pkg/snowflake"
 )
 
 var (
-	addr         string
-	logLevel     string
-	logFile      string
-	databasePath string
+	addr              string
+	logLevel          string
+	logFile           string
+	dbDriver          string
+	dbDSN             string
+	dbMaxOpenConns    int
+	dbMaxIdleConns    int
+	dbConnMaxLifetime time.Duration
+	dbConnMaxIdleTime time.Duration
+	dbAutoMigrate     bool
+
+	internalToken string
+	filterConfig  string
+	dmPolicy      string
+
+	interceptURL      string
+	interceptSecret   string
+	interceptTimeout  time.Duration
+	interceptFailOpen bool
+
+	cacheRedisAddr     string
+	cacheRedisPassword string
+	cacheRedisDB       int
+	cachePrefix        string
+	cacheTTL           time.Duration
+
+	jwtSecret        string
+	jwtPublicKeyFile string
+	jwtIssuer        string
+
+	auditHashChain bool
 )
 
 func init() {
 	flag.StringVar(&addr, "addr", "127.0.0.1:8080", "WebSocket服务监听地址")
 	flag.StringVar(&logLevel, "log-level", "info", "日志级别 (debug, info, warn, error)")
 	flag.StringVar(&logFile, "log-file", "", "日志文件路径，为空时仅输出到控制台")
-	flag.StringVar(&databasePath, "db-path", "gim.db", "SQLite数据库文件路径")
+	flag.StringVar(&dbDriver, "db-driver", "sqlite", "数据库驱动 (sqlite, postgres, mysql)")
+	flag.StringVar(&dbDSN, "db-dsn", "gim.db", "数据源名称，SQLite为数据库文件路径")
+	flag.StringVar(&dbDSN, "db-path", "gim.db", "SQLite数据库文件路径，已废弃，请使用 -db-dsn")
+	flag.IntVar(&dbMaxOpenConns, "db-max-open-conns", 0, "最大打开连接数，0表示不限制")
+	flag.IntVar(&dbMaxIdleConns, "db-max-idle-conns", 0, "最大空闲连接数，0表示使用默认值")
+	flag.DurationVar(&dbConnMaxLifetime, "db-conn-max-lifetime", 0, "连接最大存活时间，0表示不限制")
+	flag.DurationVar(&dbConnMaxIdleTime, "db-conn-max-idle-time", 0, "连接最大空闲时间，0表示不限制")
+	flag.BoolVar(&dbAutoMigrate, "db-auto-migrate", true, "启动时自动执行未执行的数据库迁移")
+	flag.StringVar(&internalToken, "internal-token", "", "供apiserver调用的内部接口令牌，为空时禁用内部接口")
+	flag.StringVar(&filterConfig, "filter-config", "", "内容过滤规则文件路径，修改后自动重新加载，为空时不启用")
+	flag.StringVar(&dmPolicy, "dm-policy", string(handler.DMPolicyAllow), "非联系人之间的单聊策略 (allow: 允许, contacts: 只允许联系人之间单聊)")
+	flag.StringVar(&interceptURL, "intercept-url", "", "消息投递前的审核回调地址，为空时不启用")
+	flag.StringVar(&interceptSecret, "intercept-secret", "", "审核回调的签名密钥")
+	flag.DurationVar(&interceptTimeout, "intercept-timeout", 500*time.Millisecond, "审核回调超时时间")
+	flag.BoolVar(&interceptFailOpen, "intercept-fail-open", false, "审核回调失败时是否放行消息")
+	flag.StringVar(&cacheRedisAddr, "cache-redis-addr", "", "apiserver 使用的 Redis 缓存地址，网关写入消息时删除会话缓存，为空时不启用")
+	flag.StringVar(&cacheRedisPassword, "cache-redis-password", "", "Redis 缓存密码")
+	flag.IntVar(&cacheRedisDB, "cache-redis-db", 0, "Redis 缓存数据库编号")
+	flag.StringVar(&cachePrefix, "cache-prefix", "gim:", "缓存键前缀，需要与 apiserver 一致")
+	flag.DurationVar(&cacheTTL, "cache-ttl", 5*time.Minute, "缓存过期时间")
+	flag.StringVar(&jwtSecret, "jwt-secret", "", "校验访问令牌的共享密钥，与 apiserver 的 JWT_SECRET 一致")
+	flag.StringVar(&jwtPublicKeyFile, "jwt-public-key", "", "校验访问令牌的公钥文件，与 -jwt-secret 都为空时信任连接请求中的 user_id")
+	flag.StringVar(&jwtIssuer, "jwt-issuer", "gim", "访问令牌的签发者")
+	flag.BoolVar(&auditHashChain, "audit-hash-chain", false, "以哈希链保护网关写入的审计日志，通常与 apiserver 的 AUDIT_HASH_CHAIN 保持一致")
 }
 
 func main() {
@@ -55,27 +115,108 @@ func main() {
 
 	// 初始化数据库连接
 	if err := db.Init(&db.Config{
-		DatabasePath: databasePath,
+		Logger:          l,
+		Driver:          dbDriver,
+		DSN:             dbDSN,
+		MaxOpenConns:    dbMaxOpenConns,
+		MaxIdleConns:    dbMaxIdleConns,
+		ConnMaxLifetime: dbConnMaxLifetime,
+		ConnMaxIdleTime: dbConnMaxIdleTime,
+		Migrations:      migrations.All(),
+		AutoMigrate:     dbAutoMigrate,
 	}); err != nil {
 		l.Error("初始化数据库连接失败", logger.Error(err))
 		os.Exit(1)
 	}
 
+	// 创建回调分发器
+	dispatcher := webhook.NewDispatcher(
+		stores.NewWebhookStore(db.GetDB()),
+		l.With(logger.String("domain", "webhook")),
+		nil,
+	)
+
+	policy, err := handler.ParseDMPolicy(dmPolicy)
+	if err != nil {
+		l.Error("单聊策略配置错误", logger.Error(err))
+		os.Exit(1)
+	}
+
 	// 创建网关实例
-	gateway, err := wsgateway.NewWSGateway(
+	opts := []wsgateway.Option{
 		wsgateway.WithLogger(l.With(logger.String("domain", "gateway"))),
-	)
+		wsgateway.WithWebhookDispatcher(dispatcher),
+		wsgateway.WithDMPolicy(policy),
+		wsgateway.WithAuditRecorder(audit.NewRecorder(
+			stores.NewAuditStore(db.GetDB()),
+			auditHashChain,
+			l.With(logger.String("domain", "audit")),
+		)),
+	}
+	if filterConfig != "" {
+		f, err := filter.LoadFile(filterConfig, l.With(logger.String("domain", "filter")))
+		if err != nil {
+			l.Error("加载内容过滤规则失败", logger.Error(err))
+			os.Exit(1)
+		}
+		opts = append(opts, wsgateway.WithContentFilter(f))
+	}
+	if idx, err := search.New(db.GetDB()); err == nil {
+		opts = append(opts, wsgateway.WithSearchIndex(idx))
+	} else {
+		// 不支持全文检索时由 apiserver 使用内存索引
+		l.Info("数据库不支持全文检索，网关不写入消息索引", logger.Error(err))
+	}
+	if interceptURL != "" {
+		opts = append(opts, wsgateway.WithInterceptor(
+			intercept.NewHTTPInterceptor(interceptURL, interceptSecret, interceptTimeout),
+			handler.InterceptConfig{Timeout: interceptTimeout, FailOpen: interceptFailOpen},
+		))
+	}
+	if cacheRedisAddr != "" {
+		c, err := cache.New(&cache.Config{
+			Driver:        cache.DriverRedis,
+			RedisAddr:     cacheRedisAddr,
+			RedisPassword: cacheRedisPassword,
+			RedisDB:       cacheRedisDB,
+			Prefix:        cachePrefix,
+			TTL:           cacheTTL,
+		})
+		if err != nil {
+			l.Error("连接缓存失败", logger.Error(err))
+			os.Exit(1)
+		}
+		opts = append(opts, wsgateway.WithMessageCache(c))
+	}
+	if jwtSecret != "" || jwtPublicKeyFile != "" {
+		tokens, err := auth.NewJWT(&auth.JWTConfig{
+			Secret:        jwtSecret,
+			PublicKeyFile: jwtPublicKeyFile,
+			Issuer:        jwtIssuer,
+		})
+		if err != nil {
+			l.Error("初始化访问令牌校验失败", logger.Error(err))
+			os.Exit(1)
+		}
+		opts = append(opts, wsgateway.WithTokenVerifier(
+			auth.NewAuthenticator(tokens, stores.NewSessionStore(db.GetDB())),
+		))
+	} else {
+		l.Warn("未配置访问令牌校验，连接请求中的 user_id 不会被校验")
+	}
+	gateway, err := wsgateway.NewWSGateway(opts...)
 	if err != nil {
 		l.Error("创建WebSocket网关失败", logger.Error(err))
 		os.Exit(1)
 	}
 
 	// 创建HTTP服务器
+	mux := http.NewServeMux()
+	mux.Handle("/internal/", gateway.InternalHandler(internalToken))
+	mux.HandleFunc("/", gateway.HandleNewConnection)
 	server := &http.Server{
-		Addr: addr,
-		Handler: http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-			gateway.HandleNewConnection(w, r)
-		}),
+		Addr:              addr,
+		Handler:           mux,
 		ReadHeaderTimeout: 10 * time.Second, // 防止 Slowloris 攻击
 	}
 
@@ -85,6 +226,7 @@ func main() {
 		l.Error("启动网关服务失败", logger.Error(err))
 		os.Exit(1)
 	}
+	dispatcher.Start(ctx)
 
 	// 启动HTTP服务器
 	go func() {
@@ -120,6 +262,9 @@ func main() {
 		l.Error("关闭网关服务失败", logger.Error(err))
 	}
 
+	// 停止回调投递
+	dispatcher.Stop()
+
 	// 关闭数据库连接
 	if err := db.Close(); err != nil {
 		l.Error("关闭数据库连接失败", logger.Error(err))
@@ -11,6 +11,7 @@ import (
 	. "github.com/onsi/ginkgo/v2"
 	. "github.com/onsi/gomega"
 
+	"github.com/woxQAQ/gim/internal/migrations"
 	"github.com/woxQAQ/gim/internal/wsgateway"
 	"github.com/woxQAQ/gim/pkg/db"
 	"github.com/woxQAQ/gim/pkg/logger"
@@ -34,7 +35,11 @@ var _ = BeforeSuite(func() {
 
 	// 初始化数据库
 	// 设置测试数据库为内存模式
-	Err := db.Init(&db.Config{DatabasePath: ":memory:"})
+	Err := db.Init(&db.Config{
+		DSN:         ":memory:",
+		Migrations:  migrations.All(),
+		AutoMigrate: true,
+	})
 	Expect(Err).NotTo(HaveOccurred())
 
 	// 创建网关实例
@@ -7,7 +7,9 @@ import (
 	"time"
 
 	"github.com/woxQAQ/gim/e2e/pkg/client"
+	"github.com/woxQAQ/gim/internal/apiserver/stores"
 	"github.com/woxQAQ/gim/internal/types"
+	"github.com/woxQAQ/gim/pkg/db"
 )
 
 var _ = Describe("WebSocket Gateway Messaging Tests", func() {
@@ -66,6 +68,34 @@ var _ = Describe("WebSocket Gateway Messaging Tests", func() {
 		})
 	})
 
+	Context("发送方校验", func() {
+		It("冒充其他用户发送的消息应该被拒绝", func() {
+			c := client.New(baseURL+"?user_id=test1", "test1", 1)
+			Expect(c.Connect()).To(Succeed())
+			clients = append(clients, c)
+
+			spoofed := types.Message{
+				Header:  types.MessageHeader{Type: types.MessageTypeText, From: "test3", To: "test2", ID: "spoofed-1", Platform: 1},
+				Payload: []byte("hello"),
+			}
+			genuine := types.Message{
+				Header:  types.MessageHeader{Type: types.MessageTypeText, From: "test1", To: "test2", ID: "genuine-1", Platform: 1},
+				Payload: []byte("hello"),
+			}
+			Expect(c.SendMessage(spoofed)).To(Succeed())
+			Expect(c.SendMessage(genuine)).To(Succeed())
+
+			// 同一连接上的消息按顺序处理，后一条存储后前一条一定已经处理完
+			messages := stores.NewMessageStore(db.GetDB())
+			Eventually(func() error {
+				_, err := messages.GetMessage("genuine-1")
+				return err
+			}).Should(Succeed())
+			_, err := messages.GetMessage("spoofed-1")
+			Expect(err).To(HaveOccurred())
+		})
+	})
+
 	Context("心跳测试", func() {
 		It("应该正确处理心跳消息", func() {
 			c := client.New(baseURL+"?user_id=test1", "test1", 1)
@@ -3,6 +3,7 @@ package config
 import (
 	"github.com/spf13/viper"
 
+	"github.com/woxQAQ/gim/internal/migrations"
 	"github.com/woxQAQ/gim/pkg/constants"
 	"github.com/woxQAQ/gim/pkg/db"
 	"github.com/woxQAQ/gim/pkg/logger"
@@ -10,8 +11,15 @@ import (
 
 func SetupDatabase(l logger.Logger) {
 	if err := db.Init(&db.Config{
-		Logger:       l,
-		DatabasePath: viper.GetString(constants.DBPath),
+		Logger:          l,
+		Driver:          viper.GetString(constants.DBDriver),
+		DSN:             viper.GetString(constants.DBDSN),
+		MaxOpenConns:    viper.GetInt(constants.DBMaxOpenConns),
+		MaxIdleConns:    viper.GetInt(constants.DBMaxIdleConns),
+		ConnMaxLifetime: viper.GetDuration(constants.DBConnMaxLifetime),
+		ConnMaxIdleTime: viper.GetDuration(constants.DBConnMaxIdleTime),
+		Migrations:      migrations.All(),
+		AutoMigrate:     viper.GetBool(constants.DBAutoMigrate),
 	}); err != nil {
 		l.Error(err.Error())
 		panic(err)
@@ -2,24 +2,284 @@ package config
 
 import (
 	"github.com/go-fuego/fuego"
+	"github.com/spf13/viper"
 	"gorm.io/gorm"
 
 	"github.com/woxQAQ/gim/internal/apiserver/controllers"
+	"github.com/woxQAQ/gim/internal/apiserver/gateway"
 	"github.com/woxQAQ/gim/internal/apiserver/services"
 	"github.com/woxQAQ/gim/internal/apiserver/stores"
+	"github.com/woxQAQ/gim/internal/archive"
+	"github.com/woxQAQ/gim/internal/audit"
+	"github.com/woxQAQ/gim/internal/models"
+	"github.com/woxQAQ/gim/internal/search"
+	"github.com/woxQAQ/gim/pkg/auth"
+	"github.com/woxQAQ/gim/pkg/cache"
+	"github.com/woxQAQ/gim/pkg/constants"
+	"github.com/woxQAQ/gim/pkg/logger"
+	"github.com/woxQAQ/gim/pkg/mailer"
+	"github.com/woxQAQ/gim/pkg/middleware"
 )
 
-func Register(sv *fuego.Server, db *gorm.DB) {
-	ustore := stores.NewUserStore(db)
-	mstore := stores.NewMessageStore(db)
-	us := services.NewUserService(ustore)
-	ms := services.NewMessageService(mstore)
-	uc := controllers.NewUserController(us)
+// Services 需要在后台运行的服务
+type Services struct {
+	Notice      *services.NoticeService
+	Retention   *services.RetentionService
+	DataRequest *services.DataRequestService
+	Session     *services.SessionService
+	LoginGuard  *services.LoginGuard
+}
+
+func Register(sv *fuego.Server, db *gorm.DB, l logger.Logger) *Services {
+	c := newCache(l)
+	ttl := viper.GetDuration(constants.CacheTTL)
+	ustore := stores.NewUserStore(db).WithCache(c, ttl)
+	// 网关也会写入消息，进程内缓存无法在网关写入后失效，只有共享缓存才用于会话最新消息
+	var mcache cache.Cache
+	if viper.GetString(constants.CacheDriver) == cache.DriverRedis {
+		mcache = c
+	}
+	mstore := stores.NewMessageStore(db).WithCache(mcache, ttl)
+	wstore := stores.NewWebhookStore(db)
+	modstore := stores.NewModerationStore(db)
+	nstore := stores.NewNoticeStore(db)
+	gstore := stores.NewGroupStore(db).WithCache(c, ttl)
+	rstore := stores.NewRetentionStore(db)
+	drstore := stores.NewDataRequestStore(db)
+	sesstore := stores.NewSessionStore(db)
+	cstore := stores.NewContactStore(db)
+	bstore := stores.NewBlockStore(db)
+	banstore := stores.NewBanStore(db)
+	astore := stores.NewAuditStore(db)
+	gw := gateway.NewHTTPClient(
+		viper.GetString(constants.GatewayURL),
+		viper.GetString(constants.GatewayInternalToken),
+		viper.GetDuration(constants.GatewayTimeout),
+	)
+	blobs := newBlobStore()
+	recorder := audit.NewRecorder(astore, viper.GetBool(constants.AuditHashChain),
+		l.With(logger.String("domain", "audit")))
+	guard := services.NewLoginGuard(stores.NewLoginAttemptStore(db), newCaptcha(), recorder,
+		l.With(logger.String("domain", "login_guard")),
+		&services.LoginGuardConfig{
+			AccountMaxFailures: viper.GetInt(constants.LoginMaxFailures),
+			IPMaxFailures:      viper.GetInt(constants.LoginIPMaxFailures),
+			FailureWindow:      viper.GetDuration(constants.LoginFailureWindow),
+			LockoutDuration:    viper.GetDuration(constants.LoginLockoutDuration),
+			DelayBase:          viper.GetDuration(constants.LoginDelayBase),
+			MaxDelay:           viper.GetDuration(constants.LoginMaxDelay),
+			CaptchaAfter:       viper.GetInt(constants.CaptchaAfterFailures),
+			RegisterPerIP:      viper.GetInt(constants.RegisterPerIP),
+		},
+	)
+	us := services.NewUserService(ustore, banstore, blobs, recorder, guard,
+		&services.UserConfig{RequireVerifiedEmail: viper.GetBool(constants.RequireVerifiedEmail)},
+	)
+	es := newEmailService(ustore, recorder, l)
+	tokens := newJWT(l)
+	ss := services.NewSessionService(sesstore, tokens, gw,
+		l.With(logger.String("domain", "session")),
+		&services.SessionConfig{RefreshTTL: viper.GetDuration(constants.RefreshTokenTTL)},
+	)
+	searchIndex := newSearchIndex(db, mstore, l)
+	coldArchive, archiver := newArchive(l)
+	cs := services.NewContactService(cstore, bstore, ustore, stores.NewUnitOfWork(db), gw,
+		l.With(logger.String("domain", "contact")))
+	bs := services.NewBlockService(bstore, cstore, ustore)
+	bans := services.NewBanService(banstore, ustore, sesstore, gw, recorder, l.With(logger.String("domain", "ban")))
+	ms := services.NewMessageService(mstore, gstore, searchIndex, coldArchive)
+	ws := services.NewWebhookService(wstore)
+	mods := services.NewModerationService(modstore)
+	ns := services.NewNoticeService(nstore, gstore, gw, recorder, l.With(logger.String("domain", "notice")))
+	rs := services.NewRetentionService(rstore, mstore, searchIndex, archiver, blobs,
+		l.With(logger.String("domain", "retention")),
+		&services.RetentionConfig{
+			BatchSize:  viper.GetInt(constants.RetentionBatchSize),
+			BatchPause: viper.GetDuration(constants.RetentionBatchPause),
+		},
+	)
+	drs := services.NewDataRequestService(drstore, ustore, mstore, gstore, modstore, nstore, sesstore, cstore, bstore,
+		searchIndex, coldArchive, blobs, gw, recorder,
+		l.With(logger.String("domain", "data_request")),
+		&services.DataRequestConfig{
+			ExportDir: viper.GetString(constants.DataExportDir),
+			ExportTTL: viper.GetDuration(constants.DataExportTTL),
+		},
+	)
+	uc := controllers.NewUserController(us, ss, es)
+	cc := controllers.NewContactController(cs)
+	bc := controllers.NewBlockController(bs)
 	mc := controllers.NewMessageController(ms)
+	wc := controllers.NewWebhookController(ws)
+	modc := controllers.NewModerationController(mods)
+	nc := controllers.NewNoticeController(ns)
+	rc := controllers.NewRetentionController(rs)
+	drc := controllers.NewDataRequestController(drs)
+	ac := controllers.NewAuditController(services.NewAuditService(astore))
+	banc := controllers.NewBanController(bans)
+	bots := services.NewBotService(ustore, stores.NewAPIKeyStore(db), gstore, banstore, gw, recorder,
+		l.With(logger.String("domain", "bot")))
+	botc := controllers.NewBotController(bots)
 	apiv1 := fuego.Group(sv, "/api/v1",
 		fuego.OptionDescription("API v1"),
-		fuego.OptionHeader("Authentication", "Bearer Token", fuego.ParamRequired()),
 	)
 	uc.Route(apiv1)
-	mc.Route(apiv1)
+
+	// 其余用户接口需要访问令牌，处理函数从请求上下文获取当前用户
+	authed := fuego.Group(apiv1, "",
+		fuego.OptionHeader(middleware.AuthorizationHeader, "Bearer Token", fuego.ParamRequired()),
+		fuego.OptionMiddleware(middleware.JWTAuth(auth.NewAuthenticator(tokens, sesstore))),
+	)
+	uc.RouteAuthed(authed)
+	cc.Route(authed)
+	bc.Route(authed)
+	mc.Route(authed)
+	nc.Route(authed)
+	drc.Route(authed)
+
+	// 机器人接口使用 API 密钥鉴权，不接受访问令牌
+	botAPI := fuego.Group(apiv1, "/bot",
+		fuego.OptionHeader(middleware.APIKeyHeader, "API Key"),
+		fuego.OptionMiddleware(middleware.APIKeyAuth(bots, models.ScopeMessagesSend)),
+	)
+	botc.Route(botAPI)
+
+	// 审核接口需要审核员或管理员角色
+	moderators := fuego.Group(authed, "",
+		fuego.OptionMiddleware(middleware.RequireRole(ustore, models.RoleModerator, models.RoleAdmin)),
+	)
+	modc.Route(moderators)
+
+	// 管理员接口需要管理员角色
+	admin := fuego.Group(authed, "/admin",
+		fuego.OptionDescription("管理员接口"),
+		fuego.OptionMiddleware(middleware.RequireRole(ustore, models.RoleAdmin)),
+	)
+	uc.RouteAdmin(admin)
+	nc.RouteAdmin(admin)
+	rc.RouteAdmin(admin)
+	banc.RouteAdmin(admin)
+	ac.RouteAdmin(admin)
+	botc.RouteAdmin(admin)
+	wc.RouteAdmin(admin)
+
+	return &Services{Notice: ns, Retention: rs, DataRequest: drs, Session: ss, LoginGuard: guard}
+}
+
+// newJWT 使用配置的密钥或密钥对签发和校验访问令牌，未配置时无法启动
+func newJWT(l logger.Logger) *auth.JWT {
+	tokens, err := auth.NewJWT(&auth.JWTConfig{
+		Secret:         viper.GetString(constants.JWTSecret),
+		PrivateKeyFile: viper.GetString(constants.JWTPrivateKeyFile),
+		PublicKeyFile:  viper.GetString(constants.JWTPublicKeyFile),
+		Issuer:         viper.GetString(constants.JWTIssuer),
+		TTL:            viper.GetDuration(constants.JWTTTL),
+	})
+	if err != nil {
+		l.Error("初始化访问令牌失败", logger.Error(err))
+		panic(err)
+	}
+	return tokens
+}
+
+// newCache 配置了缓存驱动时启用缓存，未配置时返回 nil
+func newCache(l logger.Logger) cache.Cache {
+	driver := viper.GetString(constants.CacheDriver)
+	if driver == cache.DriverNone {
+		return nil
+	}
+	c, err := cache.New(&cache.Config{
+		Driver:        driver,
+		TTL:           viper.GetDuration(constants.CacheTTL),
+		Size:          viper.GetInt(constants.CacheSize),
+		RedisAddr:     viper.GetString(constants.CacheRedisAddr),
+		RedisPassword: viper.GetString(constants.CacheRedisPassword),
+		RedisDB:       viper.GetInt(constants.CacheRedisDB),
+		Prefix:        viper.GetString(constants.CachePrefix),
+	})
+	if err != nil {
+		l.Error("初始化缓存失败", logger.String("driver", driver), logger.Error(err))
+		panic(err)
+	}
+	return c
+}
+
+// newArchive 配置了归档目录时启用冷归档
+func newArchive(l logger.Logger) (*archive.Archive, services.MessageArchiver) {
+	dir := viper.GetString(constants.ArchiveDir)
+	if dir == "" {
+		return nil, nil
+	}
+	a, err := archive.Open(dir)
+	if err != nil {
+		l.Error("打开消息归档失败", logger.String("dir", dir), logger.Error(err))
+		panic(err)
+	}
+	return a, a
+}
+
+// newBlobStore 配置了附件目录时管理本地附件文件
+func newBlobStore() services.BlobStore {
+	dir := viper.GetString(constants.AttachmentDir)
+	if dir == "" {
+		return nil
+	}
+	return &services.FileBlobStore{Root: dir}
+}
+
+// newCaptcha 配置了固定令牌时使用只接受该令牌的人机验证，便于本地调试，未配置时不要求人机验证
+func newCaptcha() services.CaptchaVerifier {
+	token := viper.GetString(constants.CaptchaStaticToken)
+	if token == "" {
+		return nil
+	}
+	return &services.StaticCaptcha{Token: token}
+}
+
+// newEmailService 配置了邮件驱动时发送验证邮件和重置密码邮件，
+// 操作令牌使用 EMAIL_TOKEN_SECRET 签名，未配置时使用 JWT_SECRET
+func newEmailService(ustore *stores.UserStore, recorder *audit.Recorder, l logger.Logger) *services.EmailService {
+	driver := viper.GetString(constants.MailDriver)
+	m, err := mailer.New(&mailer.Config{
+		Driver:       driver,
+		From:         viper.GetString(constants.MailFrom),
+		SMTPAddr:     viper.GetString(constants.SMTPAddr),
+		SMTPUsername: viper.GetString(constants.SMTPUsername),
+		SMTPPassword: viper.GetString(constants.SMTPPassword),
+		Dir:          viper.GetString(constants.MailDir),
+	})
+	if err != nil {
+		l.Error("初始化邮件服务失败", logger.String("driver", driver), logger.Error(err))
+		panic(err)
+	}
+	var tokens *auth.ActionTokens
+	if m != nil {
+		secret := viper.GetString(constants.EmailTokenSecret)
+		if secret == "" {
+			secret = viper.GetString(constants.JWTSecret)
+		}
+		tokens, err = auth.NewActionTokens(secret)
+		if err != nil {
+			l.Error("初始化邮件令牌失败", logger.Error(err))
+			panic(err)
+		}
+	}
+	return services.NewEmailService(ustore, tokens, m, recorder,
+		l.With(logger.String("domain", "email")),
+		&services.EmailConfig{
+			VerifyTTL:   viper.GetDuration(constants.EmailVerifyTTL),
+			ResetTTL:    viper.GetDuration(constants.PasswordResetTTL),
+			LinkBaseURL: viper.GetString(constants.MailLinkBaseURL),
+		},
+	)
+}
+
+// newSearchIndex 数据库支持全文检索时使用数据库索引，否则使用从消息表增量同步的内存索引
+func newSearchIndex(db *gorm.DB, mstore *stores.MessageStore, l logger.Logger) search.Index {
+	idx, err := search.New(db)
+	if err == nil {
+		return idx
+	}
+	l.Warn("数据库不支持全文检索，使用内存索引", logger.Error(err))
+	return search.NewSyncedIndex(mstore)
 }
@@ -0,0 +1,128 @@
+package config_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+
+	"github.com/go-fuego/fuego"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"github.com/spf13/viper"
+	"gorm.io/gorm"
+
+	"github.com/woxQAQ/gim/internal/apiserver/config"
+	"github.com/woxQAQ/gim/internal/apiserver/stores"
+	"github.com/woxQAQ/gim/internal/migrations"
+	"github.com/woxQAQ/gim/internal/models"
+	"github.com/woxQAQ/gim/internal/types"
+	"github.com/woxQAQ/gim/pkg/constants"
+	"github.com/woxQAQ/gim/pkg/db"
+	"github.com/woxQAQ/gim/pkg/logger"
+)
+
+var _ = Describe("Register", func() {
+	var (
+		gdb *gorm.DB
+		sv  *fuego.Server
+	)
+
+	// do 发送请求并返回响应，token 为空时不携带访问令牌
+	do := func(method, path, token string, body any) *httptest.ResponseRecorder {
+		var buf bytes.Buffer
+		if body != nil {
+			Expect(json.NewEncoder(&buf).Encode(body)).To(Succeed())
+		}
+		req := httptest.NewRequest(method, path, &buf)
+		req.Header.Set("Content-Type", "application/json")
+		if token != "" {
+			req.Header.Set("Authorization", "Bearer "+token)
+		}
+		rec := httptest.NewRecorder()
+		sv.Mux.ServeHTTP(rec, req)
+		return rec
+	}
+
+	// login 注册用户并返回访问令牌，role 不为空时修改用户角色
+	login := func(username, role string) string {
+		creds := map[string]string{"username": username, "password": "password1"}
+		Expect(do(http.MethodPost, "/api/v1/users/register", "", creds).Code).To(Equal(http.StatusOK))
+		if role != "" {
+			Expect(gdb.Model(&models.User{}).Where("username = ?", username).Update("role", role).Error).To(Succeed())
+		}
+		rec := do(http.MethodPost, "/api/v1/users/login", "", creds)
+		Expect(rec.Code).To(Equal(http.StatusOK))
+		var resp struct {
+			Token string `json:"token"`
+		}
+		Expect(json.Unmarshal(rec.Body.Bytes(), &resp)).To(Succeed())
+		return resp.Token
+	}
+
+	// userID 返回用户名对应的用户ID
+	userID := func(username string) string {
+		var u models.User
+		Expect(gdb.Where("username = ?", username).First(&u).Error).To(Succeed())
+		return u.ID
+	}
+
+	BeforeEach(func() {
+		var err error
+		gdb, err = db.Open(&db.Config{DSN: filepath.Join(GinkgoT().TempDir(), "route.db")})
+		Expect(err).NotTo(HaveOccurred())
+		m, err := db.NewMigrator(gdb, migrations.All())
+		Expect(err).NotTo(HaveOccurred())
+		_, err = m.Up()
+		Expect(err).NotTo(HaveOccurred())
+
+		viper.Set(constants.JWTSecret, "0123456789abcdef0123456789abcdef")
+		viper.Set(constants.CacheDriver, "memory")
+		viper.Set(constants.CacheTTL, "5m")
+		viper.Set(constants.CacheSize, 100)
+		DeferCleanup(viper.Reset)
+		l, _ := logger.NewLogger(&logger.Config{Level: "error"})
+		l.Disable()
+		sv = fuego.NewServer(fuego.WithoutLogger())
+		config.Register(sv, gdb, l)
+	})
+
+	It("进程内缓存时，网关写入的消息可以立即查询到", func() {
+		alice := login("alice", "")
+		login("bob", "")
+		aliceID, bobID := userID("alice"), userID("bob")
+
+		// 网关使用自己的 MessageStore 写入消息，无法让 apiserver 的进程内缓存失效
+		gatewayStore := stores.NewMessageStore(gdb)
+		history := func() []any {
+			rec := do(http.MethodGet, "/api/v1/messages/history?peer_id="+bobID, alice, nil)
+			Expect(rec.Code).To(Equal(http.StatusOK))
+			var resp struct {
+				Messages []any `json:"messages"`
+			}
+			Expect(json.Unmarshal(rec.Body.Bytes(), &resp)).To(Succeed())
+			return resp.Messages
+		}
+		for i := 1; i <= 2; i++ {
+			m := &models.Message{}
+			m.FromTypes(types.NewMessage(types.MessageTypeText, aliceID, bobID, 1, []byte("hello")))
+			Expect(gatewayStore.CreateMessage(m)).To(Succeed())
+			Expect(history()).To(HaveLen(i))
+		}
+	})
+
+	It("回调接口只允许管理员访问", func() {
+		endpoint := map[string]any{"url": "https://example.com/hook", "events": []string{"*"}}
+
+		user := login("alice", "")
+		Expect(do(http.MethodPost, "/api/v1/admin/webhooks/endpoints", user, endpoint).Code).To(Equal(http.StatusForbidden))
+		Expect(do(http.MethodGet, "/api/v1/admin/webhooks/deliveries", user, nil).Code).To(Equal(http.StatusForbidden))
+		Expect(do(http.MethodGet, "/api/v1/admin/webhooks/dead-letters", user, nil).Code).To(Equal(http.StatusForbidden))
+		Expect(do(http.MethodGet, "/api/v1/webhooks/endpoints", user, nil).Code).To(Equal(http.StatusNotFound))
+
+		admin := login("root", models.RoleAdmin)
+		Expect(do(http.MethodPost, "/api/v1/admin/webhooks/endpoints", admin, endpoint).Code).To(Equal(http.StatusOK))
+		Expect(do(http.MethodGet, "/api/v1/admin/webhooks/endpoints", admin, nil).Code).To(Equal(http.StatusOK))
+	})
+})
@@ -1,10 +1,7 @@
 package config
 
 import (
-	"time"
-
 	"github.com/go-fuego/fuego"
-	"github.com/golang-jwt/jwt/v5"
 	"github.com/spf13/viper"
 
 	"github.com/woxQAQ/gim/pkg/constants"
@@ -27,13 +24,6 @@ func SetupApiServer(l logger.Logger) *fuego.Server {
 			PrettyFormatJson: true,
 		}),
 		fuego.WithoutAutoGroupTags(),
-		fuego.WithAutoAuth(func(user, password string) (jwt.Claims, error) {
-			return jwt.MapClaims{
-				"user_id":  user,
-				"password": password,
-				"exp":      time.Now().Add(24 * time.Hour).Unix(),
-			}, nil
-		}),
 	)
 	// 设置全局中间件
 	fuego.Use(server, middleware.Recovery(l))
@@ -41,5 +31,8 @@ func SetupApiServer(l logger.Logger) *fuego.Server {
 	// 设置日志中间件
 	fuego.Use(server, middleware.Logger(l))
 
+	// 审计日志从请求上下文获取客户端地址
+	fuego.Use(server, middleware.ClientIP())
+
 	return server
 }
@@ -0,0 +1,63 @@
+package controllers
+
+import (
+	"github.com/go-fuego/fuego"
+
+	"github.com/woxQAQ/gim/internal/apiserver/services"
+	"github.com/woxQAQ/gim/internal/apiserver/types/request"
+	"github.com/woxQAQ/gim/internal/apiserver/types/response"
+)
+
+// AuditController 处理审计日志相关的HTTP请求
+type AuditController struct {
+	auditService *services.AuditService
+}
+
+// NewAuditController 创建AuditController实例
+func NewAuditController(auditService *services.AuditService) *AuditController {
+	return &AuditController{
+		auditService: auditService,
+	}
+}
+
+// RouteAdmin 注册管理员接口，sv 需要已经挂载管理员鉴权
+func (c *AuditController) RouteAdmin(sv *fuego.Server) {
+	g := fuego.Group(sv, "/audit",
+		fuego.OptionDescription("审计日志接口"),
+		fuego.OptionTags("admin"),
+	)
+
+	fuego.Get(g, "/logs", c.List,
+		fuego.OptionDescription("查询审计日志，按时间倒序"),
+		fuego.OptionQuery("actor_id", "操作者用户ID"),
+		fuego.OptionQuery("action", "审计动作，例如 login.failure、user.ban"),
+		fuego.OptionQuery("since", "起始时间(RFC3339，包含)"),
+		fuego.OptionQuery("until", "结束时间(RFC3339，不包含)"),
+		fuego.OptionQueryInt("page_size", "每页数量", fuego.ParamDefault(20)),
+		fuego.OptionQuery("page_token", "分页游标"),
+	)
+	fuego.Get(g, "/verify", c.Verify, fuego.OptionDescription("校验审计日志的序号和哈希链"))
+}
+
+// List 处理查询审计日志请求
+func (c *AuditController) List(ctx fuego.ContextNoBody) (*response.AuditLogListResponse, error) {
+	req := &request.ListAuditLogsRequest{
+		ActorID:   ctx.QueryParam("actor_id"),
+		Action:    ctx.QueryParam("action"),
+		PageSize:  ctx.QueryParamInt("page_size"),
+		PageToken: ctx.QueryParam("page_token"),
+	}
+	var err error
+	if req.Since, err = parseTime("since", ctx.QueryParam("since")); err != nil {
+		return nil, err
+	}
+	if req.Until, err = parseTime("until", ctx.QueryParam("until")); err != nil {
+		return nil, err
+	}
+	return c.auditService.List(req)
+}
+
+// Verify 处理校验哈希链请求
+func (c *AuditController) Verify(ctx fuego.ContextNoBody) (*response.AuditVerifyResponse, error) {
+	return c.auditService.Verify()
+}
@@ -0,0 +1,71 @@
+package controllers
+
+import (
+	"errors"
+
+	"github.com/go-fuego/fuego"
+
+	"github.com/woxQAQ/gim/internal/apiserver/services"
+	"github.com/woxQAQ/gim/internal/apiserver/stores"
+	"github.com/woxQAQ/gim/internal/apiserver/types/request"
+	"github.com/woxQAQ/gim/internal/apiserver/types/response"
+	"github.com/woxQAQ/gim/pkg/auth"
+)
+
+// BanController 处理用户封禁相关的HTTP请求
+type BanController struct {
+	banService *services.BanService
+}
+
+// NewBanController 创建BanController实例
+func NewBanController(banService *services.BanService) *BanController {
+	return &BanController{
+		banService: banService,
+	}
+}
+
+// RouteAdmin 注册管理员接口，sv 需要已经挂载管理员鉴权
+func (c *BanController) RouteAdmin(sv *fuego.Server) {
+	g := fuego.Group(sv, "/users",
+		fuego.OptionDescription("用户封禁管理接口"),
+		fuego.OptionTags("admin"),
+	)
+
+	fuego.Post(g, "/{id}/ban", c.Ban,
+		fuego.OptionDescription("封禁用户，吊销全部登录会话并立即断开用户的所有连接"))
+	fuego.Delete(g, "/{id}/ban", c.Lift, fuego.OptionDescription("解除用户全部生效的封禁"))
+	fuego.Get(g, "/{id}/bans", c.List, fuego.OptionDescription("查询用户的封禁记录"))
+}
+
+// Ban 处理封禁用户请求
+func (c *BanController) Ban(ctx fuego.ContextWithBody[request.BanUserRequest]) (*response.BanResponse, error) {
+	req, err := ctx.Body()
+	if err != nil {
+		return nil, err
+	}
+	req.CreatedBy = auth.UserIDFromContext(ctx.Context())
+	ban, err := c.banService.Ban(ctx.Context(), ctx.PathParam("id"), &req)
+	if errors.Is(err, stores.ErrUserNotFound) {
+		return nil, fuego.NotFoundError{Title: "Not Found", Detail: err.Error(), Err: err}
+	}
+	return ban, err
+}
+
+// Lift 处理解除封禁请求
+func (c *BanController) Lift(ctx fuego.ContextWithBody[request.LiftBanRequest]) (any, error) {
+	req, err := ctx.Body()
+	if err != nil {
+		return nil, err
+	}
+	req.LiftedBy = auth.UserIDFromContext(ctx.Context())
+	err = c.banService.Lift(ctx.Context(), ctx.PathParam("id"), &req)
+	if errors.Is(err, stores.ErrBanNotFound) {
+		return nil, fuego.NotFoundError{Title: "Not Found", Detail: err.Error(), Err: err}
+	}
+	return nil, err
+}
+
+// List 处理查询封禁记录请求
+func (c *BanController) List(ctx fuego.ContextNoBody) ([]*response.BanResponse, error) {
+	return c.banService.List(ctx.PathParam("id"))
+}
@@ -0,0 +1,67 @@
+package controllers
+
+import (
+	"errors"
+
+	"github.com/go-fuego/fuego"
+
+	"github.com/woxQAQ/gim/internal/apiserver/services"
+	"github.com/woxQAQ/gim/internal/apiserver/stores"
+	"github.com/woxQAQ/gim/internal/apiserver/types/request"
+	"github.com/woxQAQ/gim/internal/apiserver/types/response"
+	"github.com/woxQAQ/gim/pkg/auth"
+)
+
+// BlockController 处理用户黑名单相关的HTTP请求
+type BlockController struct {
+	blockService *services.BlockService
+}
+
+// NewBlockController 创建BlockController实例
+func NewBlockController(blockService *services.BlockService) *BlockController {
+	return &BlockController{
+		blockService: blockService,
+	}
+}
+
+func (c *BlockController) Route(sv *fuego.Server) {
+	g := fuego.Group(sv, "/blocks",
+		fuego.OptionDescription("黑名单相关接口"),
+		fuego.OptionTags("contact"),
+	)
+
+	fuego.Get(g, "", c.List, fuego.OptionDescription("获取当前用户的黑名单"))
+	fuego.Post(g, "", c.Block,
+		fuego.OptionDescription("拉黑用户，对方的单聊消息和好友申请会被拒绝，也看不到自己的在线状态"))
+	fuego.Delete(g, "/{id}", c.Unblock, fuego.OptionDescription("将用户移出黑名单"))
+}
+
+// List 处理获取黑名单请求
+func (c *BlockController) List(ctx fuego.ContextNoBody) ([]*response.BlockResponse, error) {
+	return c.blockService.List(auth.UserIDFromContext(ctx.Context()))
+}
+
+// Block 处理拉黑用户请求
+func (c *BlockController) Block(ctx fuego.ContextWithBody[request.BlockUserRequest]) (any, error) {
+	req, err := ctx.Body()
+	if err != nil {
+		return nil, err
+	}
+	err = c.blockService.Block(auth.UserIDFromContext(ctx.Context()), req.UserID)
+	switch {
+	case errors.Is(err, services.ErrBlockSelf):
+		return nil, fuego.BadRequestError{Title: "Bad Request", Detail: err.Error(), Err: err}
+	case errors.Is(err, stores.ErrUserNotFound):
+		return nil, fuego.NotFoundError{Title: "Not Found", Detail: err.Error(), Err: err}
+	}
+	return nil, err
+}
+
+// Unblock 处理移出黑名单请求
+func (c *BlockController) Unblock(ctx fuego.ContextNoBody) (any, error) {
+	err := c.blockService.Unblock(auth.UserIDFromContext(ctx.Context()), ctx.PathParam("id"))
+	if errors.Is(err, services.ErrNotBlocked) {
+		return nil, fuego.NotFoundError{Title: "Not Found", Detail: err.Error(), Err: err}
+	}
+	return nil, err
+}
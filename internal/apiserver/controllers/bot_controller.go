@@ -0,0 +1,138 @@
+package controllers
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/go-fuego/fuego"
+
+	"github.com/woxQAQ/gim/internal/apiserver/gateway"
+	"github.com/woxQAQ/gim/internal/apiserver/services"
+	"github.com/woxQAQ/gim/internal/apiserver/stores"
+	"github.com/woxQAQ/gim/internal/apiserver/types/request"
+	"github.com/woxQAQ/gim/internal/apiserver/types/response"
+	"github.com/woxQAQ/gim/pkg/auth"
+)
+
+// BotController 处理机器人账号、API 密钥和机器人消息相关的HTTP请求
+type BotController struct {
+	botService *services.BotService
+}
+
+// NewBotController 创建BotController实例
+func NewBotController(botService *services.BotService) *BotController {
+	return &BotController{
+		botService: botService,
+	}
+}
+
+// Route 注册机器人接口，sv 需要已经挂载 API 密钥鉴权
+func (c *BotController) Route(sv *fuego.Server) {
+	g := fuego.Group(sv, "",
+		fuego.OptionDescription("机器人接口"),
+		fuego.OptionTags("bot"),
+	)
+
+	fuego.Post(g, "/messages", c.SendMessage,
+		fuego.OptionDescription("以机器人的身份向用户或群发送消息，与 WebSocket 消息经过相同的校验、投递和存储"))
+}
+
+// RouteAdmin 注册管理员接口，sv 需要已经挂载管理员鉴权
+func (c *BotController) RouteAdmin(sv *fuego.Server) {
+	g := fuego.Group(sv, "/bots",
+		fuego.OptionDescription("机器人账号管理接口"),
+		fuego.OptionTags("admin"),
+	)
+
+	fuego.Post(g, "", c.CreateBot,
+		fuego.OptionDescription("创建机器人账号，机器人不能登录，查询机器人使用 GET /admin/users?role=bot"))
+	fuego.Post(g, "/{id}/keys", c.CreateKey, fuego.OptionDescription("为机器人创建 API 密钥，密钥只在响应中返回一次"))
+	fuego.Get(g, "/{id}/keys", c.ListKeys, fuego.OptionDescription("查询机器人的 API 密钥，不包含密钥本身"))
+	fuego.Post(g, "/{id}/keys/{key_id}/rotate", c.RotateKey,
+		fuego.OptionDescription("轮换 API 密钥，旧密钥在宽限期后失效"))
+	fuego.Delete(g, "/{id}/keys/{key_id}", c.RevokeKey, fuego.OptionDescription("立即吊销 API 密钥"))
+}
+
+// CreateBot 处理创建机器人账号请求
+func (c *BotController) CreateBot(ctx fuego.ContextWithBody[request.CreateBotRequest]) (*response.UserResponse, error) {
+	req, err := ctx.Body()
+	if err != nil {
+		return nil, err
+	}
+	req.CreatedBy = auth.UserIDFromContext(ctx.Context())
+	bot, err := c.botService.CreateBot(ctx.Context(), &req)
+	if errors.Is(err, services.ErrUsernameTaken) {
+		return nil, fuego.ConflictError{Title: "Conflict", Detail: err.Error(), Err: err}
+	}
+	return bot, err
+}
+
+// CreateKey 处理创建 API 密钥请求
+func (c *BotController) CreateKey(ctx fuego.ContextWithBody[request.CreateAPIKeyRequest]) (*response.CreatedAPIKeyResponse, error) {
+	req, err := ctx.Body()
+	if err != nil {
+		return nil, err
+	}
+	req.CreatedBy = auth.UserIDFromContext(ctx.Context())
+	key, err := c.botService.CreateKey(ctx.Context(), ctx.PathParam("id"), &req)
+	return key, botError(err)
+}
+
+// ListKeys 处理查询 API 密钥请求
+func (c *BotController) ListKeys(ctx fuego.ContextNoBody) ([]*response.APIKeyResponse, error) {
+	keys, err := c.botService.ListKeys(ctx.PathParam("id"))
+	return keys, botError(err)
+}
+
+// RotateKey 处理轮换 API 密钥请求
+func (c *BotController) RotateKey(ctx fuego.ContextWithBody[request.RotateAPIKeyRequest]) (*response.CreatedAPIKeyResponse, error) {
+	req, err := ctx.Body()
+	if err != nil {
+		return nil, err
+	}
+	req.CreatedBy = auth.UserIDFromContext(ctx.Context())
+	key, err := c.botService.RotateKey(ctx.Context(), ctx.PathParam("id"), ctx.PathParam("key_id"), &req)
+	return key, botError(err)
+}
+
+// RevokeKey 处理吊销 API 密钥请求
+func (c *BotController) RevokeKey(ctx fuego.ContextNoBody) (any, error) {
+	err := c.botService.RevokeKey(ctx.Context(), ctx.PathParam("id"), ctx.PathParam("key_id"),
+		auth.UserIDFromContext(ctx.Context()))
+	return nil, botError(err)
+}
+
+// SendMessage 处理机器人发送消息请求
+func (c *BotController) SendMessage(ctx fuego.ContextWithBody[request.SendMessageRequest]) (*response.SendMessageResponse, error) {
+	req, err := ctx.Body()
+	if err != nil {
+		return nil, err
+	}
+	principal, ok := auth.APIKeyFromContext(ctx.Context())
+	if !ok {
+		return nil, fuego.UnauthorizedError{Title: "Unauthorized", Err: errors.New("missing api key")}
+	}
+	resp, err := c.botService.SendMessage(ctx.Context(), principal.UserID, &req)
+	return resp, botError(err)
+}
+
+// botError 将机器人相关的业务错误转换为HTTP错误
+func botError(err error) error {
+	switch {
+	case err == nil:
+		return nil
+	case errors.Is(err, stores.ErrUserNotFound), errors.Is(err, stores.ErrAPIKeyNotFound),
+		errors.Is(err, stores.ErrGroupNotFound), errors.Is(err, services.ErrRecipientNotFound):
+		return fuego.NotFoundError{Title: "Not Found", Detail: err.Error(), Err: err}
+	case errors.Is(err, services.ErrNotBot), errors.Is(err, services.ErrInvalidScope),
+		errors.Is(err, services.ErrInvalidMessageTarget), errors.Is(err, services.ErrInvalidMessageType),
+		errors.Is(err, services.ErrSubTypeRequired):
+		return fuego.BadRequestError{Title: "Bad Request", Detail: err.Error(), Err: err}
+	case errors.Is(err, services.ErrMessageRejected):
+		return fuego.HTTPError{Title: "message_rejected", Status: http.StatusUnprocessableEntity, Detail: err.Error(), Err: err}
+	case errors.Is(err, gateway.ErrGatewayDisabled):
+		return fuego.HTTPError{Title: "Service Unavailable", Status: http.StatusServiceUnavailable, Detail: err.Error(), Err: err}
+	default:
+		return err
+	}
+}
@@ -0,0 +1,117 @@
+package controllers
+
+import (
+	"errors"
+
+	"github.com/go-fuego/fuego"
+
+	"github.com/woxQAQ/gim/internal/apiserver/services"
+	"github.com/woxQAQ/gim/internal/apiserver/stores"
+	"github.com/woxQAQ/gim/internal/apiserver/types/request"
+	"github.com/woxQAQ/gim/internal/apiserver/types/response"
+	"github.com/woxQAQ/gim/pkg/auth"
+)
+
+// ContactController 处理好友申请和联系人相关的HTTP请求
+type ContactController struct {
+	contactService *services.ContactService
+}
+
+// NewContactController 创建ContactController实例
+func NewContactController(contactService *services.ContactService) *ContactController {
+	return &ContactController{
+		contactService: contactService,
+	}
+}
+
+func (c *ContactController) Route(sv *fuego.Server) {
+	g := fuego.Group(sv, "/contacts",
+		fuego.OptionDescription("联系人相关接口"),
+		fuego.OptionTags("contact"),
+	)
+
+	fuego.Get(g, "", c.List, fuego.OptionDescription("获取当前用户的联系人及在线状态"))
+	fuego.Delete(g, "/{id}", c.Delete, fuego.OptionDescription("删除联系人，对方的联系人列表中同时删除"))
+	fuego.Put(g, "/{id}/remark", c.SetRemark, fuego.OptionDescription("设置联系人备注，只对自己可见"))
+	fuego.Post(g, "/requests", c.SendRequest,
+		fuego.OptionDescription("发送好友申请，对方也申请过时直接成为联系人"))
+	fuego.Get(g, "/requests", c.ListRequests,
+		fuego.OptionDescription("获取收到或发出的好友申请"),
+		fuego.OptionQuery("direction", "incoming 为收到的申请，outgoing 为发出的申请", fuego.ParamDefault("incoming")),
+		fuego.OptionQuery("before", "分页游标，返回ID小于该值的申请"),
+		fuego.OptionQueryInt("page_size", "每页数量", fuego.ParamDefault(20)),
+	)
+	fuego.Post(g, "/requests/{id}/accept", c.Accept, fuego.OptionDescription("同意好友申请"))
+	fuego.Post(g, "/requests/{id}/reject", c.Reject, fuego.OptionDescription("拒绝好友申请"))
+}
+
+// List 处理获取联系人请求
+func (c *ContactController) List(ctx fuego.ContextNoBody) ([]*response.ContactResponse, error) {
+	return c.contactService.ListContacts(ctx.Context(), auth.UserIDFromContext(ctx.Context()))
+}
+
+// Delete 处理删除联系人请求
+func (c *ContactController) Delete(ctx fuego.ContextNoBody) (any, error) {
+	return contactError[any](nil, c.contactService.Delete(ctx.Context(), auth.UserIDFromContext(ctx.Context()), ctx.PathParam("id")))
+}
+
+// SetRemark 处理设置联系人备注请求
+func (c *ContactController) SetRemark(ctx fuego.ContextWithBody[request.SetContactRemarkRequest]) (any, error) {
+	req, err := ctx.Body()
+	if err != nil {
+		return nil, err
+	}
+	return contactError[any](nil, c.contactService.SetRemark(auth.UserIDFromContext(ctx.Context()), ctx.PathParam("id"), req.Remark))
+}
+
+// SendRequest 处理发送好友申请请求
+func (c *ContactController) SendRequest(ctx fuego.ContextWithBody[request.SendFriendRequestRequest]) (*response.FriendRequestResponse, error) {
+	req, err := ctx.Body()
+	if err != nil {
+		return nil, err
+	}
+	return contactError(c.contactService.SendRequest(ctx.Context(), auth.UserIDFromContext(ctx.Context()), req.ToID, req.Message))
+}
+
+// ListRequests 处理获取好友申请请求
+func (c *ContactController) ListRequests(ctx fuego.ContextNoBody) ([]*response.FriendRequestResponse, error) {
+	var incoming bool
+	switch ctx.QueryParam("direction") {
+	case "", "incoming":
+		incoming = true
+	case "outgoing":
+	default:
+		return nil, fuego.BadRequestError{Title: "direction 只能为 incoming 或 outgoing", Err: errors.New("invalid direction")}
+	}
+	return c.contactService.ListRequests(auth.UserIDFromContext(ctx.Context()), incoming,
+		ctx.QueryParam("before"), ctx.QueryParamInt("page_size"))
+}
+
+// Accept 处理同意好友申请请求
+func (c *ContactController) Accept(ctx fuego.ContextNoBody) (*response.FriendRequestResponse, error) {
+	return contactError(c.contactService.Accept(ctx.Context(), auth.UserIDFromContext(ctx.Context()), ctx.PathParam("id")))
+}
+
+// Reject 处理拒绝好友申请请求
+func (c *ContactController) Reject(ctx fuego.ContextNoBody) (*response.FriendRequestResponse, error) {
+	return contactError(c.contactService.Reject(ctx.Context(), auth.UserIDFromContext(ctx.Context()), ctx.PathParam("id")))
+}
+
+// contactError 将联系人相关的业务错误转换为对应的HTTP错误
+func contactError[T any](v T, err error) (T, error) {
+	switch {
+	case err == nil:
+		return v, nil
+	case errors.Is(err, stores.ErrUserNotFound),
+		errors.Is(err, stores.ErrFriendRequestNotFound),
+		errors.Is(err, stores.ErrContactNotFound):
+		return v, fuego.NotFoundError{Title: "Not Found", Detail: err.Error(), Err: err}
+	case errors.Is(err, services.ErrAlreadyContacts), errors.Is(err, services.ErrFriendRequestHandled):
+		return v, fuego.ConflictError{Title: "Conflict", Detail: err.Error(), Err: err}
+	case errors.Is(err, services.ErrBlocked):
+		return v, fuego.ForbiddenError{Title: "Forbidden", Detail: err.Error(), Err: err}
+	case errors.Is(err, services.ErrFriendRequestSelf):
+		return v, fuego.BadRequestError{Title: "Bad Request", Detail: err.Error(), Err: err}
+	}
+	return v, err
+}
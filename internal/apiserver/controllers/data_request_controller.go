@@ -0,0 +1,84 @@
+package controllers
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/go-fuego/fuego"
+
+	"github.com/woxQAQ/gim/internal/apiserver/services"
+	"github.com/woxQAQ/gim/internal/apiserver/stores"
+	"github.com/woxQAQ/gim/internal/apiserver/types/request"
+	"github.com/woxQAQ/gim/internal/apiserver/types/response"
+	"github.com/woxQAQ/gim/pkg/auth"
+)
+
+// DataRequestController 处理用户数据导出与删除相关的HTTP请求
+type DataRequestController struct {
+	dataRequestService *services.DataRequestService
+}
+
+// NewDataRequestController 创建DataRequestController实例
+func NewDataRequestController(dataRequestService *services.DataRequestService) *DataRequestController {
+	return &DataRequestController{
+		dataRequestService: dataRequestService,
+	}
+}
+
+func (c *DataRequestController) Route(sv *fuego.Server) {
+	g := fuego.Group(sv, "/data-requests",
+		fuego.OptionDescription("用户数据导出与删除接口"),
+		fuego.OptionTags("privacy"),
+	)
+
+	fuego.Post(g, "", c.Create, fuego.OptionDescription("为当前用户提交数据导出或删除任务，任务在后台执行"))
+	fuego.Get(g, "", c.ListByUser, fuego.OptionDescription("查询当前用户的数据任务"))
+	fuego.Get(g, "/{id}", c.Get, fuego.OptionDescription("查询数据任务状态"))
+	fuego.GetStd(g, "/{id}/download", c.Download, fuego.OptionDescription("下载导出的数据文件"))
+}
+
+// Create 处理创建数据任务请求
+func (c *DataRequestController) Create(ctx fuego.ContextWithBody[request.CreateDataRequestRequest]) (*response.DataRequestResponse, error) {
+	req, err := ctx.Body()
+	if err != nil {
+		return nil, err
+	}
+	req.UserID = auth.UserIDFromContext(ctx.Context())
+	req.RequestedBy = req.UserID
+	return c.dataRequestService.Create(ctx.Context(), &req)
+}
+
+// ListByUser 处理查询用户数据任务请求
+func (c *DataRequestController) ListByUser(ctx fuego.ContextNoBody) ([]*response.DataRequestResponse, error) {
+	return c.dataRequestService.ListByUser(auth.UserIDFromContext(ctx.Context()))
+}
+
+// Get 处理查询数据任务状态请求
+func (c *DataRequestController) Get(ctx fuego.ContextNoBody) (*response.DataRequestResponse, error) {
+	resp, err := c.dataRequestService.Get(auth.UserIDFromContext(ctx.Context()), ctx.PathParam("id"))
+	if errors.Is(err, stores.ErrDataRequestNotFound) {
+		return nil, fuego.NotFoundError{Title: "Not Found", Detail: err.Error(), Err: err}
+	}
+	return resp, err
+}
+
+// Download 处理下载导出文件请求
+func (c *DataRequestController) Download(w http.ResponseWriter, r *http.Request) {
+	f, dr, err := c.dataRequestService.OpenExport(auth.UserIDFromContext(r.Context()), r.PathValue("id"))
+	if err != nil {
+		status := http.StatusInternalServerError
+		switch {
+		case errors.Is(err, services.ErrExportNotReady):
+			status = http.StatusConflict
+		case errors.Is(err, stores.ErrDataRequestNotFound):
+			status = http.StatusNotFound
+		}
+		http.Error(w, err.Error(), status)
+		return
+	}
+	defer f.Close()
+
+	w.Header().Set("Content-Type", "application/zip")
+	w.Header().Set("Content-Disposition", `attachment; filename="gim-export-`+dr.UserID+`.zip"`)
+	http.ServeContent(w, r, "", dr.UpdatedAt, f)
+}
@@ -1,11 +1,17 @@
 package controllers
 
 import (
+	"errors"
+	"strconv"
+	"strings"
+	"time"
+
 	"github.com/go-fuego/fuego"
 
 	"github.com/woxQAQ/gim/internal/apiserver/services"
 	"github.com/woxQAQ/gim/internal/apiserver/types/request"
 	"github.com/woxQAQ/gim/internal/apiserver/types/response"
+	"github.com/woxQAQ/gim/pkg/auth"
 )
 
 // MessageController 处理消息相关的HTTP请求
@@ -26,16 +32,108 @@ func (c *MessageController) Route(sv *fuego.Server) {
 		fuego.OptionTags("message"),
 	)
 
-	fuego.Get(g, "/history", c.GetMessageHistory, fuego.OptionDescription("获取消息历史记录"))
+	fuego.Get(g, "/history", c.GetMessageHistory,
+		fuego.OptionDescription("获取消息历史记录"),
+		fuego.OptionQuery("peer_id", "对方用户ID，返回两人之间的单聊记录"),
+		fuego.OptionQuery("conversation_id", "会话ID，未指定会话时返回当前用户收发的全部消息"),
+		fuego.OptionQuery("types", "消息类型，逗号分隔"),
+		fuego.OptionQuery("since", "起始时间(RFC3339，包含)"),
+		fuego.OptionQuery("until", "结束时间(RFC3339，不包含)"),
+		fuego.OptionQuery("before", "返回ID小于该值的消息，按ID倒序"),
+		fuego.OptionQuery("after", "返回ID大于该值的消息，按ID正序"),
+		fuego.OptionQueryInt("page_size", "每页数量", fuego.ParamDefault(20)),
+	)
+	fuego.Get(g, "/search", c.SearchMessages,
+		fuego.OptionDescription("在当前用户参与的会话中按关键词搜索消息"),
+		fuego.OptionQuery("q", "关键词", fuego.ParamRequired()),
+		fuego.OptionQuery("conversation_id", "会话ID"),
+		fuego.OptionQuery("types", "消息类型，逗号分隔"),
+		fuego.OptionQuery("since", "起始时间(RFC3339，包含)"),
+		fuego.OptionQuery("until", "结束时间(RFC3339，不包含)"),
+		fuego.OptionQuery("before", "分页游标，返回ID小于该值的消息"),
+		fuego.OptionQueryInt("page_size", "每页数量", fuego.ParamDefault(20)),
+	)
 }
 
 // GetMessageHistory 处理获取消息历史记录请求
-func (c *MessageController) GetMessageHistory(ctx fuego.ContextWithBody[request.GetMessageHistoryRequest]) (*response.MessageHistoryResponse, error) {
-	req, err := ctx.Body()
-	if err != nil {
+func (c *MessageController) GetMessageHistory(ctx fuego.ContextNoBody) (*response.MessageHistoryResponse, error) {
+	req := &request.GetMessageHistoryRequest{
+		UserID:         auth.UserIDFromContext(ctx.Context()),
+		PeerID:         ctx.QueryParam("peer_id"),
+		ConversationID: ctx.QueryParam("conversation_id"),
+		Before:         ctx.QueryParam("before"),
+		After:          ctx.QueryParam("after"),
+		PageSize:       ctx.QueryParamInt("page_size"),
+	}
+	var err error
+	if req.Types, err = parseTypes(ctx.QueryParam("types")); err != nil {
+		return nil, err
+	}
+	if req.Since, err = parseTime("since", ctx.QueryParam("since")); err != nil {
+		return nil, err
+	}
+	if req.Until, err = parseTime("until", ctx.QueryParam("until")); err != nil {
 		return nil, err
 	}
 
 	// 调用service层获取消息历史记录
-	return c.messageService.GetMessageHistory(req.UserID, req.PageSize, req.PageToken)
+	return forbidden(c.messageService.GetMessageHistory(req))
+}
+
+// SearchMessages 处理搜索消息请求
+func (c *MessageController) SearchMessages(ctx fuego.ContextNoBody) (*response.MessageSearchResponse, error) {
+	req := &request.SearchMessagesRequest{
+		UserID:         auth.UserIDFromContext(ctx.Context()),
+		Keyword:        ctx.QueryParam("q"),
+		ConversationID: ctx.QueryParam("conversation_id"),
+		Before:         ctx.QueryParam("before"),
+		PageSize:       ctx.QueryParamInt("page_size"),
+	}
+	var err error
+	if req.Types, err = parseTypes(ctx.QueryParam("types")); err != nil {
+		return nil, err
+	}
+	if req.Since, err = parseTime("since", ctx.QueryParam("since")); err != nil {
+		return nil, err
+	}
+	if req.Until, err = parseTime("until", ctx.QueryParam("until")); err != nil {
+		return nil, err
+	}
+	return forbidden(c.messageService.SearchMessages(req))
+}
+
+// forbidden 将无权访问会话的错误转换为 403
+func forbidden[T any](v T, err error) (T, error) {
+	if errors.Is(err, services.ErrConversationForbidden) {
+		return v, fuego.ForbiddenError{Title: "Forbidden", Detail: err.Error(), Err: err}
+	}
+	return v, err
+}
+
+// parseTypes 解析逗号分隔的消息类型
+func parseTypes(raw string) ([]int32, error) {
+	if raw == "" {
+		return nil, nil
+	}
+	var out []int32
+	for _, part := range strings.Split(raw, ",") {
+		t, err := strconv.ParseInt(strings.TrimSpace(part), 10, 32)
+		if err != nil {
+			return nil, fuego.BadRequestError{Title: "types 格式错误", Err: err}
+		}
+		out = append(out, int32(t))
+	}
+	return out, nil
+}
+
+// parseTime 解析 RFC3339 格式的时间参数，为空时返回 nil
+func parseTime(name, raw string) (*time.Time, error) {
+	if raw == "" {
+		return nil, nil
+	}
+	t, err := time.Parse(time.RFC3339, raw)
+	if err != nil {
+		return nil, fuego.BadRequestError{Title: name + " 格式错误", Err: err}
+	}
+	return &t, nil
 }
@@ -0,0 +1,56 @@
+package controllers
+
+import (
+	"github.com/go-fuego/fuego"
+
+	"github.com/woxQAQ/gim/internal/apiserver/services"
+	"github.com/woxQAQ/gim/internal/apiserver/types/request"
+	"github.com/woxQAQ/gim/internal/apiserver/types/response"
+	"github.com/woxQAQ/gim/pkg/auth"
+)
+
+// ModerationController 处理内容审核相关的HTTP请求
+type ModerationController struct {
+	moderationService *services.ModerationService
+}
+
+// NewModerationController 创建ModerationController实例
+func NewModerationController(moderationService *services.ModerationService) *ModerationController {
+	return &ModerationController{
+		moderationService: moderationService,
+	}
+}
+
+// Route 注册审核接口，sv 需要已经挂载审核员鉴权
+func (c *ModerationController) Route(sv *fuego.Server) {
+	g := fuego.Group(sv, "/moderation",
+		fuego.OptionDescription("内容审核相关接口"),
+		fuego.OptionTags("moderation"),
+	)
+
+	fuego.Get(g, "/records", c.ListRecords,
+		fuego.OptionDescription("查询被内容过滤标记的消息"),
+		fuego.OptionQuery("status", "审核状态: pending, approved, removed"),
+		fuego.OptionQueryInt("page_size", "每页数量", fuego.ParamDefault(20)),
+		fuego.OptionQuery("page_token", "分页游标"),
+	)
+	fuego.Post(g, "/records/{id}/review", c.Review, fuego.OptionDescription("审核被标记的消息"))
+}
+
+// ListRecords 处理查询审核记录请求
+func (c *ModerationController) ListRecords(ctx fuego.ContextNoBody) (*response.ModerationRecordListResponse, error) {
+	return c.moderationService.ListRecords(
+		ctx.QueryParam("status"),
+		ctx.QueryParamInt("page_size"),
+		ctx.QueryParam("page_token"),
+	)
+}
+
+// Review 处理审核请求
+func (c *ModerationController) Review(ctx fuego.ContextWithBody[request.ReviewModerationRequest]) (*response.ModerationRecordResponse, error) {
+	req, err := ctx.Body()
+	if err != nil {
+		return nil, err
+	}
+	return c.moderationService.Review(ctx.PathParam("id"), req.Status, auth.UserIDFromContext(ctx.Context()))
+}
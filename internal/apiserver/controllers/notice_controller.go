@@ -0,0 +1,85 @@
+package controllers
+
+import (
+	"github.com/go-fuego/fuego"
+
+	"github.com/woxQAQ/gim/internal/apiserver/services"
+	"github.com/woxQAQ/gim/internal/apiserver/types/request"
+	"github.com/woxQAQ/gim/internal/apiserver/types/response"
+	"github.com/woxQAQ/gim/pkg/auth"
+)
+
+// NoticeController 处理系统通知相关的HTTP请求
+type NoticeController struct {
+	noticeService *services.NoticeService
+}
+
+// NewNoticeController 创建NoticeController实例
+func NewNoticeController(noticeService *services.NoticeService) *NoticeController {
+	return &NoticeController{
+		noticeService: noticeService,
+	}
+}
+
+func (c *NoticeController) Route(sv *fuego.Server) {
+	g := fuego.Group(sv, "/notices",
+		fuego.OptionDescription("系统通知相关接口"),
+		fuego.OptionTags("notice"),
+	)
+
+	fuego.Get(g, "", c.ListForUser,
+		fuego.OptionDescription("拉取当前用户的离线系统通知"),
+		fuego.OptionQueryInt("page_size", "每页数量", fuego.ParamDefault(20)),
+		fuego.OptionQuery("page_token", "分页游标"),
+	)
+}
+
+// RouteAdmin 注册管理员接口，sv 需要已经挂载管理员鉴权
+func (c *NoticeController) RouteAdmin(sv *fuego.Server) {
+	g := fuego.Group(sv, "/notices",
+		fuego.OptionDescription("系统通知管理接口"),
+		fuego.OptionTags("admin"),
+	)
+
+	fuego.Post(g, "", c.Create, fuego.OptionDescription("推送或定时推送系统通知"))
+	fuego.Get(g, "", c.List,
+		fuego.OptionDescription("查询系统通知"),
+		fuego.OptionQuery("status", "通知状态: scheduled, sent, failed, canceled"),
+		fuego.OptionQueryInt("page_size", "每页数量", fuego.ParamDefault(20)),
+		fuego.OptionQuery("page_token", "分页游标"),
+	)
+	fuego.Post(g, "/{id}/cancel", c.Cancel, fuego.OptionDescription("取消定时通知"))
+}
+
+// Create 处理创建系统通知请求
+func (c *NoticeController) Create(ctx fuego.ContextWithBody[request.CreateNoticeRequest]) (*response.NoticeResponse, error) {
+	req, err := ctx.Body()
+	if err != nil {
+		return nil, err
+	}
+	req.CreatedBy = auth.UserIDFromContext(ctx.Context())
+	return c.noticeService.Create(ctx, &req)
+}
+
+// List 处理查询系统通知请求
+func (c *NoticeController) List(ctx fuego.ContextNoBody) (*response.NoticeListResponse, error) {
+	return c.noticeService.List(
+		ctx.QueryParam("status"),
+		ctx.QueryParamInt("page_size"),
+		ctx.QueryParam("page_token"),
+	)
+}
+
+// Cancel 处理取消定时通知请求
+func (c *NoticeController) Cancel(ctx fuego.ContextNoBody) (any, error) {
+	return nil, c.noticeService.Cancel(ctx.PathParam("id"))
+}
+
+// ListForUser 处理拉取用户系统通知请求
+func (c *NoticeController) ListForUser(ctx fuego.ContextNoBody) (*response.NoticeListResponse, error) {
+	return c.noticeService.ListForUser(
+		auth.UserIDFromContext(ctx.Context()),
+		ctx.QueryParamInt("page_size"),
+		ctx.QueryParam("page_token"),
+	)
+}
@@ -0,0 +1,73 @@
+package controllers
+
+import (
+	"github.com/go-fuego/fuego"
+
+	"github.com/woxQAQ/gim/internal/apiserver/services"
+	"github.com/woxQAQ/gim/internal/apiserver/types/request"
+	"github.com/woxQAQ/gim/internal/apiserver/types/response"
+	"github.com/woxQAQ/gim/pkg/auth"
+)
+
+// RetentionController 处理消息保留策略相关的HTTP请求
+type RetentionController struct {
+	retentionService *services.RetentionService
+}
+
+// NewRetentionController 创建RetentionController实例
+func NewRetentionController(retentionService *services.RetentionService) *RetentionController {
+	return &RetentionController{
+		retentionService: retentionService,
+	}
+}
+
+// RouteAdmin 注册管理员接口，sv 需要已经挂载管理员鉴权
+func (c *RetentionController) RouteAdmin(sv *fuego.Server) {
+	g := fuego.Group(sv, "/retention",
+		fuego.OptionDescription("消息保留策略管理接口"),
+		fuego.OptionTags("admin"),
+	)
+
+	fuego.Post(g, "/policies", c.CreatePolicy, fuego.OptionDescription("创建或覆盖保留策略"))
+	fuego.Get(g, "/policies", c.ListPolicies, fuego.OptionDescription("查询保留策略"))
+	fuego.Delete(g, "/policies/{id}", c.DeletePolicy, fuego.OptionDescription("删除保留策略"))
+	fuego.Get(g, "/runs", c.ListRuns,
+		fuego.OptionDescription("查询过期清理记录"),
+		fuego.OptionQueryInt("page_size", "每页数量", fuego.ParamDefault(20)),
+		fuego.OptionQuery("page_token", "分页游标"),
+	)
+	fuego.Post(g, "/runs", c.Purge, fuego.OptionDescription("立即执行一次过期清理"))
+}
+
+// CreatePolicy 处理创建保留策略请求
+func (c *RetentionController) CreatePolicy(ctx fuego.ContextWithBody[request.CreateRetentionPolicyRequest]) (*response.RetentionPolicyResponse, error) {
+	req, err := ctx.Body()
+	if err != nil {
+		return nil, err
+	}
+	req.CreatedBy = auth.UserIDFromContext(ctx.Context())
+	return c.retentionService.CreatePolicy(&req)
+}
+
+// ListPolicies 处理查询保留策略请求
+func (c *RetentionController) ListPolicies(ctx fuego.ContextNoBody) ([]*response.RetentionPolicyResponse, error) {
+	return c.retentionService.ListPolicies()
+}
+
+// DeletePolicy 处理删除保留策略请求
+func (c *RetentionController) DeletePolicy(ctx fuego.ContextNoBody) (any, error) {
+	return nil, c.retentionService.DeletePolicy(ctx.PathParam("id"))
+}
+
+// ListRuns 处理查询清理记录请求
+func (c *RetentionController) ListRuns(ctx fuego.ContextNoBody) (*response.RetentionRunListResponse, error) {
+	return c.retentionService.ListRuns(
+		ctx.QueryParamInt("page_size"),
+		ctx.QueryParam("page_token"),
+	)
+}
+
+// Purge 处理立即清理请求
+func (c *RetentionController) Purge(ctx fuego.ContextNoBody) (*response.RetentionRunResponse, error) {
+	return c.retentionService.Purge(ctx)
+}
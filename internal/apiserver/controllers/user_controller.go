@@ -1,35 +1,93 @@
 package controllers
 
 import (
+	"errors"
+	"io"
+	"io/fs"
+	"math"
+	"net/http"
+	"strconv"
 	"time"
 
 	"github.com/go-fuego/fuego"
-	"github.com/golang-jwt/jwt/v5"
 
 	"github.com/woxQAQ/gim/internal/apiserver/services"
+	"github.com/woxQAQ/gim/internal/apiserver/stores"
 	"github.com/woxQAQ/gim/internal/apiserver/types/request"
 	"github.com/woxQAQ/gim/internal/apiserver/types/response"
 	"github.com/woxQAQ/gim/internal/models"
+	"github.com/woxQAQ/gim/pkg/auth"
 )
 
 // UserController 处理用户相关的HTTP请求
 type UserController struct {
-	userService *services.UserService
+	userService    *services.UserService
+	sessionService *services.SessionService
+	emailService   *services.EmailService
 }
 
+// Route 注册用户接口，注册与登录不需要访问令牌
 func (c *UserController) Route(sv *fuego.Server) {
 	g := fuego.Group(sv, "/users",
 		fuego.OptionDescription("用户相关接口"),
 		fuego.OptionTags("user"),
 	)
 	fuego.Post(g, "/register", c.Register, fuego.OptionDescription("注册用户"))
-	fuego.Post(g, "/login", c.Login, fuego.OptionDescription("用户登录"))
+	fuego.Post(g, "/login", c.Login, fuego.OptionDescription("用户登录，每次登录创建一个新的会话"))
+	fuego.Post(g, "/refresh", c.Refresh, fuego.OptionDescription("使用刷新令牌换取新的访问令牌，刷新令牌同时轮换"))
+	fuego.Post(g, "/email/verify", c.VerifyEmail, fuego.OptionDescription("使用验证邮件中的令牌验证邮箱"))
+	fuego.Post(g, "/email/verification", c.ResendVerification,
+		fuego.OptionDescription("向未验证的邮箱重新发送验证邮件，邮箱是否注册都返回成功"))
+	fuego.Post(g, "/password/forgot", c.ForgotPassword,
+		fuego.OptionDescription("向邮箱发送重置密码邮件，邮箱是否注册都返回成功"))
+	fuego.Post(g, "/password/reset", c.ResetPassword,
+		fuego.OptionDescription("使用重置密码邮件中的令牌设置新密码，用户的全部会话随之失效"))
+}
+
+// RouteAuthed 注册需要访问令牌的用户接口
+func (c *UserController) RouteAuthed(sv *fuego.Server) {
+	g := fuego.Group(sv, "/users",
+		fuego.OptionDescription("用户相关接口"),
+		fuego.OptionTags("user"),
+	)
+	fuego.Post(g, "/logout", c.Logout, fuego.OptionDescription("登出当前会话并断开该平台的连接"))
+	fuego.Get(g, "/me", c.GetSelf, fuego.OptionDescription("获取当前用户的资料"))
+	fuego.Patch(g, "/me", c.UpdateProfile, fuego.OptionDescription("修改当前用户的资料，只修改请求中给出的字段"))
+	fuego.Put(g, "/me/password", c.ChangePassword,
+		fuego.OptionDescription("校验当前密码后修改密码，其他登录会话随之失效"))
+	fuego.Post(g, "/me/email/verification", c.SendVerification, fuego.OptionDescription("向当前用户的邮箱发送验证邮件"))
+	fuego.Put(g, "/me/avatar", c.UploadAvatar,
+		fuego.OptionDescription("上传头像图片，使用 multipart/form-data 的 avatar 字段"),
+		fuego.OptionRequestContentType("multipart/form-data"),
+	)
+	fuego.Post(g, "/batch", c.BatchGet,
+		fuego.OptionDescription("批量获取用户资料，不存在的用户被忽略，只有本人和管理员可以看到邮箱和手机号"))
+	fuego.Get(g, "/{id}", c.GetByID, fuego.OptionDescription("获取用户资料，只有本人和管理员可以看到邮箱和手机号"))
+	fuego.GetStd(g, "/{id}/avatar", c.GetAvatar, fuego.OptionDescription("获取用户头像图片，外部地址的头像重定向到该地址"))
+}
+
+// RouteAdmin 注册管理员接口，sv 需要已经挂载管理员鉴权
+func (c *UserController) RouteAdmin(sv *fuego.Server) {
+	g := fuego.Group(sv, "/users",
+		fuego.OptionDescription("用户管理接口"),
+		fuego.OptionTags("admin"),
+	)
+	fuego.Get(g, "", c.Search,
+		fuego.OptionDescription("按用户名、昵称或邮箱查询用户"),
+		fuego.OptionQuery("q", "查询关键字"),
+		fuego.OptionQuery("role", "角色: user, moderator, admin, bot"),
+		fuego.OptionQueryInt("page_size", "每页数量", fuego.ParamDefault(20)),
+		fuego.OptionQuery("page_token", "分页游标"),
+	)
+	fuego.Put(g, "/{id}/role", c.SetRole, fuego.OptionDescription("修改用户角色，立即生效"))
 }
 
 // NewUserController 创建UserController实例
-func NewUserController(userService *services.UserService) *UserController {
+func NewUserController(userService *services.UserService, sessionService *services.SessionService, emailService *services.EmailService) *UserController {
 	return &UserController{
-		userService: userService,
+		userService:    userService,
+		sessionService: sessionService,
+		emailService:   emailService,
 	}
 }
 
@@ -48,9 +106,19 @@ func (uc *UserController) Register(c fuego.ContextWithBody[request.RegisterReque
 	}
 
 	// 调用service层处理注册逻辑
-	err = uc.userService.Register(user)
-	if err != nil {
-		return nil, err
+	err = uc.userService.Register(c.Context(), user, req.CaptchaToken)
+	switch {
+	case errors.Is(err, services.ErrUsernameTaken), errors.Is(err, services.ErrEmailTaken):
+		return nil, fuego.ConflictError{Title: "Conflict", Detail: err.Error(), Err: err}
+	case errors.Is(err, services.ErrEmailRequired):
+		return nil, fuego.BadRequestError{Title: "Bad Request", Detail: err.Error(), Err: err}
+	case err != nil:
+		return nil, guardError(c.Response(), err)
+	}
+
+	// 注册时提供了邮箱则发送验证邮件，发送失败时用户可以稍后重新发送
+	if user.Email != "" && uc.emailService.Enabled() {
+		_ = uc.emailService.SendVerification(c.Context(), user.ID)
 	}
 	return user.ToResponse(), nil
 }
@@ -62,27 +130,284 @@ func (uc *UserController) Login(c fuego.ContextWithBody[request.LoginRequest]) (
 		return nil, err
 	}
 
-	token, err := uc.generateToken(req.UserId)
-	if err != nil {
-		return nil, fuego.InternalServerError{}
+	// 调用service层处理登录逻辑
+	user, err := uc.userService.Login(c.Context(), req.Username, req.Password, req.CaptchaToken)
+	switch {
+	case errors.Is(err, services.ErrInvalidCredentials):
+		return nil, fuego.UnauthorizedError{Title: "Unauthorized", Detail: err.Error(), Err: err}
+	case errors.Is(err, services.ErrAccountDisabled), errors.Is(err, services.ErrAccountBanned):
+		return nil, fuego.ForbiddenError{Title: "Forbidden", Detail: err.Error(), Err: err}
+	case errors.Is(err, services.ErrEmailNotVerified):
+		return nil, fuego.ForbiddenError{Title: "email_not_verified", Detail: err.Error(), Err: err}
+	case err != nil:
+		return nil, guardError(c.Response(), err)
 	}
 
-	// 调用service层处理登录逻辑
-	user, err := uc.userService.Login(req.Username, req.Password)
+	// 为通过校验的用户创建会话并签发令牌
+	platform := req.Platform
+	if platform == 0 {
+		platform = 1
+	}
+	tokens, err := uc.sessionService.Start(user.ID, platform, req.DeviceID)
 	if err != nil {
 		return nil, err
 	}
 
 	return &response.LoginResponse{
-		Token: token,
-		User:  user,
+		TokenResponse: *tokens,
+		User:          user,
 	}, nil
 }
 
-func (uc *UserController) generateToken(userID string) (string, error) {
-	token := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.MapClaims{
-		"user_id": userID,
-		"exp":     time.Now().Add(24 * time.Hour).Unix(),
-	})
-	return token.SignedString([]byte(""))
+// guardError 将登录和注册的频率限制转换为带错误码的响应，Title 为客户端可以识别的错误码，
+// 被限制时通过 Retry-After 告知需要等待的秒数
+func guardError(w http.ResponseWriter, err error) error {
+	var throttle *services.ThrottleError
+	switch {
+	case errors.As(err, &throttle):
+		secs := int64(math.Ceil(throttle.RetryAfter.Seconds()))
+		w.Header().Set("Retry-After", strconv.FormatInt(max(secs, 1), 10))
+		return fuego.HTTPError{Title: throttle.Code, Status: http.StatusTooManyRequests, Detail: err.Error(), Err: err}
+	case errors.Is(err, services.ErrCaptchaRequired):
+		return fuego.HTTPError{Title: "captcha_required", Status: http.StatusPreconditionRequired, Detail: err.Error(), Err: err}
+	case errors.Is(err, services.ErrCaptchaInvalid):
+		return fuego.ForbiddenError{Title: "captcha_invalid", Detail: err.Error(), Err: err}
+	default:
+		return err
+	}
+}
+
+// Refresh 处理刷新访问令牌请求
+func (uc *UserController) Refresh(c fuego.ContextWithBody[request.RefreshTokenRequest]) (*response.TokenResponse, error) {
+	req, err := c.Body()
+	if err != nil {
+		return nil, err
+	}
+	tokens, err := uc.sessionService.Refresh(req.RefreshToken)
+	if errors.Is(err, services.ErrInvalidRefreshToken) {
+		return nil, fuego.UnauthorizedError{Title: "Unauthorized", Detail: err.Error(), Err: err}
+	}
+	return tokens, err
+}
+
+// Logout 处理登出请求
+func (uc *UserController) Logout(c fuego.ContextNoBody) (any, error) {
+	claims, ok := auth.ClaimsFromContext(c.Context())
+	if !ok {
+		return nil, fuego.UnauthorizedError{Title: "Unauthorized", Err: errors.New("missing token")}
+	}
+	return nil, uc.sessionService.Logout(c.Context(), claims)
+}
+
+// GetSelf 处理获取当前用户资料请求
+func (uc *UserController) GetSelf(c fuego.ContextNoBody) (*response.UserResponse, error) {
+	return userResponse(uc.userService.GetUserByID(auth.UserIDFromContext(c.Context())))
+}
+
+// GetByID 处理获取用户资料请求
+func (uc *UserController) GetByID(c fuego.ContextNoBody) (*response.UserResponse, error) {
+	user, err := uc.userService.GetUserProfile(auth.UserIDFromContext(c.Context()), c.PathParam("id"))
+	if errors.Is(err, stores.ErrUserNotFound) {
+		return nil, fuego.NotFoundError{Title: "Not Found", Detail: err.Error(), Err: err}
+	}
+	return user, err
+}
+
+// BatchGet 处理批量获取用户资料请求
+func (uc *UserController) BatchGet(c fuego.ContextWithBody[request.BatchGetUsersRequest]) ([]*response.UserResponse, error) {
+	req, err := c.Body()
+	if err != nil {
+		return nil, err
+	}
+	return uc.userService.BatchGetUsers(auth.UserIDFromContext(c.Context()), req.IDs)
+}
+
+// UpdateProfile 处理修改用户资料请求
+func (uc *UserController) UpdateProfile(c fuego.ContextWithBody[request.UpdateProfileRequest]) (*response.UserResponse, error) {
+	req, err := c.Body()
+	if err != nil {
+		return nil, err
+	}
+	user, err := uc.userService.UpdateProfile(auth.UserIDFromContext(c.Context()), &req)
+	if errors.Is(err, services.ErrEmailTaken) {
+		return nil, fuego.ConflictError{Title: "Conflict", Detail: err.Error(), Err: err}
+	}
+	return userResponse(user, err)
+}
+
+// ChangePassword 处理修改密码请求
+func (uc *UserController) ChangePassword(c fuego.ContextWithBody[request.ChangePasswordRequest]) (any, error) {
+	req, err := c.Body()
+	if err != nil {
+		return nil, err
+	}
+	claims, ok := auth.ClaimsFromContext(c.Context())
+	if !ok {
+		return nil, fuego.UnauthorizedError{Title: "Unauthorized", Err: errors.New("missing token")}
+	}
+	err = uc.userService.ChangePassword(c.Context(), claims.UserID(), req.OldPassword, req.NewPassword)
+	if errors.Is(err, services.ErrWrongPassword) {
+		return nil, fuego.ForbiddenError{Title: "Forbidden", Detail: err.Error(), Err: err}
+	}
+	if err != nil {
+		return nil, err
+	}
+	return nil, uc.sessionService.RevokeOtherSessions(c.Context(), claims, "password changed")
+}
+
+// SendVerification 处理向当前用户的邮箱发送验证邮件请求
+func (uc *UserController) SendVerification(c fuego.ContextNoBody) (any, error) {
+	claims, ok := auth.ClaimsFromContext(c.Context())
+	if !ok {
+		return nil, fuego.UnauthorizedError{Title: "Unauthorized", Err: errors.New("missing token")}
+	}
+	return nil, emailError(uc.emailService.SendVerification(c.Context(), claims.UserID()))
+}
+
+// VerifyEmail 处理验证邮箱请求
+func (uc *UserController) VerifyEmail(c fuego.ContextWithBody[request.VerifyEmailRequest]) (*response.UserResponse, error) {
+	req, err := c.Body()
+	if err != nil {
+		return nil, err
+	}
+	user, err := uc.emailService.VerifyEmail(c.Context(), req.Token)
+	if err != nil {
+		return nil, emailError(err)
+	}
+	return user.ToResponse(), nil
+}
+
+// ResendVerification 处理按邮箱重新发送验证邮件请求
+func (uc *UserController) ResendVerification(c fuego.ContextWithBody[request.EmailRequest]) (any, error) {
+	req, err := c.Body()
+	if err != nil {
+		return nil, err
+	}
+	return nil, emailError(uc.emailService.ResendVerification(c.Context(), req.Email))
+}
+
+// ForgotPassword 处理发送重置密码邮件请求
+func (uc *UserController) ForgotPassword(c fuego.ContextWithBody[request.EmailRequest]) (any, error) {
+	req, err := c.Body()
+	if err != nil {
+		return nil, err
+	}
+	return nil, emailError(uc.emailService.RequestPasswordReset(c.Context(), req.Email))
+}
+
+// ResetPassword 处理重置密码请求，重置后吊销用户的全部会话
+func (uc *UserController) ResetPassword(c fuego.ContextWithBody[request.ResetPasswordRequest]) (any, error) {
+	req, err := c.Body()
+	if err != nil {
+		return nil, err
+	}
+	userID, err := uc.emailService.ResetPassword(c.Context(), req.Token, req.NewPassword)
+	if err != nil {
+		return nil, emailError(err)
+	}
+	return nil, uc.sessionService.RevokeAllSessions(c.Context(), userID, "password reset")
+}
+
+// emailError 将邮件验证和重置密码的错误转换为对应的响应
+func emailError(err error) error {
+	switch {
+	case err == nil:
+		return nil
+	case errors.Is(err, services.ErrMailDisabled):
+		return fuego.HTTPError{Title: "Service Unavailable", Status: http.StatusServiceUnavailable, Detail: err.Error(), Err: err}
+	case errors.Is(err, services.ErrNoEmail), errors.Is(err, services.ErrInvalidEmailToken):
+		return fuego.BadRequestError{Title: "Bad Request", Detail: err.Error(), Err: err}
+	case errors.Is(err, services.ErrEmailAlreadyVerified):
+		return fuego.ConflictError{Title: "Conflict", Detail: err.Error(), Err: err}
+	default:
+		return err
+	}
+}
+
+// UploadAvatar 处理上传头像请求
+func (uc *UserController) UploadAvatar(c fuego.ContextNoBody) (*response.UserResponse, error) {
+	r := c.Request()
+	// 为 multipart 的边界和其他字段预留空间，文件大小由 service 检查
+	r.Body = http.MaxBytesReader(c.Response(), r.Body, services.MaxAvatarSize+64<<10)
+	file, _, err := r.FormFile("avatar")
+	if err != nil {
+		return nil, fuego.BadRequestError{Title: "缺少头像文件", Detail: err.Error(), Err: err}
+	}
+	defer file.Close()
+
+	user, err := uc.userService.UploadAvatar(c.Context(), auth.UserIDFromContext(c.Context()), file)
+	switch {
+	case errors.Is(err, services.ErrAvatarTooLarge):
+		return nil, fuego.HTTPError{Title: "Payload Too Large", Status: http.StatusRequestEntityTooLarge, Detail: err.Error(), Err: err}
+	case errors.Is(err, services.ErrAvatarType):
+		return nil, fuego.HTTPError{Title: "Unsupported Media Type", Status: http.StatusUnsupportedMediaType, Detail: err.Error(), Err: err}
+	case errors.Is(err, services.ErrAvatarUploadDisabled):
+		return nil, fuego.HTTPError{Title: "Service Unavailable", Status: http.StatusServiceUnavailable, Detail: err.Error(), Err: err}
+	}
+	return userResponse(user, err)
+}
+
+// GetAvatar 处理获取头像请求
+func (uc *UserController) GetAvatar(w http.ResponseWriter, r *http.Request) {
+	f, url, err := uc.userService.OpenAvatar(r.Context(), r.PathValue("id"))
+	switch {
+	case errors.Is(err, services.ErrBlobNotManaged):
+		http.Redirect(w, r, url, http.StatusFound)
+		return
+	case errors.Is(err, stores.ErrUserNotFound), errors.Is(err, services.ErrNoAvatar), errors.Is(err, fs.ErrNotExist):
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	case err != nil:
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	defer f.Close()
+
+	w.Header().Set("Cache-Control", "private, max-age=86400")
+	if rs, ok := f.(io.ReadSeeker); ok {
+		http.ServeContent(w, r, "", time.Time{}, rs)
+		return
+	}
+	_, _ = io.Copy(w, f)
+}
+
+// userResponse 转换用户资料，用户不存在时返回 404
+func userResponse(user *models.User, err error) (*response.UserResponse, error) {
+	if errors.Is(err, stores.ErrUserNotFound) {
+		return nil, fuego.NotFoundError{Title: "Not Found", Detail: err.Error(), Err: err}
+	}
+	if err != nil {
+		return nil, err
+	}
+	return user.ToResponse(), nil
+}
+
+// Search 处理查询用户请求
+func (uc *UserController) Search(c fuego.ContextNoBody) (*response.UserListResponse, error) {
+	resp, err := uc.userService.SearchUsers(
+		c.QueryParam("q"),
+		c.QueryParam("role"),
+		c.QueryParamInt("page_size"),
+		c.QueryParam("page_token"),
+	)
+	if errors.Is(err, services.ErrInvalidRole) {
+		return nil, fuego.BadRequestError{Title: "Bad Request", Detail: err.Error(), Err: err}
+	}
+	return resp, err
+}
+
+// SetRole 处理修改用户角色请求
+func (uc *UserController) SetRole(c fuego.ContextWithBody[request.SetRoleRequest]) (*response.UserResponse, error) {
+	req, err := c.Body()
+	if err != nil {
+		return nil, err
+	}
+	user, err := uc.userService.SetRole(c.Context(), auth.UserIDFromContext(c.Context()), c.PathParam("id"), req.Role)
+	switch {
+	case errors.Is(err, services.ErrInvalidRole), errors.Is(err, services.ErrChangeOwnRole), errors.Is(err, services.ErrBotRole):
+		return nil, fuego.BadRequestError{Title: "Bad Request", Detail: err.Error(), Err: err}
+	case errors.Is(err, stores.ErrUserNotFound):
+		return nil, fuego.NotFoundError{Title: "Not Found", Detail: err.Error(), Err: err}
+	}
+	return user, err
 }
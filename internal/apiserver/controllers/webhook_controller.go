@@ -0,0 +1,88 @@
+package controllers
+
+import (
+	"github.com/go-fuego/fuego"
+
+	"github.com/woxQAQ/gim/internal/apiserver/services"
+	"github.com/woxQAQ/gim/internal/apiserver/types/request"
+	"github.com/woxQAQ/gim/internal/apiserver/types/response"
+)
+
+// WebhookController 处理回调配置和投递日志相关的HTTP请求
+type WebhookController struct {
+	webhookService *services.WebhookService
+}
+
+// NewWebhookController 创建WebhookController实例
+func NewWebhookController(webhookService *services.WebhookService) *WebhookController {
+	return &WebhookController{
+		webhookService: webhookService,
+	}
+}
+
+// RouteAdmin 注册管理员接口，sv 需要已经挂载管理员鉴权。
+// 回调可以订阅全部消息事件，投递日志包含消息内容，只允许管理员配置和查询
+func (c *WebhookController) RouteAdmin(sv *fuego.Server) {
+	g := fuego.Group(sv, "/webhooks",
+		fuego.OptionDescription("回调相关接口"),
+		fuego.OptionTags("admin"),
+	)
+
+	fuego.Post(g, "/endpoints", c.CreateEndpoint, fuego.OptionDescription("创建回调地址"))
+	fuego.Get(g, "/endpoints", c.ListEndpoints, fuego.OptionDescription("获取回调地址列表"))
+	fuego.Delete(g, "/endpoints/{id}", c.DeleteEndpoint, fuego.OptionDescription("删除回调地址"))
+	fuego.Get(g, "/deliveries", c.ListDeliveries,
+		fuego.OptionDescription("查询回调投递日志"),
+		fuego.OptionQuery("endpoint_id", "回调地址ID"),
+		fuego.OptionQuery("event", "事件类型"),
+		fuego.OptionQuery("status", "投递状态: pending, succeeded, dead"),
+		fuego.OptionQueryInt("page_size", "每页数量", fuego.ParamDefault(20)),
+		fuego.OptionQuery("page_token", "分页游标"),
+	)
+	fuego.Get(g, "/dead-letters", c.ListDeadLetters,
+		fuego.OptionDescription("查询回调死信"),
+		fuego.OptionQuery("endpoint_id", "回调地址ID"),
+		fuego.OptionQueryInt("limit", "最大数量", fuego.ParamDefault(50)),
+	)
+	fuego.Post(g, "/dead-letters/{id}/retry", c.RetryDeadLetter, fuego.OptionDescription("重新投递死信"))
+}
+
+// CreateEndpoint 处理创建回调地址请求
+func (c *WebhookController) CreateEndpoint(ctx fuego.ContextWithBody[request.CreateWebhookEndpointRequest]) (*response.WebhookEndpointResponse, error) {
+	req, err := ctx.Body()
+	if err != nil {
+		return nil, err
+	}
+	return c.webhookService.CreateEndpoint(&req)
+}
+
+// ListEndpoints 处理获取回调地址列表请求
+func (c *WebhookController) ListEndpoints(ctx fuego.ContextNoBody) ([]*response.WebhookEndpointResponse, error) {
+	return c.webhookService.ListEndpoints()
+}
+
+// DeleteEndpoint 处理删除回调地址请求
+func (c *WebhookController) DeleteEndpoint(ctx fuego.ContextNoBody) (any, error) {
+	return nil, c.webhookService.DeleteEndpoint(ctx.PathParam("id"))
+}
+
+// ListDeliveries 处理查询回调投递日志请求
+func (c *WebhookController) ListDeliveries(ctx fuego.ContextNoBody) (*response.WebhookDeliveryListResponse, error) {
+	return c.webhookService.ListDeliveries(
+		ctx.QueryParam("endpoint_id"),
+		ctx.QueryParam("event"),
+		ctx.QueryParam("status"),
+		ctx.QueryParamInt("page_size"),
+		ctx.QueryParam("page_token"),
+	)
+}
+
+// ListDeadLetters 处理查询回调死信请求
+func (c *WebhookController) ListDeadLetters(ctx fuego.ContextNoBody) ([]*response.WebhookDeadLetterResponse, error) {
+	return c.webhookService.ListDeadLetters(ctx.QueryParam("endpoint_id"), ctx.QueryParamInt("limit"))
+}
+
+// RetryDeadLetter 处理重新投递死信请求
+func (c *WebhookController) RetryDeadLetter(ctx fuego.ContextNoBody) (any, error) {
+	return nil, c.webhookService.RetryDeadLetter(ctx.PathParam("id"))
+}
@@ -0,0 +1,120 @@
+package gateway
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/woxQAQ/gim/internal/types"
+)
+
+// internalTokenHeader 与网关内部接口约定的鉴权请求头
+const internalTokenHeader = "X-Internal-Token"
+
+// ErrGatewayDisabled 未配置网关地址
+var ErrGatewayDisabled = errors.New("gateway client is not configured")
+
+// Client 定义 apiserver 访问 WebSocket 网关的接口
+type Client interface {
+	// Push 按目标向在线用户推送消息
+	Push(ctx context.Context, req *types.PushRequest) (*types.PushResult, error)
+	// Send 发送业务消息，消息经过与 WebSocket 消息相同的校验、投递和存储
+	Send(ctx context.Context, req *types.SendRequest) (*types.SendResult, error)
+	// Disconnect 断开用户的连接，未指定平台时断开所有平台
+	Disconnect(ctx context.Context, req *types.DisconnectRequest) (*types.DisconnectResult, error)
+	// Presence 查询用户在各平台的在线状态
+	Presence(ctx context.Context, req *types.PresenceRequest) (*types.PresenceResult, error)
+}
+
+var _ Client = (*HTTPClient)(nil)
+
+// HTTPClient 通过网关内部 HTTP 接口实现 Client
+type HTTPClient struct {
+	baseURL string
+	token   string
+	client  *http.Client
+}
+
+// NewHTTPClient 创建网关客户端
+func NewHTTPClient(baseURL, token string, timeout time.Duration) *HTTPClient {
+	return &HTTPClient{
+		baseURL: strings.TrimRight(baseURL, "/"),
+		token:   token,
+		client:  &http.Client{Timeout: timeout},
+	}
+}
+
+// Push 实现 Client 接口
+func (c *HTTPClient) Push(ctx context.Context, req *types.PushRequest) (*types.PushResult, error) {
+	result := new(types.PushResult)
+	if err := c.post(ctx, "/internal/push", req, result); err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// Send 实现 Client 接口
+func (c *HTTPClient) Send(ctx context.Context, req *types.SendRequest) (*types.SendResult, error) {
+	result := new(types.SendResult)
+	if err := c.post(ctx, "/internal/send", req, result); err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// Disconnect 实现 Client 接口
+func (c *HTTPClient) Disconnect(ctx context.Context, req *types.DisconnectRequest) (*types.DisconnectResult, error) {
+	result := new(types.DisconnectResult)
+	if err := c.post(ctx, "/internal/disconnect", req, result); err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// Presence 实现 Client 接口
+func (c *HTTPClient) Presence(ctx context.Context, req *types.PresenceRequest) (*types.PresenceResult, error) {
+	result := new(types.PresenceResult)
+	if err := c.post(ctx, "/internal/presence", req, result); err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// post 发送 JSON 请求并解析 JSON 响应
+func (c *HTTPClient) post(ctx context.Context, path string, body, out interface{}) error {
+	if c.baseURL == "" {
+		return ErrGatewayDisabled
+	}
+
+	data, err := json.Marshal(body)
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, c.baseURL+path, bytes.NewReader(data))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(internalTokenHeader, c.token)
+
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		return fmt.Errorf("gateway returned status %d: %s", resp.StatusCode, strings.TrimSpace(string(msg)))
+	}
+	if out == nil {
+		return nil
+	}
+	return json.NewDecoder(resp.Body).Decode(out)
+}
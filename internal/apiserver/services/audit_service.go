@@ -0,0 +1,86 @@
+package services
+
+import (
+	"fmt"
+
+	"github.com/woxQAQ/gim/internal/apiserver/stores"
+	"github.com/woxQAQ/gim/internal/apiserver/types/request"
+	"github.com/woxQAQ/gim/internal/apiserver/types/response"
+)
+
+// auditVerifyBatch 校验哈希链时每次读取的记录数量
+const auditVerifyBatch = 500
+
+// AuditService 查询审计日志并校验哈希链，审计日志由 audit.Recorder 写入
+type AuditService struct {
+	auditStore *stores.AuditStore
+}
+
+// NewAuditService 创建AuditService实例
+func NewAuditService(auditStore *stores.AuditStore) *AuditService {
+	return &AuditService{
+		auditStore: auditStore,
+	}
+}
+
+// List 按操作者、动作和时间范围查询审计日志
+func (s *AuditService) List(req *request.ListAuditLogsRequest) (*response.AuditLogListResponse, error) {
+	pageSize := req.PageSize
+	if pageSize <= 0 || pageSize > 100 {
+		pageSize = 20
+	}
+	logs, err := s.auditStore.ListLogs(req, pageSize+1)
+	if err != nil {
+		return nil, err
+	}
+
+	resp := &response.AuditLogListResponse{
+		Logs: make([]*response.AuditLogResponse, 0, len(logs)),
+	}
+	if len(logs) > pageSize {
+		logs = logs[:pageSize]
+		resp.NextToken = logs[len(logs)-1].ID
+	}
+	for _, l := range logs {
+		resp.Logs = append(resp.Logs, l.ToResponse())
+	}
+	return resp, nil
+}
+
+// Verify 按序号校验全部审计日志：序号必须连续，带哈希的记录必须链接上一条记录的哈希且哈希与内容一致。
+// 未开启哈希链时写入的记录没有哈希，只计入 Unchained
+func (s *AuditService) Verify() (*response.AuditVerifyResponse, error) {
+	resp := &response.AuditVerifyResponse{Valid: true}
+	var lastSeq int64
+	var lastHash string
+	for {
+		logs, err := s.auditStore.ListBySeq(lastSeq, auditVerifyBatch)
+		if err != nil {
+			return nil, err
+		}
+		for _, l := range logs {
+			reason := ""
+			switch {
+			case l.Seq != lastSeq+1:
+				reason = fmt.Sprintf("序号不连续，缺少 %d", lastSeq+1)
+			case l.Hash == "":
+				resp.Unchained++
+			case l.PrevHash != lastHash:
+				reason = "与上一条记录的哈希不一致"
+			case l.Hash != l.ComputeHash():
+				reason = "记录内容与哈希不一致"
+			}
+			if reason != "" {
+				resp.Valid = false
+				resp.BrokenSeq = l.Seq
+				resp.Reason = reason
+				return resp, nil
+			}
+			resp.Checked++
+			lastSeq, lastHash = l.Seq, l.Hash
+		}
+		if len(logs) < auditVerifyBatch {
+			return resp, nil
+		}
+	}
+}
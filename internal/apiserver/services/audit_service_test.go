@@ -0,0 +1,112 @@
+package services_test
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"gorm.io/gorm"
+
+	"github.com/woxQAQ/gim/internal/apiserver/services"
+	"github.com/woxQAQ/gim/internal/apiserver/stores"
+	"github.com/woxQAQ/gim/internal/apiserver/types/request"
+	"github.com/woxQAQ/gim/internal/audit"
+	"github.com/woxQAQ/gim/internal/models"
+	"github.com/woxQAQ/gim/pkg/auth"
+	"github.com/woxQAQ/gim/pkg/logger"
+)
+
+var _ = Describe("AuditService", func() {
+	var (
+		gdb      *gorm.DB
+		astore   *stores.AuditStore
+		l        logger.Logger
+		recorder *audit.Recorder
+		svc      *services.AuditService
+	)
+
+	BeforeEach(func() {
+		gdb = openDB()
+		astore = stores.NewAuditStore(gdb)
+		l, _ = logger.NewLogger(&logger.Config{Level: "error"})
+		l.Disable()
+		recorder = audit.NewRecorder(astore, true, l)
+		svc = services.NewAuditService(astore)
+	})
+
+	It("应该从请求上下文取得操作者，并按条件查询", func() {
+		ctx := auth.WithClaims(context.Background(), &auth.Claims{RegisteredClaims: jwt.RegisteredClaims{Subject: "admin"}})
+		recorder.Record(ctx, &audit.Entry{Action: audit.ActionUserBan, TargetID: "alice", Detail: map[string]any{"reason": "spam"}})
+		recorder.Record(context.Background(), &audit.Entry{Action: audit.ActionLoginFailure, Detail: map[string]any{"username": "bob"}})
+
+		resp, err := svc.List(&request.ListAuditLogsRequest{ActorID: "admin", PageSize: 20})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(resp.Logs).To(HaveLen(1))
+		Expect(resp.Logs[0].Action).To(Equal(audit.ActionUserBan))
+		Expect(string(resp.Logs[0].Detail)).To(MatchJSON(`{"reason":"spam"}`))
+
+		future := time.Now().Add(time.Hour)
+		resp, err = svc.List(&request.ListAuditLogsRequest{Action: audit.ActionLoginFailure, Since: &future, PageSize: 20})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(resp.Logs).To(BeEmpty())
+
+		// 每页数量不合法时使用默认值
+		for _, size := range []int{0, -1, 1000} {
+			resp, err = svc.List(&request.ListAuditLogsRequest{PageSize: size})
+			Expect(err).NotTo(HaveOccurred())
+			Expect(resp.Logs).To(HaveLen(2))
+			Expect(resp.NextToken).To(BeEmpty())
+		}
+	})
+
+	It("并发写入时序号连续，修改历史记录后哈希链校验失败", func() {
+		// 两个 Recorder 模拟 apiserver 和网关同时写入
+		other := audit.NewRecorder(astore, true, l)
+		var wg sync.WaitGroup
+		for i := 0; i < 10; i++ {
+			wg.Add(2)
+			go func() {
+				defer wg.Done()
+				recorder.Record(context.Background(), &audit.Entry{Action: audit.ActionLoginSuccess, ActorID: "alice"})
+			}()
+			go func() {
+				defer wg.Done()
+				other.Record(context.Background(), &audit.Entry{Action: audit.ActionMessageRecall, ActorID: "bob"})
+			}()
+		}
+		wg.Wait()
+
+		result, err := svc.Verify()
+		Expect(err).NotTo(HaveOccurred())
+		Expect(result.Valid).To(BeTrue())
+		Expect(result.Checked).To(BeEquivalentTo(20))
+
+		Expect(gdb.Model(&models.AuditLog{}).Where("seq = ?", 7).Update("actor_id", "mallory").Error).To(Succeed())
+		result, err = svc.Verify()
+		Expect(err).NotTo(HaveOccurred())
+		Expect(result.Valid).To(BeFalse())
+		Expect(result.BrokenSeq).To(BeEquivalentTo(7))
+
+		Expect(gdb.Delete(&models.AuditLog{}, "seq = ?", 7).Error).To(Succeed())
+		result, err = svc.Verify()
+		Expect(err).NotTo(HaveOccurred())
+		Expect(result.Valid).To(BeFalse())
+		Expect(result.BrokenSeq).To(BeEquivalentTo(8))
+	})
+
+	It("未开启哈希链时写入的记录不参与哈希校验", func() {
+		unchained := audit.NewRecorder(astore, false, l)
+		recorder.Record(context.Background(), &audit.Entry{Action: audit.ActionLoginSuccess})
+		unchained.Record(context.Background(), &audit.Entry{Action: audit.ActionLoginSuccess})
+		recorder.Record(context.Background(), &audit.Entry{Action: audit.ActionLoginSuccess})
+
+		result, err := svc.Verify()
+		Expect(err).NotTo(HaveOccurred())
+		Expect(result.Valid).To(BeTrue())
+		Expect(result.Checked).To(BeEquivalentTo(3))
+		Expect(result.Unchained).To(BeEquivalentTo(1))
+	})
+})
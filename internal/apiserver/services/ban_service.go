@@ -0,0 +1,170 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/woxQAQ/gim/internal/apiserver/gateway"
+	"github.com/woxQAQ/gim/internal/apiserver/stores"
+	"github.com/woxQAQ/gim/internal/apiserver/types/request"
+	"github.com/woxQAQ/gim/internal/apiserver/types/response"
+	"github.com/woxQAQ/gim/internal/audit"
+	"github.com/woxQAQ/gim/internal/models"
+	"github.com/woxQAQ/gim/internal/types"
+	"github.com/woxQAQ/gim/pkg/logger"
+	"github.com/woxQAQ/gim/pkg/snowflake"
+)
+
+var (
+	// ErrAccountDisabled 账号已被禁用
+	ErrAccountDisabled = errors.New("账号已被禁用")
+	// ErrAccountBanned 账号处于封禁期，具体原因见 BannedError
+	ErrAccountBanned = errors.New("账号已被封禁")
+)
+
+// BanDisconnectCode 封禁时断开连接的系统通知代码
+const BanDisconnectCode = "account_banned"
+
+// BannedError 账号被封禁的原因和结束时间，errors.Is 匹配 ErrAccountBanned
+type BannedError struct {
+	Reason    string
+	ExpiresAt *time.Time // 为空时永久封禁
+}
+
+func (e *BannedError) Error() string {
+	if e.ExpiresAt == nil {
+		return fmt.Sprintf("%s: %s", ErrAccountBanned, e.Reason)
+	}
+	return fmt.Sprintf("%s: %s，解封时间 %s", ErrAccountBanned, e.Reason, e.ExpiresAt.Format(time.RFC3339))
+}
+
+func (e *BannedError) Is(target error) bool {
+	return target == ErrAccountBanned
+}
+
+// checkAccount 检查账号是否可以登录，禁用时返回 ErrAccountDisabled，封禁时返回 *BannedError
+func checkAccount(user *models.User, banStore *stores.BanStore, now time.Time) error {
+	if user.Status == models.UserStatusDisabled {
+		return ErrAccountDisabled
+	}
+	ban, err := banStore.ActiveBan(user.ID, now)
+	if errors.Is(err, stores.ErrBanNotFound) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	return &BannedError{Reason: ban.Reason, ExpiresAt: ban.ExpiresAt}
+}
+
+// BanService 处理用户封禁相关的业务逻辑
+type BanService struct {
+	banStore     *stores.BanStore
+	userStore    *stores.UserStore
+	sessionStore *stores.SessionStore
+	gateway      gateway.Client
+	audit        *audit.Recorder
+	logger       logger.Logger
+	now          func() time.Time
+}
+
+// NewBanService 创建BanService实例，gw 为空时封禁不断开网关上的连接
+func NewBanService(banStore *stores.BanStore, userStore *stores.UserStore, sessionStore *stores.SessionStore, gw gateway.Client, recorder *audit.Recorder, l logger.Logger) *BanService {
+	return &BanService{
+		banStore:     banStore,
+		userStore:    userStore,
+		sessionStore: sessionStore,
+		gateway:      gw,
+		audit:        recorder,
+		logger:       l,
+		now:          time.Now,
+	}
+}
+
+// Ban 封禁用户：吊销全部登录会话，并通过网关断开用户的所有连接。
+// 网关不可用时只记录日志，用户重连时握手会被拒绝
+func (s *BanService) Ban(ctx context.Context, userID string, req *request.BanUserRequest) (*response.BanResponse, error) {
+	if _, err := s.userStore.GetUserByID(userID); err != nil {
+		return nil, err
+	}
+	ban := &models.UserBan{
+		ID:        snowflake.GenerateID(),
+		UserID:    userID,
+		Reason:    req.Reason,
+		CreatedBy: req.CreatedBy,
+	}
+	if req.DurationSeconds > 0 {
+		expiresAt := s.now().Add(time.Duration(req.DurationSeconds) * time.Second)
+		ban.ExpiresAt = &expiresAt
+	}
+	if err := s.banStore.CreateBan(ban); err != nil {
+		return nil, err
+	}
+	detail := map[string]any{"ban_id": ban.ID, "reason": ban.Reason}
+	if ban.ExpiresAt != nil {
+		detail["expires_at"] = ban.ExpiresAt
+	}
+	s.audit.Record(ctx, &audit.Entry{
+		Action:   audit.ActionUserBan,
+		ActorID:  ban.CreatedBy,
+		TargetID: userID,
+		Detail:   detail,
+	})
+	if _, err := s.sessionStore.RevokeUserSessions(userID); err != nil {
+		return nil, err
+	}
+	s.disconnect(ctx, ban)
+	return ban.ToResponse(), nil
+}
+
+// Lift 解除用户全部生效的封禁
+func (s *BanService) Lift(ctx context.Context, userID string, req *request.LiftBanRequest) error {
+	n, err := s.banStore.LiftBans(userID, req.LiftedBy, s.now())
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		return stores.ErrBanNotFound
+	}
+	s.audit.Record(ctx, &audit.Entry{
+		Action:   audit.ActionUserUnban,
+		ActorID:  req.LiftedBy,
+		TargetID: userID,
+		Detail:   map[string]any{"lifted": n},
+	})
+	return nil
+}
+
+// List 获取用户的封禁记录
+func (s *BanService) List(userID string) ([]*response.BanResponse, error) {
+	bans, err := s.banStore.ListBans(userID)
+	if err != nil {
+		return nil, err
+	}
+	out := make([]*response.BanResponse, 0, len(bans))
+	for _, b := range bans {
+		out = append(out, b.ToResponse())
+	}
+	return out, nil
+}
+
+// disconnect 断开被封禁用户在网关上的全部连接，连接收到带封禁原因的系统通知
+func (s *BanService) disconnect(ctx context.Context, ban *models.UserBan) {
+	if s.gateway == nil {
+		return
+	}
+	dctx, cancel := context.WithTimeout(ctx, disconnectTimeout)
+	defer cancel()
+	_, err := s.gateway.Disconnect(dctx, &types.DisconnectRequest{
+		UserIDs: []string{ban.UserID},
+		Code:    BanDisconnectCode,
+		Reason:  ban.Reason,
+	})
+	if err != nil && !errors.Is(err, gateway.ErrGatewayDisabled) {
+		s.logger.Warn("断开被封禁用户的连接失败",
+			logger.String("user_id", ban.UserID),
+			logger.Error(err))
+	}
+}
@@ -0,0 +1,110 @@
+package services_test
+
+import (
+	"context"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"gorm.io/gorm"
+
+	"github.com/woxQAQ/gim/internal/apiserver/services"
+	"github.com/woxQAQ/gim/internal/apiserver/stores"
+	"github.com/woxQAQ/gim/internal/apiserver/types/request"
+	"github.com/woxQAQ/gim/internal/audit"
+	"github.com/woxQAQ/gim/internal/models"
+	"github.com/woxQAQ/gim/pkg/logger"
+)
+
+var _ = Describe("BanService", func() {
+	var (
+		gdb      *gorm.DB
+		sessions *stores.SessionStore
+		gw       *fakeGateway
+		users    *services.UserService
+		svc      *services.BanService
+		alice    *models.User
+	)
+
+	BeforeEach(func() {
+		gdb = openDB()
+		ustore := stores.NewUserStore(gdb)
+		bstore := stores.NewBanStore(gdb)
+		sessions = stores.NewSessionStore(gdb)
+		gw = &fakeGateway{}
+
+		l, _ := logger.NewLogger(&logger.Config{Level: "error"})
+		l.Disable()
+		recorder := audit.NewRecorder(stores.NewAuditStore(gdb), true, l)
+		users = services.NewUserService(ustore, bstore, nil, recorder, nil, nil)
+		svc = services.NewBanService(bstore, ustore, sessions, gw, recorder, l)
+
+		alice = &models.User{Username: "alice", Password: "secret"}
+		Expect(users.Register(context.Background(), alice, "")).To(Succeed())
+	})
+
+	It("封禁后应该拒绝登录，吊销会话并断开全部连接", func() {
+		Expect(sessions.CreateSession(&models.Session{ID: "s1", UserID: alice.ID, Platform: 1, ExpiresAt: time.Now().Add(time.Hour)})).To(Succeed())
+
+		ban, err := svc.Ban(context.Background(), alice.ID, &request.BanUserRequest{Reason: "spam", DurationSeconds: 3600, CreatedBy: "admin"})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(ban.Active).To(BeTrue())
+		Expect(ban.ExpiresAt).NotTo(BeNil())
+
+		revoked, err := sessions.IsRevoked("s1")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(revoked).To(BeTrue())
+		Expect(gw.requests).To(HaveLen(1))
+		Expect(gw.requests[0].UserIDs).To(Equal([]string{alice.ID}))
+		Expect(gw.requests[0].PlatformID).To(BeZero())
+		Expect(gw.requests[0].Code).To(Equal(services.BanDisconnectCode))
+		Expect(gw.requests[0].Reason).To(Equal("spam"))
+
+		_, err = users.Login(context.Background(), "alice", "secret", "")
+		Expect(err).To(MatchError(services.ErrAccountBanned))
+		var banned *services.BannedError
+		Expect(err).To(BeAssignableToTypeOf(banned))
+		Expect(err.(*services.BannedError).Reason).To(Equal("spam"))
+
+		// 密码错误时不应该透露封禁状态
+		_, err = users.Login(context.Background(), "alice", "wrong", "")
+		Expect(err).To(MatchError(services.ErrInvalidCredentials))
+
+		var actions []string
+		Expect(gdb.Model(&models.AuditLog{}).Order("seq").Pluck("action", &actions).Error).To(Succeed())
+		Expect(actions).To(Equal([]string{audit.ActionUserBan, audit.ActionLoginFailure, audit.ActionLoginFailure}))
+	})
+
+	It("封禁过期或解除后应该可以登录", func() {
+		_, err := svc.Ban(context.Background(), alice.ID, &request.BanUserRequest{Reason: "spam", DurationSeconds: 60})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(gdb.Model(&models.UserBan{}).Where("user_id = ?", alice.ID).
+			Update("expires_at", time.Now().Add(-time.Second)).Error).To(Succeed())
+		_, err = users.Login(context.Background(), "alice", "secret", "")
+		Expect(err).NotTo(HaveOccurred())
+
+		_, err = svc.Ban(context.Background(), alice.ID, &request.BanUserRequest{Reason: "abuse"})
+		Expect(err).NotTo(HaveOccurred())
+		_, err = users.Login(context.Background(), "alice", "secret", "")
+		Expect(err).To(MatchError(services.ErrAccountBanned))
+
+		Expect(svc.Lift(context.Background(), alice.ID, &request.LiftBanRequest{LiftedBy: "admin"})).To(Succeed())
+		Expect(svc.Lift(context.Background(), alice.ID, &request.LiftBanRequest{})).To(MatchError(stores.ErrBanNotFound))
+		_, err = users.Login(context.Background(), "alice", "secret", "")
+		Expect(err).NotTo(HaveOccurred())
+
+		bans, err := svc.List(alice.ID)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(bans).To(HaveLen(2))
+		Expect(bans[0].Reason).To(Equal("abuse"))
+		Expect(bans[0].LiftedBy).To(Equal("admin"))
+		Expect(bans[0].Active).To(BeFalse())
+	})
+
+	It("禁用的账号应该不能登录", func() {
+		Expect(gdb.Model(&models.User{}).Where("id = ?", alice.ID).
+			Update("status", models.UserStatusDisabled).Error).To(Succeed())
+		_, err := users.Login(context.Background(), "alice", "secret", "")
+		Expect(err).To(MatchError(services.ErrAccountDisabled))
+	})
+})
@@ -0,0 +1,101 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/url"
+	"os"
+	"path/filepath"
+)
+
+// BlobRemover 删除附件对应的文件
+type BlobRemover interface {
+	Remove(ctx context.Context, url string) error
+}
+
+// BlobStore 保存、读取和删除附件对应的文件
+type BlobStore interface {
+	BlobRemover
+	// Put 保存文件，返回之后用于读取和删除的地址
+	Put(ctx context.Context, name string, r io.Reader) (string, error)
+	// Open 打开附件文件，不由该存储管理的地址返回 ErrBlobNotManaged
+	Open(ctx context.Context, url string) (io.ReadCloser, error)
+}
+
+// ErrBlobNotManaged 附件地址不由当前存储管理，例如外部CDN地址
+var ErrBlobNotManaged = errors.New("blob is not managed by this store")
+
+var _ BlobStore = (*FileBlobStore)(nil)
+
+// FileBlobStore 本地目录中的附件文件，只处理相对路径和 file:// 地址
+type FileBlobStore struct {
+	Root string
+}
+
+// Open 实现 BlobStore 接口
+func (s *FileBlobStore) Open(_ context.Context, rawURL string) (io.ReadCloser, error) {
+	path, err := s.path(rawURL)
+	if err != nil {
+		return nil, err
+	}
+	return os.Open(path)
+}
+
+// Put 实现 BlobStore 接口，先写入临时文件再重命名，返回相对于根目录的路径
+func (s *FileBlobStore) Put(_ context.Context, name string, r io.Reader) (string, error) {
+	path, err := s.path(name)
+	if err != nil {
+		return "", err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return "", err
+	}
+	f, err := os.CreateTemp(filepath.Dir(path), ".upload-*")
+	if err != nil {
+		return "", err
+	}
+	defer os.Remove(f.Name())
+	if _, err := io.Copy(f, r); err != nil {
+		f.Close()
+		return "", err
+	}
+	if err := f.Close(); err != nil {
+		return "", err
+	}
+	if err := os.Rename(f.Name(), path); err != nil {
+		return "", err
+	}
+	rel, err := filepath.Rel(s.Root, path)
+	if err != nil {
+		return "", err
+	}
+	return filepath.ToSlash(rel), nil
+}
+
+// Remove 实现 BlobRemover 接口，其他地址和不存在的文件不返回错误
+func (s *FileBlobStore) Remove(_ context.Context, rawURL string) error {
+	path, err := s.path(rawURL)
+	if errors.Is(err, ErrBlobNotManaged) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	return nil
+}
+
+// path 将附件地址转换为根目录下的路径，防止路径穿越
+func (s *FileBlobStore) path(rawURL string) (string, error) {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return "", err
+	}
+	if u.Scheme != "" && u.Scheme != "file" {
+		return "", ErrBlobNotManaged
+	}
+	return filepath.Join(s.Root, filepath.Clean("/"+u.Path)), nil
+}
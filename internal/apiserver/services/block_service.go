@@ -0,0 +1,98 @@
+package services
+
+import (
+	"errors"
+
+	"github.com/woxQAQ/gim/internal/apiserver/stores"
+	"github.com/woxQAQ/gim/internal/apiserver/types/response"
+	"github.com/woxQAQ/gim/internal/models"
+)
+
+var (
+	// ErrBlockSelf 不能拉黑自己
+	ErrBlockSelf = errors.New("不能拉黑自己")
+	// ErrNotBlocked 用户不在黑名单中
+	ErrNotBlocked = errors.New("用户不在黑名单中")
+	// ErrBlocked 对方已将当前用户拉黑
+	ErrBlocked = errors.New("对方已将你拉黑")
+)
+
+// BlockService 处理用户黑名单相关的业务逻辑，单聊消息的拦截由网关完成
+type BlockService struct {
+	blockStore   *stores.BlockStore
+	contactStore *stores.ContactStore
+	userStore    *stores.UserStore
+}
+
+// NewBlockService 创建BlockService实例
+func NewBlockService(blockStore *stores.BlockStore, contactStore *stores.ContactStore, userStore *stores.UserStore) *BlockService {
+	return &BlockService{
+		blockStore:   blockStore,
+		contactStore: contactStore,
+		userStore:    userStore,
+	}
+}
+
+// Block 拉黑用户，对方发来的待处理好友申请同时被拒绝
+func (s *BlockService) Block(userID, blockedID string) error {
+	if userID == blockedID {
+		return ErrBlockSelf
+	}
+	if _, err := s.userStore.GetUserByID(blockedID); err != nil {
+		return err
+	}
+	if err := s.blockStore.Block(userID, blockedID); err != nil {
+		return err
+	}
+	req, err := s.contactStore.GetPendingRequest(blockedID, userID)
+	if errors.Is(err, stores.ErrFriendRequestNotFound) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	_, err = s.contactStore.HandleRequest(req.ID, models.FriendRequestRejected)
+	return err
+}
+
+// Unblock 将用户移出黑名单
+func (s *BlockService) Unblock(userID, blockedID string) error {
+	ok, err := s.blockStore.Unblock(userID, blockedID)
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return ErrNotBlocked
+	}
+	return nil
+}
+
+// List 获取用户的黑名单
+func (s *BlockService) List(userID string) ([]*response.BlockResponse, error) {
+	blocks, err := s.blockStore.ListBlocked(userID)
+	if err != nil {
+		return nil, err
+	}
+	ids := make([]string, 0, len(blocks))
+	for _, b := range blocks {
+		ids = append(ids, b.BlockedID)
+	}
+	users, err := s.userStore.GetUsersByIDs(ids)
+	if err != nil {
+		return nil, err
+	}
+	byID := make(map[string]*models.User, len(users))
+	for _, u := range users {
+		byID[u.ID] = u
+	}
+
+	out := make([]*response.BlockResponse, 0, len(blocks))
+	for _, b := range blocks {
+		u, ok := byID[b.BlockedID]
+		if !ok {
+			continue
+		}
+		out = append(out, &response.BlockResponse{User: u.ToPublicResponse(), CreatedAt: b.CreatedAt})
+	}
+	return out, nil
+}
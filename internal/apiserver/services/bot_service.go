@@ -0,0 +1,349 @@
+package services
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/woxQAQ/gim/internal/apiserver/gateway"
+	"github.com/woxQAQ/gim/internal/apiserver/stores"
+	"github.com/woxQAQ/gim/internal/apiserver/types/request"
+	"github.com/woxQAQ/gim/internal/apiserver/types/response"
+	"github.com/woxQAQ/gim/internal/audit"
+	"github.com/woxQAQ/gim/internal/models"
+	"github.com/woxQAQ/gim/internal/types"
+	"github.com/woxQAQ/gim/pkg/auth"
+	"github.com/woxQAQ/gim/pkg/logger"
+	"github.com/woxQAQ/gim/pkg/snowflake"
+)
+
+var (
+	// ErrNotBot 用户不是机器人账号，不能创建 API 密钥
+	ErrNotBot = errors.New("用户不是机器人账号")
+	// ErrInvalidScope 未知的 API 密钥权限范围
+	ErrInvalidScope = errors.New("未知的权限范围")
+	// ErrInvalidMessageTarget 机器人消息需要且只能指定接收用户或群中的一个
+	ErrInvalidMessageTarget = errors.New("需要指定接收用户或群中的一个")
+	// ErrInvalidMessageType 机器人只能发送文本、图片、视频、音频、文件和自定义消息
+	ErrInvalidMessageType = errors.New("不支持的消息类型")
+	// ErrSubTypeRequired 自定义消息需要指定子类型
+	ErrSubTypeRequired = errors.New("自定义消息需要指定子类型")
+	// ErrRecipientNotFound 接收消息的用户不存在
+	ErrRecipientNotFound = errors.New("接收用户不存在")
+	// ErrMessageRejected 消息被网关拒绝，具体原因见 MessageRejectedError
+	ErrMessageRejected = errors.New("消息被拒绝")
+)
+
+// MessageRejectedError 消息被黑名单、单聊策略、内容过滤或投递前审核拒绝，errors.Is 匹配 ErrMessageRejected
+type MessageRejectedError struct {
+	Reason string
+}
+
+func (e *MessageRejectedError) Error() string {
+	return ErrMessageRejected.Error() + ": " + e.Reason
+}
+
+func (e *MessageRejectedError) Is(target error) bool {
+	return target == ErrMessageRejected
+}
+
+// apiKeyTouchInterval 记录密钥使用时间的最小间隔
+const apiKeyTouchInterval = time.Minute
+
+// BotService 管理机器人账号和 API 密钥，并以机器人的身份发送消息。
+// 消息通过网关的消息路由发送，与 WebSocket 上的消息经过相同的校验、投递和存储
+type BotService struct {
+	userStore  *stores.UserStore
+	keyStore   *stores.APIKeyStore
+	groupStore *stores.GroupStore
+	banStore   *stores.BanStore
+	gateway    gateway.Client
+	audit      *audit.Recorder
+	logger     logger.Logger
+	now        func() time.Time
+}
+
+// NewBotService 创建BotService实例，recorder 为空时不记录审计日志
+func NewBotService(
+	userStore *stores.UserStore,
+	keyStore *stores.APIKeyStore,
+	groupStore *stores.GroupStore,
+	banStore *stores.BanStore,
+	gw gateway.Client,
+	recorder *audit.Recorder,
+	l logger.Logger,
+) *BotService {
+	return &BotService{
+		userStore:  userStore,
+		keyStore:   keyStore,
+		groupStore: groupStore,
+		banStore:   banStore,
+		gateway:    gw,
+		audit:      recorder,
+		logger:     l,
+		now:        time.Now,
+	}
+}
+
+// CreateBot 创建机器人账号。机器人的密码随机生成且不返回，只能通过 API 密钥调用机器人接口
+func (s *BotService) CreateBot(ctx context.Context, req *request.CreateBotRequest) (*response.UserResponse, error) {
+	password, err := randomSecret()
+	if err != nil {
+		return nil, err
+	}
+	bot := &models.User{
+		ID:       snowflake.GenerateID(),
+		Username: req.Username,
+		Password: password,
+		Nickname: req.Nickname,
+		Avatar:   req.Avatar,
+		Role:     models.RoleBot,
+	}
+	if err := userConflict(s.userStore.CreateUser(bot)); err != nil {
+		return nil, err
+	}
+	s.audit.Record(ctx, &audit.Entry{
+		Action:   audit.ActionBotCreate,
+		ActorID:  req.CreatedBy,
+		TargetID: bot.ID,
+		Detail:   map[string]any{"username": bot.Username},
+	})
+	return bot.ToResponse(), nil
+}
+
+// CreateKey 为机器人创建 API 密钥，返回的密钥只在此时可见
+func (s *BotService) CreateKey(ctx context.Context, botID string, req *request.CreateAPIKeyRequest) (*response.CreatedAPIKeyResponse, error) {
+	if _, err := s.getBot(botID); err != nil {
+		return nil, err
+	}
+	for _, scope := range req.Scopes {
+		if !models.ValidScope(scope) {
+			return nil, ErrInvalidScope
+		}
+	}
+	var ttl time.Duration
+	if req.ExpiresInSeconds > 0 {
+		ttl = time.Duration(req.ExpiresInSeconds) * time.Second
+	}
+	key, secret, err := s.newKey(botID, req.Name, strings.Join(req.Scopes, " "), req.CreatedBy, ttl)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.keyStore.CreateAPIKey(key); err != nil {
+		return nil, err
+	}
+	s.audit.Record(ctx, &audit.Entry{
+		Action:   audit.ActionAPIKeyCreate,
+		ActorID:  req.CreatedBy,
+		TargetID: botID,
+		Detail:   map[string]any{"key_id": key.ID, "prefix": key.Prefix, "scopes": key.ScopeList()},
+	})
+	return &response.CreatedAPIKeyResponse{APIKeyResponse: *key.ToResponse(), Key: secret}, nil
+}
+
+// ListKeys 获取机器人的全部 API 密钥
+func (s *BotService) ListKeys(botID string) ([]*response.APIKeyResponse, error) {
+	if _, err := s.getBot(botID); err != nil {
+		return nil, err
+	}
+	keys, err := s.keyStore.ListAPIKeys(botID)
+	if err != nil {
+		return nil, err
+	}
+	out := make([]*response.APIKeyResponse, 0, len(keys))
+	for _, k := range keys {
+		out = append(out, k.ToResponse())
+	}
+	return out, nil
+}
+
+// RotateKey 为有效的密钥签发替代密钥，新密钥沿用名称、权限范围和有效期长度。
+// 旧密钥在 GracePeriodSeconds 后失效，为0时立即吊销
+func (s *BotService) RotateKey(ctx context.Context, botID, keyID string, req *request.RotateAPIKeyRequest) (*response.CreatedAPIKeyResponse, error) {
+	old, err := s.keyStore.GetAPIKey(botID, keyID)
+	if err != nil {
+		return nil, err
+	}
+	now := s.now()
+	if !old.Active(now) {
+		return nil, stores.ErrAPIKeyNotFound
+	}
+	var ttl time.Duration
+	if old.ExpiresAt != nil {
+		ttl = old.ExpiresAt.Sub(old.CreatedAt)
+	}
+	key, secret, err := s.newKey(botID, old.Name, old.Scopes, req.CreatedBy, ttl)
+	if err != nil {
+		return nil, err
+	}
+	// 宽限期不会延长旧密钥原本的有效期
+	retireAt := now.Add(time.Duration(req.GracePeriodSeconds) * time.Second)
+	if old.ExpiresAt != nil && old.ExpiresAt.Before(retireAt) {
+		retireAt = *old.ExpiresAt
+	}
+	if err := s.keyStore.RotateAPIKey(old, key, now, retireAt); err != nil {
+		return nil, err
+	}
+	s.audit.Record(ctx, &audit.Entry{
+		Action:   audit.ActionAPIKeyRotate,
+		ActorID:  req.CreatedBy,
+		TargetID: botID,
+		Detail:   map[string]any{"key_id": old.ID, "new_key_id": key.ID, "retire_at": retireAt},
+	})
+	return &response.CreatedAPIKeyResponse{APIKeyResponse: *key.ToResponse(), Key: secret}, nil
+}
+
+// RevokeKey 立即吊销机器人的 API 密钥，revokedBy 为执行操作的管理员
+func (s *BotService) RevokeKey(ctx context.Context, botID, keyID, revokedBy string) error {
+	if err := s.keyStore.RevokeAPIKey(botID, keyID, s.now()); err != nil {
+		return err
+	}
+	s.audit.Record(ctx, &audit.Entry{
+		Action:   audit.ActionAPIKeyRevoke,
+		ActorID:  revokedBy,
+		TargetID: botID,
+		Detail:   map[string]any{"key_id": keyID},
+	})
+	return nil
+}
+
+// VerifyAPIKey 校验 API 密钥，实现 middleware.APIKeyVerifier。
+// 密钥无效、已失效，或所属机器人已禁用、被封禁时返回 auth.ErrInvalidToken
+func (s *BotService) VerifyAPIKey(secret string) (*auth.APIKeyPrincipal, error) {
+	if !auth.LooksLikeAPIKey(secret) {
+		return nil, auth.ErrInvalidToken
+	}
+	key, err := s.keyStore.GetAPIKeyByHash(auth.HashAPIKey(secret))
+	if errors.Is(err, stores.ErrAPIKeyNotFound) {
+		return nil, auth.ErrInvalidToken
+	}
+	if err != nil {
+		return nil, err
+	}
+	now := s.now()
+	if !key.Active(now) {
+		return nil, auth.ErrInvalidToken
+	}
+	bot, err := s.userStore.GetUserByID(key.UserID)
+	if errors.Is(err, stores.ErrUserNotFound) {
+		return nil, auth.ErrInvalidToken
+	}
+	if err != nil {
+		return nil, err
+	}
+	if bot.Role != models.RoleBot {
+		return nil, auth.ErrInvalidToken
+	}
+	if err := checkAccount(bot, s.banStore, now); err != nil {
+		if errors.Is(err, ErrAccountDisabled) || errors.Is(err, ErrAccountBanned) {
+			return nil, auth.ErrInvalidToken
+		}
+		return nil, err
+	}
+	if err := s.keyStore.TouchAPIKey(key.ID, now, now.Add(-apiKeyTouchInterval)); err != nil {
+		s.logger.Warn("记录 API 密钥使用时间失败", logger.String("key_id", key.ID), logger.Error(err))
+	}
+	return &auth.APIKeyPrincipal{KeyID: key.ID, UserID: bot.ID, Scopes: key.ScopeList()}, nil
+}
+
+// SendMessage 以机器人的身份向用户或群发送消息。
+// 消息由网关校验、投递和存储，被拒绝时返回 *MessageRejectedError，未配置网关时返回 gateway.ErrGatewayDisabled
+func (s *BotService) SendMessage(ctx context.Context, botID string, req *request.SendMessageRequest) (*response.SendMessageResponse, error) {
+	if (req.ReceiverID == "") == (req.GroupID == "") {
+		return nil, ErrInvalidMessageTarget
+	}
+	msgType := types.MessageType(req.Type)
+	switch msgType {
+	case types.MessageTypeUnknown:
+		msgType = types.MessageTypeText
+	case types.MessageTypeText, types.MessageTypeImage, types.MessageTypeVideo,
+		types.MessageTypeAudio, types.MessageTypeFile, types.MessageTypeCustom:
+	default:
+		return nil, ErrInvalidMessageType
+	}
+	if msgType == types.MessageTypeCustom && req.SubType == "" {
+		return nil, ErrSubTypeRequired
+	}
+
+	var conversationID string
+	if req.GroupID != "" {
+		if _, err := s.groupStore.GetGroupByID(req.GroupID); err != nil {
+			return nil, err
+		}
+		conversationID = models.GroupConversationID(req.GroupID)
+	} else {
+		_, err := s.userStore.GetUserByID(req.ReceiverID)
+		if errors.Is(err, stores.ErrUserNotFound) {
+			return nil, ErrRecipientNotFound
+		}
+		if err != nil {
+			return nil, err
+		}
+		conversationID = models.DirectConversationID(botID, req.ReceiverID)
+	}
+
+	// 机器人消息不属于任何客户端平台，平台为0
+	msg := types.NewMessage(msgType, botID, req.ReceiverID, 0, []byte(req.Content))
+	msg.Header.GroupID = req.GroupID
+	msg.Header.SubType = req.SubType
+	result, err := s.gateway.Send(ctx, &types.SendRequest{Message: msg})
+	if err != nil {
+		return nil, err
+	}
+	if result.Rejected != "" {
+		return nil, &MessageRejectedError{Reason: result.Rejected}
+	}
+	return &response.SendMessageResponse{
+		ID:             msg.GetID(),
+		ConversationID: conversationID,
+		CreatedAt:      msg.GetTimestamp(),
+	}, nil
+}
+
+// getBot 获取机器人账号，用户不是机器人时返回 ErrNotBot
+func (s *BotService) getBot(botID string) (*models.User, error) {
+	bot, err := s.userStore.GetUserByID(botID)
+	if err != nil {
+		return nil, err
+	}
+	if bot.Role != models.RoleBot {
+		return nil, ErrNotBot
+	}
+	return bot, nil
+}
+
+// newKey 生成新的 API 密钥，ttl 为0时不过期，返回的明文密钥只在创建和轮换时交给调用方
+func (s *BotService) newKey(botID, name, scopes, createdBy string, ttl time.Duration) (*models.APIKey, string, error) {
+	secret, prefix, err := auth.GenerateAPIKey()
+	if err != nil {
+		return nil, "", err
+	}
+	now := s.now()
+	key := &models.APIKey{
+		ID:        snowflake.GenerateID(),
+		UserID:    botID,
+		Name:      name,
+		Prefix:    prefix,
+		Hash:      auth.HashAPIKey(secret),
+		Scopes:    scopes,
+		CreatedBy: createdBy,
+		CreatedAt: now,
+	}
+	if ttl > 0 {
+		expiresAt := now.Add(ttl)
+		key.ExpiresAt = &expiresAt
+	}
+	return key, secret, nil
+}
+
+// randomSecret 生成不会告知任何人的随机密码
+func randomSecret() (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
@@ -0,0 +1,196 @@
+package services_test
+
+import (
+	"context"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"gorm.io/gorm"
+
+	"github.com/woxQAQ/gim/internal/apiserver/services"
+	"github.com/woxQAQ/gim/internal/apiserver/stores"
+	"github.com/woxQAQ/gim/internal/apiserver/types/request"
+	"github.com/woxQAQ/gim/internal/audit"
+	"github.com/woxQAQ/gim/internal/models"
+	"github.com/woxQAQ/gim/internal/wsgateway/base"
+	"github.com/woxQAQ/gim/internal/wsgateway/codec"
+	"github.com/woxQAQ/gim/internal/wsgateway/handler"
+	"github.com/woxQAQ/gim/internal/wsgateway/user"
+	"github.com/woxQAQ/gim/pkg/auth"
+	"github.com/woxQAQ/gim/pkg/logger"
+)
+
+// deliveries 记录网关转发消息的接收方，其余方法不应被调用
+type deliveries struct {
+	user.IUserManager
+	to []string
+}
+
+func (d *deliveries) SendMessage(userID string, _ base.IMessage) []error {
+	d.to = append(d.to, userID)
+	return nil
+}
+
+var _ = Describe("BotService", func() {
+	var (
+		gdb       *gorm.DB
+		ustore    *stores.UserStore
+		mstore    *stores.MessageStore
+		delivered *deliveries
+		users     *services.UserService
+		svc       *services.BotService
+		bot       string
+		alice     *models.User
+		ctx       = context.Background()
+	)
+
+	// newKey 为机器人创建可以发送消息的密钥
+	newKey := func() string {
+		key, err := svc.CreateKey(ctx, bot, &request.CreateAPIKeyRequest{
+			Name: "backend", Scopes: []string{models.ScopeMessagesSend}, CreatedBy: "admin",
+		})
+		Expect(err).NotTo(HaveOccurred())
+		return key.Key
+	}
+
+	BeforeEach(func() {
+		gdb = openDB()
+		ustore = stores.NewUserStore(gdb)
+		mstore = stores.NewMessageStore(gdb)
+		gstore := stores.NewGroupStore(gdb)
+		bstore := stores.NewBanStore(gdb)
+		l, _ := logger.NewLogger(&logger.Config{Level: "error"})
+		l.Disable()
+		recorder := audit.NewRecorder(stores.NewAuditStore(gdb), false, l)
+
+		// 网关使用与 WebSocket 消息相同的消息路由
+		encoder := codec.NewJSONEncoder()
+		delivered = &deliveries{}
+		gw := &fakeGateway{router: handler.NewMessageRouter(&handler.MessageRouterConfig{
+			UserManager:   delivered,
+			MessageStore:  mstore,
+			Encoder:       encoder,
+			BeforeDeliver: []handler.Handler{handler.NewBlockHandler(stores.NewBlockStore(gdb), encoder)},
+			Groups:        gstore,
+			Roles:         ustore,
+		})}
+		users = services.NewUserService(ustore, bstore, nil, recorder, nil, nil)
+		svc = services.NewBotService(ustore, stores.NewAPIKeyStore(gdb), gstore, bstore, gw, recorder, l)
+
+		resp, err := svc.CreateBot(ctx, &request.CreateBotRequest{Username: "notifier", CreatedBy: "admin"})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(resp.Role).To(Equal(models.RoleBot))
+		bot = resp.ID
+		alice = &models.User{Username: "alice", Password: "secret"}
+		Expect(users.Register(ctx, alice, "")).To(Succeed())
+	})
+
+	It("密钥只保存摘要，吊销后或机器人被封禁后失效", func() {
+		key := newKey()
+		principal, err := svc.VerifyAPIKey(key)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(principal.UserID).To(Equal(bot))
+		Expect(principal.HasScope(models.ScopeMessagesSend)).To(BeTrue())
+
+		var stored models.APIKey
+		Expect(gdb.First(&stored, "id = ?", principal.KeyID).Error).To(Succeed())
+		Expect(stored.Hash).NotTo(Equal(key))
+		Expect(key).To(HavePrefix(stored.Prefix))
+		Expect(stored.LastUsedAt).NotTo(BeNil())
+
+		_, err = svc.VerifyAPIKey(key + "x")
+		Expect(err).To(MatchError(auth.ErrInvalidToken))
+
+		Expect(stores.NewBanStore(gdb).CreateBan(&models.UserBan{ID: "b1", UserID: bot, Reason: "spam"})).To(Succeed())
+		_, err = svc.VerifyAPIKey(key)
+		Expect(err).To(MatchError(auth.ErrInvalidToken))
+		_, err = stores.NewBanStore(gdb).LiftBans(bot, "admin", stored.CreatedAt.AddDate(1, 0, 0))
+		Expect(err).NotTo(HaveOccurred())
+
+		Expect(svc.RevokeKey(ctx, bot, principal.KeyID, "admin")).To(Succeed())
+		_, err = svc.VerifyAPIKey(key)
+		Expect(err).To(MatchError(auth.ErrInvalidToken))
+		Expect(svc.RevokeKey(ctx, bot, principal.KeyID, "admin")).To(MatchError(stores.ErrAPIKeyNotFound))
+	})
+
+	It("机器人不能登录，普通用户不能创建密钥，也不能被改为机器人", func() {
+		_, err := users.Login(ctx, "notifier", "", "")
+		Expect(err).To(MatchError(services.ErrInvalidCredentials))
+
+		_, err = svc.CreateKey(ctx, alice.ID, &request.CreateAPIKeyRequest{Scopes: []string{models.ScopeMessagesSend}})
+		Expect(err).To(MatchError(services.ErrNotBot))
+		_, err = users.SetRole(ctx, "admin", bot, models.RoleAdmin)
+		Expect(err).To(MatchError(services.ErrBotRole))
+		_, err = users.SetRole(ctx, "admin", alice.ID, models.RoleBot)
+		Expect(err).To(MatchError(services.ErrInvalidRole))
+	})
+
+	It("轮换后旧密钥在宽限期内仍然有效，没有宽限期时立即失效", func() {
+		old := newKey()
+		principal, err := svc.VerifyAPIKey(old)
+		Expect(err).NotTo(HaveOccurred())
+
+		rotated, err := svc.RotateKey(ctx, bot, principal.KeyID, &request.RotateAPIKeyRequest{GracePeriodSeconds: 3600})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(rotated.Name).To(Equal("backend"))
+		Expect(rotated.Scopes).To(Equal([]string{models.ScopeMessagesSend}))
+		_, err = svc.VerifyAPIKey(old)
+		Expect(err).NotTo(HaveOccurred())
+		_, err = svc.VerifyAPIKey(rotated.Key)
+		Expect(err).NotTo(HaveOccurred())
+
+		again, err := svc.RotateKey(ctx, bot, rotated.ID, &request.RotateAPIKeyRequest{})
+		Expect(err).NotTo(HaveOccurred())
+		_, err = svc.VerifyAPIKey(rotated.Key)
+		Expect(err).To(MatchError(auth.ErrInvalidToken))
+		_, err = svc.VerifyAPIKey(again.Key)
+		Expect(err).NotTo(HaveOccurred())
+
+		_, err = svc.RotateKey(ctx, bot, rotated.ID, &request.RotateAPIKeyRequest{})
+		Expect(err).To(MatchError(stores.ErrAPIKeyNotFound))
+		keys, err := svc.ListKeys(bot)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(keys).To(HaveLen(3))
+	})
+
+	It("单聊消息经过网关的消息路由投递和存储，被拉黑时拒绝", func() {
+		sent, err := svc.SendMessage(ctx, bot, &request.SendMessageRequest{ReceiverID: alice.ID, Content: "欢迎"})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(sent.ConversationID).To(Equal(models.DirectConversationID(bot, alice.ID)))
+		Expect(delivered.to).To(Equal([]string{alice.ID}))
+		stored, err := mstore.GetMessage(sent.ID)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(stored.Content).To(Equal("欢迎"))
+		Expect(stored.FromID).To(Equal(bot))
+
+		_, err = svc.SendMessage(ctx, bot, &request.SendMessageRequest{ReceiverID: "nobody", Content: "x"})
+		Expect(err).To(MatchError(services.ErrRecipientNotFound))
+		_, err = svc.SendMessage(ctx, bot, &request.SendMessageRequest{ReceiverID: alice.ID, Content: "x", Type: 8})
+		Expect(err).To(MatchError(services.ErrSubTypeRequired))
+
+		Expect(stores.NewBlockStore(gdb).Block(alice.ID, bot)).To(Succeed())
+		_, err = svc.SendMessage(ctx, bot, &request.SendMessageRequest{ReceiverID: alice.ID, Content: "再次欢迎"})
+		Expect(err).To(MatchError(services.ErrMessageRejected))
+		Expect(delivered.to).To(HaveLen(1))
+	})
+
+	It("群聊消息转发给全部成员并存储到群会话", func() {
+		Expect(gdb.Create(&models.Group{ID: "g1", Name: "team", OwnerID: alice.ID}).Error).To(Succeed())
+		for _, id := range []string{alice.ID, "u2"} {
+			Expect(gdb.Create(&models.GroupMember{GroupID: "g1", UserID: id}).Error).To(Succeed())
+		}
+
+		sent, err := svc.SendMessage(ctx, bot, &request.SendMessageRequest{GroupID: "g1", Content: "今晚维护"})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(sent.ConversationID).To(Equal(models.GroupConversationID("g1")))
+		Expect(delivered.to).To(ConsistOf(alice.ID, "u2"))
+		stored, err := mstore.GetMessage(sent.ID)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(stored.ToID).To(Equal("g1"))
+
+		_, err = svc.SendMessage(ctx, bot, &request.SendMessageRequest{GroupID: "g2", Content: "x"})
+		Expect(err).To(MatchError(stores.ErrGroupNotFound))
+		_, err = svc.SendMessage(ctx, bot, &request.SendMessageRequest{GroupID: "g1", ReceiverID: alice.ID, Content: "x"})
+		Expect(err).To(MatchError(services.ErrInvalidMessageTarget))
+	})
+})
@@ -0,0 +1,30 @@
+package services
+
+import (
+	"context"
+	"crypto/subtle"
+	"errors"
+)
+
+var (
+	// ErrCaptchaRequired 失败次数过多，需要先完成人机验证
+	ErrCaptchaRequired = errors.New("需要完成人机验证")
+	// ErrCaptchaInvalid 人机验证未通过
+	ErrCaptchaInvalid = errors.New("人机验证未通过")
+)
+
+// CaptchaVerifier 校验客户端提交的人机验证令牌，接入第三方验证服务时实现该接口
+type CaptchaVerifier interface {
+	// Verify 校验令牌，remoteIP 为客户端地址，可能为空
+	Verify(ctx context.Context, token, remoteIP string) (bool, error)
+}
+
+// StaticCaptcha 只接受固定令牌的人机验证，用于本地开发和测试
+type StaticCaptcha struct {
+	Token string
+}
+
+// Verify 令牌与配置的固定令牌一致时通过
+func (c *StaticCaptcha) Verify(_ context.Context, token, _ string) (bool, error) {
+	return c.Token != "" && subtle.ConstantTimeCompare([]byte(token), []byte(c.Token)) == 1, nil
+}
@@ -0,0 +1,317 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"time"
+
+	"github.com/woxQAQ/gim/internal/apiserver/gateway"
+	"github.com/woxQAQ/gim/internal/apiserver/stores"
+	"github.com/woxQAQ/gim/internal/apiserver/types/response"
+	"github.com/woxQAQ/gim/internal/models"
+	"github.com/woxQAQ/gim/internal/types"
+	"github.com/woxQAQ/gim/pkg/logger"
+	"github.com/woxQAQ/gim/pkg/snowflake"
+)
+
+var (
+	// ErrFriendRequestSelf 不能添加自己为联系人
+	ErrFriendRequestSelf = errors.New("不能添加自己为联系人")
+	// ErrAlreadyContacts 双方已经是联系人
+	ErrAlreadyContacts = errors.New("已经是联系人")
+	// ErrFriendRequestHandled 好友申请已经处理过
+	ErrFriendRequestHandled = errors.New("好友申请已处理")
+)
+
+// 推送给联系人双方的事件，作为系统消息的 SubType
+const (
+	ContactEventRequest  = "contact.request"
+	ContactEventAccepted = "contact.accepted"
+	ContactEventRejected = "contact.rejected"
+	ContactEventDeleted  = "contact.deleted"
+)
+
+// contactPushTimeout 单次推送联系人事件的超时时间
+const contactPushTimeout = 5 * time.Second
+
+// ContactEvent 联系人事件系统消息的内容
+type ContactEvent struct {
+	Event   string                          `json:"event"`
+	UserID  string                          `json:"user_id"` // 触发事件的用户
+	Request *response.FriendRequestResponse `json:"request,omitempty"`
+}
+
+// ContactService 处理好友申请和联系人相关的业务逻辑
+type ContactService struct {
+	contactStore *stores.ContactStore
+	blockStore   *stores.BlockStore
+	userStore    *stores.UserStore
+	uow          *stores.UnitOfWork
+	gateway      gateway.Client
+	logger       logger.Logger
+}
+
+// NewContactService 创建ContactService实例，gw 为空时不推送事件也不返回在线状态
+func NewContactService(contactStore *stores.ContactStore, blockStore *stores.BlockStore, userStore *stores.UserStore, uow *stores.UnitOfWork, gw gateway.Client, l logger.Logger) *ContactService {
+	return &ContactService{
+		contactStore: contactStore,
+		blockStore:   blockStore,
+		userStore:    userStore,
+		uow:          uow,
+		gateway:      gw,
+		logger:       l,
+	}
+}
+
+// SendRequest 向 toID 发送好友申请。
+// 已有待处理的申请时只更新附言；对方也向自己发过申请时直接成为联系人
+func (s *ContactService) SendRequest(ctx context.Context, fromID, toID, message string) (*response.FriendRequestResponse, error) {
+	if fromID == toID {
+		return nil, ErrFriendRequestSelf
+	}
+	if _, err := s.userStore.GetUserByID(toID); err != nil {
+		return nil, err
+	}
+	if blocked, err := s.blockStore.IsBlocked(toID, fromID); err != nil {
+		return nil, err
+	} else if blocked {
+		return nil, ErrBlocked
+	}
+	if ok, err := s.contactStore.IsContact(fromID, toID); err != nil {
+		return nil, err
+	} else if ok {
+		return nil, ErrAlreadyContacts
+	}
+
+	if reverse, err := s.contactStore.GetPendingRequest(toID, fromID); err == nil {
+		return s.Accept(ctx, fromID, reverse.ID)
+	} else if !errors.Is(err, stores.ErrFriendRequestNotFound) {
+		return nil, err
+	}
+
+	req, err := s.contactStore.GetPendingRequest(fromID, toID)
+	switch {
+	case err == nil:
+		if err := s.contactStore.UpdateRequestMessage(req.ID, message); err != nil {
+			return nil, err
+		}
+		req.Message = message
+	case errors.Is(err, stores.ErrFriendRequestNotFound):
+		req = &models.FriendRequest{
+			ID:      snowflake.GenerateID(),
+			FromID:  fromID,
+			ToID:    toID,
+			Message: message,
+			Status:  models.FriendRequestPending,
+		}
+		if err := s.contactStore.CreateRequest(req); err != nil {
+			return nil, err
+		}
+	default:
+		return nil, err
+	}
+
+	resp := req.ToResponse()
+	s.push(ctx, toID, &ContactEvent{Event: ContactEventRequest, UserID: fromID, Request: resp})
+	return resp, nil
+}
+
+// Accept 同意发给 userID 的好友申请，双方成为联系人
+func (s *ContactService) Accept(ctx context.Context, userID, requestID string) (*response.FriendRequestResponse, error) {
+	req, err := s.incomingRequest(userID, requestID)
+	if err != nil {
+		return nil, err
+	}
+	err = s.uow.Do(ctx, func(tx *stores.Tx) error {
+		contacts := s.contactStore.WithTx(tx)
+		ok, err := contacts.HandleRequest(req.ID, models.FriendRequestAccepted)
+		if err != nil {
+			return err
+		}
+		if !ok {
+			return ErrFriendRequestHandled
+		}
+		// 自己发给对方的申请同时失效
+		if reverse, err := contacts.GetPendingRequest(userID, req.FromID); err == nil {
+			if _, err := contacts.HandleRequest(reverse.ID, models.FriendRequestAccepted); err != nil {
+				return err
+			}
+		} else if !errors.Is(err, stores.ErrFriendRequestNotFound) {
+			return err
+		}
+		return contacts.AddContacts(req.FromID, req.ToID)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	req.Status = models.FriendRequestAccepted
+	req.HandledAt = &now
+	resp := req.ToResponse()
+	s.push(ctx, req.FromID, &ContactEvent{Event: ContactEventAccepted, UserID: userID, Request: resp})
+	return resp, nil
+}
+
+// Reject 拒绝发给 userID 的好友申请
+func (s *ContactService) Reject(ctx context.Context, userID, requestID string) (*response.FriendRequestResponse, error) {
+	req, err := s.incomingRequest(userID, requestID)
+	if err != nil {
+		return nil, err
+	}
+	ok, err := s.contactStore.HandleRequest(req.ID, models.FriendRequestRejected)
+	if err != nil {
+		return nil, err
+	}
+	if !ok {
+		return nil, ErrFriendRequestHandled
+	}
+
+	now := time.Now()
+	req.Status = models.FriendRequestRejected
+	req.HandledAt = &now
+	resp := req.ToResponse()
+	s.push(ctx, req.FromID, &ContactEvent{Event: ContactEventRejected, UserID: userID, Request: resp})
+	return resp, nil
+}
+
+// incomingRequest 获取发给 userID 的待处理申请，其他用户的申请视为不存在
+func (s *ContactService) incomingRequest(userID, requestID string) (*models.FriendRequest, error) {
+	req, err := s.contactStore.GetRequest(requestID)
+	if err != nil {
+		return nil, err
+	}
+	if req.ToID != userID {
+		return nil, stores.ErrFriendRequestNotFound
+	}
+	if req.Status != models.FriendRequestPending {
+		return nil, ErrFriendRequestHandled
+	}
+	return req, nil
+}
+
+// ListRequests 获取用户收到或发出的好友申请
+func (s *ContactService) ListRequests(userID string, incoming bool, before string, pageSize int) ([]*response.FriendRequestResponse, error) {
+	if pageSize <= 0 || pageSize > 100 {
+		pageSize = 20
+	}
+	reqs, err := s.contactStore.ListRequests(userID, incoming, before, pageSize)
+	if err != nil {
+		return nil, err
+	}
+	out := make([]*response.FriendRequestResponse, 0, len(reqs))
+	for _, r := range reqs {
+		out = append(out, r.ToResponse())
+	}
+	return out, nil
+}
+
+// Delete 删除联系人，双方的联系人记录都会删除
+func (s *ContactService) Delete(ctx context.Context, userID, contactID string) error {
+	n, err := s.contactStore.RemoveContacts(userID, contactID)
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		return stores.ErrContactNotFound
+	}
+	s.push(ctx, contactID, &ContactEvent{Event: ContactEventDeleted, UserID: userID})
+	return nil
+}
+
+// SetRemark 设置联系人备注，只对自己可见
+func (s *ContactService) SetRemark(userID, contactID, remark string) error {
+	return s.contactStore.SetRemark(userID, contactID, remark)
+}
+
+// ListContacts 获取用户的联系人及其在线状态。
+// 网关不可用时在线状态均为离线，把用户拉黑的联系人也始终显示为离线
+func (s *ContactService) ListContacts(ctx context.Context, userID string) ([]*response.ContactResponse, error) {
+	contacts, err := s.contactStore.ListContacts(userID)
+	if err != nil {
+		return nil, err
+	}
+	ids := make([]string, 0, len(contacts))
+	for _, c := range contacts {
+		ids = append(ids, c.ContactID)
+	}
+	users, err := s.userStore.GetUsersByIDs(ids)
+	if err != nil {
+		return nil, err
+	}
+	byID := make(map[string]*models.User, len(users))
+	for _, u := range users {
+		byID[u.ID] = u
+	}
+	online := s.presence(ctx, ids)
+	hidden, err := s.blockStore.BlockedBy(userID, ids)
+	if err != nil {
+		return nil, err
+	}
+
+	out := make([]*response.ContactResponse, 0, len(contacts))
+	for _, c := range contacts {
+		u, ok := byID[c.ContactID]
+		if !ok {
+			continue
+		}
+		var platforms []int32
+		if !hidden[c.ContactID] {
+			platforms = online[c.ContactID]
+		}
+		out = append(out, &response.ContactResponse{
+			User:      u.ToPublicResponse(),
+			Remark:    c.Remark,
+			Online:    len(platforms) > 0,
+			Platforms: platforms,
+			CreatedAt: c.CreatedAt,
+		})
+	}
+	return out, nil
+}
+
+// presence 查询在线状态，失败时只记录日志
+func (s *ContactService) presence(ctx context.Context, ids []string) map[string][]int32 {
+	if s.gateway == nil || len(ids) == 0 {
+		return nil
+	}
+	ctx, cancel := context.WithTimeout(ctx, contactPushTimeout)
+	defer cancel()
+	result, err := s.gateway.Presence(ctx, &types.PresenceRequest{UserIDs: ids})
+	if err != nil {
+		if !errors.Is(err, gateway.ErrGatewayDisabled) {
+			s.logger.Warn("查询联系人在线状态失败", logger.Error(err))
+		}
+		return nil
+	}
+	return result.Online
+}
+
+// push 以系统消息向 userID 推送联系人事件，对方不在线或推送失败时不影响操作结果
+func (s *ContactService) push(ctx context.Context, userID string, event *ContactEvent) {
+	if s.gateway == nil {
+		return
+	}
+	payload, err := json.Marshal(event)
+	if err != nil {
+		s.logger.Error("编码联系人事件失败", logger.Error(err))
+		return
+	}
+	msg := types.NewMessage(types.MessageTypeSystem, "system", userID, 0, payload)
+	msg.Header.SubType = event.Event
+
+	ctx, cancel := context.WithTimeout(ctx, contactPushTimeout)
+	defer cancel()
+	_, err = s.gateway.Push(ctx, &types.PushRequest{
+		Target:  types.PushTargetUsers,
+		UserIDs: []string{userID},
+		Message: msg,
+	})
+	if err != nil && !errors.Is(err, gateway.ErrGatewayDisabled) {
+		s.logger.Warn("推送联系人事件失败",
+			logger.String("event", event.Event),
+			logger.String("user_id", userID),
+			logger.Error(err))
+	}
+}
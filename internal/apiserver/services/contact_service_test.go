@@ -0,0 +1,171 @@
+package services_test
+
+import (
+	"context"
+	"encoding/json"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"github.com/woxQAQ/gim/internal/apiserver/services"
+	"github.com/woxQAQ/gim/internal/apiserver/stores"
+	"github.com/woxQAQ/gim/internal/models"
+	"github.com/woxQAQ/gim/internal/types"
+	"github.com/woxQAQ/gim/pkg/logger"
+)
+
+var _ = Describe("ContactService", func() {
+	var (
+		ctx      context.Context
+		contacts *stores.ContactStore
+		blocks   *services.BlockService
+		gw       *fakeGateway
+		svc      *services.ContactService
+		alice    *models.User
+		bob      *models.User
+	)
+
+	// events 返回推送给 userID 的联系人事件
+	events := func(userID string) []string {
+		var out []string
+		for _, req := range gw.pushed {
+			if req.UserIDs[0] != userID {
+				continue
+			}
+			Expect(req.Message.Header.Type).To(Equal(types.MessageTypeSystem))
+			var event services.ContactEvent
+			Expect(json.Unmarshal(req.Message.Payload, &event)).To(Succeed())
+			out = append(out, event.Event)
+		}
+		return out
+	}
+
+	BeforeEach(func() {
+		ctx = context.Background()
+		gdb := openDB()
+		users := stores.NewUserStore(gdb)
+		contacts = stores.NewContactStore(gdb)
+		gw = &fakeGateway{}
+		l, _ := logger.NewLogger(&logger.Config{Level: "error"})
+		l.Disable()
+		blockStore := stores.NewBlockStore(gdb)
+		blocks = services.NewBlockService(blockStore, contacts, users)
+		svc = services.NewContactService(contacts, blockStore, users, stores.NewUnitOfWork(gdb), gw, l)
+
+		alice = &models.User{ID: "alice", Username: "alice", Password: "x"}
+		bob = &models.User{ID: "bob", Username: "bob", Password: "x"}
+		Expect(users.CreateUser(alice)).To(Succeed())
+		Expect(users.CreateUser(bob)).To(Succeed())
+	})
+
+	It("同意申请后双方成为联系人并通知申请人", func() {
+		req, err := svc.SendRequest(ctx, alice.ID, bob.ID, "你好")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(events(bob.ID)).To(Equal([]string{services.ContactEventRequest}))
+
+		incoming, err := svc.ListRequests(bob.ID, true, "", 0)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(incoming).To(HaveLen(1))
+		Expect(incoming[0].Message).To(Equal("你好"))
+
+		_, err = svc.Accept(ctx, alice.ID, req.ID)
+		Expect(err).To(MatchError(stores.ErrFriendRequestNotFound))
+		accepted, err := svc.Accept(ctx, bob.ID, req.ID)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(accepted.Status).To(Equal("accepted"))
+		Expect(events(alice.ID)).To(Equal([]string{services.ContactEventAccepted}))
+
+		for _, pair := range [][2]string{{alice.ID, bob.ID}, {bob.ID, alice.ID}} {
+			ok, err := contacts.IsContact(pair[0], pair[1])
+			Expect(err).NotTo(HaveOccurred())
+			Expect(ok).To(BeTrue())
+		}
+		_, err = svc.SendRequest(ctx, alice.ID, bob.ID, "")
+		Expect(err).To(MatchError(services.ErrAlreadyContacts))
+		_, err = svc.Reject(ctx, bob.ID, req.ID)
+		Expect(err).To(MatchError(services.ErrFriendRequestHandled))
+	})
+
+	It("双方互相申请时直接成为联系人", func() {
+		_, err := svc.SendRequest(ctx, alice.ID, bob.ID, "")
+		Expect(err).NotTo(HaveOccurred())
+		resp, err := svc.SendRequest(ctx, bob.ID, alice.ID, "")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(resp.Status).To(Equal("accepted"))
+
+		outgoing, err := svc.ListRequests(bob.ID, false, "", 0)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(outgoing).To(BeEmpty())
+	})
+
+	It("拒绝和删除联系人时通知对方，备注只属于自己", func() {
+		req, err := svc.SendRequest(ctx, alice.ID, bob.ID, "")
+		Expect(err).NotTo(HaveOccurred())
+		_, err = svc.Reject(ctx, bob.ID, req.ID)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(events(alice.ID)).To(Equal([]string{services.ContactEventRejected}))
+
+		req, err = svc.SendRequest(ctx, alice.ID, bob.ID, "再试一次")
+		Expect(err).NotTo(HaveOccurred())
+		_, err = svc.Accept(ctx, bob.ID, req.ID)
+		Expect(err).NotTo(HaveOccurred())
+
+		Expect(svc.SetRemark(alice.ID, bob.ID, "老王")).To(Succeed())
+		gw.online = map[string][]int32{bob.ID: {1, 2}}
+		list, err := svc.ListContacts(ctx, alice.ID)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(list).To(HaveLen(1))
+		Expect(list[0].User.ID).To(Equal(bob.ID))
+		Expect(list[0].Remark).To(Equal("老王"))
+		Expect(list[0].Online).To(BeTrue())
+		Expect(list[0].Platforms).To(ConsistOf(int32(1), int32(2)))
+		list, err = svc.ListContacts(ctx, bob.ID)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(list[0].Remark).To(BeEmpty())
+		Expect(list[0].Online).To(BeFalse())
+
+		Expect(svc.Delete(ctx, bob.ID, alice.ID)).To(Succeed())
+		Expect(events(alice.ID)).To(ContainElement(services.ContactEventDeleted))
+		list, err = svc.ListContacts(ctx, alice.ID)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(list).To(BeEmpty())
+		Expect(svc.Delete(ctx, bob.ID, alice.ID)).To(MatchError(stores.ErrContactNotFound))
+		Expect(svc.SetRemark(alice.ID, bob.ID, "")).To(MatchError(stores.ErrContactNotFound))
+	})
+
+	It("拉黑后拒绝对方的好友申请并隐藏自己的在线状态", func() {
+		_, err := svc.SendRequest(ctx, bob.ID, alice.ID, "")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(blocks.Block(alice.ID, bob.ID)).To(Succeed())
+		Expect(blocks.Block(alice.ID, alice.ID)).To(MatchError(services.ErrBlockSelf))
+
+		outgoing, err := svc.ListRequests(bob.ID, false, "", 0)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(outgoing[0].Status).To(Equal("rejected"))
+		_, err = svc.SendRequest(ctx, bob.ID, alice.ID, "")
+		Expect(err).To(MatchError(services.ErrBlocked))
+
+		// 拉黑前已经是联系人时仍保留联系人关系，但对方看不到在线状态
+		Expect(blocks.Unblock(alice.ID, bob.ID)).To(Succeed())
+		req, err := svc.SendRequest(ctx, bob.ID, alice.ID, "")
+		Expect(err).NotTo(HaveOccurred())
+		_, err = svc.Accept(ctx, alice.ID, req.ID)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(blocks.Block(alice.ID, bob.ID)).To(Succeed())
+		gw.online = map[string][]int32{alice.ID: {1}, bob.ID: {1}}
+
+		list, err := svc.ListContacts(ctx, bob.ID)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(list[0].Online).To(BeFalse())
+		list, err = svc.ListContacts(ctx, alice.ID)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(list[0].Online).To(BeTrue())
+
+		blocked, err := blocks.List(alice.ID)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(blocked).To(HaveLen(1))
+		Expect(blocked[0].User.ID).To(Equal(bob.ID))
+		Expect(blocks.Unblock(alice.ID, bob.ID)).To(Succeed())
+		Expect(blocks.Unblock(alice.ID, bob.ID)).To(MatchError(services.ErrNotBlocked))
+	})
+})
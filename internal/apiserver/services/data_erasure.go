@@ -0,0 +1,206 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/woxQAQ/gim/internal/apiserver/gateway"
+	"github.com/woxQAQ/gim/internal/apiserver/stores"
+	"github.com/woxQAQ/gim/internal/archive"
+	"github.com/woxQAQ/gim/internal/models"
+	"github.com/woxQAQ/gim/internal/types"
+	"github.com/woxQAQ/gim/pkg/logger"
+)
+
+// disconnectTimeout 删除数据前断开用户连接的超时时间
+const disconnectTimeout = 10 * time.Second
+
+// erase 删除或匿名化用户数据。
+//
+// 先吊销登录会话并断开用户在网关上的连接，避免删除过程中写入新消息；网关不可用时任务失败，可以重新提交。
+// 各步骤都可以重复执行，任务中途失败后重新提交会从头处理剩余的数据。
+func (s *DataRequestService) erase(ctx context.Context, dr *models.DataRequest) (map[string]int64, error) {
+	summary := map[string]int64{}
+	remove := dr.Mode == models.DeletionRemove
+
+	// 先吊销登录会话，断开连接后已签发的令牌不能再重新连接或访问接口
+	revoked, err := s.sessionStore.RevokeUserSessions(dr.UserID)
+	if err != nil {
+		return summary, err
+	}
+	summary["revoked_sessions"] = revoked
+
+	if s.gateway != nil {
+		dctx, cancel := context.WithTimeout(ctx, disconnectTimeout)
+		result, err := s.gateway.Disconnect(dctx, &types.DisconnectRequest{
+			UserIDs: []string{dr.UserID},
+			Reason:  "account deleted",
+		})
+		cancel()
+		switch {
+		case errors.Is(err, gateway.ErrGatewayDisabled):
+		case err != nil:
+			return summary, err
+		default:
+			summary["sessions"] = int64(result.Disconnected)
+		}
+	}
+
+	n, err := s.groupStore.RemoveMemberships(dr.UserID)
+	if err != nil {
+		return summary, err
+	}
+	summary["groups"] = n
+	if n, err = s.noticeStore.RemoveRecipient(dr.UserID); err != nil {
+		return summary, err
+	}
+	summary["notices"] = n
+	if n, err = s.contactStore.RemoveByUser(dr.UserID); err != nil {
+		return summary, err
+	}
+	summary["contacts"] = n
+	if n, err = s.blockStore.RemoveByUser(dr.UserID); err != nil {
+		return summary, err
+	}
+	summary["blocks"] = n
+
+	if err := s.eraseMessages(ctx, dr.UserID, remove, summary); err != nil {
+		return summary, err
+	}
+	if err := s.eraseArchived(ctx, dr.UserID, remove, summary); err != nil {
+		return summary, err
+	}
+
+	if remove {
+		n, err = s.moderationStore.DeleteRecordsByUser(dr.UserID)
+	} else {
+		n, err = s.moderationStore.ScrubRecordsByUser(dr.UserID)
+	}
+	if err != nil {
+		return summary, err
+	}
+	summary["moderation_records"] = n
+
+	// 已生成的导出文件同样包含用户数据
+	exports, err := s.requestStore.ListRequestsByUser(dr.UserID)
+	if err != nil {
+		return summary, err
+	}
+	for _, e := range exports {
+		if e.Kind == models.DataRequestExport && e.FilePath != "" {
+			s.expireExport(e)
+			summary["exports"]++
+		}
+	}
+
+	// 上传的头像文件，外部地址由 BlobStore 忽略
+	if s.blobs != nil {
+		user, err := s.userStore.GetUserByID(dr.UserID)
+		if err == nil && user.Avatar != "" {
+			err = s.blobs.Remove(ctx, user.Avatar)
+		}
+		if err != nil && !errors.Is(err, stores.ErrUserNotFound) {
+			return summary, err
+		}
+	}
+
+	if remove {
+		err = s.userStore.DeleteUser(dr.UserID)
+	} else {
+		err = s.userStore.AnonymizeUser(dr.UserID)
+	}
+	return summary, err
+}
+
+// eraseMessages 分批处理消息表中的消息：remove 删除收发的全部消息，否则清空发送的消息
+func (s *DataRequestService) eraseMessages(ctx context.Context, userID string, remove bool, summary map[string]int64) error {
+	q := &stores.MessageQuery{UserID: userID, Limit: s.cfg.BatchSize}
+	if !remove {
+		q.SenderID = userID
+	}
+	for {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		messages, err := s.messageStore.ListMessages(q)
+		if err != nil {
+			return err
+		}
+		if len(messages) == 0 {
+			return nil
+		}
+		ids := make([]string, 0, len(messages))
+		for _, m := range messages {
+			ids = append(ids, m.ID)
+		}
+		attachments, err := s.messageStore.GetAttachments(ids)
+		if err != nil {
+			return err
+		}
+
+		if remove {
+			err = s.messageStore.DeleteMessages(ids)
+		} else {
+			err = s.messageStore.ScrubMessages(ids)
+			// 清空后的消息仍然满足条件，用游标继续
+			q.Before = ids[len(ids)-1]
+		}
+		if err != nil {
+			return err
+		}
+		summary["messages"] += int64(len(ids))
+		summary["attachments"] += int64(len(attachments))
+		s.cleanup(ctx, ids, attachments)
+
+		if len(messages) < s.cfg.BatchSize {
+			return nil
+		}
+	}
+}
+
+// eraseArchived 处理冷归档中的消息，规则与消息表一致
+func (s *DataRequestService) eraseArchived(ctx context.Context, userID string, remove bool, summary map[string]int64) error {
+	if s.archive == nil {
+		return nil
+	}
+	var ids []string
+	var attachments []*models.MessageAttachment
+	n, err := s.archive.EraseUser(userID, func(r *archive.Record) archive.Action {
+		if r.FromID != userID && (!remove || r.ToID != userID) {
+			return archive.Keep
+		}
+		ids = append(ids, r.ID)
+		for _, a := range r.Attachments {
+			attachments = append(attachments, &models.MessageAttachment{ID: a.ID, MessageID: r.ID, URL: a.URL})
+		}
+		if remove {
+			return archive.Drop
+		}
+		return archive.Scrub
+	})
+	if err != nil {
+		return err
+	}
+	summary["archived_messages"] = int64(n)
+	s.cleanup(ctx, ids, attachments)
+	return nil
+}
+
+// cleanup 删除附件文件和检索索引，失败只记录日志
+func (s *DataRequestService) cleanup(ctx context.Context, ids []string, attachments []*models.MessageAttachment) {
+	if s.blobs != nil {
+		for _, a := range attachments {
+			if err := s.blobs.Remove(ctx, a.URL); err != nil {
+				s.logger.Warn("删除附件文件失败", logger.String("attachment_id", a.ID), logger.Error(err))
+			}
+		}
+	}
+	if s.searchIndex != nil {
+		for _, id := range ids {
+			if err := s.searchIndex.Delete(id); err != nil {
+				s.logger.Warn("删除消息索引失败", logger.String("message_id", id), logger.Error(err))
+			}
+		}
+	}
+}
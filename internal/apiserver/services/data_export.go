@@ -0,0 +1,338 @@
+package services
+
+import (
+	"archive/zip"
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"time"
+
+	"github.com/woxQAQ/gim/internal/apiserver/stores"
+	"github.com/woxQAQ/gim/internal/apiserver/types/response"
+	"github.com/woxQAQ/gim/internal/archive"
+	"github.com/woxQAQ/gim/internal/models"
+)
+
+// exportProfile 导出文件中的用户资料，不包含密码
+type exportProfile struct {
+	ID        string    `json:"id"`
+	Username  string    `json:"username"`
+	Nickname  string    `json:"nickname"`
+	Avatar    string    `json:"avatar"`
+	Gender    int8      `json:"gender"`
+	Phone     string    `json:"phone"`
+	Email     string    `json:"email"`
+	Status    int8      `json:"status"`
+	Bio       string    `json:"bio"`
+	LastLogin time.Time `json:"last_login"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// exportMessage 导出文件中的一条消息
+type exportMessage struct {
+	ID             string              `json:"id"`
+	ConversationID string              `json:"conversation_id"`
+	FromID         string              `json:"from_id"`
+	ToID           string              `json:"to_id"`
+	Type           int32               `json:"type"`
+	Content        string              `json:"content"`
+	Status         int32               `json:"status"`
+	CreatedAt      time.Time           `json:"created_at"`
+	Archived       bool                `json:"archived,omitempty"`
+	Attachments    []*exportAttachment `json:"attachments,omitempty"`
+}
+
+// exportAttachment 导出文件中的附件，File 为附件文件在压缩包中的路径
+type exportAttachment struct {
+	ID        string `json:"id"`
+	MessageID string `json:"message_id"`
+	Type      string `json:"type"`
+	URL       string `json:"url"`
+	Size      int64  `json:"size"`
+	MimeType  string `json:"mime_type,omitempty"`
+	File      string `json:"file,omitempty"`
+}
+
+// exportGroups 导出文件中的群组信息
+type exportGroups struct {
+	Memberships []*models.GroupMember `json:"memberships"`
+	Owned       []*models.Group       `json:"owned"`
+}
+
+// exportContacts 导出文件中的联系人、好友申请和黑名单
+type exportContacts struct {
+	Contacts []*models.Contact       `json:"contacts"`
+	Sent     []*models.FriendRequest `json:"sent_requests"`
+	Received []*models.FriendRequest `json:"received_requests"`
+	Blocked  []*models.Block         `json:"blocked"`
+}
+
+// export 将用户数据打包为 zip 文件：
+//
+//	profile.json      用户资料
+//	messages.jsonl    收发的全部消息（包括冷归档），附件信息内联
+//	attachments.json  全部附件，file 为附件文件在压缩包中的路径
+//	attachments/      附件文件，仅包含本地存储的附件
+//	groups.json       加入和创建的群组
+//	contacts.json     联系人、收发的好友申请和黑名单
+//	moderation.json   发送的消息的审核记录
+func (s *DataRequestService) export(ctx context.Context, dr *models.DataRequest) (map[string]int64, error) {
+	summary := map[string]int64{}
+	user, err := s.userStore.GetUserByID(dr.UserID)
+	if err != nil {
+		return summary, err
+	}
+	if err := os.MkdirAll(s.cfg.ExportDir, 0o700); err != nil {
+		return summary, err
+	}
+
+	tmp, err := os.CreateTemp(s.cfg.ExportDir, dr.ID+".zip.tmp-*")
+	if err != nil {
+		return summary, err
+	}
+	defer os.Remove(tmp.Name())
+	defer tmp.Close()
+
+	zw := zip.NewWriter(tmp)
+	if err := writeZipJSON(zw, "profile.json", &exportProfile{
+		ID:        user.ID,
+		Username:  user.Username,
+		Nickname:  user.Nickname,
+		Avatar:    user.Avatar,
+		Gender:    user.Gender,
+		Phone:     user.Phone,
+		Email:     user.Email,
+		Status:    user.Status,
+		Bio:       user.Bio,
+		LastLogin: user.LastLogin,
+		CreatedAt: user.CreatedAt,
+		UpdatedAt: user.UpdatedAt,
+	}); err != nil {
+		return summary, err
+	}
+	if err := s.exportMessages(ctx, zw, dr.UserID, summary); err != nil {
+		return summary, err
+	}
+
+	memberships, err := s.groupStore.ListMemberships(dr.UserID)
+	if err != nil {
+		return summary, err
+	}
+	owned, err := s.groupStore.ListGroupsByOwner(dr.UserID)
+	if err != nil {
+		return summary, err
+	}
+	summary["groups"] = int64(len(memberships))
+	if err := writeZipJSON(zw, "groups.json", &exportGroups{Memberships: memberships, Owned: owned}); err != nil {
+		return summary, err
+	}
+
+	contacts := &exportContacts{}
+	if contacts.Contacts, err = s.contactStore.ListContacts(dr.UserID); err != nil {
+		return summary, err
+	}
+	if contacts.Sent, err = s.contactStore.ListRequests(dr.UserID, false, "", 0); err != nil {
+		return summary, err
+	}
+	if contacts.Received, err = s.contactStore.ListRequests(dr.UserID, true, "", 0); err != nil {
+		return summary, err
+	}
+	if contacts.Blocked, err = s.blockStore.ListBlocked(dr.UserID); err != nil {
+		return summary, err
+	}
+	summary["contacts"] = int64(len(contacts.Contacts))
+	if err := writeZipJSON(zw, "contacts.json", contacts); err != nil {
+		return summary, err
+	}
+
+	records, err := s.moderationStore.ListRecordsByUser(dr.UserID)
+	if err != nil {
+		return summary, err
+	}
+	moderation := make([]*response.ModerationRecordResponse, 0, len(records))
+	for _, r := range records {
+		moderation = append(moderation, r.ToResponse())
+	}
+	summary["moderation_records"] = int64(len(records))
+	if err := writeZipJSON(zw, "moderation.json", moderation); err != nil {
+		return summary, err
+	}
+
+	if err := zw.Close(); err != nil {
+		return summary, err
+	}
+	if err := tmp.Sync(); err != nil {
+		return summary, err
+	}
+	info, err := tmp.Stat()
+	if err != nil {
+		return summary, err
+	}
+	if err := tmp.Close(); err != nil {
+		return summary, err
+	}
+	target := filepath.Join(s.cfg.ExportDir, dr.ID+".zip")
+	if err := os.Rename(tmp.Name(), target); err != nil {
+		return summary, err
+	}
+
+	expires := time.Now().Add(s.cfg.ExportTTL)
+	dr.FilePath = target
+	dr.FileSize = info.Size()
+	dr.ExpiresAt = &expires
+	return summary, nil
+}
+
+// exportMessages 分批写入消息表和冷归档中的消息，同时存在于两处的消息只写一次
+func (s *DataRequestService) exportMessages(ctx context.Context, zw *zip.Writer, userID string, summary map[string]int64) error {
+	w, err := zw.Create("messages.jsonl")
+	if err != nil {
+		return err
+	}
+	enc := json.NewEncoder(w)
+	var files []*exportAttachment
+
+	written := map[string]struct{}{}
+	cursor := ""
+	for {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		messages, err := s.messageStore.ListMessages(&stores.MessageQuery{
+			UserID: userID,
+			Before: cursor,
+			Limit:  s.cfg.BatchSize,
+		})
+		if err != nil {
+			return err
+		}
+		if len(messages) == 0 {
+			break
+		}
+		ids := make([]string, 0, len(messages))
+		for _, m := range messages {
+			ids = append(ids, m.ID)
+		}
+		attachments, err := s.messageStore.GetAttachments(ids)
+		if err != nil {
+			return err
+		}
+		byMessage := map[string][]*exportAttachment{}
+		for _, a := range attachments {
+			ea := &exportAttachment{ID: a.ID, MessageID: a.MessageID, Type: a.Type, URL: a.URL, Size: a.Size, MimeType: a.MimeType}
+			byMessage[a.MessageID] = append(byMessage[a.MessageID], ea)
+			files = append(files, ea)
+		}
+		for _, m := range messages {
+			if err := enc.Encode(&exportMessage{
+				ID:             m.ID,
+				ConversationID: m.ConversationID,
+				FromID:         m.FromID,
+				ToID:           m.ToID,
+				Type:           int32(m.Type),
+				Content:        m.Content,
+				Status:         int32(m.Status),
+				CreatedAt:      m.CreatedAt,
+				Attachments:    byMessage[m.ID],
+			}); err != nil {
+				return err
+			}
+			written[m.ID] = struct{}{}
+		}
+		cursor = messages[len(messages)-1].ID
+		if len(messages) < s.cfg.BatchSize {
+			break
+		}
+	}
+
+	if s.archive != nil {
+		records, err := s.archive.ListRecords(&archive.Query{UserID: userID})
+		if err != nil {
+			return err
+		}
+		for _, r := range records {
+			if _, ok := written[r.ID]; ok {
+				continue
+			}
+			em := &exportMessage{
+				ID:             r.ID,
+				ConversationID: r.ConversationID,
+				FromID:         r.FromID,
+				ToID:           r.ToID,
+				Type:           int32(r.Type),
+				Content:        r.Content,
+				Status:         int32(r.Status),
+				CreatedAt:      r.CreatedAt,
+				Archived:       true,
+			}
+			for _, a := range r.Attachments {
+				ea := &exportAttachment{ID: a.ID, MessageID: r.ID, Type: a.Type, URL: a.URL, Size: a.Size, MimeType: a.MimeType}
+				em.Attachments = append(em.Attachments, ea)
+				files = append(files, ea)
+			}
+			if err := enc.Encode(em); err != nil {
+				return err
+			}
+			written[r.ID] = struct{}{}
+			summary["archived_messages"]++
+		}
+	}
+	summary["messages"] = int64(len(written))
+	summary["attachments"] = int64(len(files))
+
+	// 附件文件在消息之后写入，zip 不支持同时写两个文件
+	for _, ea := range files {
+		ok, err := s.exportBlob(ctx, zw, ea)
+		if err != nil {
+			return err
+		}
+		if ok {
+			summary["attachment_files"]++
+		}
+	}
+	if files == nil {
+		files = []*exportAttachment{}
+	}
+	return writeZipJSON(zw, "attachments.json", files)
+}
+
+// exportBlob 复制本地存储的附件文件，外部地址和已丢失的文件跳过
+func (s *DataRequestService) exportBlob(ctx context.Context, zw *zip.Writer, ea *exportAttachment) (bool, error) {
+	if s.blobs == nil {
+		return false, nil
+	}
+	r, err := s.blobs.Open(ctx, ea.URL)
+	if errors.Is(err, ErrBlobNotManaged) || os.IsNotExist(err) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	defer r.Close()
+
+	name := path.Join("attachments", ea.ID+"-"+path.Base(ea.URL))
+	w, err := zw.Create(name)
+	if err != nil {
+		return false, err
+	}
+	if _, err := io.Copy(w, r); err != nil {
+		return false, err
+	}
+	ea.File = name
+	return true, nil
+}
+
+func writeZipJSON(zw *zip.Writer, name string, v interface{}) error {
+	w, err := zw.Create(name)
+	if err != nil {
+		return err
+	}
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(v)
+}
@@ -0,0 +1,343 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"os"
+	"time"
+
+	"github.com/woxQAQ/gim/internal/apiserver/gateway"
+	"github.com/woxQAQ/gim/internal/apiserver/stores"
+	"github.com/woxQAQ/gim/internal/apiserver/types/request"
+	"github.com/woxQAQ/gim/internal/apiserver/types/response"
+	"github.com/woxQAQ/gim/internal/archive"
+	"github.com/woxQAQ/gim/internal/audit"
+	"github.com/woxQAQ/gim/internal/models"
+	"github.com/woxQAQ/gim/internal/search"
+	"github.com/woxQAQ/gim/pkg/logger"
+	"github.com/woxQAQ/gim/pkg/snowflake"
+)
+
+// ErrExportNotReady 导出任务尚未完成或文件已过期
+var ErrExportNotReady = errors.New("data export is not available")
+
+// DataRequestConfig 用户数据任务配置
+type DataRequestConfig struct {
+	// ExportDir 导出文件保存目录
+	ExportDir string
+	// ExportTTL 导出文件保留时长，过期后删除
+	ExportTTL time.Duration
+	// BatchSize 每批处理的消息数量
+	BatchSize int
+	// StaleAfter 执行超过该时长的任务视为进程已退出，重新放回队列
+	StaleAfter time.Duration
+}
+
+// DefaultDataRequestConfig 默认的用户数据任务配置
+var DefaultDataRequestConfig = DataRequestConfig{
+	ExportDir:  "data/exports",
+	ExportTTL:  7 * 24 * time.Hour,
+	BatchSize:  500,
+	StaleAfter: time.Hour,
+}
+
+// DataRequestService 处理用户数据导出与删除任务
+//
+// 任务创建后由 Run 在后台执行，状态依次为 pending、running、completed 或 failed。
+// 失败的任务不会自动重试，可以重新提交。
+type DataRequestService struct {
+	requestStore    *stores.DataRequestStore
+	userStore       *stores.UserStore
+	messageStore    *stores.MessageStore
+	groupStore      *stores.GroupStore
+	moderationStore *stores.ModerationStore
+	noticeStore     *stores.NoticeStore
+	sessionStore    *stores.SessionStore
+	contactStore    *stores.ContactStore
+	blockStore      *stores.BlockStore
+	searchIndex     search.Index
+	archive         *archive.Archive
+	blobs           BlobStore
+	gateway         gateway.Client
+	audit           *audit.Recorder
+	cfg             DataRequestConfig
+	logger          logger.Logger
+
+	wake chan struct{}
+}
+
+// NewDataRequestService 创建DataRequestService实例，searchIndex、archive 和 blobs 可以为空
+func NewDataRequestService(
+	requestStore *stores.DataRequestStore,
+	userStore *stores.UserStore,
+	messageStore *stores.MessageStore,
+	groupStore *stores.GroupStore,
+	moderationStore *stores.ModerationStore,
+	noticeStore *stores.NoticeStore,
+	sessionStore *stores.SessionStore,
+	contactStore *stores.ContactStore,
+	blockStore *stores.BlockStore,
+	searchIndex search.Index,
+	coldArchive *archive.Archive,
+	blobs BlobStore,
+	gw gateway.Client,
+	recorder *audit.Recorder,
+	l logger.Logger,
+	cfg *DataRequestConfig,
+) *DataRequestService {
+	c := DefaultDataRequestConfig
+	if cfg != nil {
+		if cfg.ExportDir != "" {
+			c.ExportDir = cfg.ExportDir
+		}
+		if cfg.ExportTTL > 0 {
+			c.ExportTTL = cfg.ExportTTL
+		}
+		if cfg.BatchSize > 0 {
+			c.BatchSize = cfg.BatchSize
+		}
+		if cfg.StaleAfter > 0 {
+			c.StaleAfter = cfg.StaleAfter
+		}
+	}
+	return &DataRequestService{
+		requestStore:    requestStore,
+		userStore:       userStore,
+		messageStore:    messageStore,
+		groupStore:      groupStore,
+		moderationStore: moderationStore,
+		noticeStore:     noticeStore,
+		sessionStore:    sessionStore,
+		contactStore:    contactStore,
+		blockStore:      blockStore,
+		searchIndex:     searchIndex,
+		archive:         coldArchive,
+		blobs:           blobs,
+		gateway:         gw,
+		audit:           recorder,
+		cfg:             c,
+		logger:          l,
+		wake:            make(chan struct{}, 1),
+	}
+}
+
+// Create 创建导出或删除任务，同一用户同类型的任务未完成时不能重复提交
+func (s *DataRequestService) Create(ctx context.Context, req *request.CreateDataRequestRequest) (*response.DataRequestResponse, error) {
+	dr := &models.DataRequest{
+		ID:          snowflake.GenerateID(),
+		UserID:      req.UserID,
+		Kind:        req.Kind,
+		Status:      models.DataRequestPending,
+		RequestedBy: req.RequestedBy,
+	}
+	switch req.Kind {
+	case models.DataRequestExport:
+	case models.DataRequestDelete:
+		dr.Mode = req.Mode
+		if dr.Mode == "" {
+			dr.Mode = models.DeletionAnonymize
+		}
+		if dr.Mode != models.DeletionAnonymize && dr.Mode != models.DeletionRemove {
+			return nil, errors.New("未知的删除方式: " + req.Mode)
+		}
+	default:
+		return nil, errors.New("未知的任务类型: " + req.Kind)
+	}
+
+	if _, err := s.userStore.GetUserByID(req.UserID); err != nil {
+		return nil, err
+	}
+	active, err := s.requestStore.HasActiveRequest(req.UserID, req.Kind)
+	if err != nil {
+		return nil, err
+	}
+	if active {
+		return nil, errors.New("已有未完成的同类任务")
+	}
+	if err := s.requestStore.CreateRequest(dr); err != nil {
+		return nil, err
+	}
+	action, detail := audit.ActionDataExport, map[string]any{"request_id": dr.ID}
+	if dr.Kind == models.DataRequestDelete {
+		action, detail["mode"] = audit.ActionDataDelete, dr.Mode
+	}
+	s.audit.Record(ctx, &audit.Entry{
+		Action:   action,
+		ActorID:  dr.RequestedBy,
+		TargetID: dr.UserID,
+		Detail:   detail,
+	})
+
+	// 唤醒后台任务立即执行
+	select {
+	case s.wake <- struct{}{}:
+	default:
+	}
+	return dr.ToResponse(), nil
+}
+
+// Get 获取任务状态
+func (s *DataRequestService) Get(userID, id string) (*response.DataRequestResponse, error) {
+	dr, err := s.getOwned(userID, id)
+	if err != nil {
+		return nil, err
+	}
+	return dr.ToResponse(), nil
+}
+
+// getOwned 获取属于用户的任务，不属于该用户时与任务不存在一样返回 ErrDataRequestNotFound
+func (s *DataRequestService) getOwned(userID, id string) (*models.DataRequest, error) {
+	dr, err := s.requestStore.GetRequest(id)
+	if err != nil {
+		return nil, err
+	}
+	if dr.UserID != userID {
+		return nil, stores.ErrDataRequestNotFound
+	}
+	return dr, nil
+}
+
+// ListByUser 获取用户的全部任务
+func (s *DataRequestService) ListByUser(userID string) ([]*response.DataRequestResponse, error) {
+	reqs, err := s.requestStore.ListRequestsByUser(userID)
+	if err != nil {
+		return nil, err
+	}
+	resp := make([]*response.DataRequestResponse, 0, len(reqs))
+	for _, dr := range reqs {
+		resp = append(resp, dr.ToResponse())
+	}
+	return resp, nil
+}
+
+// OpenExport 打开已完成的导出文件
+func (s *DataRequestService) OpenExport(userID, id string) (*os.File, *models.DataRequest, error) {
+	dr, err := s.getOwned(userID, id)
+	if err != nil {
+		return nil, nil, err
+	}
+	if dr.Kind != models.DataRequestExport || dr.Status != models.DataRequestCompleted || dr.FilePath == "" {
+		return nil, nil, ErrExportNotReady
+	}
+	f, err := os.Open(dr.FilePath)
+	if err != nil {
+		return nil, nil, err
+	}
+	return f, dr, nil
+}
+
+// Run 在后台执行任务并清理过期的导出文件，直到 ctx 结束
+func (s *DataRequestService) Run(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		s.ProcessPending(ctx)
+		s.cleanupExpired()
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		case <-s.wake:
+		}
+	}
+}
+
+// ProcessPending 执行所有等待中的任务
+func (s *DataRequestService) ProcessPending(ctx context.Context) {
+	if n, err := s.requestStore.ResetStaleRequests(time.Now().Add(-s.cfg.StaleAfter)); err != nil {
+		s.logger.Error("恢复超时任务失败", logger.Error(err))
+	} else if n > 0 {
+		s.logger.Warn("超时任务已重新排队", logger.Int64("count", n))
+	}
+
+	reqs, err := s.requestStore.GetPendingRequests(10)
+	if err != nil {
+		s.logger.Error("获取待执行任务失败", logger.Error(err))
+		return
+	}
+	for _, dr := range reqs {
+		if ctx.Err() != nil {
+			return
+		}
+		// 多个实例同时扫描时只有抢占成功的实例执行
+		ok, err := s.requestStore.ClaimRequest(dr.ID)
+		if err != nil {
+			s.logger.Error("抢占任务失败", logger.String("request_id", dr.ID), logger.Error(err))
+			continue
+		}
+		if ok {
+			s.process(ctx, dr)
+		}
+	}
+}
+
+// process 执行任务并写回结果
+func (s *DataRequestService) process(ctx context.Context, dr *models.DataRequest) {
+	started := time.Now()
+	dr.Status = models.DataRequestRunning
+	dr.StartedAt = &started
+
+	var summary map[string]int64
+	var err error
+	if dr.Kind == models.DataRequestExport {
+		summary, err = s.export(ctx, dr)
+	} else {
+		summary, err = s.erase(ctx, dr)
+	}
+
+	finished := time.Now()
+	dr.FinishedAt = &finished
+	dr.Status = models.DataRequestCompleted
+	dr.LastError = ""
+	if err != nil {
+		dr.Status = models.DataRequestFailed
+		dr.LastError = err.Error()
+	}
+	if data, mErr := json.Marshal(summary); mErr == nil {
+		dr.Summary = string(data)
+	}
+	if err := s.requestStore.UpdateRequest(dr); err != nil {
+		s.logger.Error("更新任务状态失败", logger.String("request_id", dr.ID), logger.Error(err))
+	}
+
+	fields := []logger.Field{
+		logger.String("request_id", dr.ID),
+		logger.String("user_id", dr.UserID),
+		logger.String("kind", dr.Kind),
+		logger.Any("summary", summary),
+		logger.Duration("elapsed", finished.Sub(started)),
+	}
+	if err != nil {
+		s.logger.Error("用户数据任务失败", append(fields, logger.Error(err))...)
+		return
+	}
+	s.logger.Info("用户数据任务完成", fields...)
+}
+
+// cleanupExpired 删除过期的导出文件
+func (s *DataRequestService) cleanupExpired() {
+	reqs, err := s.requestStore.GetExpiredExports(time.Now(), 100)
+	if err != nil {
+		s.logger.Error("获取过期导出失败", logger.Error(err))
+		return
+	}
+	for _, dr := range reqs {
+		s.expireExport(dr)
+	}
+}
+
+// expireExport 删除导出文件并将任务标记为已过期
+func (s *DataRequestService) expireExport(dr *models.DataRequest) {
+	if dr.FilePath != "" {
+		if err := os.Remove(dr.FilePath); err != nil && !os.IsNotExist(err) {
+			s.logger.Error("删除导出文件失败", logger.String("request_id", dr.ID), logger.Error(err))
+			return
+		}
+	}
+	dr.FilePath = ""
+	dr.Status = models.DataRequestExpired
+	if err := s.requestStore.UpdateRequest(dr); err != nil {
+		s.logger.Error("更新任务状态失败", logger.String("request_id", dr.ID), logger.Error(err))
+	}
+}
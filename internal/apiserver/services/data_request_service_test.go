@@ -0,0 +1,259 @@
+package services_test
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"gorm.io/gorm"
+
+	"github.com/woxQAQ/gim/internal/apiserver/services"
+	"github.com/woxQAQ/gim/internal/apiserver/stores"
+	"github.com/woxQAQ/gim/internal/apiserver/types/request"
+	"github.com/woxQAQ/gim/internal/archive"
+	"github.com/woxQAQ/gim/internal/models"
+	"github.com/woxQAQ/gim/internal/search"
+	"github.com/woxQAQ/gim/internal/types"
+	"github.com/woxQAQ/gim/internal/wsgateway/codec"
+	"github.com/woxQAQ/gim/internal/wsgateway/handler"
+	"github.com/woxQAQ/gim/pkg/logger"
+)
+
+// fakeGateway 记录推送和断开连接请求的网关客户端
+type fakeGateway struct {
+	disconnected []string
+	requests     []*types.DisconnectRequest
+	pushed       []*types.PushRequest
+	online       map[string][]int32
+	// router 不为空时 Send 将消息交给该消息路由处理
+	router *handler.Router
+}
+
+func (g *fakeGateway) Send(_ context.Context, req *types.SendRequest) (*types.SendResult, error) {
+	result := &types.SendResult{MessageID: req.Message.GetID()}
+	if g.router == nil {
+		return result, nil
+	}
+	data, err := codec.NewJSONEncoder().Encode(req.Message)
+	if err != nil {
+		return nil, err
+	}
+	err = g.router.Process(data)
+	var rejected *handler.RejectedError
+	if errors.As(err, &rejected) {
+		result.Rejected = rejected.Reason
+		return result, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+func (g *fakeGateway) Push(_ context.Context, req *types.PushRequest) (*types.PushResult, error) {
+	g.pushed = append(g.pushed, req)
+	return &types.PushResult{Delivered: len(req.UserIDs)}, nil
+}
+
+func (g *fakeGateway) Presence(_ context.Context, req *types.PresenceRequest) (*types.PresenceResult, error) {
+	result := &types.PresenceResult{Online: map[string][]int32{}}
+	for _, id := range req.UserIDs {
+		if platforms, ok := g.online[id]; ok {
+			result.Online[id] = platforms
+		}
+	}
+	return result, nil
+}
+
+func (g *fakeGateway) Disconnect(_ context.Context, req *types.DisconnectRequest) (*types.DisconnectResult, error) {
+	g.disconnected = append(g.disconnected, req.UserIDs...)
+	g.requests = append(g.requests, req)
+	return &types.DisconnectResult{Disconnected: len(req.UserIDs)}, nil
+}
+
+var _ = Describe("DataRequestService", func() {
+	var (
+		gdb     *gorm.DB
+		ustore  *stores.UserStore
+		mstore  *stores.MessageStore
+		gw      *fakeGateway
+		cold    *archive.Archive
+		blobDir string
+		svc     *services.DataRequestService
+	)
+
+	createMessage := func(id, from, to string) *models.Message {
+		m := &models.Message{}
+		m.FromTypes(&types.Message{
+			Header: types.MessageHeader{
+				ID:        id,
+				Type:      types.MessageTypeText,
+				From:      from,
+				To:        to,
+				Platform:  1,
+				Timestamp: time.Now(),
+			},
+			Payload: []byte("secret from " + from),
+		})
+		return m
+	}
+
+	// run 提交任务并执行，返回执行后的任务
+	run := func(req *request.CreateDataRequestRequest) *models.DataRequest {
+		resp, err := svc.Create(context.Background(), req)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(resp.Status).To(Equal("pending"))
+		svc.ProcessPending(context.Background())
+
+		dr, err := stores.NewDataRequestStore(gdb).GetRequest(resp.ID)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(dr.LastError).To(BeEmpty())
+		Expect(dr.Status).To(Equal(models.DataRequestCompleted))
+		return dr
+	}
+
+	BeforeEach(func() {
+		gdb = openDB()
+		ustore = stores.NewUserStore(gdb)
+		mstore = stores.NewMessageStore(gdb)
+		gw = &fakeGateway{}
+		blobDir = GinkgoT().TempDir()
+		var err error
+		cold, err = archive.Open(GinkgoT().TempDir())
+		Expect(err).NotTo(HaveOccurred())
+
+		l, _ := logger.NewLogger(&logger.Config{Level: "error"})
+		l.Disable()
+		svc = services.NewDataRequestService(
+			stores.NewDataRequestStore(gdb), ustore, mstore,
+			stores.NewGroupStore(gdb), stores.NewModerationStore(gdb), stores.NewNoticeStore(gdb),
+			stores.NewSessionStore(gdb), stores.NewContactStore(gdb), stores.NewBlockStore(gdb), search.NewMemoryIndex(), cold, &services.FileBlobStore{Root: blobDir}, gw, nil, l,
+			&services.DataRequestConfig{ExportDir: GinkgoT().TempDir(), BatchSize: 2},
+		)
+
+		for _, name := range []string{"alice", "bob"} {
+			Expect(ustore.CreateUser(&models.User{
+				ID:       name,
+				Username: name,
+				Password: "password",
+				Email:    name + "@example.com",
+			})).To(Succeed())
+		}
+		Expect(gdb.Create(&models.GroupMember{GroupID: "g1", UserID: "alice"}).Error).To(Succeed())
+
+		// 1001-1004 在消息表中，1005-1006 已归档
+		for i, pair := range [][2]string{{"alice", "bob"}, {"bob", "alice"}, {"alice", "bob"}, {"bob", "alice"}} {
+			Expect(mstore.CreateMessage(createMessage(fmt.Sprintf("%d", 1001+i), pair[0], pair[1]))).To(Succeed())
+		}
+		Expect(os.WriteFile(filepath.Join(blobDir, "photo.png"), []byte("png"), 0o600)).To(Succeed())
+		Expect(mstore.CreateAttachment(&models.MessageAttachment{
+			ID: "att-1", MessageID: "1001", Type: "image", URL: "photo.png", Size: 3,
+		})).To(Succeed())
+		Expect(cold.Archive(context.Background(), []*models.Message{
+			createMessage("1005", "alice", "bob"),
+			createMessage("1006", "bob", "alice"),
+		}, nil)).To(Succeed())
+	})
+
+	It("导出应该包含资料、消息、归档消息、附件文件和群组", func() {
+		dr := run(&request.CreateDataRequestRequest{UserID: "alice", Kind: models.DataRequestExport})
+		Expect(dr.ExpiresAt).NotTo(BeNil())
+
+		zr, err := zip.OpenReader(dr.FilePath)
+		Expect(err).NotTo(HaveOccurred())
+		defer zr.Close()
+
+		files := map[string][]byte{}
+		for _, f := range zr.File {
+			rc, err := f.Open()
+			Expect(err).NotTo(HaveOccurred())
+			data, err := io.ReadAll(rc)
+			Expect(err).NotTo(HaveOccurred())
+			rc.Close()
+			files[f.Name] = data
+		}
+		Expect(files).To(HaveKey("profile.json"))
+		Expect(string(files["profile.json"])).To(ContainSubstring("alice@example.com"))
+		Expect(string(files["profile.json"])).NotTo(ContainSubstring("password"))
+		Expect(string(files["groups.json"])).To(ContainSubstring("g1"))
+		Expect(files["attachments/att-1-photo.png"]).To(Equal([]byte("png")))
+
+		var lines int
+		dec := json.NewDecoder(bytes.NewReader(files["messages.jsonl"]))
+		for dec.More() {
+			var m map[string]any
+			Expect(dec.Decode(&m)).To(Succeed())
+			lines++
+		}
+		Expect(lines).To(Equal(6))
+
+		resp, err := svc.Get("alice", dr.ID)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(resp.Summary).To(HaveKeyWithValue("messages", int64(6)))
+		Expect(resp.Summary).To(HaveKeyWithValue("archived_messages", int64(2)))
+	})
+
+	It("同一用户的同类任务未完成时不能重复提交", func() {
+		_, err := svc.Create(context.Background(), &request.CreateDataRequestRequest{UserID: "alice", Kind: models.DataRequestExport})
+		Expect(err).NotTo(HaveOccurred())
+		_, err = svc.Create(context.Background(), &request.CreateDataRequestRequest{UserID: "alice", Kind: models.DataRequestExport})
+		Expect(err).To(HaveOccurred())
+	})
+
+	It("匿名化应该断开连接、清空资料和发送的消息，保留对方的消息", func() {
+		run(&request.CreateDataRequestRequest{UserID: "alice", Kind: models.DataRequestDelete})
+		Expect(gw.disconnected).To(Equal([]string{"alice"}))
+
+		user, err := ustore.GetUserByID("alice")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(user.Username).To(Equal("deleted-alice"))
+		Expect(user.Email).To(BeEmpty())
+		Expect(user.Status).To(Equal(int8(0)))
+
+		sent, err := mstore.GetMessage("1001")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(sent.Content).To(BeEmpty())
+		Expect(sent.Status).To(Equal(models.MessageStatusRecalled))
+		received, err := mstore.GetMessage("1002")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(received.Content).To(Equal("secret from bob"))
+
+		Expect(filepath.Join(blobDir, "photo.png")).NotTo(BeAnExistingFile())
+		var members int64
+		Expect(gdb.Model(&models.GroupMember{}).Where("user_id = ?", "alice").Count(&members).Error).To(Succeed())
+		Expect(members).To(BeZero())
+
+		archived, err := cold.ListMessages(&archive.Query{UserID: "alice"})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(archived).To(HaveLen(2))
+		for _, m := range archived {
+			if m.FromID == "alice" {
+				Expect(m.Content).To(BeEmpty())
+			} else {
+				Expect(m.Content).To(Equal("secret from bob"))
+			}
+		}
+	})
+
+	It("删除应该移除账号和收发的全部消息", func() {
+		run(&request.CreateDataRequestRequest{UserID: "alice", Kind: models.DataRequestDelete, Mode: models.DeletionRemove})
+
+		_, err := ustore.GetUserByID("alice")
+		Expect(err).To(HaveOccurred())
+		messages, err := mstore.ListMessages(&stores.MessageQuery{UserID: "bob"})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(messages).To(BeEmpty())
+		archived, err := cold.ListMessages(&archive.Query{UserID: "bob"})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(archived).To(BeEmpty())
+	})
+})
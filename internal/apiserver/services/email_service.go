@@ -0,0 +1,274 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/url"
+	"time"
+
+	"github.com/woxQAQ/gim/internal/apiserver/stores"
+	"github.com/woxQAQ/gim/internal/audit"
+	"github.com/woxQAQ/gim/internal/models"
+	"github.com/woxQAQ/gim/pkg/auth"
+	"github.com/woxQAQ/gim/pkg/logger"
+	"github.com/woxQAQ/gim/pkg/mailer"
+)
+
+var (
+	// ErrMailDisabled 未配置邮件服务，不能发送验证邮件和重置密码邮件
+	ErrMailDisabled = errors.New("未配置邮件服务")
+	// ErrNoEmail 用户没有设置邮箱
+	ErrNoEmail = errors.New("用户未设置邮箱")
+	// ErrEmailAlreadyVerified 用户的邮箱已经验证过
+	ErrEmailAlreadyVerified = errors.New("邮箱已经验证")
+	// ErrInvalidEmailToken 验证或重置令牌无效、已过期或已使用
+	ErrInvalidEmailToken = errors.New("链接无效或已过期")
+)
+
+// 操作令牌的用途
+const (
+	tokenVerifyEmail   = "verify_email"
+	tokenResetPassword = "reset_password"
+)
+
+// EmailConfig 邮件验证与重置密码配置
+type EmailConfig struct {
+	// VerifyTTL 邮箱验证链接的有效期
+	VerifyTTL time.Duration
+	// ResetTTL 重置密码链接的有效期
+	ResetTTL time.Duration
+	// LinkBaseURL 邮件中链接指向的客户端地址，令牌以 token 查询参数附加在后面；
+	// 为空时邮件中只包含令牌
+	LinkBaseURL string
+}
+
+// DefaultEmailConfig 默认的邮件验证与重置密码配置
+var DefaultEmailConfig = EmailConfig{
+	VerifyTTL: 24 * time.Hour,
+	ResetTTL:  time.Hour,
+}
+
+// EmailService 发送邮箱验证和重置密码邮件，并校验邮件中的令牌。
+// 验证令牌绑定用户当前的邮箱，重置令牌绑定当前的密码哈希，邮箱或密码变化后旧的令牌随之失效
+type EmailService struct {
+	userStore *stores.UserStore
+	tokens    *auth.ActionTokens
+	mailer    mailer.Mailer
+	audit     *audit.Recorder
+	logger    logger.Logger
+	cfg       EmailConfig
+	now       func() time.Time
+}
+
+// NewEmailService 创建EmailService实例，m 为空时不能发送邮件，recorder 为空时不记录审计日志
+func NewEmailService(
+	userStore *stores.UserStore,
+	tokens *auth.ActionTokens,
+	m mailer.Mailer,
+	recorder *audit.Recorder,
+	l logger.Logger,
+	cfg *EmailConfig,
+) *EmailService {
+	c := DefaultEmailConfig
+	if cfg != nil {
+		if cfg.VerifyTTL > 0 {
+			c.VerifyTTL = cfg.VerifyTTL
+		}
+		if cfg.ResetTTL > 0 {
+			c.ResetTTL = cfg.ResetTTL
+		}
+		c.LinkBaseURL = cfg.LinkBaseURL
+	}
+	return &EmailService{
+		userStore: userStore,
+		tokens:    tokens,
+		mailer:    m,
+		audit:     recorder,
+		logger:    l,
+		cfg:       c,
+		now:       time.Now,
+	}
+}
+
+// Enabled 判断是否配置了邮件服务
+func (s *EmailService) Enabled() bool {
+	return s != nil && s.mailer != nil
+}
+
+// SendVerification 向用户当前的邮箱发送验证邮件
+func (s *EmailService) SendVerification(ctx context.Context, userID string) error {
+	if !s.Enabled() {
+		return ErrMailDisabled
+	}
+	user, err := s.userStore.GetUserByID(userID)
+	if err != nil {
+		return err
+	}
+	if user.Email == "" {
+		return ErrNoEmail
+	}
+	if user.EmailVerified() {
+		return ErrEmailAlreadyVerified
+	}
+	return s.sendVerification(ctx, user)
+}
+
+// ResendVerification 向邮箱对应的未验证用户重新发送验证邮件，用于要求验证邮箱时还不能登录的用户。
+// 为了不泄露邮箱是否注册，邮箱不存在或已经验证时同样返回成功
+func (s *EmailService) ResendVerification(ctx context.Context, email string) error {
+	if !s.Enabled() {
+		return ErrMailDisabled
+	}
+	user, err := s.userStore.GetUserByEmail(email)
+	if errors.Is(err, stores.ErrUserNotFound) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	if user.EmailVerified() || user.Status == models.UserStatusDisabled {
+		return nil
+	}
+	if err := s.sendVerification(ctx, user); err != nil {
+		s.logger.Error("发送验证邮件失败", logger.String("user_id", user.ID), logger.Error(err))
+	}
+	return nil
+}
+
+func (s *EmailService) sendVerification(ctx context.Context, user *models.User) error {
+	token, err := s.tokens.Issue(tokenVerifyEmail, user.ID, user.Email, s.cfg.VerifyTTL)
+	if err != nil {
+		return err
+	}
+	return s.mailer.Send(ctx, &mailer.Message{
+		To:      user.Email,
+		Subject: "验证你的邮箱",
+		Body: fmt.Sprintf("%s，你好：\n\n请在 %s 内使用下面的链接验证邮箱：\n\n%s\n\n如果不是你本人操作，请忽略这封邮件。\n",
+			user.Username, s.cfg.VerifyTTL, s.link("verify-email", token)),
+	})
+}
+
+// VerifyEmail 校验验证令牌并将用户的邮箱标记为已验证，返回验证后的用户
+func (s *EmailService) VerifyEmail(ctx context.Context, token string) (*models.User, error) {
+	claims, err := s.tokens.Verify(token, tokenVerifyEmail)
+	if err != nil {
+		return nil, ErrInvalidEmailToken
+	}
+	user, err := s.userStore.GetUserByID(claims.Subject)
+	if errors.Is(err, stores.ErrUserNotFound) {
+		return nil, ErrInvalidEmailToken
+	}
+	if err != nil {
+		return nil, err
+	}
+	if user.Email == "" || !claims.Bound(user.Email) {
+		return nil, ErrInvalidEmailToken
+	}
+	if user.EmailVerified() {
+		return user, nil
+	}
+	now := s.now()
+	ok, err := s.userStore.MarkEmailVerified(user.ID, user.Email, now)
+	if err != nil {
+		return nil, err
+	}
+	if !ok {
+		// 校验期间邮箱被修改
+		return nil, ErrInvalidEmailToken
+	}
+	s.audit.Record(ctx, &audit.Entry{
+		Action:   audit.ActionEmailVerify,
+		ActorID:  user.ID,
+		TargetID: user.ID,
+		Detail:   map[string]any{"email": user.Email},
+	})
+	user.EmailVerifiedAt = &now
+	return user, nil
+}
+
+// RequestPasswordReset 向邮箱对应的用户发送重置密码邮件。
+// 为了不泄露邮箱是否注册，邮箱不存在或账号已禁用时同样返回成功。
+//
+// 重置令牌绑定签发时的密码哈希，密码以任何方式修改后（包括使用其中一封邮件重置），
+// 此前发出的全部重置链接都会失效，即使改回原来的密码也一样，因为每次生成的哈希都不同。
+// 失效的链接在 ResetPassword 中与过期链接一样返回 ErrInvalidEmailToken，邮件正文中会提示这一点
+func (s *EmailService) RequestPasswordReset(ctx context.Context, email string) error {
+	if !s.Enabled() {
+		return ErrMailDisabled
+	}
+	user, err := s.userStore.GetUserByEmail(email)
+	if errors.Is(err, stores.ErrUserNotFound) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	if user.Status == models.UserStatusDisabled {
+		return nil
+	}
+	token, err := s.tokens.Issue(tokenResetPassword, user.ID, user.Password, s.cfg.ResetTTL)
+	if err != nil {
+		return err
+	}
+	err = s.mailer.Send(ctx, &mailer.Message{
+		To:      user.Email,
+		Subject: "重置密码",
+		Body: fmt.Sprintf("%s，你好：\n\n请在 %s 内使用下面的链接重置密码，链接只能使用一次，密码被修改后链接同样失效：\n\n%s\n\n如果不是你本人操作，请忽略这封邮件，你的密码不会改变。\n",
+			user.Username, s.cfg.ResetTTL, s.link("reset-password", token)),
+	})
+	if err != nil {
+		// 发送失败只记录日志，返回错误同样会泄露邮箱已注册
+		s.logger.Error("发送重置密码邮件失败", logger.String("user_id", user.ID), logger.Error(err))
+	}
+	return nil
+}
+
+// ResetPassword 校验重置令牌后修改密码，返回用户ID，调用方需要随后吊销该用户的全部会话。
+// 重置令牌绑定旧的密码哈希，修改成功后同一令牌以及此前签发的其他重置令牌都不能再使用
+func (s *EmailService) ResetPassword(ctx context.Context, token, newPassword string) (string, error) {
+	claims, err := s.tokens.Verify(token, tokenResetPassword)
+	if err != nil {
+		return "", ErrInvalidEmailToken
+	}
+	user, err := s.userStore.GetUserByID(claims.Subject)
+	if errors.Is(err, stores.ErrUserNotFound) {
+		return "", ErrInvalidEmailToken
+	}
+	if err != nil {
+		return "", err
+	}
+	if user.Status == models.UserStatusDisabled || !claims.Bound(user.Password) {
+		return "", ErrInvalidEmailToken
+	}
+	ok, err := s.userStore.ReplacePassword(user.ID, user.Password, newPassword)
+	if err != nil {
+		return "", err
+	}
+	if !ok {
+		// 同一令牌被并发使用，或校验期间密码已被修改
+		return "", ErrInvalidEmailToken
+	}
+	s.audit.Record(ctx, &audit.Entry{
+		Action:   audit.ActionPasswordReset,
+		ActorID:  user.ID,
+		TargetID: user.ID,
+	})
+	return user.ID, nil
+}
+
+// link 返回邮件中的链接，未配置 LinkBaseURL 时只返回令牌
+func (s *EmailService) link(path, token string) string {
+	if s.cfg.LinkBaseURL == "" {
+		return token
+	}
+	u, err := url.Parse(s.cfg.LinkBaseURL)
+	if err != nil {
+		return token
+	}
+	u = u.JoinPath(path)
+	q := u.Query()
+	q.Set("token", token)
+	u.RawQuery = q.Encode()
+	return u.String()
+}
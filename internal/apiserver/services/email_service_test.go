@@ -0,0 +1,164 @@
+package services_test
+
+import (
+	"context"
+	"net/url"
+	"regexp"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"gorm.io/gorm"
+
+	"github.com/woxQAQ/gim/internal/apiserver/services"
+	"github.com/woxQAQ/gim/internal/apiserver/stores"
+	"github.com/woxQAQ/gim/internal/apiserver/types/request"
+	"github.com/woxQAQ/gim/internal/models"
+	"github.com/woxQAQ/gim/pkg/auth"
+	"github.com/woxQAQ/gim/pkg/logger"
+	"github.com/woxQAQ/gim/pkg/mailer"
+)
+
+// linkPattern 匹配邮件正文中的链接
+var linkPattern = regexp.MustCompile(`https://app\.example\.com/\S+`)
+
+var _ = Describe("EmailService", func() {
+	var (
+		gdb    *gorm.DB
+		ustore *stores.UserStore
+		mails  *mailer.Memory
+		users  *services.UserService
+		svc    *services.EmailService
+		alice  *models.User
+		ctx    = context.Background()
+	)
+
+	// tokenIn 从发给 to 的最后一封邮件中取出令牌
+	tokenIn := func(to string) string {
+		msg := mails.Last(to)
+		Expect(msg).NotTo(BeNil())
+		link := linkPattern.FindString(msg.Body)
+		Expect(link).NotTo(BeEmpty())
+		u, err := url.Parse(link)
+		Expect(err).NotTo(HaveOccurred())
+		return u.Query().Get("token")
+	}
+
+	BeforeEach(func() {
+		gdb = openDB()
+		ustore = stores.NewUserStore(gdb)
+		mails = mailer.NewMemory()
+		tokens, err := auth.NewActionTokens("0123456789abcdef0123456789abcdef")
+		Expect(err).NotTo(HaveOccurred())
+		l, _ := logger.NewLogger(&logger.Config{Level: "error"})
+		l.Disable()
+		svc = services.NewEmailService(ustore, tokens, mails, nil, l,
+			&services.EmailConfig{LinkBaseURL: "https://app.example.com/"})
+		users = services.NewUserService(ustore, stores.NewBanStore(gdb), nil, nil, nil,
+			&services.UserConfig{RequireVerifiedEmail: true})
+
+		alice = &models.User{Username: "alice", Password: "secret", Email: "alice@example.com"}
+		Expect(users.Register(ctx, alice, "")).To(Succeed())
+	})
+
+	It("要求验证邮箱时，验证前不能登录，注册必须提供邮箱", func() {
+		Expect(users.Register(ctx, &models.User{Username: "bob", Password: "x"}, "")).To(MatchError(services.ErrEmailRequired))
+
+		_, err := users.Login(ctx, "alice", "secret", "")
+		Expect(err).To(MatchError(services.ErrEmailNotVerified))
+
+		Expect(svc.SendVerification(ctx, alice.ID)).To(Succeed())
+		Expect(mails.Last("alice@example.com").Body).To(ContainSubstring("https://app.example.com/verify-email?token="))
+		user, err := svc.VerifyEmail(ctx, tokenIn("alice@example.com"))
+		Expect(err).NotTo(HaveOccurred())
+		Expect(user.ToResponse().EmailVerified).To(BeTrue())
+
+		resp, err := users.Login(ctx, "alice", "secret", "")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(resp.EmailVerified).To(BeTrue())
+		Expect(svc.SendVerification(ctx, alice.ID)).To(MatchError(services.ErrEmailAlreadyVerified))
+	})
+
+	It("修改邮箱后旧的验证令牌失效，新邮箱需要重新验证", func() {
+		Expect(svc.SendVerification(ctx, alice.ID)).To(Succeed())
+		oldToken := tokenIn("alice@example.com")
+
+		email := "alice@example.org"
+		user, err := users.UpdateProfile(alice.ID, &request.UpdateProfileRequest{Email: &email})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(user.EmailVerified()).To(BeFalse())
+
+		_, err = svc.VerifyEmail(ctx, oldToken)
+		Expect(err).To(MatchError(services.ErrInvalidEmailToken))
+		_, err = svc.VerifyEmail(ctx, "garbage")
+		Expect(err).To(MatchError(services.ErrInvalidEmailToken))
+
+		Expect(svc.ResendVerification(ctx, "alice@example.org")).To(Succeed())
+		_, err = svc.VerifyEmail(ctx, tokenIn("alice@example.org"))
+		Expect(err).NotTo(HaveOccurred())
+	})
+
+	It("重置密码令牌只能使用一次，未注册的邮箱不发送邮件", func() {
+		Expect(svc.RequestPasswordReset(ctx, "nobody@example.com")).To(Succeed())
+		Expect(mails.Messages()).To(BeEmpty())
+
+		Expect(svc.RequestPasswordReset(ctx, "alice@example.com")).To(Succeed())
+		token := tokenIn("alice@example.com")
+
+		userID, err := svc.ResetPassword(ctx, token, "new-password")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(userID).To(Equal(alice.ID))
+
+		_, err = svc.ResetPassword(ctx, token, "another-password")
+		Expect(err).To(MatchError(services.ErrInvalidEmailToken))
+
+		user, err := ustore.GetUserByID(alice.ID)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(user.ValidatePassword("new-password")).To(BeTrue())
+	})
+
+	It("密码被修改后，此前发出的重置链接全部失效", func() {
+		Expect(svc.RequestPasswordReset(ctx, "alice@example.com")).To(Succeed())
+		first := tokenIn("alice@example.com")
+
+		// 改成新密码再改回原密码，哈希已经不同
+		Expect(users.ChangePassword(ctx, alice.ID, "secret", "changed")).To(Succeed())
+		Expect(users.ChangePassword(ctx, alice.ID, "changed", "secret")).To(Succeed())
+		_, err := svc.ResetPassword(ctx, first, "new-password")
+		Expect(err).To(MatchError(services.ErrInvalidEmailToken))
+
+		// 同时有效的两个链接，使用其中一个重置后另一个失效
+		Expect(svc.RequestPasswordReset(ctx, "alice@example.com")).To(Succeed())
+		second := tokenIn("alice@example.com")
+		Expect(svc.RequestPasswordReset(ctx, "alice@example.com")).To(Succeed())
+		third := tokenIn("alice@example.com")
+		_, err = svc.ResetPassword(ctx, third, "new-password")
+		Expect(err).NotTo(HaveOccurred())
+		_, err = svc.ResetPassword(ctx, second, "another-password")
+		Expect(err).To(MatchError(services.ErrInvalidEmailToken))
+
+		user, err := ustore.GetUserByID(alice.ID)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(user.ValidatePassword("new-password")).To(BeTrue())
+	})
+
+	It("重置密码后应该吊销用户的全部会话", func() {
+		sessions := stores.NewSessionStore(gdb)
+		jwt, err := auth.NewJWT(&auth.JWTConfig{Secret: "0123456789abcdef0123456789abcdef", TTL: time.Minute})
+		Expect(err).NotTo(HaveOccurred())
+		gw := &fakeGateway{}
+		l, _ := logger.NewLogger(&logger.Config{Level: "error"})
+		l.Disable()
+		ss := services.NewSessionService(sessions, jwt, gw, l, &services.SessionConfig{RefreshTTL: time.Hour})
+		for _, platform := range []int32{1, 2} {
+			_, err := ss.Start(alice.ID, platform, "")
+			Expect(err).NotTo(HaveOccurred())
+		}
+
+		Expect(ss.RevokeAllSessions(ctx, alice.ID, "password reset")).To(Succeed())
+		active, err := sessions.ListActiveSessions(alice.ID)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(active).To(BeEmpty())
+		Expect(gw.requests).To(HaveLen(2))
+	})
+})
@@ -0,0 +1,351 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/woxQAQ/gim/internal/apiserver/stores"
+	"github.com/woxQAQ/gim/internal/audit"
+	"github.com/woxQAQ/gim/internal/models"
+	"github.com/woxQAQ/gim/pkg/logger"
+	"github.com/woxQAQ/gim/pkg/middleware"
+)
+
+// ErrTooManyAttempts 尝试过于频繁，具体原因和重试时间见 *ThrottleError
+var ErrTooManyAttempts = errors.New("尝试过于频繁")
+
+// ThrottleError 的错误码
+const (
+	// CodeLoginThrottled 距离上次登录失败太近，需要等待后再试
+	CodeLoginThrottled = "login_throttled"
+	// CodeAccountLocked 账号连续登录失败次数过多，暂时锁定
+	CodeAccountLocked = "account_locked"
+	// CodeIPLocked 客户端地址登录失败次数过多，暂时锁定
+	CodeIPLocked = "ip_locked"
+	// CodeRegistrationLimited 客户端地址注册次数过多，暂时不能注册
+	CodeRegistrationLimited = "registration_limited"
+)
+
+// ThrottleError 登录或注册被限制，RetryAfter 后可以重试
+type ThrottleError struct {
+	Code       string
+	RetryAfter time.Duration
+}
+
+func (e *ThrottleError) Error() string {
+	wait := e.RetryAfter.Round(time.Second)
+	switch e.Code {
+	case CodeAccountLocked:
+		return fmt.Sprintf("登录失败次数过多，账号已锁定，请在 %s 后重试", wait)
+	case CodeIPLocked:
+		return fmt.Sprintf("登录失败次数过多，请在 %s 后重试", wait)
+	case CodeRegistrationLimited:
+		return fmt.Sprintf("注册过于频繁，请在 %s 后重试", wait)
+	default:
+		return fmt.Sprintf("登录过于频繁，请在 %s 后重试", wait)
+	}
+}
+
+// Is 使 errors.Is(err, ErrTooManyAttempts) 对所有限制生效
+func (e *ThrottleError) Is(target error) bool {
+	return target == ErrTooManyAttempts
+}
+
+// 计数键的前缀
+const (
+	attemptLoginAccount = "login:account:"
+	attemptLoginIP      = "login:ip:"
+	attemptRegisterIP   = "register:ip:"
+)
+
+// LoginGuardConfig 暴力破解防护配置，为零的字段使用 DefaultLoginGuardConfig 中的值
+type LoginGuardConfig struct {
+	// AccountMaxFailures 同一账号在 FailureWindow 内允许的失败次数，达到后锁定账号
+	AccountMaxFailures int
+	// IPMaxFailures 同一客户端地址在 FailureWindow 内允许的失败次数，达到后锁定该地址
+	IPMaxFailures int
+	// FailureWindow 超过该时间没有新的失败时重新计数
+	FailureWindow time.Duration
+	// LockoutDuration 锁定的时长
+	LockoutDuration time.Duration
+	// DelayBase 账号第一次失败后需要等待的时间，之后每次失败翻倍
+	DelayBase time.Duration
+	// MaxDelay 两次登录之间需要等待的最长时间
+	MaxDelay time.Duration
+	// CaptchaAfter 账号或客户端地址失败达到该次数后需要人机验证，未配置 CaptchaVerifier 时不生效
+	CaptchaAfter int
+	// RegisterPerIP 同一客户端地址在 FailureWindow 内允许的注册次数
+	RegisterPerIP int
+}
+
+// DefaultLoginGuardConfig 默认的暴力破解防护配置
+var DefaultLoginGuardConfig = LoginGuardConfig{
+	AccountMaxFailures: 5,
+	IPMaxFailures:      20,
+	FailureWindow:      15 * time.Minute,
+	LockoutDuration:    15 * time.Minute,
+	DelayBase:          time.Second,
+	MaxDelay:           30 * time.Second,
+	CaptchaAfter:       3,
+	RegisterPerIP:      10,
+}
+
+// LoginGuard 按账号和客户端地址记录登录失败次数，失败后逐步延长需要等待的时间，
+// 失败过多时暂时锁定，并在需要时要求人机验证
+//
+// 客户端地址只用于锁定而不计算等待时间，避免同一出口地址后的其他用户受到影响
+type LoginGuard struct {
+	attemptStore *stores.LoginAttemptStore
+	captcha      CaptchaVerifier
+	audit        *audit.Recorder
+	logger       logger.Logger
+	cfg          LoginGuardConfig
+	now          func() time.Time
+}
+
+// NewLoginGuard 创建LoginGuard实例，captcha 为空时不要求人机验证，recorder 为空时不记录锁定事件
+func NewLoginGuard(attemptStore *stores.LoginAttemptStore, captcha CaptchaVerifier, recorder *audit.Recorder, l logger.Logger, cfg *LoginGuardConfig) *LoginGuard {
+	c := DefaultLoginGuardConfig
+	if cfg != nil {
+		if cfg.AccountMaxFailures > 0 {
+			c.AccountMaxFailures = cfg.AccountMaxFailures
+		}
+		if cfg.IPMaxFailures > 0 {
+			c.IPMaxFailures = cfg.IPMaxFailures
+		}
+		if cfg.FailureWindow > 0 {
+			c.FailureWindow = cfg.FailureWindow
+		}
+		if cfg.LockoutDuration > 0 {
+			c.LockoutDuration = cfg.LockoutDuration
+		}
+		if cfg.DelayBase > 0 {
+			c.DelayBase = cfg.DelayBase
+		}
+		if cfg.MaxDelay > 0 {
+			c.MaxDelay = cfg.MaxDelay
+		}
+		if cfg.CaptchaAfter > 0 {
+			c.CaptchaAfter = cfg.CaptchaAfter
+		}
+		if cfg.RegisterPerIP > 0 {
+			c.RegisterPerIP = cfg.RegisterPerIP
+		}
+	}
+	return &LoginGuard{
+		attemptStore: attemptStore,
+		captcha:      captcha,
+		audit:        recorder,
+		logger:       l,
+		cfg:          c,
+		now:          time.Now,
+	}
+}
+
+// CheckLogin 在校验密码之前检查是否允许这次登录，被限制时返回 *ThrottleError，
+// 需要人机验证时返回 ErrCaptchaRequired 或 ErrCaptchaInvalid
+func (g *LoginGuard) CheckLogin(ctx context.Context, username, captchaToken string) error {
+	if g == nil {
+		return nil
+	}
+	now := g.now()
+	accountKey := attemptLoginAccount + strings.ToLower(username)
+	ipKey := ipAttemptKey(ctx, attemptLoginIP)
+	attempts, err := g.attemptStore.GetAttempts(nonEmpty(accountKey, ipKey))
+	if err != nil {
+		return err
+	}
+	account, ip := attempts[accountKey], attempts[ipKey]
+
+	if account != nil && account.Locked(now) {
+		return &ThrottleError{Code: CodeAccountLocked, RetryAfter: account.LockedUntil.Sub(now)}
+	}
+	if ip != nil && ip.Locked(now) {
+		return &ThrottleError{Code: CodeIPLocked, RetryAfter: ip.LockedUntil.Sub(now)}
+	}
+	if n := g.failures(account, now); n > 0 {
+		if wait := account.LastFailureAt.Add(g.delay(n)).Sub(now); wait > 0 {
+			return &ThrottleError{Code: CodeLoginThrottled, RetryAfter: wait}
+		}
+	}
+	if g.failures(account, now) >= g.cfg.CaptchaAfter || g.failures(ip, now) >= g.cfg.CaptchaAfter {
+		return g.verifyCaptcha(ctx, captchaToken)
+	}
+	return nil
+}
+
+// LoginFailed 记录一次登录失败，达到上限时锁定账号或客户端地址，userID 为空表示用户名不存在
+func (g *LoginGuard) LoginFailed(ctx context.Context, username, userID string) {
+	if g == nil {
+		return
+	}
+	g.fail(ctx, attemptLoginAccount+strings.ToLower(username), g.cfg.AccountMaxFailures,
+		userID, map[string]any{"scope": "account", "username": username})
+	if key := ipAttemptKey(ctx, attemptLoginIP); key != "" {
+		g.fail(ctx, key, g.cfg.IPMaxFailures, "", map[string]any{"scope": "ip"})
+	}
+}
+
+// LoginSucceeded 登录成功后清除账号的失败计数，客户端地址的计数保留到过期，
+// 避免攻击者用自己的账号重置地址计数
+func (g *LoginGuard) LoginSucceeded(username string) {
+	if g == nil {
+		return
+	}
+	if err := g.attemptStore.DeleteAttempt(attemptLoginAccount + strings.ToLower(username)); err != nil {
+		g.logger.Error("清除登录失败计数失败", logger.String("username", username), logger.Error(err))
+	}
+}
+
+// CheckRegister 在注册之前检查客户端地址是否允许注册，返回的错误与 CheckLogin 相同
+func (g *LoginGuard) CheckRegister(ctx context.Context, captchaToken string) error {
+	if g == nil {
+		return nil
+	}
+	key := ipAttemptKey(ctx, attemptRegisterIP)
+	if key == "" {
+		return nil
+	}
+	now := g.now()
+	attempts, err := g.attemptStore.GetAttempts([]string{key})
+	if err != nil {
+		return err
+	}
+	a := attempts[key]
+	if a != nil && a.Locked(now) {
+		return &ThrottleError{Code: CodeRegistrationLimited, RetryAfter: a.LockedUntil.Sub(now)}
+	}
+	if g.failures(a, now) >= g.cfg.CaptchaAfter {
+		return g.verifyCaptcha(ctx, captchaToken)
+	}
+	return nil
+}
+
+// Registered 记录一次注册尝试，无论是否成功都计入，避免借注册探测用户名
+func (g *LoginGuard) Registered(ctx context.Context) {
+	if g == nil {
+		return
+	}
+	if key := ipAttemptKey(ctx, attemptRegisterIP); key != "" {
+		g.fail(ctx, key, g.cfg.RegisterPerIP, "", map[string]any{"scope": "register_ip"})
+	}
+}
+
+// Run 定期清理过期的计数，直到 ctx 结束
+func (g *LoginGuard) Run(ctx context.Context, interval time.Duration) {
+	if g == nil {
+		return
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			now := g.now()
+			n, err := g.attemptStore.DeleteStaleAttempts(now.Add(-g.cfg.FailureWindow), now)
+			if err != nil {
+				g.logger.Error("清理登录失败计数失败", logger.Error(err))
+			} else if n > 0 {
+				g.logger.Info("清理登录失败计数", logger.Int64("count", n))
+			}
+		}
+	}
+}
+
+// fail 增加键的计数，达到 max 时锁定并记录审计日志
+func (g *LoginGuard) fail(ctx context.Context, key string, max int, targetID string, detail map[string]any) {
+	now := g.now()
+	locked := false
+	a, err := g.attemptStore.UpdateAttempt(key, func(a *models.LoginAttempt) {
+		// 上一次锁定已结束或计数已过期时重新计数
+		if g.failures(a, now) == 0 {
+			a.Failures = 0
+			a.LockedUntil = nil
+		}
+		a.Failures++
+		a.LastFailureAt = now
+		if a.Failures >= max && !a.Locked(now) {
+			until := now.Add(g.cfg.LockoutDuration)
+			a.LockedUntil = &until
+			locked = true
+		}
+	})
+	if err != nil {
+		g.logger.Error("记录登录失败计数失败", logger.String("key", key), logger.Error(err))
+		return
+	}
+	if !locked {
+		return
+	}
+	detail["failures"] = a.Failures
+	detail["locked_until"] = a.LockedUntil
+	g.audit.Record(ctx, &audit.Entry{
+		Action:   audit.ActionLockout,
+		TargetID: targetID,
+		Detail:   detail,
+	})
+}
+
+// failures 返回在 now 时仍然有效的失败次数，锁定结束或超过 FailureWindow 没有失败时为 0
+func (g *LoginGuard) failures(a *models.LoginAttempt, now time.Time) int {
+	if a == nil {
+		return 0
+	}
+	if a.LockedUntil != nil && !a.Locked(now) {
+		return 0
+	}
+	if now.Sub(a.LastFailureAt) > g.cfg.FailureWindow {
+		return 0
+	}
+	return a.Failures
+}
+
+// delay 返回第 n 次失败后需要等待的时间
+func (g *LoginGuard) delay(n int) time.Duration {
+	d := g.cfg.DelayBase
+	for i := 1; i < n && d < g.cfg.MaxDelay; i++ {
+		d *= 2
+	}
+	return min(d, g.cfg.MaxDelay)
+}
+
+func (g *LoginGuard) verifyCaptcha(ctx context.Context, token string) error {
+	if g.captcha == nil {
+		return nil
+	}
+	if token == "" {
+		return ErrCaptchaRequired
+	}
+	ok, err := g.captcha.Verify(ctx, token, middleware.ClientIPFromContext(ctx))
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return ErrCaptchaInvalid
+	}
+	return nil
+}
+
+// ipAttemptKey 返回请求客户端地址的计数键，无法获取客户端地址时返回空字符串
+func ipAttemptKey(ctx context.Context, prefix string) string {
+	ip := middleware.ClientIPFromContext(ctx)
+	if ip == "" {
+		return ""
+	}
+	return prefix + ip
+}
+
+func nonEmpty(keys ...string) []string {
+	out := keys[:0]
+	for _, k := range keys {
+		if k != "" {
+			out = append(out, k)
+		}
+	}
+	return out
+}
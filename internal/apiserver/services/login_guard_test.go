@@ -0,0 +1,133 @@
+package services_test
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"gorm.io/gorm"
+
+	"github.com/woxQAQ/gim/internal/apiserver/services"
+	"github.com/woxQAQ/gim/internal/apiserver/stores"
+	"github.com/woxQAQ/gim/internal/audit"
+	"github.com/woxQAQ/gim/internal/models"
+	"github.com/woxQAQ/gim/pkg/logger"
+	"github.com/woxQAQ/gim/pkg/middleware"
+)
+
+var _ = Describe("LoginGuard", func() {
+	var (
+		gdb    *gorm.DB
+		astore *stores.AuditStore
+		l      logger.Logger
+		ctx    context.Context
+	)
+
+	// newUsers 使用给定配置创建带暴力破解防护的 UserService，并注册用户 alice
+	newUsers := func(cfg *services.LoginGuardConfig, captcha services.CaptchaVerifier) *services.UserService {
+		recorder := audit.NewRecorder(astore, false, l)
+		guard := services.NewLoginGuard(stores.NewLoginAttemptStore(gdb), captcha, recorder, l, cfg)
+		svc := services.NewUserService(stores.NewUserStore(gdb), stores.NewBanStore(gdb), nil, recorder, guard, nil)
+		Expect(svc.Register(ctx, &models.User{Username: "alice", Password: "secret"}, "")).To(Succeed())
+		return svc
+	}
+
+	BeforeEach(func() {
+		gdb = openDB()
+		astore = stores.NewAuditStore(gdb)
+		l, _ = logger.NewLogger(&logger.Config{Level: "error"})
+		l.Disable()
+		ctx = middleware.WithClientIP(context.Background(), "10.0.0.1")
+	})
+
+	It("应该在账号连续失败达到上限后锁定，并记录审计日志", func() {
+		svc := newUsers(&services.LoginGuardConfig{AccountMaxFailures: 3, DelayBase: time.Nanosecond, MaxDelay: time.Nanosecond}, nil)
+		for range 3 {
+			_, err := svc.Login(ctx, "alice", "wrong", "")
+			Expect(err).To(MatchError(services.ErrInvalidCredentials))
+		}
+
+		// 锁定期内正确的密码也不能登录
+		_, err := svc.Login(ctx, "Alice", "secret", "")
+		var throttle *services.ThrottleError
+		Expect(errors.As(err, &throttle)).To(BeTrue())
+		Expect(throttle.Code).To(Equal(services.CodeAccountLocked))
+		Expect(throttle.RetryAfter).To(BeNumerically("~", 15*time.Minute, time.Minute))
+		Expect(err).To(MatchError(services.ErrTooManyAttempts))
+
+		var lockouts []*models.AuditLog
+		Expect(gdb.Where("action = ?", audit.ActionLockout).Find(&lockouts).Error).To(Succeed())
+		Expect(lockouts).To(HaveLen(1))
+		Expect(lockouts[0].IP).To(Equal("10.0.0.1"))
+		Expect(lockouts[0].Detail).To(ContainSubstring(`"scope":"account"`))
+	})
+
+	It("应该在失败后要求等待逐步延长的时间，成功登录后清除账号计数", func() {
+		svc := newUsers(&services.LoginGuardConfig{DelayBase: 50 * time.Millisecond, MaxDelay: time.Second}, nil)
+		_, err := svc.Login(ctx, "alice", "wrong", "")
+		Expect(err).To(MatchError(services.ErrInvalidCredentials))
+
+		_, err = svc.Login(ctx, "alice", "secret", "")
+		var throttle *services.ThrottleError
+		Expect(errors.As(err, &throttle)).To(BeTrue())
+		Expect(throttle.Code).To(Equal(services.CodeLoginThrottled))
+
+		time.Sleep(60 * time.Millisecond)
+		_, err = svc.Login(ctx, "alice", "secret", "")
+		Expect(err).NotTo(HaveOccurred())
+
+		// 计数已清除，再次失败后只需等待最初的时间
+		_, err = svc.Login(ctx, "alice", "wrong", "")
+		Expect(err).To(MatchError(services.ErrInvalidCredentials))
+		_, err = svc.Login(ctx, "alice", "secret", "")
+		Expect(errors.As(err, &throttle)).To(BeTrue())
+		Expect(throttle.RetryAfter).To(BeNumerically("<=", 50*time.Millisecond))
+	})
+
+	It("应该在失败较多后要求人机验证", func() {
+		svc := newUsers(&services.LoginGuardConfig{CaptchaAfter: 2, DelayBase: time.Nanosecond, MaxDelay: time.Nanosecond},
+			&services.StaticCaptcha{Token: "pass"})
+		_, err := svc.Login(ctx, "alice", "secret", "")
+		Expect(err).NotTo(HaveOccurred())
+		for range 2 {
+			_, err = svc.Login(ctx, "alice", "wrong", "")
+			Expect(err).To(MatchError(services.ErrInvalidCredentials))
+		}
+
+		_, err = svc.Login(ctx, "alice", "secret", "")
+		Expect(err).To(MatchError(services.ErrCaptchaRequired))
+		_, err = svc.Login(ctx, "alice", "secret", "nope")
+		Expect(err).To(MatchError(services.ErrCaptchaInvalid))
+		_, err = svc.Login(ctx, "alice", "secret", "pass")
+		Expect(err).NotTo(HaveOccurred())
+	})
+
+	It("应该锁定失败过多的客户端地址，不影响其他地址", func() {
+		svc := newUsers(&services.LoginGuardConfig{IPMaxFailures: 2, DelayBase: time.Nanosecond, MaxDelay: time.Nanosecond}, nil)
+		_, err := svc.Login(ctx, "nobody", "x", "")
+		Expect(err).To(MatchError(services.ErrInvalidCredentials))
+		_, err = svc.Login(ctx, "someone", "x", "")
+		Expect(err).To(MatchError(services.ErrInvalidCredentials))
+
+		_, err = svc.Login(ctx, "alice", "secret", "")
+		var throttle *services.ThrottleError
+		Expect(errors.As(err, &throttle)).To(BeTrue())
+		Expect(throttle.Code).To(Equal(services.CodeIPLocked))
+
+		other := middleware.WithClientIP(context.Background(), "10.0.0.2")
+		_, err = svc.Login(other, "alice", "secret", "")
+		Expect(err).NotTo(HaveOccurred())
+	})
+
+	It("应该限制同一客户端地址的注册次数", func() {
+		svc := newUsers(&services.LoginGuardConfig{RegisterPerIP: 2}, nil)
+		Expect(svc.Register(ctx, &models.User{Username: "bob", Password: "x"}, "")).To(Succeed())
+
+		err := svc.Register(ctx, &models.User{Username: "carol", Password: "x"}, "")
+		var throttle *services.ThrottleError
+		Expect(errors.As(err, &throttle)).To(BeTrue())
+		Expect(throttle.Code).To(Equal(services.CodeRegistrationLimited))
+	})
+})
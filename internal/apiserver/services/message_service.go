@@ -1,39 +1,92 @@
 package services
 
 import (
+	"errors"
+	"sort"
+	"strings"
+
 	"github.com/woxQAQ/gim/internal/apiserver/stores"
+	"github.com/woxQAQ/gim/internal/apiserver/types/request"
 	"github.com/woxQAQ/gim/internal/apiserver/types/response"
+	"github.com/woxQAQ/gim/internal/archive"
+	"github.com/woxQAQ/gim/internal/models"
+	"github.com/woxQAQ/gim/internal/search"
+	"github.com/woxQAQ/gim/internal/types"
 )
 
+// ErrConversationForbidden 当前用户没有参与该会话
+var ErrConversationForbidden = errors.New("无权访问该会话")
+
 // MessageService 处理消息相关的业务逻辑
 type MessageService struct {
 	messageStore *stores.MessageStore
+	groupStore   *stores.GroupStore
+	searchIndex  search.Index
+	archive      *archive.Archive
 }
 
-// NewMessageService 创建MessageService实例
-func NewMessageService(messageStore *stores.MessageStore) *MessageService {
+// NewMessageService 创建MessageService实例，未启用冷归档时 archive 为空
+func NewMessageService(messageStore *stores.MessageStore, groupStore *stores.GroupStore, searchIndex search.Index, archive *archive.Archive) *MessageService {
 	return &MessageService{
 		messageStore: messageStore,
+		groupStore:   groupStore,
+		searchIndex:  searchIndex,
+		archive:      archive,
 	}
 }
 
 // GetMessageHistory 获取消息历史记录
-func (s *MessageService) GetMessageHistory(userID string, pageSize int, lastMessageID string) (*response.MessageHistoryResponse, error) {
+func (s *MessageService) GetMessageHistory(req *request.GetMessageHistoryRequest) (*response.MessageHistoryResponse, error) {
+	if req.UserID == "" {
+		return nil, errors.New("user_id 不能为空")
+	}
+	if req.PageSize <= 0 {
+		return nil, errors.New("page_size 必须大于0")
+	}
+	if req.Before != "" && req.After != "" {
+		return nil, errors.New("before 与 after 不能同时使用")
+	}
+	if req.ConversationID != "" {
+		if err := s.checkConversationAccess(req.UserID, req.ConversationID); err != nil {
+			return nil, err
+		}
+	}
+
+	q := &stores.MessageQuery{
+		ConversationID: req.ConversationID,
+		UserID:         req.UserID,
+		PeerID:         req.PeerID,
+		Since:          req.Since,
+		Until:          req.Until,
+		Before:         req.Before,
+		After:          req.After,
+		Limit:          req.PageSize + 1,
+	}
+	for _, t := range req.Types {
+		q.Types = append(q.Types, types.MessageType(t))
+	}
+
 	// 获取消息列表
-	messages, err := s.messageStore.GetMessagesByUserID(userID, pageSize+1, lastMessageID)
+	messages, err := s.messageStore.ListMessages(q)
 	if err != nil {
 		return nil, err
 	}
+	if s.archive != nil {
+		if messages, err = s.withArchived(q, messages); err != nil {
+			return nil, err
+		}
+	}
 
 	// 构建响应
 	response := &response.MessageHistoryResponse{
 		Messages: make([]*response.MessageResponse, 0, len(messages)),
 	}
 
-	// 如果获取到的消息数量超过pageSize，说明还有更多消息
-	if len(messages) > pageSize {
+	// 如果获取到的消息数量超过pageSize，说明还有更多消息，
+	// 游标为本页最后一条消息的ID，与 before/after 的方向一致
+	if len(messages) > req.PageSize {
+		messages = messages[:req.PageSize] // 只返回请求的数量
 		response.NextToken = messages[len(messages)-1].ID
-		messages = messages[:pageSize] // 只返回请求的数量
 	}
 
 	// 转换消息格式
@@ -43,3 +96,143 @@ func (s *MessageService) GetMessageHistory(userID string, pageSize int, lastMess
 
 	return response, nil
 }
+
+// checkConversationAccess 检查用户是否参与了会话：单聊会话ID包含双方用户ID，群聊需要是群成员
+func (s *MessageService) checkConversationAccess(userID, conversationID string) error {
+	switch {
+	case strings.HasPrefix(conversationID, "d:"):
+		a, b, ok := strings.Cut(strings.TrimPrefix(conversationID, "d:"), ":")
+		if ok && (a == userID || b == userID) {
+			return nil
+		}
+	case strings.HasPrefix(conversationID, "g:"):
+		ok, err := s.groupStore.IsMember(strings.TrimPrefix(conversationID, "g:"), userID)
+		if err != nil {
+			return err
+		}
+		if ok {
+			return nil
+		}
+	}
+	return ErrConversationForbidden
+}
+
+// withArchived 合并冷归档中的消息。
+// 倒序翻页时只有消息表中的结果不足一页才查询归档，游标从本页最后一条消息继续；
+// 正序翻页时归档中的消息更早，需要与消息表的结果合并。
+func (s *MessageService) withArchived(q *stores.MessageQuery, messages []*models.Message) ([]*models.Message, error) {
+	aq := &archive.Query{
+		ConversationID: q.ConversationID,
+		UserID:         q.UserID,
+		PeerID:         q.PeerID,
+		Types:          q.Types,
+		Since:          q.Since,
+		Until:          q.Until,
+		Before:         q.Before,
+		After:          q.After,
+		Limit:          q.Limit,
+	}
+
+	if q.After == "" {
+		if len(messages) >= q.Limit {
+			return messages, nil
+		}
+		if len(messages) > 0 {
+			aq.Before = messages[len(messages)-1].ID
+		}
+		aq.Limit = q.Limit - len(messages)
+		archived, err := s.archive.ListMessages(aq)
+		if err != nil {
+			return nil, err
+		}
+		return append(messages, archived...), nil
+	}
+
+	archived, err := s.archive.ListMessages(aq)
+	if err != nil {
+		return nil, err
+	}
+	if len(archived) == 0 {
+		return messages, nil
+	}
+	seen := make(map[string]struct{}, len(messages))
+	for _, m := range messages {
+		seen[m.ID] = struct{}{}
+	}
+	for _, m := range archived {
+		if _, ok := seen[m.ID]; !ok {
+			messages = append(messages, m)
+		}
+	}
+	sort.Slice(messages, func(i, j int) bool { return messages[i].ID < messages[j].ID })
+	if len(messages) > q.Limit {
+		messages = messages[:q.Limit]
+	}
+	return messages, nil
+}
+
+// SearchMessages 在用户参与的会话中按关键词搜索消息
+func (s *MessageService) SearchMessages(req *request.SearchMessagesRequest) (*response.MessageSearchResponse, error) {
+	if req.UserID == "" {
+		return nil, errors.New("user_id 不能为空")
+	}
+	if strings.TrimSpace(req.Keyword) == "" {
+		return nil, errors.New("q 不能为空")
+	}
+	if req.PageSize <= 0 {
+		return nil, errors.New("page_size 必须大于0")
+	}
+	if req.ConversationID != "" {
+		if err := s.checkConversationAccess(req.UserID, req.ConversationID); err != nil {
+			return nil, err
+		}
+	}
+
+	q := &search.Query{
+		UserID:         req.UserID,
+		Keyword:        req.Keyword,
+		ConversationID: req.ConversationID,
+		Since:          req.Since,
+		Until:          req.Until,
+		Before:         req.Before,
+		Limit:          req.PageSize + 1,
+	}
+	for _, t := range req.Types {
+		q.Types = append(q.Types, types.MessageType(t))
+	}
+	memberships, err := s.groupStore.ListMemberships(req.UserID)
+	if err != nil {
+		return nil, err
+	}
+	for _, m := range memberships {
+		q.GroupIDs = append(q.GroupIDs, m.GroupID)
+	}
+	hits, err := s.searchIndex.Search(q)
+	if err != nil {
+		return nil, err
+	}
+
+	resp := &response.MessageSearchResponse{
+		Hits: make([]*response.MessageSearchHit, 0, len(hits)),
+	}
+	if len(hits) > req.PageSize {
+		hits = hits[:req.PageSize]
+		resp.NextToken = hits[len(hits)-1].Document.ID
+	}
+	for _, h := range hits {
+		d := h.Document
+		resp.Hits = append(resp.Hits, &response.MessageSearchHit{
+			Message: &response.MessageResponse{
+				ID:             d.ID,
+				ConversationID: d.ConversationID,
+				SenderID:       d.FromID,
+				ReceiverID:     d.ToID,
+				Content:        d.Content,
+				Type:           int32(d.Type),
+				CreatedAt:      d.CreatedAt,
+			},
+			Highlight: h.Highlight,
+		})
+	}
+	return resp, nil
+}
@@ -0,0 +1,116 @@
+package services_test
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"github.com/woxQAQ/gim/internal/apiserver/services"
+	"github.com/woxQAQ/gim/internal/apiserver/stores"
+	"github.com/woxQAQ/gim/internal/apiserver/types/request"
+	"github.com/woxQAQ/gim/internal/archive"
+	"github.com/woxQAQ/gim/internal/models"
+	"github.com/woxQAQ/gim/internal/search"
+	"github.com/woxQAQ/gim/internal/types"
+)
+
+var _ = Describe("MessageService", func() {
+	var (
+		mstore *stores.MessageStore
+		index  *search.MemoryIndex
+		svc    *services.MessageService
+	)
+
+	BeforeEach(func() {
+		gdb := openDB()
+		mstore = stores.NewMessageStore(gdb)
+		cold, err := archive.Open(GinkgoT().TempDir())
+		Expect(err).NotTo(HaveOccurred())
+		index = search.NewMemoryIndex()
+		svc = services.NewMessageService(mstore, stores.NewGroupStore(gdb), index, cold)
+		Expect(gdb.Create(&models.GroupMember{GroupID: "g1", UserID: "alice"}).Error).To(Succeed())
+
+		// 1001-1003 已归档，1004-1006 仍在消息表中
+		base := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+		var archived []*models.Message
+		for i := 1; i <= 6; i++ {
+			m := &models.Message{}
+			m.FromTypes(&types.Message{
+				Header: types.MessageHeader{
+					ID:        fmt.Sprintf("%d", 1000+i),
+					Type:      types.MessageTypeText,
+					From:      "alice",
+					To:        "bob",
+					Platform:  1,
+					Timestamp: base.Add(time.Duration(i) * time.Minute),
+				},
+				Payload: []byte("hi"),
+			})
+			if i <= 3 {
+				archived = append(archived, m)
+				continue
+			}
+			Expect(mstore.CreateMessage(m)).To(Succeed())
+		}
+		Expect(cold.Archive(context.Background(), archived, nil)).To(Succeed())
+	})
+
+	history := func(req *request.GetMessageHistoryRequest) []string {
+		resp, err := svc.GetMessageHistory(req)
+		Expect(err).NotTo(HaveOccurred())
+		out := make([]string, 0, len(resp.Messages))
+		for _, m := range resp.Messages {
+			out = append(out, m.ID)
+		}
+		return append(out, "next="+resp.NextToken)
+	}
+
+	It("游标越过消息表后应该继续从归档中翻页", func() {
+		Expect(history(&request.GetMessageHistoryRequest{UserID: "alice", PeerID: "bob", PageSize: 2})).
+			To(Equal([]string{"1006", "1005", "next=1005"}))
+		Expect(history(&request.GetMessageHistoryRequest{UserID: "alice", PeerID: "bob", PageSize: 2, Before: "1005"})).
+			To(Equal([]string{"1004", "1003", "next=1003"}))
+		Expect(history(&request.GetMessageHistoryRequest{UserID: "alice", PeerID: "bob", PageSize: 2, Before: "1003"})).
+			To(Equal([]string{"1002", "1001", "next="}))
+	})
+
+	It("正序翻页应该先返回归档中的消息", func() {
+		Expect(history(&request.GetMessageHistoryRequest{UserID: "alice", PageSize: 3, After: "1001"})).
+			To(Equal([]string{"1002", "1003", "1004", "next=1004"}))
+	})
+
+	It("只能查询自己参与的会话", func() {
+		direct := models.DirectConversationID("alice", "bob")
+		_, err := svc.GetMessageHistory(&request.GetMessageHistoryRequest{UserID: "bob", ConversationID: direct, PageSize: 2})
+		Expect(err).NotTo(HaveOccurred())
+		_, err = svc.GetMessageHistory(&request.GetMessageHistoryRequest{UserID: "carol", ConversationID: direct, PageSize: 2})
+		Expect(err).To(MatchError(services.ErrConversationForbidden))
+
+		group := models.GroupConversationID("g1")
+		_, err = svc.GetMessageHistory(&request.GetMessageHistoryRequest{UserID: "alice", ConversationID: group, PageSize: 2})
+		Expect(err).NotTo(HaveOccurred())
+		_, err = svc.SearchMessages(&request.SearchMessagesRequest{UserID: "bob", Keyword: "hi", ConversationID: group, PageSize: 2})
+		Expect(err).To(MatchError(services.ErrConversationForbidden))
+	})
+
+	It("搜索应该返回所在群中其他成员发送的消息", func() {
+		msg := &models.Message{}
+		msg.FromTypes(&types.Message{
+			Header:  types.MessageHeader{ID: "2001", Type: types.MessageTypeText, From: "carol", GroupID: "g1", Platform: 1},
+			Payload: []byte("hi team"),
+		})
+		Expect(index.Index(search.DocumentFromMessage(msg))).To(Succeed())
+
+		resp, err := svc.SearchMessages(&request.SearchMessagesRequest{UserID: "alice", Keyword: "team", PageSize: 2})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(resp.Hits).To(HaveLen(1))
+		Expect(resp.Hits[0].Message.ID).To(Equal("2001"))
+
+		resp, err = svc.SearchMessages(&request.SearchMessagesRequest{UserID: "bob", Keyword: "team", PageSize: 2})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(resp.Hits).To(BeEmpty())
+	})
+})
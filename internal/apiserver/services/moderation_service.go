@@ -0,0 +1,82 @@
+package services
+
+import (
+	"errors"
+
+	"github.com/woxQAQ/gim/internal/apiserver/stores"
+	"github.com/woxQAQ/gim/internal/apiserver/types/response"
+	"github.com/woxQAQ/gim/internal/models"
+)
+
+// ModerationService 处理内容审核相关的业务逻辑
+type ModerationService struct {
+	moderationStore *stores.ModerationStore
+}
+
+// NewModerationService 创建ModerationService实例
+func NewModerationService(moderationStore *stores.ModerationStore) *ModerationService {
+	return &ModerationService{
+		moderationStore: moderationStore,
+	}
+}
+
+// ListRecords 查询审核记录
+func (s *ModerationService) ListRecords(status string, pageSize int, pageToken string) (*response.ModerationRecordListResponse, error) {
+	if pageSize <= 0 || pageSize > 100 {
+		pageSize = 20
+	}
+	var filter *models.ModerationStatus
+	if status != "" {
+		st, err := parseModerationStatus(status)
+		if err != nil {
+			return nil, err
+		}
+		filter = &st
+	}
+
+	records, err := s.moderationStore.ListRecords(filter, pageSize+1, pageToken)
+	if err != nil {
+		return nil, err
+	}
+
+	resp := &response.ModerationRecordListResponse{
+		Records: make([]*response.ModerationRecordResponse, 0, len(records)),
+	}
+	if len(records) > pageSize {
+		records = records[:pageSize]
+		resp.NextToken = records[len(records)-1].ID
+	}
+	for _, r := range records {
+		resp.Records = append(resp.Records, r.ToResponse())
+	}
+	return resp, nil
+}
+
+// Review 审核消息
+func (s *ModerationService) Review(id, status, reviewerID string) (*response.ModerationRecordResponse, error) {
+	st, err := parseModerationStatus(status)
+	if err != nil {
+		return nil, err
+	}
+	if st == models.ModerationPending {
+		return nil, errors.New("审核结果只能是 approved 或 removed")
+	}
+	record, err := s.moderationStore.Review(id, st, reviewerID)
+	if err != nil {
+		return nil, err
+	}
+	return record.ToResponse(), nil
+}
+
+func parseModerationStatus(status string) (models.ModerationStatus, error) {
+	for _, st := range []models.ModerationStatus{
+		models.ModerationPending,
+		models.ModerationApproved,
+		models.ModerationRemoved,
+	} {
+		if st.String() == status {
+			return st, nil
+		}
+	}
+	return 0, errors.New("未知的审核状态: " + status)
+}
@@ -0,0 +1,284 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/woxQAQ/gim/internal/apiserver/gateway"
+	"github.com/woxQAQ/gim/internal/apiserver/stores"
+	"github.com/woxQAQ/gim/internal/apiserver/types/request"
+	"github.com/woxQAQ/gim/internal/apiserver/types/response"
+	"github.com/woxQAQ/gim/internal/audit"
+	"github.com/woxQAQ/gim/internal/models"
+	"github.com/woxQAQ/gim/internal/types"
+	"github.com/woxQAQ/gim/pkg/logger"
+	"github.com/woxQAQ/gim/pkg/snowflake"
+)
+
+// noticePushTimeout 单次推送通知的超时时间
+const noticePushTimeout = 10 * time.Second
+
+// NoticePayload 推送给客户端的系统通知内容
+type NoticePayload struct {
+	NoticeID string `json:"notice_id"`
+	Title    string `json:"title"`
+	Content  string `json:"content"`
+}
+
+// NoticeService 处理系统通知相关的业务逻辑
+type NoticeService struct {
+	noticeStore *stores.NoticeStore
+	groupStore  *stores.GroupStore
+	gateway     gateway.Client
+	audit       *audit.Recorder
+	logger      logger.Logger
+}
+
+// NewNoticeService 创建NoticeService实例
+func NewNoticeService(noticeStore *stores.NoticeStore, groupStore *stores.GroupStore, gw gateway.Client, recorder *audit.Recorder, l logger.Logger) *NoticeService {
+	return &NoticeService{
+		noticeStore: noticeStore,
+		groupStore:  groupStore,
+		gateway:     gw,
+		audit:       recorder,
+		logger:      l,
+	}
+}
+
+// Create 创建系统通知，未指定发送时间或发送时间已过时立即发送
+func (s *NoticeService) Create(ctx context.Context, req *request.CreateNoticeRequest) (*response.NoticeResponse, error) {
+	notice := &models.SystemNotice{
+		ID:         snowflake.GenerateID(),
+		Title:      req.Title,
+		Content:    req.Content,
+		TargetType: req.TargetType,
+		PlatformID: req.PlatformID,
+		Persist:    req.Persist,
+		Status:     models.NoticeScheduled,
+		DeliverAt:  time.Now(),
+		CreatedBy:  req.CreatedBy,
+	}
+
+	switch req.TargetType {
+	case models.NoticeTargetAll:
+	case models.NoticeTargetUsers:
+		if len(req.UserIDs) == 0 {
+			return nil, errors.New("user_ids 不能为空")
+		}
+		notice.TargetIDs = strings.Join(req.UserIDs, ",")
+	case models.NoticeTargetGroup:
+		if req.GroupID == "" {
+			return nil, errors.New("group_id 不能为空")
+		}
+		if _, err := s.groupStore.GetGroupByID(req.GroupID); err != nil {
+			return nil, err
+		}
+		notice.TargetIDs = req.GroupID
+	case models.NoticeTargetPlatform:
+		if req.PlatformID == 0 {
+			return nil, errors.New("platform_id 不能为空")
+		}
+	default:
+		return nil, errors.New("未知的通知目标类型: " + req.TargetType)
+	}
+
+	scheduled := req.DeliverAt != nil && req.DeliverAt.After(time.Now())
+	if scheduled {
+		notice.DeliverAt = *req.DeliverAt
+	}
+	if err := s.noticeStore.CreateNotice(notice); err != nil {
+		return nil, err
+	}
+	s.audit.Record(ctx, &audit.Entry{
+		Action:   audit.ActionNoticeCreate,
+		ActorID:  notice.CreatedBy,
+		TargetID: notice.ID,
+		Detail: map[string]any{
+			"title":       notice.Title,
+			"target_type": notice.TargetType,
+			"target_ids":  notice.TargetIDs,
+			"deliver_at":  notice.DeliverAt,
+		},
+	})
+
+	if !scheduled {
+		if _, err := s.noticeStore.ClaimNotice(notice.ID, models.NoticeSent); err != nil {
+			return nil, err
+		}
+		s.deliver(ctx, notice)
+	}
+	return notice.ToResponse(), nil
+}
+
+// Cancel 取消尚未发送的定时通知
+func (s *NoticeService) Cancel(id string) error {
+	ok, err := s.noticeStore.ClaimNotice(id, models.NoticeCanceled)
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return errors.New("通知不存在或已发送")
+	}
+	return nil
+}
+
+// List 查询系统通知
+func (s *NoticeService) List(status string, pageSize int, pageToken string) (*response.NoticeListResponse, error) {
+	if pageSize <= 0 || pageSize > 100 {
+		pageSize = 20
+	}
+	var filter *models.NoticeStatus
+	if status != "" {
+		st, err := parseNoticeStatus(status)
+		if err != nil {
+			return nil, err
+		}
+		filter = &st
+	}
+	notices, err := s.noticeStore.ListNotices(filter, pageSize+1, pageToken)
+	if err != nil {
+		return nil, err
+	}
+	return buildNoticeList(notices, pageSize), nil
+}
+
+// ListForUser 获取用户可以拉取的持久化通知
+func (s *NoticeService) ListForUser(userID string, pageSize int, pageToken string) (*response.NoticeListResponse, error) {
+	if pageSize <= 0 || pageSize > 100 {
+		pageSize = 20
+	}
+	notices, err := s.noticeStore.ListNoticesForUser(userID, pageSize+1, pageToken)
+	if err != nil {
+		return nil, err
+	}
+	return buildNoticeList(notices, pageSize), nil
+}
+
+// Run 定时扫描并发送到期的通知，直到 ctx 结束
+func (s *NoticeService) Run(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			s.DeliverDue(ctx)
+		}
+	}
+}
+
+// DeliverDue 发送所有到期的定时通知
+func (s *NoticeService) DeliverDue(ctx context.Context) {
+	notices, err := s.noticeStore.GetDueNotices(time.Now(), 100)
+	if err != nil {
+		s.logger.Error("获取到期通知失败", logger.Error(err))
+		return
+	}
+	for _, notice := range notices {
+		// 多个实例同时扫描时只有抢占成功的实例发送
+		ok, err := s.noticeStore.ClaimNotice(notice.ID, models.NoticeSent)
+		if err != nil {
+			s.logger.Error("抢占定时通知失败", logger.String("notice_id", notice.ID), logger.Error(err))
+			continue
+		}
+		if ok {
+			s.deliver(ctx, notice)
+		}
+	}
+}
+
+// deliver 解析接收人、保存离线记录并通过网关推送，结果写回通知
+func (s *NoticeService) deliver(ctx context.Context, notice *models.SystemNotice) {
+	err := s.push(ctx, notice)
+	now := time.Now()
+	notice.SentAt = &now
+	notice.Status = models.NoticeSent
+	if err != nil {
+		notice.Status = models.NoticeFailed
+		notice.LastError = err.Error()
+		s.logger.Error("发送系统通知失败", logger.String("notice_id", notice.ID), logger.Error(err))
+	}
+	if err := s.noticeStore.UpdateNotice(notice); err != nil {
+		s.logger.Error("更新系统通知失败", logger.String("notice_id", notice.ID), logger.Error(err))
+	}
+}
+
+func (s *NoticeService) push(ctx context.Context, notice *models.SystemNotice) error {
+	req := &types.PushRequest{PlatformID: notice.PlatformID}
+	switch notice.TargetType {
+	case models.NoticeTargetAll:
+		req.Target = types.PushTargetAll
+	case models.NoticeTargetPlatform:
+		req.Target = types.PushTargetPlatform
+	case models.NoticeTargetUsers:
+		req.Target = types.PushTargetUsers
+		req.UserIDs = notice.TargetIDList()
+	case models.NoticeTargetGroup:
+		members, err := s.groupStore.GetMemberIDs(notice.TargetIDs)
+		if err != nil {
+			return err
+		}
+		req.Target = types.PushTargetUsers
+		req.UserIDs = members
+	}
+
+	if notice.Persist && req.Target == types.PushTargetUsers {
+		if err := s.noticeStore.AddRecipients(notice.ID, req.UserIDs); err != nil {
+			return err
+		}
+	}
+
+	payload, err := json.Marshal(&NoticePayload{
+		NoticeID: notice.ID,
+		Title:    notice.Title,
+		Content:  notice.Content,
+	})
+	if err != nil {
+		return err
+	}
+	req.Message = types.NewMessage(types.MessageTypeSystem, "system", "", notice.PlatformID, payload)
+
+	ctx, cancel := context.WithTimeout(ctx, noticePushTimeout)
+	defer cancel()
+	result, err := s.gateway.Push(ctx, req)
+	if err != nil {
+		return err
+	}
+	notice.Delivered = result.Delivered
+	if len(result.Errors) > 0 {
+		return errors.New(strings.Join(result.Errors, "; "))
+	}
+	return nil
+}
+
+func buildNoticeList(notices []*models.SystemNotice, pageSize int) *response.NoticeListResponse {
+	resp := &response.NoticeListResponse{
+		Notices: make([]*response.NoticeResponse, 0, len(notices)),
+	}
+	if len(notices) > pageSize {
+		notices = notices[:pageSize]
+		resp.NextToken = notices[len(notices)-1].ID
+	}
+	for _, n := range notices {
+		resp.Notices = append(resp.Notices, n.ToResponse())
+	}
+	return resp
+}
+
+func parseNoticeStatus(status string) (models.NoticeStatus, error) {
+	for _, st := range []models.NoticeStatus{
+		models.NoticeScheduled,
+		models.NoticeSent,
+		models.NoticeFailed,
+		models.NoticeCanceled,
+	} {
+		if st.String() == status {
+			return st, nil
+		}
+	}
+	return 0, errors.New("未知的通知状态: " + status)
+}
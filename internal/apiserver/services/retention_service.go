@@ -0,0 +1,340 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"sort"
+	"sync/atomic"
+	"time"
+
+	"github.com/woxQAQ/gim/internal/apiserver/stores"
+	"github.com/woxQAQ/gim/internal/apiserver/types/request"
+	"github.com/woxQAQ/gim/internal/apiserver/types/response"
+	"github.com/woxQAQ/gim/internal/models"
+	"github.com/woxQAQ/gim/internal/search"
+	"github.com/woxQAQ/gim/internal/types"
+	"github.com/woxQAQ/gim/pkg/logger"
+	"github.com/woxQAQ/gim/pkg/snowflake"
+)
+
+// ErrRetentionRunning 上一次清理尚未结束
+var ErrRetentionRunning = errors.New("retention purge is already running")
+
+// MessageArchiver 在删除前归档过期消息
+type MessageArchiver interface {
+	Archive(ctx context.Context, messages []*models.Message, attachments []*models.MessageAttachment) error
+}
+
+// RetentionConfig 过期清理配置
+type RetentionConfig struct {
+	// BatchSize 每个事务删除的消息数量
+	BatchSize int
+	// BatchPause 两个批次之间的间隔，避免长时间占用数据库
+	BatchPause time.Duration
+}
+
+// DefaultRetentionConfig 默认的过期清理配置
+var DefaultRetentionConfig = RetentionConfig{
+	BatchSize:  500,
+	BatchPause: 50 * time.Millisecond,
+}
+
+// RetentionService 管理消息保留策略并清理过期消息
+type RetentionService struct {
+	retentionStore *stores.RetentionStore
+	messageStore   *stores.MessageStore
+	searchIndex    search.Index
+	archiver       MessageArchiver
+	blobs          BlobRemover
+	cfg            RetentionConfig
+	logger         logger.Logger
+
+	running atomic.Bool
+}
+
+// NewRetentionService 创建RetentionService实例，searchIndex、archiver 和 blobs 可以为空
+func NewRetentionService(
+	retentionStore *stores.RetentionStore,
+	messageStore *stores.MessageStore,
+	searchIndex search.Index,
+	archiver MessageArchiver,
+	blobs BlobRemover,
+	l logger.Logger,
+	cfg *RetentionConfig,
+) *RetentionService {
+	c := DefaultRetentionConfig
+	if cfg != nil {
+		if cfg.BatchSize > 0 {
+			c.BatchSize = cfg.BatchSize
+		}
+		if cfg.BatchPause > 0 {
+			c.BatchPause = cfg.BatchPause
+		}
+	}
+	return &RetentionService{
+		retentionStore: retentionStore,
+		messageStore:   messageStore,
+		searchIndex:    searchIndex,
+		archiver:       archiver,
+		blobs:          blobs,
+		cfg:            c,
+		logger:         l,
+	}
+}
+
+// CreatePolicy 创建或覆盖保留策略
+func (s *RetentionService) CreatePolicy(req *request.CreateRetentionPolicyRequest) (*response.RetentionPolicyResponse, error) {
+	if req.TTLSeconds < 0 {
+		return nil, errors.New("ttl_seconds 不能小于0")
+	}
+	if req.Action != models.RetentionDelete && req.Action != models.RetentionArchive {
+		return nil, errors.New("未知的处理方式: " + req.Action)
+	}
+	if req.Action == models.RetentionArchive && s.archiver == nil {
+		return nil, errors.New("未配置归档，不能使用 archive")
+	}
+	policy := &models.RetentionPolicy{
+		ID:             snowflake.GenerateID(),
+		ConversationID: req.ConversationID,
+		MessageType:    types.MessageType(req.MessageType),
+		TTLSeconds:     req.TTLSeconds,
+		Action:         req.Action,
+		CreatedBy:      req.CreatedBy,
+	}
+	if err := s.retentionStore.UpsertPolicy(policy); err != nil {
+		return nil, err
+	}
+	return policy.ToResponse(), nil
+}
+
+// ListPolicies 获取全部保留策略
+func (s *RetentionService) ListPolicies() ([]*response.RetentionPolicyResponse, error) {
+	policies, err := s.retentionStore.ListPolicies()
+	if err != nil {
+		return nil, err
+	}
+	resp := make([]*response.RetentionPolicyResponse, 0, len(policies))
+	for _, p := range policies {
+		resp = append(resp, p.ToResponse())
+	}
+	return resp, nil
+}
+
+// DeletePolicy 删除保留策略
+func (s *RetentionService) DeletePolicy(id string) error {
+	return s.retentionStore.DeletePolicy(id)
+}
+
+// ListRuns 获取清理执行记录
+func (s *RetentionService) ListRuns(pageSize int, pageToken string) (*response.RetentionRunListResponse, error) {
+	if pageSize <= 0 || pageSize > 100 {
+		pageSize = 20
+	}
+	runs, err := s.retentionStore.ListRuns(pageSize+1, pageToken)
+	if err != nil {
+		return nil, err
+	}
+	resp := &response.RetentionRunListResponse{
+		Runs: make([]*response.RetentionRunResponse, 0, len(runs)),
+	}
+	if len(runs) > pageSize {
+		runs = runs[:pageSize]
+		resp.NextToken = runs[len(runs)-1].ID
+	}
+	for _, r := range runs {
+		resp.Runs = append(resp.Runs, r.ToResponse())
+	}
+	return resp, nil
+}
+
+// Run 定时清理过期消息，直到 ctx 结束
+func (s *RetentionService) Run(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if _, err := s.Purge(ctx); err != nil && !errors.Is(err, ErrRetentionRunning) {
+				s.logger.Error("清理过期消息失败", logger.Error(err))
+			}
+		}
+	}
+}
+
+// policyResult 单条策略的清理结果
+type policyResult struct {
+	PolicyID       string `json:"policy_id"`
+	ConversationID string `json:"conversation_id,omitempty"`
+	MessageType    int32  `json:"message_type,omitempty"`
+	Action         string `json:"action"`
+	Messages       int64  `json:"messages"`
+	Attachments    int64  `json:"attachments"`
+	Error          string `json:"error,omitempty"`
+}
+
+// Purge 按保留策略分批删除或归档过期消息，并保存执行记录
+func (s *RetentionService) Purge(ctx context.Context) (*response.RetentionRunResponse, error) {
+	if !s.running.CompareAndSwap(false, true) {
+		return nil, ErrRetentionRunning
+	}
+	defer s.running.Store(false)
+
+	policies, err := s.retentionStore.ListPolicies()
+	if err != nil {
+		return nil, err
+	}
+
+	run := &models.RetentionRun{ID: snowflake.GenerateID(), StartedAt: time.Now()}
+	var results []*policyResult
+	var errs []error
+	for _, p := range policies {
+		if p.TTLSeconds == 0 {
+			continue
+		}
+		result, err := s.purgePolicy(ctx, p, planFilter(p, policies, run.StartedAt))
+		results = append(results, result)
+		if err != nil {
+			result.Error = err.Error()
+			errs = append(errs, err)
+		}
+		if p.Action == models.RetentionArchive {
+			run.Archived += result.Messages
+		} else {
+			run.Deleted += result.Messages
+		}
+		run.Attachments += result.Attachments
+	}
+
+	run.FinishedAt = time.Now()
+	if err := errors.Join(errs...); err != nil {
+		run.LastError = err.Error()
+	}
+	details, _ := json.Marshal(results)
+	run.Details = string(details)
+	if err := s.retentionStore.CreateRun(run); err != nil {
+		return nil, err
+	}
+
+	s.logger.Info("过期消息清理完成",
+		logger.Int64("deleted", run.Deleted),
+		logger.Int64("archived", run.Archived),
+		logger.Int64("attachments", run.Attachments),
+		logger.Duration("elapsed", run.FinishedAt.Sub(run.StartedAt)),
+	)
+	return run.ToResponse(), nil
+}
+
+// purgePolicy 分批处理一条策略覆盖的过期消息，每批一个短事务
+func (s *RetentionService) purgePolicy(ctx context.Context, p *models.RetentionPolicy, filter *stores.ExpiredMessageFilter) (*policyResult, error) {
+	result := &policyResult{
+		PolicyID:       p.ID,
+		ConversationID: p.ConversationID,
+		MessageType:    int32(p.MessageType),
+		Action:         p.Action,
+	}
+	if p.Action == models.RetentionArchive && s.archiver == nil {
+		return result, errors.New("未配置归档，跳过 archive 策略")
+	}
+
+	for {
+		if err := ctx.Err(); err != nil {
+			return result, err
+		}
+		messages, err := s.messageStore.FindExpiredMessages(filter, s.cfg.BatchSize)
+		if err != nil {
+			return result, err
+		}
+		if len(messages) == 0 {
+			return result, nil
+		}
+		ids := make([]string, 0, len(messages))
+		for _, m := range messages {
+			ids = append(ids, m.ID)
+		}
+		attachments, err := s.messageStore.GetAttachments(ids)
+		if err != nil {
+			return result, err
+		}
+
+		if p.Action == models.RetentionArchive {
+			if err := s.archiver.Archive(ctx, messages, attachments); err != nil {
+				return result, err
+			}
+		}
+		if err := s.messageStore.DeleteMessages(ids); err != nil {
+			return result, err
+		}
+		result.Messages += int64(len(ids))
+		result.Attachments += int64(len(attachments))
+		s.cleanup(ctx, ids, attachments)
+
+		if len(messages) < s.cfg.BatchSize {
+			return result, nil
+		}
+		select {
+		case <-ctx.Done():
+			return result, ctx.Err()
+		case <-time.After(s.cfg.BatchPause):
+		}
+	}
+}
+
+// cleanup 删除附件文件和检索索引，失败只记录日志，不影响已经删除的消息
+func (s *RetentionService) cleanup(ctx context.Context, ids []string, attachments []*models.MessageAttachment) {
+	if s.blobs != nil {
+		for _, a := range attachments {
+			if err := s.blobs.Remove(ctx, a.URL); err != nil {
+				s.logger.Warn("删除附件文件失败", logger.String("attachment_id", a.ID), logger.Error(err))
+			}
+		}
+	}
+	if s.searchIndex != nil {
+		for _, id := range ids {
+			if err := s.searchIndex.Delete(id); err != nil {
+				s.logger.Warn("删除消息索引失败", logger.String("message_id", id), logger.Error(err))
+			}
+		}
+	}
+}
+
+// planFilter 计算策略 p 负责的过期消息，排除由更具体的策略负责的部分
+func planFilter(p *models.RetentionPolicy, policies []*models.RetentionPolicy, now time.Time) *stores.ExpiredMessageFilter {
+	f := &stores.ExpiredMessageFilter{
+		ConversationID: p.ConversationID,
+		Type:           p.MessageType,
+		Before:         now.Add(-p.TTL()),
+	}
+	for _, q := range policies {
+		if q.Specificity() <= p.Specificity() {
+			continue
+		}
+		// q 必须与 p 的作用范围重叠
+		if p.ConversationID != "" && q.ConversationID != p.ConversationID {
+			continue
+		}
+		if p.MessageType != types.MessageTypeUnknown && q.MessageType != types.MessageTypeUnknown && q.MessageType != p.MessageType {
+			continue
+		}
+
+		switch {
+		case q.ConversationID != "" && q.MessageType != types.MessageTypeUnknown:
+			switch {
+			case p.ConversationID != "":
+				f.ExcludeTypes = append(f.ExcludeTypes, q.MessageType)
+			case p.MessageType != types.MessageTypeUnknown:
+				f.ExcludeConversations = append(f.ExcludeConversations, q.ConversationID)
+			default:
+				f.ExcludePairs = append(f.ExcludePairs, stores.ConversationType{ConversationID: q.ConversationID, Type: q.MessageType})
+			}
+		case q.ConversationID != "":
+			f.ExcludeConversations = append(f.ExcludeConversations, q.ConversationID)
+		default:
+			f.ExcludeTypes = append(f.ExcludeTypes, q.MessageType)
+		}
+	}
+	sort.Strings(f.ExcludeConversations)
+	return f
+}
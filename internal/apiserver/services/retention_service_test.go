@@ -0,0 +1,169 @@
+package services_test
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"github.com/woxQAQ/gim/internal/apiserver/services"
+	"github.com/woxQAQ/gim/internal/apiserver/stores"
+	"github.com/woxQAQ/gim/internal/apiserver/types/request"
+	"github.com/woxQAQ/gim/internal/models"
+	"github.com/woxQAQ/gim/internal/search"
+	"github.com/woxQAQ/gim/internal/types"
+	"github.com/woxQAQ/gim/pkg/logger"
+)
+
+var _ = Describe("RetentionService", func() {
+	var (
+		mstore  *stores.MessageStore
+		rstore  *stores.RetentionStore
+		index   *search.MemoryIndex
+		blobDir string
+		svc     *services.RetentionService
+		seq     int
+	)
+
+	// createMessage 创建一条 age 之前发送的消息
+	createMessage := func(from, to string, typ types.MessageType, age time.Duration) string {
+		seq++
+		msg := &types.Message{
+			Header: types.MessageHeader{
+				ID:        fmt.Sprintf("%d", 1000+seq),
+				Type:      typ,
+				From:      from,
+				To:        to,
+				Platform:  1,
+				Timestamp: time.Now().Add(-age),
+			},
+			Payload: []byte("hello"),
+		}
+		m := &models.Message{}
+		m.FromTypes(msg)
+		Expect(mstore.CreateMessage(m)).To(Succeed())
+		Expect(index.Index(search.DocumentFromMessage(m))).To(Succeed())
+		return m.ID
+	}
+
+	createPolicy := func(conversationID string, typ types.MessageType, ttl time.Duration) {
+		_, err := svc.CreatePolicy(&request.CreateRetentionPolicyRequest{
+			ConversationID: conversationID,
+			MessageType:    int32(typ),
+			TTLSeconds:     int64(ttl / time.Second),
+			Action:         models.RetentionDelete,
+		})
+		Expect(err).NotTo(HaveOccurred())
+	}
+
+	exists := func(id string) bool {
+		_, err := mstore.GetMessage(id)
+		return err == nil
+	}
+
+	BeforeEach(func() {
+		gdb := openDB()
+		mstore = stores.NewMessageStore(gdb)
+		rstore = stores.NewRetentionStore(gdb)
+		index = search.NewMemoryIndex()
+		blobDir = GinkgoT().TempDir()
+		seq = 0
+
+		l, _ := logger.NewLogger(&logger.Config{Level: "error"})
+		l.Disable()
+		svc = services.NewRetentionService(rstore, mstore, index, nil,
+			&services.FileBlobStore{Root: blobDir}, l,
+			&services.RetentionConfig{BatchSize: 2, BatchPause: time.Millisecond},
+		)
+	})
+
+	It("每条消息只应该使用最具体的策略", func() {
+		ab := models.DirectConversationID("alice", "bob")
+		createPolicy("", types.MessageTypeUnknown, time.Hour)    // 全局：1小时
+		createPolicy(ab, types.MessageTypeUnknown, 0)            // alice 与 bob 永久保留
+		createPolicy(ab, types.MessageTypeImage, time.Hour)      // 但其中的图片1小时
+		createPolicy("", types.MessageTypeFile, 10*24*time.Hour) // 文件保留10天
+
+		abText := createMessage("alice", "bob", types.MessageTypeText, 2*time.Hour)
+		abImage := createMessage("alice", "bob", types.MessageTypeImage, 2*time.Hour)
+		acText := createMessage("alice", "carol", types.MessageTypeText, 2*time.Hour)
+		acFresh := createMessage("alice", "carol", types.MessageTypeText, time.Minute)
+		acFile := createMessage("alice", "carol", types.MessageTypeFile, 2*time.Hour)
+		acOldFile := createMessage("alice", "carol", types.MessageTypeFile, 20*24*time.Hour)
+
+		run, err := svc.Purge(context.Background())
+		Expect(err).NotTo(HaveOccurred())
+		Expect(run.Deleted).To(Equal(int64(3)))
+		Expect(run.LastError).To(BeEmpty())
+
+		Expect(exists(abText)).To(BeTrue())
+		Expect(exists(abImage)).To(BeFalse())
+		Expect(exists(acText)).To(BeFalse())
+		Expect(exists(acFresh)).To(BeTrue())
+		Expect(exists(acFile)).To(BeTrue())
+		Expect(exists(acOldFile)).To(BeFalse())
+	})
+
+	It("应该分批删除过期消息、附件文件和检索索引，并记录执行结果", func() {
+		createPolicy("", types.MessageTypeUnknown, time.Hour)
+
+		var expired []string
+		for i := 0; i < 5; i++ {
+			expired = append(expired, createMessage("alice", "bob", types.MessageTypeText, 2*time.Hour))
+		}
+		Expect(os.WriteFile(filepath.Join(blobDir, "a.png"), []byte("png"), 0o600)).To(Succeed())
+		Expect(mstore.CreateAttachment(&models.MessageAttachment{
+			ID:        "att-1",
+			MessageID: expired[0],
+			Type:      "image",
+			URL:       "a.png",
+		})).To(Succeed())
+
+		run, err := svc.Purge(context.Background())
+		Expect(err).NotTo(HaveOccurred())
+		Expect(run.Deleted).To(Equal(int64(5)))
+		Expect(run.Attachments).To(Equal(int64(1)))
+
+		for _, id := range expired {
+			Expect(exists(id)).To(BeFalse())
+		}
+		attachments, err := mstore.GetAttachments(expired)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(attachments).To(BeEmpty())
+		Expect(filepath.Join(blobDir, "a.png")).NotTo(BeAnExistingFile())
+
+		hits, err := index.Search(&search.Query{UserID: "alice", Keyword: "hello"})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(hits).To(BeEmpty())
+
+		var details []map[string]any
+		Expect(json.Unmarshal([]byte(run.Details), &details)).To(Succeed())
+		Expect(details).To(HaveLen(1))
+		Expect(details[0]["messages"]).To(BeEquivalentTo(5))
+
+		runs, err := svc.ListRuns(10, "")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(runs.Runs).To(HaveLen(1))
+		Expect(runs.Runs[0].ID).To(Equal(run.ID))
+
+		// 每页数量不合法时使用默认值
+		for _, size := range []int{0, -1, 1000} {
+			runs, err = svc.ListRuns(size, "")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(runs.Runs).To(HaveLen(1))
+		}
+	})
+
+	It("未配置归档时 archive 策略应该被拒绝", func() {
+		_, err := svc.CreatePolicy(&request.CreateRetentionPolicyRequest{
+			TTLSeconds: 60,
+			Action:     models.RetentionArchive,
+		})
+		Expect(err).To(HaveOccurred())
+	})
+})
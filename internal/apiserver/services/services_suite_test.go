@@ -0,0 +1,43 @@
+package services_test
+
+import (
+	"path/filepath"
+	"testing"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"gorm.io/gorm"
+
+	"github.com/woxQAQ/gim/internal/migrations"
+	"github.com/woxQAQ/gim/pkg/db"
+	"github.com/woxQAQ/gim/pkg/snowflake"
+)
+
+func TestServices(t *testing.T) {
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "Services Suite")
+}
+
+var _ = BeforeSuite(func() {
+	Expect(snowflake.InitGenerator(1)).To(Succeed())
+})
+
+// openDB 打开并迁移临时目录下的 SQLite 数据库
+func openDB() *gorm.DB {
+	gdb, err := db.Open(&db.Config{
+		Driver: db.DriverSQLite,
+		DSN:    filepath.Join(GinkgoT().TempDir(), "services.db"),
+	})
+	Expect(err).NotTo(HaveOccurred())
+	DeferCleanup(func() {
+		sqlDB, err := gdb.DB()
+		Expect(err).NotTo(HaveOccurred())
+		Expect(sqlDB.Close()).To(Succeed())
+	})
+
+	m, err := db.NewMigrator(gdb, migrations.All())
+	Expect(err).NotTo(HaveOccurred())
+	_, err = m.Up()
+	Expect(err).NotTo(HaveOccurred())
+	return gdb
+}
@@ -0,0 +1,227 @@
+package services
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"time"
+
+	"github.com/woxQAQ/gim/internal/apiserver/gateway"
+	"github.com/woxQAQ/gim/internal/apiserver/stores"
+	"github.com/woxQAQ/gim/internal/apiserver/types/response"
+	"github.com/woxQAQ/gim/internal/models"
+	"github.com/woxQAQ/gim/internal/types"
+	"github.com/woxQAQ/gim/pkg/auth"
+	"github.com/woxQAQ/gim/pkg/logger"
+	"github.com/woxQAQ/gim/pkg/snowflake"
+)
+
+// ErrInvalidRefreshToken 刷新令牌不存在、已过期、已轮换或所属会话已吊销
+var ErrInvalidRefreshToken = errors.New("刷新令牌无效")
+
+// SessionConfig 登录会话配置
+type SessionConfig struct {
+	// RefreshTTL 刷新令牌的有效期，每次刷新后重新计算
+	RefreshTTL time.Duration
+}
+
+// SessionService 管理登录会话：签发访问令牌与刷新令牌、轮换刷新令牌和登出
+type SessionService struct {
+	sessionStore *stores.SessionStore
+	tokens       *auth.JWT
+	gateway      gateway.Client
+	logger       logger.Logger
+	refreshTTL   time.Duration
+	now          func() time.Time
+}
+
+// NewSessionService 创建SessionService实例，gw 为空时登出不断开网关上的连接
+func NewSessionService(sessionStore *stores.SessionStore, tokens *auth.JWT, gw gateway.Client, l logger.Logger, cfg *SessionConfig) *SessionService {
+	refreshTTL := cfg.RefreshTTL
+	if refreshTTL <= 0 {
+		refreshTTL = 30 * 24 * time.Hour
+	}
+	return &SessionService{
+		sessionStore: sessionStore,
+		tokens:       tokens,
+		gateway:      gw,
+		logger:       l,
+		refreshTTL:   refreshTTL,
+		now:          time.Now,
+	}
+}
+
+// Start 为已通过校验的用户创建登录会话，签发访问令牌和刷新令牌
+func (s *SessionService) Start(userID string, platform int32, deviceID string) (*response.TokenResponse, error) {
+	refresh, hash, err := newRefreshToken()
+	if err != nil {
+		return nil, err
+	}
+	session := &models.Session{
+		ID:          snowflake.GenerateID(),
+		UserID:      userID,
+		Platform:    platform,
+		DeviceID:    deviceID,
+		RefreshHash: hash,
+		ExpiresAt:   s.now().Add(s.refreshTTL),
+	}
+	if err := s.sessionStore.CreateSession(session); err != nil {
+		return nil, err
+	}
+	return s.issue(session, refresh)
+}
+
+// Refresh 使用刷新令牌换取新的访问令牌，同时轮换刷新令牌。
+// 已经轮换掉的刷新令牌再次使用时说明令牌可能已泄露，整个会话会被吊销
+func (s *SessionService) Refresh(refreshToken string) (*response.TokenResponse, error) {
+	hash := hashRefreshToken(refreshToken)
+	session, err := s.sessionStore.GetByRefreshHash(hash)
+	if errors.Is(err, stores.ErrSessionNotFound) {
+		if reused, err := s.sessionStore.GetByPrevRefreshHash(hash); err == nil && reused.RevokedAt == nil {
+			s.logger.Warn("刷新令牌被重复使用，吊销会话",
+				logger.String("session_id", reused.ID),
+				logger.String("user_id", reused.UserID))
+			s.revoke(context.Background(), reused, "refresh token reused")
+		}
+		return nil, ErrInvalidRefreshToken
+	}
+	if err != nil {
+		return nil, err
+	}
+	if !session.Active(s.now()) {
+		return nil, ErrInvalidRefreshToken
+	}
+
+	refresh, newHash, err := newRefreshToken()
+	if err != nil {
+		return nil, err
+	}
+	session.ExpiresAt = s.now().Add(s.refreshTTL)
+	ok, err := s.sessionStore.RotateRefresh(session.ID, hash, newHash, session.ExpiresAt)
+	if err != nil {
+		return nil, err
+	}
+	if !ok {
+		// 并发请求已经轮换了该令牌
+		return nil, ErrInvalidRefreshToken
+	}
+	return s.issue(session, refresh)
+}
+
+// Logout 吊销当前访问令牌所属的会话，并断开该平台在网关上的连接
+func (s *SessionService) Logout(ctx context.Context, claims *auth.Claims) error {
+	session, err := s.sessionStore.GetSession(claims.SessionID)
+	if errors.Is(err, stores.ErrSessionNotFound) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	return s.revoke(ctx, session, "logout")
+}
+
+// RevokeOtherSessions 吊销用户除当前会话以外的全部会话，用于修改密码后让其他设备重新登录
+func (s *SessionService) RevokeOtherSessions(ctx context.Context, claims *auth.Claims, reason string) error {
+	sessions, err := s.sessionStore.ListActiveSessions(claims.UserID())
+	if err != nil {
+		return err
+	}
+	for _, session := range sessions {
+		if session.ID == claims.SessionID {
+			continue
+		}
+		if err := s.revoke(ctx, session, reason); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// RevokeAllSessions 吊销用户的全部会话，用于重置密码后让所有设备重新登录
+func (s *SessionService) RevokeAllSessions(ctx context.Context, userID, reason string) error {
+	sessions, err := s.sessionStore.ListActiveSessions(userID)
+	if err != nil {
+		return err
+	}
+	for _, session := range sessions {
+		if err := s.revoke(ctx, session, reason); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// revoke 吊销会话并断开对应平台的连接，网关不可用时只记录日志
+func (s *SessionService) revoke(ctx context.Context, session *models.Session, reason string) error {
+	if err := s.sessionStore.RevokeSession(session.ID); err != nil {
+		return err
+	}
+	if s.gateway == nil {
+		return nil
+	}
+	dctx, cancel := context.WithTimeout(ctx, disconnectTimeout)
+	defer cancel()
+	_, err := s.gateway.Disconnect(dctx, &types.DisconnectRequest{
+		UserIDs:    []string{session.UserID},
+		PlatformID: session.Platform,
+		Reason:     reason,
+	})
+	if err != nil && !errors.Is(err, gateway.ErrGatewayDisabled) {
+		s.logger.Warn("断开会话连接失败",
+			logger.String("session_id", session.ID),
+			logger.String("user_id", session.UserID),
+			logger.Error(err))
+	}
+	return nil
+}
+
+// Run 定期删除不再需要的会话，直到 ctx 结束。
+// 会话过期或吊销后还要保留一个访问令牌有效期，期间仍需要据此拒绝旧的访问令牌
+func (s *SessionService) Run(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			n, err := s.sessionStore.DeleteInactiveSessions(s.now().Add(-s.tokens.TTL()))
+			if err != nil {
+				s.logger.Error("清理登录会话失败", logger.Error(err))
+			} else if n > 0 {
+				s.logger.Info("清理登录会话", logger.Int64("count", n))
+			}
+		}
+	}
+}
+
+func (s *SessionService) issue(session *models.Session, refresh string) (*response.TokenResponse, error) {
+	token, claims, err := s.tokens.Issue(session.UserID, session.ID, session.Platform)
+	if err != nil {
+		return nil, err
+	}
+	return &response.TokenResponse{
+		Token:            token,
+		ExpiresAt:        claims.ExpiresAt.Time,
+		RefreshToken:     refresh,
+		RefreshExpiresAt: session.ExpiresAt,
+	}, nil
+}
+
+// newRefreshToken 生成随机的刷新令牌，返回令牌及其摘要
+func newRefreshToken() (string, string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", "", err
+	}
+	token := base64.RawURLEncoding.EncodeToString(b)
+	return token, hashRefreshToken(token), nil
+}
+
+func hashRefreshToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
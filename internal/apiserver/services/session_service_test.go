@@ -0,0 +1,110 @@
+package services_test
+
+import (
+	"context"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"github.com/woxQAQ/gim/internal/apiserver/services"
+	"github.com/woxQAQ/gim/internal/apiserver/stores"
+	"github.com/woxQAQ/gim/internal/types"
+	"github.com/woxQAQ/gim/pkg/auth"
+	"github.com/woxQAQ/gim/pkg/logger"
+)
+
+var _ = Describe("SessionService", func() {
+	var (
+		sessions *stores.SessionStore
+		verifier *auth.Authenticator
+		gw       *fakeGateway
+		svc      *services.SessionService
+	)
+
+	BeforeEach(func() {
+		sessions = stores.NewSessionStore(openDB())
+		tokens, err := auth.NewJWT(&auth.JWTConfig{Secret: "0123456789abcdef0123456789abcdef", TTL: time.Minute})
+		Expect(err).NotTo(HaveOccurred())
+		verifier = auth.NewAuthenticator(tokens, sessions)
+		gw = &fakeGateway{}
+
+		l, _ := logger.NewLogger(&logger.Config{Level: "error"})
+		l.Disable()
+		svc = services.NewSessionService(sessions, tokens, gw, l, &services.SessionConfig{RefreshTTL: time.Hour})
+	})
+
+	It("刷新后应该轮换刷新令牌，旧的刷新令牌不能再使用", func() {
+		first, err := svc.Start("alice", 2, "phone")
+		Expect(err).NotTo(HaveOccurred())
+		claims, err := verifier.Verify(first.Token)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(claims.UserID()).To(Equal("alice"))
+		Expect(claims.Platform).To(BeEquivalentTo(2))
+
+		second, err := svc.Refresh(first.RefreshToken)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(second.RefreshToken).NotTo(Equal(first.RefreshToken))
+		_, err = verifier.Verify(second.Token)
+		Expect(err).NotTo(HaveOccurred())
+
+		_, err = svc.Refresh("unknown")
+		Expect(err).To(MatchError(services.ErrInvalidRefreshToken))
+	})
+
+	It("重复使用已轮换的刷新令牌时应该吊销整个会话", func() {
+		first, err := svc.Start("alice", 1, "")
+		Expect(err).NotTo(HaveOccurred())
+		second, err := svc.Refresh(first.RefreshToken)
+		Expect(err).NotTo(HaveOccurred())
+
+		_, err = svc.Refresh(first.RefreshToken)
+		Expect(err).To(MatchError(services.ErrInvalidRefreshToken))
+
+		_, err = svc.Refresh(second.RefreshToken)
+		Expect(err).To(MatchError(services.ErrInvalidRefreshToken))
+		_, err = verifier.Verify(second.Token)
+		Expect(err).To(MatchError(auth.ErrTokenRevoked))
+	})
+
+	It("登出应该只吊销当前会话并断开该平台的连接", func() {
+		phone, err := svc.Start("alice", 2, "phone")
+		Expect(err).NotTo(HaveOccurred())
+		web, err := svc.Start("alice", 3, "web")
+		Expect(err).NotTo(HaveOccurred())
+
+		claims, err := verifier.Verify(phone.Token)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(svc.Logout(context.Background(), claims)).To(Succeed())
+
+		_, err = verifier.Verify(phone.Token)
+		Expect(err).To(MatchError(auth.ErrTokenRevoked))
+		_, err = svc.Refresh(phone.RefreshToken)
+		Expect(err).To(MatchError(services.ErrInvalidRefreshToken))
+		_, err = verifier.Verify(web.Token)
+		Expect(err).NotTo(HaveOccurred())
+
+		Expect(gw.requests).To(ConsistOf(&types.DisconnectRequest{
+			UserIDs: []string{"alice"}, PlatformID: 2, Reason: "logout",
+		}))
+	})
+
+	It("应该只清理超过访问令牌有效期的会话", func() {
+		s, err := svc.Start("alice", 1, "")
+		Expect(err).NotTo(HaveOccurred())
+		claims, err := verifier.Verify(s.Token)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(sessions.RevokeSession(claims.SessionID)).To(Succeed())
+
+		n, err := sessions.DeleteInactiveSessions(time.Now().Add(-time.Minute))
+		Expect(err).NotTo(HaveOccurred())
+		Expect(n).To(BeZero())
+		n, err = sessions.DeleteInactiveSessions(time.Now().Add(time.Second))
+		Expect(err).NotTo(HaveOccurred())
+		Expect(n).To(BeEquivalentTo(1))
+
+		revoked, err := sessions.IsRevoked(claims.SessionID)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(revoked).To(BeTrue())
+	})
+})
@@ -1,75 +1,248 @@
 package services
 
 import (
+	"bufio"
+	"context"
 	"errors"
+	"io"
+	"net/http"
 	"time"
 
 	"github.com/woxQAQ/gim/internal/apiserver/stores"
+	"github.com/woxQAQ/gim/internal/apiserver/types/request"
 	"github.com/woxQAQ/gim/internal/apiserver/types/response"
+	"github.com/woxQAQ/gim/internal/audit"
 	"github.com/woxQAQ/gim/internal/models"
 	"github.com/woxQAQ/gim/pkg/snowflake"
 )
 
+var (
+	// ErrUsernameTaken 用户名已被其他用户使用
+	ErrUsernameTaken = errors.New("用户名已存在")
+	// ErrEmailTaken 邮箱已被其他用户使用
+	ErrEmailTaken = errors.New("邮箱已被使用")
+	// ErrInvalidCredentials 用户名或密码错误
+	ErrInvalidCredentials = errors.New("用户名或密码错误")
+	// ErrWrongPassword 修改密码时提供的当前密码错误
+	ErrWrongPassword = errors.New("当前密码错误")
+	// ErrAvatarTooLarge 头像文件超过 MaxAvatarSize
+	ErrAvatarTooLarge = errors.New("头像文件过大")
+	// ErrAvatarType 头像文件不是支持的图片格式
+	ErrAvatarType = errors.New("头像只支持 PNG、JPEG、GIF 和 WebP 格式")
+	// ErrAvatarUploadDisabled 未配置附件目录，不能上传头像
+	ErrAvatarUploadDisabled = errors.New("未配置附件存储，不能上传头像")
+	// ErrNoAvatar 用户未设置头像
+	ErrNoAvatar = errors.New("用户未设置头像")
+	// ErrInvalidRole 未知的用户角色
+	ErrInvalidRole = errors.New("未知的用户角色")
+	// ErrChangeOwnRole 管理员不能修改自己的角色，避免系统中没有管理员
+	ErrChangeOwnRole = errors.New("不能修改自己的角色")
+	// ErrBotRole 机器人账号的角色不能修改
+	ErrBotRole = errors.New("不能修改机器人账号的角色")
+	// ErrEmailRequired 要求验证邮箱时注册必须提供邮箱
+	ErrEmailRequired = errors.New("注册需要提供邮箱")
+	// ErrEmailNotVerified 要求验证邮箱时，邮箱未验证的用户不能登录
+	ErrEmailNotVerified = errors.New("邮箱未验证，请先完成邮箱验证")
+)
+
+// UserConfig 用户配置
+type UserConfig struct {
+	// RequireVerifiedEmail 为 true 时注册必须提供邮箱，邮箱未验证的用户不能登录。
+	// 没有邮箱的已有用户（例如通过 gimctl 创建的管理员）不受影响
+	RequireVerifiedEmail bool
+}
+
+// MaxAvatarSize 头像文件的最大字节数
+const MaxAvatarSize = 2 << 20
+
+// avatarTypes 支持的头像格式及保存时使用的扩展名
+var avatarTypes = map[string]string{
+	"image/png":  ".png",
+	"image/jpeg": ".jpg",
+	"image/gif":  ".gif",
+	"image/webp": ".webp",
+}
+
+// genders 性别名称与 models.User.Gender 取值的对应关系
+var genders = map[string]int8{
+	"unknown": 0,
+	"male":    1,
+	"female":  2,
+}
+
 // UserService 处理用户相关的业务逻辑
 type UserService struct {
 	userStore *stores.UserStore
+	banStore  *stores.BanStore
+	blobs     BlobStore
+	audit     *audit.Recorder
+	guard     *LoginGuard
+	cfg       UserConfig
 }
 
-// NewUserService 创建UserService实例
-func NewUserService(userStore *stores.UserStore) *UserService {
-	return &UserService{
+// NewUserService 创建UserService实例，blobs 为空时不能上传头像，recorder 为空时不记录审计日志，
+// guard 为空时不限制登录和注册的频率，cfg 为空时使用默认配置
+func NewUserService(userStore *stores.UserStore, banStore *stores.BanStore, blobs BlobStore, recorder *audit.Recorder, guard *LoginGuard, cfg *UserConfig) *UserService {
+	s := &UserService{
 		userStore: userStore,
+		banStore:  banStore,
+		blobs:     blobs,
+		audit:     recorder,
+		guard:     guard,
+	}
+	if cfg != nil {
+		s.cfg = *cfg
 	}
+	return s
 }
 
-// Register 处理用户注册的业务逻辑
-func (s *UserService) Register(user *models.User) error {
+// Register 处理用户注册的业务逻辑，同一客户端地址注册过多时返回 *ThrottleError
+func (s *UserService) Register(ctx context.Context, user *models.User, captchaToken string) error {
+	if s.cfg.RequireVerifiedEmail && user.Email == "" {
+		return ErrEmailRequired
+	}
+	if err := s.guard.CheckRegister(ctx, captchaToken); err != nil {
+		return err
+	}
+	s.guard.Registered(ctx)
+
 	// 生成用户ID
 	user.ID = snowflake.GenerateID()
 
-	// 检查用户名是否已存在
-	exists, err := s.userStore.CheckUsernameExists(user.Username)
-	if err != nil {
+	// 用户名与邮箱的唯一性由数据库约束保证，先查询再插入在并发注册时会同时通过检查
+	return userConflict(s.userStore.CreateUser(user))
+}
+
+// userConflict 将用户名或邮箱冲突转换为对应的业务错误
+func userConflict(err error) error {
+	var conflict *stores.ConflictError
+	if !errors.As(err, &conflict) {
 		return err
 	}
-	if exists {
-		return errors.New("用户名已存在")
+	switch conflict.Field {
+	case "username":
+		return ErrUsernameTaken
+	case "email":
+		return ErrEmailTaken
+	default:
+		return err
 	}
+}
 
-	// 检查邮箱是否已存在
-	if user.Email != "" {
-		exists, err = s.userStore.CheckEmailExists(user.Email)
-		if err != nil {
-			return err
-		}
-		if exists {
-			return errors.New("邮箱已被使用")
-		}
+// Login 处理用户登录的业务逻辑，账号禁用时返回 ErrAccountDisabled，封禁期内返回 *BannedError，
+// 失败过多时返回 *ThrottleError 或要求人机验证
+// 成功和失败的登录都记录审计日志
+func (s *UserService) Login(ctx context.Context, username, password, captchaToken string) (*response.UserResponse, error) {
+	// 被限制的登录不校验密码，也不计入失败次数
+	if err := s.guard.CheckLogin(ctx, username, captchaToken); err != nil {
+		return nil, err
 	}
 
-	// 创建用户
-	return s.userStore.CreateUser(user)
-}
-
-// Login 处理用户登录的业务逻辑
-func (s *UserService) Login(username, password string) (*response.UserResponse, error) {
 	// 根据用户名获取用户
 	user, err := s.userStore.GetUserByUsername(username)
 	if err != nil {
-		return nil, errors.New("用户名或密码错误")
+		s.loginFailed(ctx, "", username, "unknown_user")
+		s.guard.LoginFailed(ctx, username, "")
+		return nil, ErrInvalidCredentials
 	}
 
-	// 验证密码
-	if !user.ValidatePassword(password) {
-		return nil, errors.New("用户名或密码错误")
+	// 验证密码，机器人账号只能使用 API 密钥
+	if user.Role == models.RoleBot || !user.ValidatePassword(password) {
+		s.loginFailed(ctx, user.ID, username, "wrong_password")
+		s.guard.LoginFailed(ctx, username, user.ID)
+		return nil, ErrInvalidCredentials
+	}
+	s.guard.LoginSucceeded(username)
+
+	// 密码正确后再检查账号状态，避免泄露账号是否存在
+	if err := checkAccount(user, s.banStore, time.Now()); err != nil {
+		switch {
+		case errors.Is(err, ErrAccountDisabled):
+			s.loginFailed(ctx, user.ID, username, "disabled")
+		case errors.Is(err, ErrAccountBanned):
+			s.loginFailed(ctx, user.ID, username, "banned")
+		}
+		return nil, err
+	}
+	if s.cfg.RequireVerifiedEmail && user.Email != "" && !user.EmailVerified() {
+		s.loginFailed(ctx, user.ID, username, "email_not_verified")
+		return nil, ErrEmailNotVerified
 	}
 
 	// 更新最后登录时间
 	user.LastLogin = time.Now()
-	if err := s.userStore.UpdateUser(user); err != nil {
+	if err := s.userStore.UpdateLastLogin(user.ID, user.LastLogin); err != nil {
+		return nil, err
+	}
+
+	s.audit.Record(ctx, &audit.Entry{
+		Action:   audit.ActionLoginSuccess,
+		ActorID:  user.ID,
+		TargetID: user.ID,
+	})
+	return user.ToResponse(), nil
+}
+
+// loginFailed 记录登录失败，用户名不存在时 userID 为空
+func (s *UserService) loginFailed(ctx context.Context, userID, username, reason string) {
+	s.audit.Record(ctx, &audit.Entry{
+		Action:   audit.ActionLoginFailure,
+		TargetID: userID,
+		Detail:   map[string]any{"username": username, "reason": reason},
+	})
+}
+
+// SearchUsers 按用户名、昵称或邮箱查询用户，role 为空时不限角色，为 RoleBot 时查询机器人账号
+func (s *UserService) SearchUsers(query, role string, pageSize int, pageToken string) (*response.UserListResponse, error) {
+	if role != "" && role != models.RoleBot && !models.ValidRole(role) {
+		return nil, ErrInvalidRole
+	}
+	if pageSize <= 0 || pageSize > 100 {
+		pageSize = 20
+	}
+	users, err := s.userStore.SearchUsers(query, role, pageSize+1, pageToken)
+	if err != nil {
 		return nil, err
 	}
 
+	resp := &response.UserListResponse{
+		Users: make([]*response.UserResponse, 0, len(users)),
+	}
+	if len(users) > pageSize {
+		users = users[:pageSize]
+		resp.NextToken = users[len(users)-1].ID
+	}
+	for _, u := range users {
+		resp.Users = append(resp.Users, u.ToResponse())
+	}
+	return resp, nil
+}
+
+// SetRole 修改用户角色，operatorID 为执行修改的管理员
+func (s *UserService) SetRole(ctx context.Context, operatorID, userID, role string) (*response.UserResponse, error) {
+	if !models.ValidRole(role) {
+		return nil, ErrInvalidRole
+	}
+	if operatorID == userID {
+		return nil, ErrChangeOwnRole
+	}
+	user, err := s.userStore.GetUserByID(userID)
+	if err != nil {
+		return nil, err
+	}
+	if user.Role == models.RoleBot {
+		return nil, ErrBotRole
+	}
+	if err := s.userStore.UpdateRole(userID, role); err != nil {
+		return nil, err
+	}
+	s.audit.Record(ctx, &audit.Entry{
+		Action:   audit.ActionRoleChange,
+		ActorID:  operatorID,
+		TargetID: userID,
+		Detail:   map[string]any{"from": user.Role, "to": role},
+	})
+	user.Role = role
 	return user.ToResponse(), nil
 }
 
@@ -81,3 +254,187 @@ func (s *UserService) GetUserByID(id string) (*models.User, error) {
 	}
 	return user, nil
 }
+
+// GetUserProfile 获取用户资料，callerID 不是本人也不是管理员时不返回联系方式
+func (s *UserService) GetUserProfile(callerID, id string) (*response.UserResponse, error) {
+	user, err := s.userStore.GetUserByID(id)
+	if err != nil {
+		return nil, err
+	}
+	admin, err := s.isAdmin(callerID)
+	if err != nil {
+		return nil, err
+	}
+	if admin || callerID == id {
+		return user.ToResponse(), nil
+	}
+	return user.ToPublicResponse(), nil
+}
+
+// BatchGetUsers 批量获取用户资料，按请求的顺序返回，不存在的用户被忽略。
+// 与 GetUserProfile 相同，只有本人和管理员可以看到联系方式
+func (s *UserService) BatchGetUsers(callerID string, ids []string) ([]*response.UserResponse, error) {
+	users, err := s.userStore.GetUsersByIDs(ids)
+	if err != nil {
+		return nil, err
+	}
+	admin, err := s.isAdmin(callerID)
+	if err != nil {
+		return nil, err
+	}
+	byID := make(map[string]*models.User, len(users))
+	for _, u := range users {
+		byID[u.ID] = u
+	}
+	out := make([]*response.UserResponse, 0, len(users))
+	for _, id := range ids {
+		u, ok := byID[id]
+		if !ok {
+			continue
+		}
+		if admin || callerID == id {
+			out = append(out, u.ToResponse())
+		} else {
+			out = append(out, u.ToPublicResponse())
+		}
+		delete(byID, id)
+	}
+	return out, nil
+}
+
+// isAdmin 检查用户当前是否为管理员
+func (s *UserService) isAdmin(userID string) (bool, error) {
+	if userID == "" {
+		return false, nil
+	}
+	role, err := s.userStore.GetUserRole(userID)
+	if err != nil {
+		return false, err
+	}
+	return role == models.RoleAdmin, nil
+}
+
+// UpdateProfile 修改用户资料中请求里给出的字段
+func (s *UserService) UpdateProfile(userID string, req *request.UpdateProfileRequest) (*models.User, error) {
+	user, err := s.userStore.GetUserByID(userID)
+	if err != nil {
+		return nil, err
+	}
+	fields := map[string]interface{}{}
+	if req.Nickname != nil {
+		fields["nickname"] = *req.Nickname
+	}
+	if req.Gender != nil {
+		fields["gender"] = genders[*req.Gender]
+	}
+	if req.Phone != nil {
+		fields["phone"] = *req.Phone
+	}
+	if req.Email != nil && *req.Email != user.Email {
+		// 新邮箱需要重新验证
+		fields["email"] = *req.Email
+		fields["email_verified_at"] = nil
+	}
+	if req.Bio != nil {
+		fields["bio"] = *req.Bio
+	}
+	if err := userConflict(s.userStore.UpdateProfile(userID, fields)); err != nil {
+		return nil, err
+	}
+	return s.userStore.GetUserByID(userID)
+}
+
+// ChangePassword 校验当前密码后修改密码
+func (s *UserService) ChangePassword(ctx context.Context, userID, oldPassword, newPassword string) error {
+	user, err := s.userStore.GetUserByID(userID)
+	if err != nil {
+		return err
+	}
+	if !user.ValidatePassword(oldPassword) {
+		s.audit.Record(ctx, &audit.Entry{
+			Action:   audit.ActionPasswordChange,
+			ActorID:  userID,
+			TargetID: userID,
+			Detail:   map[string]any{"success": false, "reason": "wrong_password"},
+		})
+		return ErrWrongPassword
+	}
+	if err := s.userStore.UpdatePassword(userID, newPassword); err != nil {
+		return err
+	}
+	s.audit.Record(ctx, &audit.Entry{
+		Action:   audit.ActionPasswordChange,
+		ActorID:  userID,
+		TargetID: userID,
+		Detail:   map[string]any{"success": true},
+	})
+	return nil
+}
+
+// UploadAvatar 保存头像图片并更新用户头像，旧头像文件随后删除。
+// 图片格式根据文件内容判断，不信任客户端声明的类型
+func (s *UserService) UploadAvatar(ctx context.Context, userID string, r io.Reader) (*models.User, error) {
+	if s.blobs == nil {
+		return nil, ErrAvatarUploadDisabled
+	}
+	user, err := s.userStore.GetUserByID(userID)
+	if err != nil {
+		return nil, err
+	}
+
+	br := bufio.NewReaderSize(io.LimitReader(r, MaxAvatarSize+1), 512)
+	head, err := br.Peek(512)
+	if err != nil && !errors.Is(err, io.EOF) {
+		return nil, err
+	}
+	ext, ok := avatarTypes[http.DetectContentType(head)]
+	if !ok {
+		return nil, ErrAvatarType
+	}
+	counted := &countingReader{r: br}
+	url, err := s.blobs.Put(ctx, "avatars/"+userID+"/"+snowflake.GenerateID()+ext, counted)
+	if err != nil {
+		return nil, err
+	}
+	if counted.n > MaxAvatarSize {
+		_ = s.blobs.Remove(ctx, url)
+		return nil, ErrAvatarTooLarge
+	}
+
+	if err := s.userStore.UpdateProfile(userID, map[string]interface{}{"avatar": url}); err != nil {
+		_ = s.blobs.Remove(ctx, url)
+		return nil, err
+	}
+	// 旧头像可能是外部地址，由 BlobStore 判断是否需要删除
+	if user.Avatar != "" {
+		_ = s.blobs.Remove(ctx, user.Avatar)
+	}
+	return s.userStore.GetUserByID(userID)
+}
+
+// OpenAvatar 打开用户上传的头像文件，头像为外部地址时返回 ErrBlobNotManaged 和该地址
+func (s *UserService) OpenAvatar(ctx context.Context, userID string) (io.ReadCloser, string, error) {
+	user, err := s.userStore.GetUserByID(userID)
+	if err != nil {
+		return nil, "", err
+	}
+	if user.Avatar == "" {
+		return nil, "", ErrNoAvatar
+	}
+	if s.blobs == nil {
+		return nil, user.Avatar, ErrBlobNotManaged
+	}
+	f, err := s.blobs.Open(ctx, user.Avatar)
+	return f, user.Avatar, err
+}
+
+type countingReader struct {
+	r io.Reader
+	n int64
+}
+
+func (c *countingReader) Read(p []byte) (int, error) {
+	n, err := c.r.Read(p)
+	c.n += int64(n)
+	return n, err
+}
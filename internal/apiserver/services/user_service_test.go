@@ -0,0 +1,209 @@
+package services_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"sync"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"github.com/woxQAQ/gim/internal/apiserver/services"
+	"github.com/woxQAQ/gim/internal/apiserver/stores"
+	"github.com/woxQAQ/gim/internal/apiserver/types/request"
+	"github.com/woxQAQ/gim/internal/models"
+)
+
+var _ = Describe("UserService", func() {
+	var (
+		svc     *services.UserService
+		blobDir string
+	)
+
+	BeforeEach(func() {
+		blobDir = GinkgoT().TempDir()
+		database := openDB()
+		svc = services.NewUserService(stores.NewUserStore(database), stores.NewBanStore(database), &services.FileBlobStore{Root: blobDir}, nil, nil, nil)
+	})
+
+	It("用户名或邮箱已被使用时应该注册失败", func() {
+		Expect(svc.Register(context.Background(), &models.User{Username: "alice", Password: "x", Email: "a@example.com"}, "")).To(Succeed())
+		Expect(svc.Register(context.Background(), &models.User{Username: "alice", Password: "x"}, "")).To(MatchError(services.ErrUsernameTaken))
+		Expect(svc.Register(context.Background(), &models.User{Username: "bob", Password: "x", Email: "a@example.com"}, "")).To(MatchError(services.ErrEmailTaken))
+	})
+
+	It("并发注册同一个用户名时只有一个成功", func() {
+		var (
+			wg      sync.WaitGroup
+			mutex   sync.Mutex
+			success int
+		)
+		for i := 0; i < 8; i++ {
+			wg.Add(1)
+			go func() {
+				defer GinkgoRecover()
+				defer wg.Done()
+				err := svc.Register(context.Background(), &models.User{Username: "alice", Password: "x"}, "")
+				if err == nil {
+					mutex.Lock()
+					success++
+					mutex.Unlock()
+					return
+				}
+				Expect(err).To(MatchError(services.ErrUsernameTaken))
+			}()
+		}
+		wg.Wait()
+		Expect(success).To(Equal(1))
+	})
+
+	It("多次登录后密码仍然有效", func() {
+		Expect(svc.Register(context.Background(), &models.User{Username: "alice", Password: "secret"}, "")).To(Succeed())
+		for i := 0; i < 2; i++ {
+			_, err := svc.Login(context.Background(), "alice", "secret", "")
+			Expect(err).NotTo(HaveOccurred())
+		}
+	})
+
+	It("只修改请求中给出的资料字段", func() {
+		user := &models.User{Username: "alice", Password: "x", Email: "a@example.com"}
+		Expect(svc.Register(context.Background(), user, "")).To(Succeed())
+		Expect(svc.Register(context.Background(), &models.User{Username: "bob", Password: "x", Email: "b@example.com"}, "")).To(Succeed())
+
+		nickname, gender := "Alice", "female"
+		updated, err := svc.UpdateProfile(user.ID, &request.UpdateProfileRequest{Nickname: &nickname, Gender: &gender})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(updated.Nickname).To(Equal("Alice"))
+		Expect(updated.Gender).To(BeEquivalentTo(2))
+		Expect(updated.Email).To(Equal("a@example.com"))
+
+		email := "b@example.com"
+		_, err = svc.UpdateProfile(user.ID, &request.UpdateProfileRequest{Email: &email})
+		Expect(err).To(MatchError(services.ErrEmailTaken))
+	})
+
+	It("修改密码需要提供正确的当前密码", func() {
+		user := &models.User{Username: "alice", Password: "old-password"}
+		Expect(svc.Register(context.Background(), user, "")).To(Succeed())
+
+		Expect(svc.ChangePassword(context.Background(), user.ID, "wrong", "new-password")).To(MatchError(services.ErrWrongPassword))
+		Expect(svc.ChangePassword(context.Background(), user.ID, "old-password", "new-password")).To(Succeed())
+		_, err := svc.Login(context.Background(), "alice", "old-password", "")
+		Expect(err).To(MatchError(services.ErrInvalidCredentials))
+		_, err = svc.Login(context.Background(), "alice", "new-password", "")
+		Expect(err).NotTo(HaveOccurred())
+	})
+
+	It("按请求顺序批量返回存在的用户", func() {
+		alice := &models.User{Username: "alice", Password: "x"}
+		bob := &models.User{Username: "bob", Password: "x"}
+		Expect(svc.Register(context.Background(), alice, "")).To(Succeed())
+		Expect(svc.Register(context.Background(), bob, "")).To(Succeed())
+
+		users, err := svc.BatchGetUsers(alice.ID, []string{bob.ID, "missing", alice.ID, bob.ID})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(users).To(HaveLen(2))
+		Expect(users[0].Username).To(Equal("bob"))
+		Expect(users[1].Username).To(Equal("alice"))
+	})
+
+	It("只有本人和管理员可以看到其他用户的联系方式", func() {
+		ctx := context.Background()
+		alice := &models.User{Username: "alice", Password: "x", Email: "alice@example.com", Phone: "13800000000"}
+		bob := &models.User{Username: "bob", Password: "x"}
+		Expect(svc.Register(ctx, alice, "")).To(Succeed())
+		Expect(svc.Register(ctx, bob, "")).To(Succeed())
+
+		profile, err := svc.GetUserProfile(bob.ID, alice.ID)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(profile.Username).To(Equal("alice"))
+		Expect(profile.UserContactResponse).To(BeNil())
+		data, err := json.Marshal(profile)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(string(data)).NotTo(ContainSubstring("alice@example.com"))
+		Expect(string(data)).NotTo(ContainSubstring("phone"))
+
+		users, err := svc.BatchGetUsers(bob.ID, []string{alice.ID, bob.ID})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(users[0].UserContactResponse).To(BeNil())
+		Expect(users[1].UserContactResponse).NotTo(BeNil())
+
+		profile, err = svc.GetUserProfile(alice.ID, alice.ID)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(profile.Email).To(Equal("alice@example.com"))
+
+		_, err = svc.SetRole(ctx, "root", bob.ID, models.RoleAdmin)
+		Expect(err).NotTo(HaveOccurred())
+		profile, err = svc.GetUserProfile(bob.ID, alice.ID)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(profile.Phone).To(Equal("13800000000"))
+	})
+
+	It("上传头像时根据内容检查格式和大小并替换旧头像", func() {
+		ctx := context.Background()
+		user := &models.User{Username: "alice", Password: "x"}
+		Expect(svc.Register(context.Background(), user, "")).To(Succeed())
+
+		png := append([]byte("\x89PNG\r\n\x1a\n"), make([]byte, 64)...)
+		first, err := svc.UploadAvatar(ctx, user.ID, bytes.NewReader(png))
+		Expect(err).NotTo(HaveOccurred())
+		Expect(first.Avatar).To(HaveSuffix(".png"))
+		Expect(filepath.Join(blobDir, first.Avatar)).To(BeAnExistingFile())
+
+		second, err := svc.UploadAvatar(ctx, user.ID, bytes.NewReader(png))
+		Expect(err).NotTo(HaveOccurred())
+		Expect(filepath.Join(blobDir, second.Avatar)).To(BeAnExistingFile())
+		Expect(filepath.Join(blobDir, first.Avatar)).NotTo(BeAnExistingFile())
+
+		_, err = svc.UploadAvatar(ctx, user.ID, bytes.NewReader([]byte("<html></html>")))
+		Expect(err).To(MatchError(services.ErrAvatarType))
+		large := append(png, make([]byte, services.MaxAvatarSize)...)
+		_, err = svc.UploadAvatar(ctx, user.ID, bytes.NewReader(large))
+		Expect(err).To(MatchError(services.ErrAvatarTooLarge))
+
+		entries, err := os.ReadDir(filepath.Join(blobDir, "avatars", user.ID))
+		Expect(err).NotTo(HaveOccurred())
+		Expect(entries).To(HaveLen(1))
+	})
+
+	It("修改角色后应该能按角色查询，不能修改自己的角色", func() {
+		admin := &models.User{Username: "root", Password: "x", Role: models.RoleAdmin}
+		alice := &models.User{Username: "alice", Password: "x", Nickname: "Alice"}
+		Expect(svc.Register(context.Background(), admin, "")).To(Succeed())
+		Expect(svc.Register(context.Background(), alice, "")).To(Succeed())
+		Expect(svc.Register(context.Background(), &models.User{Username: "bob", Password: "x"}, "")).To(Succeed())
+
+		user, err := svc.SetRole(context.Background(), admin.ID, alice.ID, models.RoleModerator)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(user.Role).To(Equal(models.RoleModerator))
+		_, err = svc.SetRole(context.Background(), admin.ID, admin.ID, models.RoleUser)
+		Expect(err).To(MatchError(services.ErrChangeOwnRole))
+		_, err = svc.SetRole(context.Background(), admin.ID, alice.ID, "root")
+		Expect(err).To(MatchError(services.ErrInvalidRole))
+
+		resp, err := svc.SearchUsers("", models.RoleModerator, 20, "")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(resp.Users).To(HaveLen(1))
+		Expect(resp.Users[0].ID).To(Equal(alice.ID))
+
+		resp, err = svc.SearchUsers("o", "", 1, "")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(resp.Users).To(HaveLen(1))
+		Expect(resp.NextToken).NotTo(BeEmpty())
+		next, err := svc.SearchUsers("o", "", 1, resp.NextToken)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(next.Users).To(HaveLen(1))
+		Expect(next.Users[0].ID).NotTo(Equal(resp.Users[0].ID))
+
+		// 每页数量不合法时使用默认值
+		for _, size := range []int{0, -1, 1000} {
+			resp, err = svc.SearchUsers("", "", size, "")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(resp.Users).To(HaveLen(3))
+			Expect(resp.NextToken).To(BeEmpty())
+		}
+	})
+})
@@ -0,0 +1,155 @@
+package services
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/woxQAQ/gim/internal/apiserver/stores"
+	"github.com/woxQAQ/gim/internal/apiserver/types/request"
+	"github.com/woxQAQ/gim/internal/apiserver/types/response"
+	"github.com/woxQAQ/gim/internal/models"
+	"github.com/woxQAQ/gim/internal/webhook"
+	"github.com/woxQAQ/gim/pkg/snowflake"
+)
+
+// supportedWebhookEvents 可订阅的回调事件
+var supportedWebhookEvents = map[string]bool{
+	"*":                         true,
+	webhook.EventMessageCreated: true,
+	webhook.EventUserOnline:     true,
+	webhook.EventUserOffline:    true,
+}
+
+// WebhookService 处理回调配置和投递日志相关的业务逻辑
+type WebhookService struct {
+	webhookStore *stores.WebhookStore
+}
+
+// NewWebhookService 创建WebhookService实例
+func NewWebhookService(webhookStore *stores.WebhookStore) *WebhookService {
+	return &WebhookService{
+		webhookStore: webhookStore,
+	}
+}
+
+// CreateEndpoint 创建回调地址
+func (s *WebhookService) CreateEndpoint(req *request.CreateWebhookEndpointRequest) (*response.WebhookEndpointResponse, error) {
+	for _, event := range req.Events {
+		if !supportedWebhookEvents[event] {
+			return nil, errors.New("不支持的回调事件: " + event)
+		}
+	}
+
+	secret := req.Secret
+	if secret == "" {
+		buf := make([]byte, 32)
+		if _, err := rand.Read(buf); err != nil {
+			return nil, err
+		}
+		secret = hex.EncodeToString(buf)
+	}
+
+	endpoint := &models.WebhookEndpoint{
+		ID:      snowflake.GenerateID(),
+		URL:     req.URL,
+		Secret:  secret,
+		Events:  strings.Join(req.Events, ","),
+		Enabled: true,
+	}
+	if err := s.webhookStore.CreateEndpoint(endpoint); err != nil {
+		return nil, err
+	}
+
+	resp := endpoint.ToResponse()
+	resp.Secret = secret
+	return resp, nil
+}
+
+// ListEndpoints 获取所有回调地址
+func (s *WebhookService) ListEndpoints() ([]*response.WebhookEndpointResponse, error) {
+	endpoints, err := s.webhookStore.ListEndpoints()
+	if err != nil {
+		return nil, err
+	}
+	resp := make([]*response.WebhookEndpointResponse, 0, len(endpoints))
+	for _, endpoint := range endpoints {
+		resp = append(resp, endpoint.ToResponse())
+	}
+	return resp, nil
+}
+
+// DeleteEndpoint 删除回调地址
+func (s *WebhookService) DeleteEndpoint(id string) error {
+	return s.webhookStore.DeleteEndpoint(id)
+}
+
+// ListDeliveries 查询投递日志
+func (s *WebhookService) ListDeliveries(endpointID, event, status string, pageSize int, pageToken string) (*response.WebhookDeliveryListResponse, error) {
+	if pageSize <= 0 || pageSize > 100 {
+		pageSize = 20
+	}
+	filter := &stores.WebhookDeliveryFilter{
+		EndpointID: endpointID,
+		Event:      event,
+		Limit:      pageSize + 1,
+		LastID:     pageToken,
+	}
+	if status != "" {
+		st, err := parseDeliveryStatus(status)
+		if err != nil {
+			return nil, err
+		}
+		filter.Status = &st
+	}
+
+	deliveries, err := s.webhookStore.ListDeliveries(filter)
+	if err != nil {
+		return nil, err
+	}
+
+	resp := &response.WebhookDeliveryListResponse{
+		Deliveries: make([]*response.WebhookDeliveryResponse, 0, len(deliveries)),
+	}
+	if len(deliveries) > pageSize {
+		deliveries = deliveries[:pageSize]
+		resp.NextToken = deliveries[len(deliveries)-1].ID
+	}
+	for _, d := range deliveries {
+		resp.Deliveries = append(resp.Deliveries, d.ToResponse())
+	}
+	return resp, nil
+}
+
+// ListDeadLetters 查询死信记录
+func (s *WebhookService) ListDeadLetters(endpointID string, limit int) ([]*response.WebhookDeadLetterResponse, error) {
+	letters, err := s.webhookStore.ListDeadLetters(endpointID, limit)
+	if err != nil {
+		return nil, err
+	}
+	resp := make([]*response.WebhookDeadLetterResponse, 0, len(letters))
+	for _, l := range letters {
+		resp = append(resp, l.ToResponse())
+	}
+	return resp, nil
+}
+
+// RetryDeadLetter 将死信重新放回投递队列
+func (s *WebhookService) RetryDeadLetter(id string) error {
+	return s.webhookStore.RequeueDeadLetter(id, time.Now())
+}
+
+func parseDeliveryStatus(status string) (models.WebhookDeliveryStatus, error) {
+	for _, st := range []models.WebhookDeliveryStatus{
+		models.WebhookDeliveryPending,
+		models.WebhookDeliverySucceeded,
+		models.WebhookDeliveryDead,
+	} {
+		if st.String() == status {
+			return st, nil
+		}
+	}
+	return 0, errors.New("未知的投递状态: " + status)
+}
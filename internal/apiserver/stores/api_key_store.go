@@ -0,0 +1,97 @@
+package stores
+
+import (
+	"errors"
+	"time"
+
+	"gorm.io/gorm"
+
+	"github.com/woxQAQ/gim/internal/models"
+)
+
+// ErrAPIKeyNotFound API 密钥不存在，或已经失效
+var ErrAPIKeyNotFound = errors.New("API 密钥不存在")
+
+// APIKeyStore 处理机器人 API 密钥相关的数据库操作
+type APIKeyStore struct {
+	db *gorm.DB
+}
+
+// NewAPIKeyStore 创建APIKeyStore实例
+func NewAPIKeyStore(db *gorm.DB) *APIKeyStore {
+	return &APIKeyStore{db: db}
+}
+
+// CreateAPIKey 创建 API 密钥
+func (s *APIKeyStore) CreateAPIKey(key *models.APIKey) error {
+	return s.db.Create(key).Error
+}
+
+// GetAPIKeyByHash 根据密钥摘要获取 API 密钥，包括已失效的密钥
+func (s *APIKeyStore) GetAPIKeyByHash(hash string) (*models.APIKey, error) {
+	var key models.APIKey
+	err := s.db.First(&key, "hash = ?", hash).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, ErrAPIKeyNotFound
+	}
+	return &key, err
+}
+
+// GetAPIKey 获取机器人的一个 API 密钥
+func (s *APIKeyStore) GetAPIKey(userID, id string) (*models.APIKey, error) {
+	var key models.APIKey
+	err := s.db.First(&key, "id = ? AND user_id = ?", id, userID).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, ErrAPIKeyNotFound
+	}
+	return &key, err
+}
+
+// ListAPIKeys 获取机器人的全部 API 密钥，按创建时间倒序
+func (s *APIKeyStore) ListAPIKeys(userID string) ([]*models.APIKey, error) {
+	var keys []*models.APIKey
+	err := s.db.Where("user_id = ?", userID).Order("created_at desc").Find(&keys).Error
+	return keys, err
+}
+
+// RevokeAPIKey 吊销机器人的一个有效密钥，密钥不存在或已失效时返回 ErrAPIKeyNotFound
+func (s *APIKeyStore) RevokeAPIKey(userID, id string, now time.Time) error {
+	result := s.db.Model(&models.APIKey{}).
+		Where("id = ? AND user_id = ? AND revoked_at IS NULL AND (expires_at IS NULL OR expires_at > ?)", id, userID, now).
+		Update("revoked_at", now)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return ErrAPIKeyNotFound
+	}
+	return nil
+}
+
+// RotateAPIKey 在一个事务中创建新密钥，并让旧密钥在 retireAt 失效，retireAt 不晚于 now 时立即吊销旧密钥。
+// 旧密钥已经失效时返回 ErrAPIKeyNotFound，不创建新密钥
+func (s *APIKeyStore) RotateAPIKey(old *models.APIKey, next *models.APIKey, now, retireAt time.Time) error {
+	return s.db.Transaction(func(tx *gorm.DB) error {
+		updates := map[string]interface{}{"expires_at": retireAt}
+		if !retireAt.After(now) {
+			updates["revoked_at"] = now
+		}
+		result := tx.Model(&models.APIKey{}).
+			Where("id = ? AND revoked_at IS NULL AND (expires_at IS NULL OR expires_at > ?)", old.ID, now).
+			Updates(updates)
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return ErrAPIKeyNotFound
+		}
+		return tx.Create(next).Error
+	})
+}
+
+// TouchAPIKey 记录密钥的使用时间，上次记录晚于 since 时不更新，避免每次请求都写数据库
+func (s *APIKeyStore) TouchAPIKey(id string, now, since time.Time) error {
+	return s.db.Model(&models.APIKey{}).
+		Where("id = ? AND (last_used_at IS NULL OR last_used_at < ?)", id, since).
+		Update("last_used_at", now).Error
+}
@@ -0,0 +1,87 @@
+package stores
+
+import (
+	"errors"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+
+	"github.com/woxQAQ/gim/internal/apiserver/types/request"
+	"github.com/woxQAQ/gim/internal/models"
+)
+
+// appendRetries 多个进程同时追加审计日志时序号冲突的重试次数
+const appendRetries = 5
+
+// AuditStore 处理审计日志相关的数据库操作，审计日志只能追加和查询
+type AuditStore struct {
+	db *gorm.DB
+}
+
+// NewAuditStore 创建AuditStore实例
+func NewAuditStore(db *gorm.DB) *AuditStore {
+	return &AuditStore{db: db}
+}
+
+// Append 追加审计日志，分配下一个序号；chain 为 true 时链接上一条记录的哈希并计算本条记录的哈希。
+// 序号由唯一索引保证连续，apiserver 和网关同时写入时冲突的一方重新读取最后一条记录后重试；
+// SQLite 在另一个进程持有写锁时报告数据库已锁定，稍等后同样重试
+func (s *AuditStore) Append(log *models.AuditLog, chain bool) error {
+	var err error
+	for i := 0; i < appendRetries; i++ {
+		err = s.db.Transaction(func(tx *gorm.DB) error {
+			var last models.AuditLog
+			err := tx.Select("seq", "hash").Order("seq desc").Take(&last).Error
+			if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+				return err
+			}
+			log.Seq = last.Seq + 1
+			log.PrevHash, log.Hash = "", ""
+			if chain {
+				log.PrevHash = last.Hash
+				log.Hash = log.ComputeHash()
+			}
+			return conflictError(tx, tx.Create(log).Error, "audit_logs", "seq")
+		})
+		var conflict *ConflictError
+		switch {
+		case errors.As(err, &conflict):
+		case err != nil && strings.Contains(err.Error(), "database is locked"):
+			time.Sleep(time.Duration(i+1) * 10 * time.Millisecond)
+		default:
+			return err
+		}
+	}
+	return err
+}
+
+// ListLogs 按条件查询审计日志，按ID倒序分页
+func (s *AuditStore) ListLogs(req *request.ListAuditLogsRequest, limit int) ([]*models.AuditLog, error) {
+	var logs []*models.AuditLog
+	query := s.db.Model(&models.AuditLog{})
+	if req.ActorID != "" {
+		query = query.Where("actor_id = ?", req.ActorID)
+	}
+	if req.Action != "" {
+		query = query.Where("action = ?", req.Action)
+	}
+	if req.Since != nil {
+		query = query.Where("created_at >= ?", *req.Since)
+	}
+	if req.Until != nil {
+		query = query.Where("created_at < ?", *req.Until)
+	}
+	if req.PageToken != "" {
+		query = query.Where("id < ?", req.PageToken)
+	}
+	err := query.Order("id desc").Limit(limit).Find(&logs).Error
+	return logs, err
+}
+
+// ListBySeq 按序号正序获取序号大于 afterSeq 的审计日志，用于校验哈希链
+func (s *AuditStore) ListBySeq(afterSeq int64, limit int) ([]*models.AuditLog, error) {
+	var logs []*models.AuditLog
+	err := s.db.Where("seq > ?", afterSeq).Order("seq asc").Limit(limit).Find(&logs).Error
+	return logs, err
+}
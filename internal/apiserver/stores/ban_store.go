@@ -0,0 +1,66 @@
+package stores
+
+import (
+	"errors"
+	"time"
+
+	"gorm.io/gorm"
+
+	"github.com/woxQAQ/gim/internal/models"
+)
+
+// ErrBanNotFound 用户没有生效的封禁
+var ErrBanNotFound = errors.New("用户未被封禁")
+
+// BanStore 处理用户封禁记录相关的数据库操作
+type BanStore struct {
+	db *gorm.DB
+}
+
+// NewBanStore 创建BanStore实例
+func NewBanStore(db *gorm.DB) *BanStore {
+	return &BanStore{db: db}
+}
+
+// CreateBan 创建封禁记录
+func (s *BanStore) CreateBan(ban *models.UserBan) error {
+	return s.db.Create(ban).Error
+}
+
+// ActiveBan 获取用户在 now 时生效的封禁，有多条时返回最晚结束的一条
+func (s *BanStore) ActiveBan(userID string, now time.Time) (*models.UserBan, error) {
+	var bans []*models.UserBan
+	err := s.db.Where("user_id = ? AND lifted_at IS NULL AND (expires_at IS NULL OR expires_at > ?)", userID, now).
+		Find(&bans).Error
+	if err != nil {
+		return nil, err
+	}
+	if len(bans) == 0 {
+		return nil, ErrBanNotFound
+	}
+	latest := bans[0]
+	for _, b := range bans[1:] {
+		if b.ExpiresAt == nil || (latest.ExpiresAt != nil && b.ExpiresAt.After(*latest.ExpiresAt)) {
+			latest = b
+		}
+	}
+	return latest, nil
+}
+
+// LiftBans 解除用户全部生效的封禁，返回解除的数量
+func (s *BanStore) LiftBans(userID, liftedBy string, now time.Time) (int64, error) {
+	result := s.db.Model(&models.UserBan{}).
+		Where("user_id = ? AND lifted_at IS NULL AND (expires_at IS NULL OR expires_at > ?)", userID, now).
+		Updates(map[string]interface{}{
+			"lifted_at": now,
+			"lifted_by": liftedBy,
+		})
+	return result.RowsAffected, result.Error
+}
+
+// ListBans 获取用户的封禁记录，按创建时间倒序
+func (s *BanStore) ListBans(userID string) ([]*models.UserBan, error) {
+	var bans []*models.UserBan
+	err := s.db.Where("user_id = ?", userID).Order("created_at desc").Find(&bans).Error
+	return bans, err
+}
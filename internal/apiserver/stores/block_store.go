@@ -0,0 +1,68 @@
+package stores
+
+import (
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+
+	"github.com/woxQAQ/gim/internal/models"
+)
+
+// BlockStore 处理用户黑名单相关的数据库操作
+type BlockStore struct {
+	db *gorm.DB
+}
+
+// NewBlockStore 创建BlockStore实例
+func NewBlockStore(db *gorm.DB) *BlockStore {
+	return &BlockStore{db: db}
+}
+
+// Block 将 blockedID 加入 userID 的黑名单，已在黑名单中时不做修改
+func (s *BlockStore) Block(userID, blockedID string) error {
+	return s.db.Clauses(clause.OnConflict{DoNothing: true}).
+		Create(&models.Block{UserID: userID, BlockedID: blockedID}).Error
+}
+
+// Unblock 将 blockedID 移出 userID 的黑名单，返回是否存在该记录
+func (s *BlockStore) Unblock(userID, blockedID string) (bool, error) {
+	result := s.db.Where("user_id = ? AND blocked_id = ?", userID, blockedID).Delete(&models.Block{})
+	return result.RowsAffected > 0, result.Error
+}
+
+// IsBlocked 检查 blockedID 是否在 userID 的黑名单中
+func (s *BlockStore) IsBlocked(userID, blockedID string) (bool, error) {
+	var count int64
+	err := s.db.Model(&models.Block{}).
+		Where("user_id = ? AND blocked_id = ?", userID, blockedID).
+		Count(&count).Error
+	return count > 0, err
+}
+
+// ListBlocked 获取用户的黑名单，按拉黑时间倒序
+func (s *BlockStore) ListBlocked(userID string) ([]*models.Block, error) {
+	var blocks []*models.Block
+	err := s.db.Where("user_id = ?", userID).Order("created_at desc").Find(&blocks).Error
+	return blocks, err
+}
+
+// BlockedBy 返回 userIDs 中把 blockedID 加入黑名单的用户
+func (s *BlockStore) BlockedBy(blockedID string, userIDs []string) (map[string]bool, error) {
+	out := make(map[string]bool)
+	if len(userIDs) == 0 {
+		return out, nil
+	}
+	var ids []string
+	err := s.db.Model(&models.Block{}).
+		Where("blocked_id = ? AND user_id IN ?", blockedID, userIDs).
+		Pluck("user_id", &ids).Error
+	for _, id := range ids {
+		out[id] = true
+	}
+	return out, err
+}
+
+// RemoveByUser 删除用户拉黑和被拉黑的全部记录，返回删除的记录数
+func (s *BlockStore) RemoveByUser(userID string) (int64, error) {
+	result := s.db.Where("user_id = ? OR blocked_id = ?", userID, userID).Delete(&models.Block{})
+	return result.RowsAffected, result.Error
+}
@@ -0,0 +1,46 @@
+package stores
+
+import (
+	"context"
+	"time"
+
+	"github.com/woxQAQ/gim/pkg/cache"
+)
+
+// recentWindow 每个会话缓存的最新消息数量，首屏查询不超过该数量时直接读缓存
+const recentWindow = 50
+
+// 缓存键
+func userCacheKey(id string) string               { return "user:" + id }
+func groupMembersCacheKey(groupID string) string  { return "group:" + groupID + ":members" }
+func recentMessagesCacheKey(convID string) string { return "conv:" + convID + ":recent" }
+
+// storeCache 各个 store 共用的缓存字段，cache 为 nil 时不使用缓存
+type storeCache struct {
+	cache cache.Cache
+	ttl   time.Duration
+	// tx 不为空时 store 绑定在事务上，缓存失效推迟到事务提交之后
+	tx *Tx
+}
+
+// inTx 返回绑定到事务的缓存字段
+func (c storeCache) inTx(tx *Tx) storeCache {
+	c.tx = tx
+	return c
+}
+
+func (c *storeCache) enabled() bool {
+	return c.cache != nil
+}
+
+// invalidate 删除缓存键；失败时只能等待过期，不影响已经成功的写入
+func (c *storeCache) invalidate(keys ...string) {
+	if c.cache == nil || len(keys) == 0 {
+		return
+	}
+	if c.tx != nil {
+		c.tx.AfterCommit(func() { _ = c.cache.Delete(context.Background(), keys...) })
+		return
+	}
+	_ = c.cache.Delete(context.Background(), keys...)
+}
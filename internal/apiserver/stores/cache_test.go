@@ -0,0 +1,109 @@
+package stores_test
+
+import (
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"gorm.io/gorm"
+
+	"github.com/woxQAQ/gim/internal/apiserver/stores"
+	"github.com/woxQAQ/gim/internal/models"
+	"github.com/woxQAQ/gim/internal/types"
+	"github.com/woxQAQ/gim/pkg/cache"
+)
+
+var _ = Describe("缓存", func() {
+	var (
+		gdb *gorm.DB
+		c   *cache.LRU
+	)
+
+	BeforeEach(func() {
+		gdb = openBackend(backends[0])
+		c = cache.NewLRU(100, time.Minute)
+	})
+
+	It("修改和删除用户后应该读到最新数据", func() {
+		store := stores.NewUserStore(gdb).WithCache(c, 0)
+		Expect(store.CreateUser(&models.User{ID: "u1", Username: "alice", Password: "secret"})).To(Succeed())
+
+		u, err := store.GetUserByID("u1")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(u.Username).To(Equal("alice"))
+
+		// 绕过 store 直接修改数据库，缓存应该仍然返回旧值
+		Expect(gdb.Model(&models.User{}).Where("id = ?", "u1").Update("nickname", "stale").Error).To(Succeed())
+		u, err = store.GetUserByID("u1")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(u.Nickname).To(BeEmpty())
+
+		Expect(store.AnonymizeUser("u1")).To(Succeed())
+		u, err = store.GetUserByID("u1")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(u.Username).To(Equal("deleted-u1"))
+
+		Expect(store.DeleteUser("u1")).To(Succeed())
+		_, err = store.GetUserByID("u1")
+		Expect(err).To(HaveOccurred())
+	})
+
+	It("移出群组后成员缓存应该失效", func() {
+		store := stores.NewGroupStore(gdb).WithCache(c, 0)
+		Expect(gdb.Create(&[]models.GroupMember{
+			{GroupID: "g1", UserID: "alice"},
+			{GroupID: "g1", UserID: "bob"},
+		}).Error).To(Succeed())
+
+		ok, err := store.IsMember("g1", "bob")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(ok).To(BeTrue())
+
+		n, err := store.RemoveMemberships("bob")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(n).To(BeEquivalentTo(1))
+
+		ok, err = store.IsMember("g1", "bob")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(ok).To(BeFalse())
+		Expect(store.GetMemberIDs("g1")).To(Equal([]string{"alice"}))
+	})
+
+	It("会话最新消息应该在写入、撤回和删除后失效", func() {
+		store := stores.NewMessageStore(gdb).WithCache(c, 0)
+		create := func(id string) {
+			m := &models.Message{}
+			m.FromTypes(&types.Message{
+				Header:  types.MessageHeader{ID: id, Type: types.MessageTypeText, From: "alice", To: "bob", Platform: 1, Timestamp: time.Now()},
+				Payload: []byte("hi " + id),
+			})
+			Expect(store.CreateMessage(m)).To(Succeed())
+		}
+		latest := func() []*models.Message {
+			page, err := store.ListMessages(&stores.MessageQuery{UserID: "bob", PeerID: "alice", Limit: 2})
+			Expect(err).NotTo(HaveOccurred())
+			return page
+		}
+
+		create("2001")
+		create("2002")
+		Expect(latest()).To(HaveLen(2))
+
+		create("2003")
+		page := latest()
+		Expect(page[0].ID).To(Equal("2003"))
+		Expect(page[1].ID).To(Equal("2002"))
+
+		Expect(store.RecallMessage("2003")).To(Succeed())
+		Expect(latest()[0].Status).To(Equal(models.MessageStatusRecalled))
+
+		Expect(store.DeleteMessages([]string{"2003"})).To(Succeed())
+		Expect(latest()[0].ID).To(Equal("2002"))
+
+		// 带游标的查询不经过缓存
+		older, err := store.ListMessages(&stores.MessageQuery{UserID: "bob", PeerID: "alice", Before: "2002", Limit: 2})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(older).To(HaveLen(1))
+		Expect(older[0].ID).To(Equal("2001"))
+	})
+})
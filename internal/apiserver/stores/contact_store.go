@@ -0,0 +1,161 @@
+package stores
+
+import (
+	"errors"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+
+	"github.com/woxQAQ/gim/internal/models"
+)
+
+// ErrFriendRequestNotFound 好友申请不存在
+var ErrFriendRequestNotFound = errors.New("好友申请不存在")
+
+// ErrContactNotFound 联系人不存在
+var ErrContactNotFound = errors.New("联系人不存在")
+
+// ContactStore 处理好友申请和联系人相关的数据库操作
+type ContactStore struct {
+	db *gorm.DB
+}
+
+// NewContactStore 创建ContactStore实例
+func NewContactStore(db *gorm.DB) *ContactStore {
+	return &ContactStore{db: db}
+}
+
+// WithTx 返回绑定到事务的ContactStore
+func (s *ContactStore) WithTx(tx *Tx) *ContactStore {
+	return &ContactStore{db: tx.db}
+}
+
+// CreateRequest 创建好友申请
+func (s *ContactStore) CreateRequest(req *models.FriendRequest) error {
+	return s.db.Create(req).Error
+}
+
+// GetRequest 根据ID获取好友申请
+func (s *ContactStore) GetRequest(id string) (*models.FriendRequest, error) {
+	var req models.FriendRequest
+	if err := s.db.First(&req, "id = ?", id).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrFriendRequestNotFound
+		}
+		return nil, err
+	}
+	return &req, nil
+}
+
+// GetPendingRequest 获取 fromID 发给 toID 的待处理申请
+func (s *ContactStore) GetPendingRequest(fromID, toID string) (*models.FriendRequest, error) {
+	var req models.FriendRequest
+	err := s.db.Where("from_id = ? AND to_id = ? AND status = ?", fromID, toID, models.FriendRequestPending).
+		Order("id desc").
+		First(&req).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrFriendRequestNotFound
+		}
+		return nil, err
+	}
+	return &req, nil
+}
+
+// UpdateRequestMessage 更新待处理申请的附言，重复申请时使用
+func (s *ContactStore) UpdateRequestMessage(id, message string) error {
+	return s.db.Model(&models.FriendRequest{}).Where("id = ?", id).Update("message", message).Error
+}
+
+// HandleRequest 将待处理的申请更新为 status，申请已被处理时返回 false
+func (s *ContactStore) HandleRequest(id string, status models.FriendRequestStatus) (bool, error) {
+	result := s.db.Model(&models.FriendRequest{}).
+		Where("id = ? AND status = ?", id, models.FriendRequestPending).
+		Updates(map[string]interface{}{
+			"status":     status,
+			"handled_at": time.Now(),
+		})
+	return result.RowsAffected == 1, result.Error
+}
+
+// ListRequests 获取用户收到或发出的好友申请，按ID倒序，before 为分页游标
+func (s *ContactStore) ListRequests(userID string, incoming bool, before string, limit int) ([]*models.FriendRequest, error) {
+	q := s.db.Model(&models.FriendRequest{})
+	if incoming {
+		q = q.Where("to_id = ?", userID)
+	} else {
+		q = q.Where("from_id = ?", userID)
+	}
+	if before != "" {
+		q = q.Where("id < ?", before)
+	}
+	if limit > 0 {
+		q = q.Limit(limit)
+	}
+	var reqs []*models.FriendRequest
+	err := q.Order("id desc").Find(&reqs).Error
+	return reqs, err
+}
+
+// AddContacts 为双方添加联系人记录，已经是联系人时不做修改
+func (s *ContactStore) AddContacts(userID, contactID string) error {
+	return s.db.Clauses(clause.OnConflict{DoNothing: true}).Create([]*models.Contact{
+		{UserID: userID, ContactID: contactID},
+		{UserID: contactID, ContactID: userID},
+	}).Error
+}
+
+// RemoveContacts 删除双方的联系人记录，返回删除的记录数
+func (s *ContactStore) RemoveContacts(userID, contactID string) (int64, error) {
+	result := s.db.Where("(user_id = ? AND contact_id = ?) OR (user_id = ? AND contact_id = ?)",
+		userID, contactID, contactID, userID).Delete(&models.Contact{})
+	return result.RowsAffected, result.Error
+}
+
+// IsContact 检查 contactID 是否为 userID 的联系人
+func (s *ContactStore) IsContact(userID, contactID string) (bool, error) {
+	var count int64
+	err := s.db.Model(&models.Contact{}).
+		Where("user_id = ? AND contact_id = ?", userID, contactID).
+		Count(&count).Error
+	return count > 0, err
+}
+
+// ListContacts 获取用户的全部联系人，按添加时间排序
+func (s *ContactStore) ListContacts(userID string) ([]*models.Contact, error) {
+	var contacts []*models.Contact
+	err := s.db.Where("user_id = ?", userID).Order("created_at asc").Find(&contacts).Error
+	return contacts, err
+}
+
+// SetRemark 设置联系人备注
+func (s *ContactStore) SetRemark(userID, contactID, remark string) error {
+	result := s.db.Model(&models.Contact{}).
+		Where("user_id = ? AND contact_id = ?", userID, contactID).
+		Update("remark", remark)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected > 0 {
+		return nil
+	}
+	// 备注没有变化时部分数据库不计入影响行数
+	ok, err := s.IsContact(userID, contactID)
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return ErrContactNotFound
+	}
+	return nil
+}
+
+// RemoveByUser 删除用户的全部联系人和好友申请，返回删除的联系人记录数
+func (s *ContactStore) RemoveByUser(userID string) (int64, error) {
+	if err := s.db.Where("from_id = ? OR to_id = ?", userID, userID).Delete(&models.FriendRequest{}).Error; err != nil {
+		return 0, err
+	}
+	result := s.db.Where("user_id = ? OR contact_id = ?", userID, userID).Delete(&models.Contact{})
+	return result.RowsAffected, result.Error
+}
@@ -0,0 +1,102 @@
+package stores
+
+import (
+	"errors"
+	"time"
+
+	"gorm.io/gorm"
+
+	"github.com/woxQAQ/gim/internal/models"
+)
+
+// DataRequestStore 处理用户数据导出与删除任务相关的数据库操作
+type DataRequestStore struct {
+	db *gorm.DB
+}
+
+// NewDataRequestStore 创建DataRequestStore实例
+func NewDataRequestStore(db *gorm.DB) *DataRequestStore {
+	return &DataRequestStore{db: db}
+}
+
+// CreateRequest 创建任务
+func (s *DataRequestStore) CreateRequest(req *models.DataRequest) error {
+	return s.db.Create(req).Error
+}
+
+// ErrDataRequestNotFound 任务不存在
+var ErrDataRequestNotFound = errors.New("任务不存在")
+
+// GetRequest 根据ID获取任务
+func (s *DataRequestStore) GetRequest(id string) (*models.DataRequest, error) {
+	var req models.DataRequest
+	result := s.db.First(&req, "id = ?", id)
+	if result.Error != nil {
+		if errors.Is(result.Error, gorm.ErrRecordNotFound) {
+			return nil, ErrDataRequestNotFound
+		}
+		return nil, result.Error
+	}
+	return &req, nil
+}
+
+// UpdateRequest 更新任务
+func (s *DataRequestStore) UpdateRequest(req *models.DataRequest) error {
+	return s.db.Save(req).Error
+}
+
+// ListRequestsByUser 获取用户的任务，按ID倒序
+func (s *DataRequestStore) ListRequestsByUser(userID string) ([]*models.DataRequest, error) {
+	var reqs []*models.DataRequest
+	err := s.db.Where("user_id = ?", userID).Order("id desc").Find(&reqs).Error
+	return reqs, err
+}
+
+// HasActiveRequest 检查用户是否有同类型的未完成任务
+func (s *DataRequestStore) HasActiveRequest(userID, kind string) (bool, error) {
+	var count int64
+	err := s.db.Model(&models.DataRequest{}).
+		Where("user_id = ? AND kind = ? AND status IN ?", userID, kind,
+			[]models.DataRequestStatus{models.DataRequestPending, models.DataRequestRunning}).
+		Count(&count).Error
+	return count > 0, err
+}
+
+// GetPendingRequests 获取等待执行的任务，按ID正序
+func (s *DataRequestStore) GetPendingRequests(limit int) ([]*models.DataRequest, error) {
+	var reqs []*models.DataRequest
+	err := s.db.Where("status = ?", models.DataRequestPending).
+		Order("id asc").
+		Limit(limit).
+		Find(&reqs).Error
+	return reqs, err
+}
+
+// ClaimRequest 将任务从 pending 改为 running，返回是否抢占成功
+func (s *DataRequestStore) ClaimRequest(id string) (bool, error) {
+	result := s.db.Model(&models.DataRequest{}).
+		Where("id = ? AND status = ?", id, models.DataRequestPending).
+		Updates(map[string]interface{}{
+			"status":     models.DataRequestRunning,
+			"started_at": time.Now(),
+		})
+	return result.RowsAffected > 0, result.Error
+}
+
+// ResetStaleRequests 将开始时间早于 before 仍在执行的任务重新放回队列，用于进程退出后恢复
+func (s *DataRequestStore) ResetStaleRequests(before time.Time) (int64, error) {
+	result := s.db.Model(&models.DataRequest{}).
+		Where("status = ? AND started_at < ?", models.DataRequestRunning, before).
+		Update("status", models.DataRequestPending)
+	return result.RowsAffected, result.Error
+}
+
+// GetExpiredExports 获取导出文件已过期的任务
+func (s *DataRequestStore) GetExpiredExports(now time.Time, limit int) ([]*models.DataRequest, error) {
+	var reqs []*models.DataRequest
+	err := s.db.Where("kind = ? AND status = ? AND expires_at <= ?",
+		models.DataRequestExport, models.DataRequestCompleted, now).
+		Limit(limit).
+		Find(&reqs).Error
+	return reqs, err
+}
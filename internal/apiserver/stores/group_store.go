@@ -0,0 +1,118 @@
+package stores
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"time"
+
+	"gorm.io/gorm"
+
+	"github.com/woxQAQ/gim/internal/models"
+	"github.com/woxQAQ/gim/pkg/cache"
+)
+
+// ErrGroupNotFound 群组不存在
+var ErrGroupNotFound = errors.New("群组不存在")
+
+// GroupStore 处理群组相关的数据库操作
+type GroupStore struct {
+	db *gorm.DB
+	storeCache
+}
+
+// NewGroupStore 创建GroupStore实例
+func NewGroupStore(db *gorm.DB) *GroupStore {
+	return &GroupStore{db: db}
+}
+
+// WithCache 为群成员列表启用缓存，成员变化时删除对应的缓存
+func (s *GroupStore) WithCache(c cache.Cache, ttl time.Duration) *GroupStore {
+	s.storeCache = storeCache{cache: c, ttl: ttl}
+	return s
+}
+
+// WithTx 返回绑定到事务的GroupStore
+func (s *GroupStore) WithTx(tx *Tx) *GroupStore {
+	return &GroupStore{db: tx.db, storeCache: s.storeCache.inTx(tx)}
+}
+
+// GetGroupByID 根据ID获取群组
+func (s *GroupStore) GetGroupByID(id string) (*models.Group, error) {
+	var group models.Group
+	result := s.db.First(&group, "id = ?", id)
+	if result.Error != nil {
+		if errors.Is(result.Error, gorm.ErrRecordNotFound) {
+			return nil, ErrGroupNotFound
+		}
+		return nil, result.Error
+	}
+	return &group, nil
+}
+
+// GetMemberIDs 获取群组所有成员的用户ID
+func (s *GroupStore) GetMemberIDs(groupID string) ([]string, error) {
+	if !s.enabled() {
+		return s.getMemberIDs(groupID)
+	}
+	return cache.GetOrLoad(context.Background(), s.cache, groupMembersCacheKey(groupID), s.ttl, func() ([]string, error) {
+		return s.getMemberIDs(groupID)
+	})
+}
+
+func (s *GroupStore) getMemberIDs(groupID string) ([]string, error) {
+	var ids []string
+	err := s.db.Model(&models.GroupMember{}).
+		Where("group_id = ?", groupID).
+		Order("joined_at asc").
+		Pluck("user_id", &ids).Error
+	return ids, err
+}
+
+// IsMember 检查用户是否是群组成员
+func (s *GroupStore) IsMember(groupID, userID string) (bool, error) {
+	if s.enabled() {
+		ids, err := s.GetMemberIDs(groupID)
+		if err != nil {
+			return false, err
+		}
+		return slices.Contains(ids, userID), nil
+	}
+	var count int64
+	err := s.db.Model(&models.GroupMember{}).
+		Where("group_id = ? AND user_id = ?", groupID, userID).
+		Count(&count).Error
+	return count > 0, err
+}
+
+// ListMemberships 获取用户加入的群组
+func (s *GroupStore) ListMemberships(userID string) ([]*models.GroupMember, error) {
+	var members []*models.GroupMember
+	err := s.db.Where("user_id = ?", userID).Order("joined_at asc").Find(&members).Error
+	return members, err
+}
+
+// ListGroupsByOwner 获取用户创建的群组
+func (s *GroupStore) ListGroupsByOwner(ownerID string) ([]*models.Group, error) {
+	var groups []*models.Group
+	err := s.db.Where("owner_id = ?", ownerID).Order("created_at asc").Find(&groups).Error
+	return groups, err
+}
+
+// RemoveMemberships 将用户移出所有群组，返回移出的群组数量
+func (s *GroupStore) RemoveMemberships(userID string) (int64, error) {
+	var groupIDs []string
+	if s.enabled() {
+		if err := s.db.Model(&models.GroupMember{}).Where("user_id = ?", userID).
+			Pluck("group_id", &groupIDs).Error; err != nil {
+			return 0, err
+		}
+	}
+	result := s.db.Delete(&models.GroupMember{}, "user_id = ?", userID)
+	keys := make([]string, 0, len(groupIDs))
+	for _, id := range groupIDs {
+		keys = append(keys, groupMembersCacheKey(id))
+	}
+	s.invalidate(keys...)
+	return result.RowsAffected, result.Error
+}
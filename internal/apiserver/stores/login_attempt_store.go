@@ -0,0 +1,60 @@
+package stores
+
+import (
+	"errors"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+
+	"github.com/woxQAQ/gim/internal/models"
+)
+
+// LoginAttemptStore 处理失败尝试计数相关的数据库操作
+type LoginAttemptStore struct {
+	db *gorm.DB
+}
+
+// NewLoginAttemptStore 创建LoginAttemptStore实例
+func NewLoginAttemptStore(db *gorm.DB) *LoginAttemptStore {
+	return &LoginAttemptStore{db: db}
+}
+
+// GetAttempts 获取多个键的计数，没有记录的键不出现在结果中
+func (s *LoginAttemptStore) GetAttempts(keys []string) (map[string]*models.LoginAttempt, error) {
+	var attempts []*models.LoginAttempt
+	if err := s.db.Where("id IN ?", keys).Find(&attempts).Error; err != nil {
+		return nil, err
+	}
+	out := make(map[string]*models.LoginAttempt, len(attempts))
+	for _, a := range attempts {
+		out[a.ID] = a
+	}
+	return out, nil
+}
+
+// UpdateAttempt 在事务中读取键的计数交给 fn 修改后保存，没有记录时 fn 收到新的空计数
+func (s *LoginAttemptStore) UpdateAttempt(key string, fn func(a *models.LoginAttempt)) (*models.LoginAttempt, error) {
+	attempt := &models.LoginAttempt{ID: key}
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		err := tx.Where("id = ?", key).Take(attempt).Error
+		if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+			return err
+		}
+		fn(attempt)
+		return tx.Clauses(clause.OnConflict{UpdateAll: true}).Create(attempt).Error
+	})
+	return attempt, err
+}
+
+// DeleteAttempt 清除键的计数
+func (s *LoginAttemptStore) DeleteAttempt(key string) error {
+	return s.db.Delete(&models.LoginAttempt{}, "id = ?", key).Error
+}
+
+// DeleteStaleAttempts 删除 before 之后没有失败且 now 时不在锁定期的计数，返回删除的数量
+func (s *LoginAttemptStore) DeleteStaleAttempts(before, now time.Time) (int64, error) {
+	result := s.db.Where("last_failure_at < ? AND (locked_until IS NULL OR locked_until < ?)", before, now).
+		Delete(&models.LoginAttempt{})
+	return result.RowsAffected, result.Error
+}
@@ -1,14 +1,63 @@
 package stores
 
 import (
+	"context"
+	"errors"
+	"time"
+
 	"github.com/woxQAQ/gim/internal/models"
+	"github.com/woxQAQ/gim/internal/types"
+	"github.com/woxQAQ/gim/pkg/cache"
 
 	"gorm.io/gorm"
 )
 
+// MessageQuery 消息查询条件
+//
+// 会话范围三选一：ConversationID；UserID+PeerID 表示两人之间的单聊；
+// 仅 UserID 表示该用户收发的全部消息。SenderID 在此基础上只保留该用户发送的消息。
+// Before 与 After 为消息ID游标，不能同时使用：
+// 未设置游标或使用 Before 时按ID倒序返回，使用 After 时按ID正序返回。
+type MessageQuery struct {
+	ConversationID string
+	UserID         string
+	PeerID         string
+	SenderID       string
+
+	Types []types.MessageType
+	Since *time.Time // 包含
+	Until *time.Time // 不包含
+
+	Before string
+	After  string
+	Limit  int
+}
+
+// ExpiredMessageFilter 过期消息的筛选条件，对应一条保留策略
+type ExpiredMessageFilter struct {
+	ConversationID string            // 为空时匹配所有会话
+	Type           types.MessageType // 为0时匹配所有类型
+	Before         time.Time         // 创建时间早于该时间的消息过期
+
+	// 由更具体的策略负责的消息，需要排除
+	ExcludeConversations []string
+	ExcludeTypes         []types.MessageType
+	ExcludePairs         []ConversationType
+}
+
+// ConversationType 会话与消息类型的组合
+type ConversationType struct {
+	ConversationID string
+	Type           types.MessageType
+}
+
+// ErrInvalidMessageQuery 查询条件不完整或互相冲突
+var ErrInvalidMessageQuery = errors.New("invalid message query")
+
 // MessageStore 处理消息相关的数据库操作
 type MessageStore struct {
 	db *gorm.DB
+	storeCache
 }
 
 // NewMessageStore 创建MessageStore实例
@@ -16,20 +65,239 @@ func NewMessageStore(db *gorm.DB) *MessageStore {
 	return &MessageStore{db: db}
 }
 
+// WithCache 为会话最新消息启用缓存，会话内消息变化时删除对应的缓存。
+// 网关与 apiserver 都会写入消息，多进程部署时需要共享同一个缓存才能及时失效。
+func (s *MessageStore) WithCache(c cache.Cache, ttl time.Duration) *MessageStore {
+	s.storeCache = storeCache{cache: c, ttl: ttl}
+	return s
+}
+
+// WithTx 返回绑定到事务的MessageStore
+func (s *MessageStore) WithTx(tx *Tx) *MessageStore {
+	return &MessageStore{db: tx.db, storeCache: s.storeCache.inTx(tx)}
+}
+
 // CreateMessage 创建新消息
 func (s *MessageStore) CreateMessage(message *models.Message) error {
-	return s.db.Create(message).Error
+	if err := s.db.Create(message).Error; err != nil {
+		return err
+	}
+	s.invalidate(recentMessagesCacheKey(message.ConversationID))
+	return nil
 }
 
-// GetMessagesByUserID 获取用户的消息历史记录
-func (s *MessageStore) GetMessagesByUserID(userID string, limit int, lastID string) ([]*models.Message, error) {
+// GetMessage 根据ID获取消息
+func (s *MessageStore) GetMessage(id string) (*models.Message, error) {
+	var message models.Message
+	if err := s.db.First(&message, "id = ?", id).Error; err != nil {
+		return nil, err
+	}
+	return &message, nil
+}
+
+// UpdateMessageContent 修改消息内容
+func (s *MessageStore) UpdateMessageContent(id, content string) error {
+	return s.invalidatingConversations([]string{id}, func() error {
+		return s.db.Model(&models.Message{}).Where("id = ?", id).Update("content", content).Error
+	})
+}
+
+// RecallMessage 撤回消息，清空内容并标记为已撤回
+func (s *MessageStore) RecallMessage(id string) error {
+	return s.invalidatingConversations([]string{id}, func() error {
+		return s.db.Model(&models.Message{}).Where("id = ?", id).Updates(map[string]interface{}{
+			"content": "",
+			"status":  models.MessageStatusRecalled,
+		}).Error
+	})
+}
+
+// ScrubMessages 在一个短事务中清空消息内容、删除附件记录，并将消息标记为已撤回
+func (s *MessageStore) ScrubMessages(ids []string) error {
+	if len(ids) == 0 {
+		return nil
+	}
+	return s.invalidatingConversations(ids, func() error {
+		return s.db.Transaction(func(tx *gorm.DB) error {
+			if err := tx.Where("message_id IN ?", ids).Delete(&models.MessageAttachment{}).Error; err != nil {
+				return err
+			}
+			return tx.Model(&models.Message{}).Where("id IN ?", ids).Updates(map[string]interface{}{
+				"content": "",
+				"status":  models.MessageStatusRecalled,
+			}).Error
+		})
+	})
+}
+
+// ListMessagesUpdatedSince 按更新时间正序返回 since 之后（包含）更新过的消息，用于同步索引
+func (s *MessageStore) ListMessagesUpdatedSince(since time.Time, limit int) ([]*models.Message, error) {
 	var messages []*models.Message
-	query := s.db.Where("sender_id = ? OR receiver_id = ?", userID, userID)
+	err := s.db.Where("updated_at >= ?", since).
+		Order("updated_at asc, id asc").
+		Limit(limit).
+		Find(&messages).Error
+	return messages, err
+}
 
-	if lastID != "" {
-		query = query.Where("id < ?", lastID)
+// ListMessages 按条件查询消息
+//
+// 启用缓存时，不带其他条件、从最新消息开始的单个会话查询读取该会话最新消息的缓存。
+func (s *MessageStore) ListMessages(q *MessageQuery) ([]*models.Message, error) {
+	if convID, ok := recentWindowQuery(q); ok && s.enabled() {
+		messages, err := cache.GetOrLoad(context.Background(), s.cache, recentMessagesCacheKey(convID), s.ttl,
+			func() ([]*models.Message, error) {
+				return s.listMessages(&MessageQuery{ConversationID: convID, Limit: recentWindow})
+			})
+		if err != nil {
+			return nil, err
+		}
+		if len(messages) > q.Limit {
+			messages = messages[:q.Limit]
+		}
+		return messages, nil
 	}
+	return s.listMessages(q)
+}
 
-	err := query.Order("created_at desc").Limit(limit).Find(&messages).Error
+// recentWindowQuery 判断查询能否由会话最新消息的缓存满足，返回会话ID
+func recentWindowQuery(q *MessageQuery) (string, bool) {
+	if q.SenderID != "" || len(q.Types) > 0 || q.Since != nil || q.Until != nil ||
+		q.Before != "" || q.After != "" || q.Limit <= 0 || q.Limit > recentWindow {
+		return "", false
+	}
+	switch {
+	case q.ConversationID != "":
+		return q.ConversationID, true
+	case q.UserID != "" && q.PeerID != "":
+		return models.DirectConversationID(q.UserID, q.PeerID), true
+	default:
+		return "", false
+	}
+}
+
+func (s *MessageStore) listMessages(q *MessageQuery) ([]*models.Message, error) {
+	if q.Before != "" && q.After != "" {
+		return nil, ErrInvalidMessageQuery
+	}
+
+	query := s.db.Model(&models.Message{})
+	switch {
+	case q.ConversationID != "":
+		query = query.Where("conversation_id = ?", q.ConversationID)
+	case q.UserID != "" && q.PeerID != "":
+		query = query.Where("conversation_id = ?", models.DirectConversationID(q.UserID, q.PeerID))
+	case q.UserID != "":
+		query = query.Where("from_id = ? OR to_id = ?", q.UserID, q.UserID)
+	default:
+		return nil, ErrInvalidMessageQuery
+	}
+
+	if q.SenderID != "" {
+		query = query.Where("from_id = ?", q.SenderID)
+	}
+	if len(q.Types) > 0 {
+		query = query.Where("type IN ?", q.Types)
+	}
+	if q.Since != nil {
+		query = query.Where("created_at >= ?", *q.Since)
+	}
+	if q.Until != nil {
+		query = query.Where("created_at < ?", *q.Until)
+	}
+
+	if q.After != "" {
+		query = query.Where("id > ?", q.After).Order("id asc")
+	} else {
+		if q.Before != "" {
+			query = query.Where("id < ?", q.Before)
+		}
+		query = query.Order("id desc")
+	}
+	if q.Limit > 0 {
+		query = query.Limit(q.Limit)
+	}
+
+	var messages []*models.Message
+	err := query.Find(&messages).Error
+	return messages, err
+}
+
+// GetMessagesByUserID 获取用户的消息历史记录，按ID倒序
+func (s *MessageStore) GetMessagesByUserID(userID string, limit int, lastID string) ([]*models.Message, error) {
+	return s.ListMessages(&MessageQuery{UserID: userID, Before: lastID, Limit: limit})
+}
+
+// FindExpiredMessages 按ID正序返回满足过期条件的消息
+func (s *MessageStore) FindExpiredMessages(f *ExpiredMessageFilter, limit int) ([]*models.Message, error) {
+	query := s.db.Where("created_at < ?", f.Before)
+	if f.ConversationID != "" {
+		query = query.Where("conversation_id = ?", f.ConversationID)
+	}
+	if f.Type != types.MessageTypeUnknown {
+		query = query.Where("type = ?", f.Type)
+	}
+	if len(f.ExcludeConversations) > 0 {
+		query = query.Where("conversation_id NOT IN ?", f.ExcludeConversations)
+	}
+	if len(f.ExcludeTypes) > 0 {
+		query = query.Where("type NOT IN ?", f.ExcludeTypes)
+	}
+	for _, pair := range f.ExcludePairs {
+		query = query.Where("NOT (conversation_id = ? AND type = ?)", pair.ConversationID, pair.Type)
+	}
+
+	var messages []*models.Message
+	err := query.Order("id asc").Limit(limit).Find(&messages).Error
 	return messages, err
 }
+
+// CreateAttachment 保存消息附件
+func (s *MessageStore) CreateAttachment(attachment *models.MessageAttachment) error {
+	return s.db.Create(attachment).Error
+}
+
+// GetAttachments 获取消息的附件
+func (s *MessageStore) GetAttachments(messageIDs []string) ([]*models.MessageAttachment, error) {
+	var attachments []*models.MessageAttachment
+	if len(messageIDs) == 0 {
+		return attachments, nil
+	}
+	err := s.db.Where("message_id IN ?", messageIDs).Find(&attachments).Error
+	return attachments, err
+}
+
+// DeleteMessages 在一个短事务中删除消息及其附件记录
+func (s *MessageStore) DeleteMessages(ids []string) error {
+	if len(ids) == 0 {
+		return nil
+	}
+	return s.invalidatingConversations(ids, func() error {
+		return s.db.Transaction(func(tx *gorm.DB) error {
+			if err := tx.Where("message_id IN ?", ids).Delete(&models.MessageAttachment{}).Error; err != nil {
+				return err
+			}
+			return tx.Where("id IN ?", ids).Delete(&models.Message{}).Error
+		})
+	})
+}
+
+// invalidatingConversations 执行修改消息的操作，并删除这些消息所在会话的缓存。
+// 会话ID需要在修改前查出，删除消息后就无法再查到。
+func (s *MessageStore) invalidatingConversations(ids []string, write func() error) error {
+	if !s.enabled() {
+		return write()
+	}
+	var convIDs []string
+	if err := s.db.Model(&models.Message{}).Where("id IN ?", ids).
+		Distinct().Pluck("conversation_id", &convIDs).Error; err != nil {
+		return err
+	}
+	err := write()
+	keys := make([]string, 0, len(convIDs))
+	for _, id := range convIDs {
+		keys = append(keys, recentMessagesCacheKey(id))
+	}
+	s.invalidate(keys...)
+	return err
+}
@@ -0,0 +1,135 @@
+package stores_test
+
+import (
+	"fmt"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"github.com/woxQAQ/gim/internal/apiserver/stores"
+	"github.com/woxQAQ/gim/internal/models"
+	"github.com/woxQAQ/gim/internal/types"
+)
+
+var _ = Describe("MessageStore", func() {
+	for _, b := range backends {
+		Context(b.driver, func() {
+			var (
+				store *stores.MessageStore
+				base  time.Time
+			)
+
+			// ids 返回消息ID列表，便于断言顺序
+			ids := func(messages []*models.Message) []string {
+				out := make([]string, 0, len(messages))
+				for _, m := range messages {
+					out = append(out, m.ID)
+				}
+				return out
+			}
+
+			BeforeEach(func() {
+				store = stores.NewMessageStore(openBackend(b))
+				base = time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+
+				// 1001-1006 为 alice 与 bob 的单聊，1007-1008 为 alice 与 carol 的单聊
+				seed := []struct {
+					from, to string
+					typ      types.MessageType
+				}{
+					{"alice", "bob", types.MessageTypeText},
+					{"bob", "alice", types.MessageTypeText},
+					{"alice", "bob", types.MessageTypeImage},
+					{"bob", "alice", types.MessageTypeText},
+					{"alice", "bob", types.MessageTypeFile},
+					{"bob", "alice", types.MessageTypeText},
+					{"carol", "alice", types.MessageTypeText},
+					{"alice", "carol", types.MessageTypeText},
+				}
+				for i, s := range seed {
+					msg := &types.Message{
+						Header: types.MessageHeader{
+							ID:        fmt.Sprintf("%d", 1001+i),
+							Type:      s.typ,
+							From:      s.from,
+							To:        s.to,
+							Platform:  1,
+							Timestamp: base.Add(time.Duration(i) * time.Minute),
+						},
+						Payload: []byte("hi"),
+					}
+					m := &models.Message{}
+					m.FromTypes(msg)
+					Expect(store.CreateMessage(m)).To(Succeed())
+				}
+			})
+
+			It("会话ID应该与收发方向无关", func() {
+				Expect(models.DirectConversationID("alice", "bob")).To(Equal(models.DirectConversationID("bob", "alice")))
+
+				m, err := store.GetMessage("1002")
+				Expect(err).NotTo(HaveOccurred())
+				Expect(m.ConversationID).To(Equal(models.DirectConversationID("alice", "bob")))
+				Expect(m.Type).To(Equal(types.MessageTypeText))
+			})
+
+			It("应该返回两个用户之间的历史消息并按ID倒序翻页", func() {
+				page, err := store.ListMessages(&stores.MessageQuery{UserID: "bob", PeerID: "alice", Limit: 4})
+				Expect(err).NotTo(HaveOccurred())
+				Expect(ids(page)).To(Equal([]string{"1006", "1005", "1004", "1003"}))
+
+				page, err = store.ListMessages(&stores.MessageQuery{UserID: "alice", PeerID: "bob", Before: "1003", Limit: 4})
+				Expect(err).NotTo(HaveOccurred())
+				Expect(ids(page)).To(Equal([]string{"1002", "1001"}))
+			})
+
+			It("应该返回会话内游标之后的消息并按ID正序", func() {
+				page, err := store.ListMessages(&stores.MessageQuery{
+					ConversationID: models.DirectConversationID("alice", "bob"),
+					After:          "1004",
+				})
+				Expect(err).NotTo(HaveOccurred())
+				Expect(ids(page)).To(Equal([]string{"1005", "1006"}))
+			})
+
+			It("应该返回用户收发的全部消息", func() {
+				page, err := store.ListMessages(&stores.MessageQuery{UserID: "carol"})
+				Expect(err).NotTo(HaveOccurred())
+				Expect(ids(page)).To(Equal([]string{"1008", "1007"}))
+
+				page, err = store.GetMessagesByUserID("alice", 3, "")
+				Expect(err).NotTo(HaveOccurred())
+				Expect(ids(page)).To(Equal([]string{"1008", "1007", "1006"}))
+			})
+
+			It("应该按消息类型和时间范围过滤", func() {
+				page, err := store.ListMessages(&stores.MessageQuery{
+					UserID: "alice",
+					PeerID: "bob",
+					Types:  []types.MessageType{types.MessageTypeImage, types.MessageTypeFile},
+				})
+				Expect(err).NotTo(HaveOccurred())
+				Expect(ids(page)).To(Equal([]string{"1005", "1003"}))
+
+				since, until := base.Add(time.Minute), base.Add(3*time.Minute)
+				page, err = store.ListMessages(&stores.MessageQuery{
+					UserID: "alice",
+					PeerID: "bob",
+					Since:  &since,
+					Until:  &until,
+				})
+				Expect(err).NotTo(HaveOccurred())
+				Expect(ids(page)).To(Equal([]string{"1003", "1002"}))
+			})
+
+			It("应该拒绝不完整或冲突的查询条件", func() {
+				_, err := store.ListMessages(&stores.MessageQuery{})
+				Expect(err).To(MatchError(stores.ErrInvalidMessageQuery))
+
+				_, err = store.ListMessages(&stores.MessageQuery{UserID: "alice", Before: "1", After: "2"})
+				Expect(err).To(MatchError(stores.ErrInvalidMessageQuery))
+			})
+		})
+	}
+})
@@ -0,0 +1,86 @@
+package stores
+
+import (
+	"errors"
+	"time"
+
+	"gorm.io/gorm"
+
+	"github.com/woxQAQ/gim/internal/models"
+)
+
+// ModerationStore 处理审核记录相关的数据库操作
+type ModerationStore struct {
+	db *gorm.DB
+}
+
+// NewModerationStore 创建ModerationStore实例
+func NewModerationStore(db *gorm.DB) *ModerationStore {
+	return &ModerationStore{db: db}
+}
+
+// CreateRecord 创建审核记录
+func (s *ModerationStore) CreateRecord(record *models.ModerationRecord) error {
+	return s.db.Create(record).Error
+}
+
+// ListRecords 按状态查询审核记录，按ID倒序
+func (s *ModerationStore) ListRecords(status *models.ModerationStatus, limit int, lastID string) ([]*models.ModerationRecord, error) {
+	var records []*models.ModerationRecord
+	query := s.db.Model(&models.ModerationRecord{})
+	if status != nil {
+		query = query.Where("status = ?", *status)
+	}
+	if lastID != "" {
+		query = query.Where("id < ?", lastID)
+	}
+	err := query.Order("id desc").Limit(limit).Find(&records).Error
+	return records, err
+}
+
+// Review 更新审核结果，审核删除时同时删除原消息
+func (s *ModerationStore) Review(id string, status models.ModerationStatus, reviewerID string) (*models.ModerationRecord, error) {
+	var record models.ModerationRecord
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.First(&record, "id = ?", id).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return errors.New("审核记录不存在")
+			}
+			return err
+		}
+		now := time.Now()
+		record.Status = status
+		record.ReviewerID = reviewerID
+		record.ReviewedAt = &now
+		if err := tx.Save(&record).Error; err != nil {
+			return err
+		}
+		if status == models.ModerationRemoved {
+			return tx.Delete(&models.Message{}, "id = ?", record.MessageID).Error
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &record, nil
+}
+
+// ListRecordsByUser 获取用户发送的消息的审核记录，按ID正序
+func (s *ModerationStore) ListRecordsByUser(userID string) ([]*models.ModerationRecord, error) {
+	var records []*models.ModerationRecord
+	err := s.db.Where("from_id = ?", userID).Order("id asc").Find(&records).Error
+	return records, err
+}
+
+// ScrubRecordsByUser 清空用户消息审核记录中的原始内容，返回处理的记录数量
+func (s *ModerationStore) ScrubRecordsByUser(userID string) (int64, error) {
+	result := s.db.Model(&models.ModerationRecord{}).Where("from_id = ?", userID).Update("content", "")
+	return result.RowsAffected, result.Error
+}
+
+// DeleteRecordsByUser 删除用户消息的审核记录，返回删除的记录数量
+func (s *ModerationStore) DeleteRecordsByUser(userID string) (int64, error) {
+	result := s.db.Delete(&models.ModerationRecord{}, "from_id = ?", userID)
+	return result.RowsAffected, result.Error
+}
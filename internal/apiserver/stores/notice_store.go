@@ -0,0 +1,108 @@
+package stores
+
+import (
+	"errors"
+	"time"
+
+	"gorm.io/gorm"
+
+	"github.com/woxQAQ/gim/internal/models"
+)
+
+// NoticeStore 处理系统通知相关的数据库操作
+type NoticeStore struct {
+	db *gorm.DB
+}
+
+// NewNoticeStore 创建NoticeStore实例
+func NewNoticeStore(db *gorm.DB) *NoticeStore {
+	return &NoticeStore{db: db}
+}
+
+// CreateNotice 创建系统通知
+func (s *NoticeStore) CreateNotice(notice *models.SystemNotice) error {
+	return s.db.Create(notice).Error
+}
+
+// GetNotice 根据ID获取系统通知
+func (s *NoticeStore) GetNotice(id string) (*models.SystemNotice, error) {
+	var notice models.SystemNotice
+	result := s.db.First(&notice, "id = ?", id)
+	if result.Error != nil {
+		if errors.Is(result.Error, gorm.ErrRecordNotFound) {
+			return nil, errors.New("通知不存在")
+		}
+		return nil, result.Error
+	}
+	return &notice, nil
+}
+
+// UpdateNotice 更新系统通知
+func (s *NoticeStore) UpdateNotice(notice *models.SystemNotice) error {
+	return s.db.Save(notice).Error
+}
+
+// GetDueNotices 获取已到发送时间的定时通知
+func (s *NoticeStore) GetDueNotices(now time.Time, limit int) ([]*models.SystemNotice, error) {
+	var notices []*models.SystemNotice
+	err := s.db.Where("status = ? AND deliver_at <= ?", models.NoticeScheduled, now).
+		Order("deliver_at asc").
+		Limit(limit).
+		Find(&notices).Error
+	return notices, err
+}
+
+// ClaimNotice 将定时通知从 scheduled 改为指定状态，返回是否抢占成功
+func (s *NoticeStore) ClaimNotice(id string, status models.NoticeStatus) (bool, error) {
+	result := s.db.Model(&models.SystemNotice{}).
+		Where("id = ? AND status = ?", id, models.NoticeScheduled).
+		Update("status", status)
+	return result.RowsAffected > 0, result.Error
+}
+
+// AddRecipients 批量保存通知接收人
+func (s *NoticeStore) AddRecipients(noticeID string, userIDs []string) error {
+	if len(userIDs) == 0 {
+		return nil
+	}
+	recipients := make([]*models.SystemNoticeRecipient, 0, len(userIDs))
+	for _, id := range userIDs {
+		recipients = append(recipients, &models.SystemNoticeRecipient{NoticeID: noticeID, UserID: id})
+	}
+	return s.db.CreateInBatches(recipients, 500).Error
+}
+
+// ListNotices 按状态查询系统通知，按ID倒序
+func (s *NoticeStore) ListNotices(status *models.NoticeStatus, limit int, lastID string) ([]*models.SystemNotice, error) {
+	var notices []*models.SystemNotice
+	query := s.db.Model(&models.SystemNotice{})
+	if status != nil {
+		query = query.Where("status = ?", *status)
+	}
+	if lastID != "" {
+		query = query.Where("id < ?", lastID)
+	}
+	err := query.Order("id desc").Limit(limit).Find(&notices).Error
+	return notices, err
+}
+
+// ListNoticesForUser 获取用户可见的持久化通知，按ID倒序
+func (s *NoticeStore) ListNoticesForUser(userID string, limit int, lastID string) ([]*models.SystemNotice, error) {
+	var notices []*models.SystemNotice
+	recipients := s.db.Model(&models.SystemNoticeRecipient{}).Select("notice_id").Where("user_id = ?", userID)
+	query := s.db.Model(&models.SystemNotice{}).
+		Where("persist = ? AND status IN ?", true, []models.NoticeStatus{models.NoticeSent, models.NoticeFailed}).
+		Where(s.db.Where("target_type IN ?", []string{models.NoticeTargetAll, models.NoticeTargetPlatform}).
+			Or("id IN (?)", recipients))
+	if lastID != "" {
+		query = query.Where("id < ?", lastID)
+	}
+	err := query.Order("id desc").Limit(limit).Find(&notices).Error
+	return notices, err
+}
+
+// RemoveRecipient 删除用户的离线通知记录
+func (s *NoticeStore) RemoveRecipient(userID string) (int64, error) {
+	result := s.db.Delete(&models.SystemNoticeRecipient{}, "user_id = ?", userID)
+	return result.RowsAffected, result.Error
+}
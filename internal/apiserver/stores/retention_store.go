@@ -0,0 +1,68 @@
+package stores
+
+import (
+	"errors"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+
+	"github.com/woxQAQ/gim/internal/models"
+)
+
+// RetentionStore 处理消息保留策略相关的数据库操作
+type RetentionStore struct {
+	db *gorm.DB
+}
+
+// NewRetentionStore 创建RetentionStore实例
+func NewRetentionStore(db *gorm.DB) *RetentionStore {
+	return &RetentionStore{db: db}
+}
+
+// UpsertPolicy 创建保留策略，相同作用范围的策略已存在时覆盖，policy 会回填为数据库中的记录
+func (s *RetentionStore) UpsertPolicy(policy *models.RetentionPolicy) error {
+	err := s.db.Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "conversation_id"}, {Name: "message_type"}},
+		DoUpdates: clause.AssignmentColumns([]string{"ttl_seconds", "action", "created_by", "updated_at"}),
+	}).Create(policy).Error
+	if err != nil {
+		return err
+	}
+	return s.db.Where("conversation_id = ? AND message_type = ?", policy.ConversationID, policy.MessageType).
+		First(policy).Error
+}
+
+// ListPolicies 获取全部保留策略
+func (s *RetentionStore) ListPolicies() ([]*models.RetentionPolicy, error) {
+	var policies []*models.RetentionPolicy
+	err := s.db.Order("conversation_id asc, message_type asc").Find(&policies).Error
+	return policies, err
+}
+
+// DeletePolicy 删除保留策略
+func (s *RetentionStore) DeletePolicy(id string) error {
+	result := s.db.Delete(&models.RetentionPolicy{}, "id = ?", id)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return errors.New("保留策略不存在")
+	}
+	return nil
+}
+
+// CreateRun 保存一次清理的执行记录
+func (s *RetentionStore) CreateRun(run *models.RetentionRun) error {
+	return s.db.Create(run).Error
+}
+
+// ListRuns 获取清理执行记录，按ID倒序
+func (s *RetentionStore) ListRuns(limit int, lastID string) ([]*models.RetentionRun, error) {
+	var runs []*models.RetentionRun
+	query := s.db.Model(&models.RetentionRun{})
+	if lastID != "" {
+		query = query.Where("id < ?", lastID)
+	}
+	err := query.Order("id desc").Limit(limit).Find(&runs).Error
+	return runs, err
+}
@@ -0,0 +1,110 @@
+package stores
+
+import (
+	"errors"
+	"time"
+
+	"gorm.io/gorm"
+
+	"github.com/woxQAQ/gim/internal/models"
+)
+
+// ErrSessionNotFound 会话不存在
+var ErrSessionNotFound = errors.New("会话不存在")
+
+// SessionStore 处理登录会话相关的数据库操作
+type SessionStore struct {
+	db *gorm.DB
+}
+
+// NewSessionStore 创建SessionStore实例
+func NewSessionStore(db *gorm.DB) *SessionStore {
+	return &SessionStore{db: db}
+}
+
+// CreateSession 创建会话
+func (s *SessionStore) CreateSession(session *models.Session) error {
+	return s.db.Create(session).Error
+}
+
+// GetSession 根据ID获取会话
+func (s *SessionStore) GetSession(id string) (*models.Session, error) {
+	return s.first("id = ?", id)
+}
+
+// GetByRefreshHash 根据当前刷新令牌的摘要获取会话
+func (s *SessionStore) GetByRefreshHash(hash string) (*models.Session, error) {
+	return s.first("refresh_hash = ?", hash)
+}
+
+// GetByPrevRefreshHash 根据上一个刷新令牌的摘要获取会话，用于发现令牌被重复使用
+func (s *SessionStore) GetByPrevRefreshHash(hash string) (*models.Session, error) {
+	return s.first("prev_refresh_hash = ? AND prev_refresh_hash <> ''", hash)
+}
+
+func (s *SessionStore) first(query string, args ...interface{}) (*models.Session, error) {
+	var session models.Session
+	if err := s.db.Where(query, args...).First(&session).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrSessionNotFound
+		}
+		return nil, err
+	}
+	return &session, nil
+}
+
+// RotateRefresh 将会话的刷新令牌从 oldHash 轮换为 newHash。
+// 只有当前令牌仍为 oldHash 且会话未吊销时才会更新，并发刷新同一个令牌时只有一个成功
+func (s *SessionStore) RotateRefresh(id, oldHash, newHash string, expiresAt time.Time) (bool, error) {
+	result := s.db.Model(&models.Session{}).
+		Where("id = ? AND refresh_hash = ? AND revoked_at IS NULL", id, oldHash).
+		Updates(map[string]interface{}{
+			"refresh_hash":      newHash,
+			"prev_refresh_hash": oldHash,
+			"expires_at":        expiresAt,
+		})
+	return result.RowsAffected == 1, result.Error
+}
+
+// RevokeSession 吊销会话
+func (s *SessionStore) RevokeSession(id string) error {
+	return s.db.Model(&models.Session{}).
+		Where("id = ? AND revoked_at IS NULL", id).
+		Update("revoked_at", time.Now()).Error
+}
+
+// RevokeUserSessions 吊销用户的全部会话，返回吊销的会话数量
+func (s *SessionStore) RevokeUserSessions(userID string) (int64, error) {
+	result := s.db.Model(&models.Session{}).
+		Where("user_id = ? AND revoked_at IS NULL", userID).
+		Update("revoked_at", time.Now())
+	return result.RowsAffected, result.Error
+}
+
+// ListActiveSessions 获取用户未吊销且未过期的会话
+func (s *SessionStore) ListActiveSessions(userID string) ([]*models.Session, error) {
+	var sessions []*models.Session
+	err := s.db.Where("user_id = ? AND revoked_at IS NULL AND expires_at > ?", userID, time.Now()).
+		Order("created_at asc").
+		Find(&sessions).Error
+	return sessions, err
+}
+
+// IsRevoked 检查会话是否已吊销，会话不存在时视为已吊销
+func (s *SessionStore) IsRevoked(id string) (bool, error) {
+	var session models.Session
+	err := s.db.Select("revoked_at").Where("id = ?", id).Take(&session).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return true, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	return session.RevokedAt != nil, nil
+}
+
+// DeleteInactiveSessions 删除在 before 之前过期或吊销的会话，返回删除的数量
+func (s *SessionStore) DeleteInactiveSessions(before time.Time) (int64, error) {
+	result := s.db.Where("expires_at < ? OR revoked_at < ?", before, before).Delete(&models.Session{})
+	return result.RowsAffected, result.Error
+}
@@ -0,0 +1,80 @@
+package stores_test
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"gorm.io/gorm"
+
+	"github.com/woxQAQ/gim/internal/migrations"
+	"github.com/woxQAQ/gim/pkg/db"
+)
+
+func TestStores(t *testing.T) {
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "Stores Suite")
+}
+
+// backend 描述测试矩阵中的一个数据库后端
+type backend struct {
+	driver string
+	// dsnEnv 为空表示使用临时目录下的 SQLite 文件
+	dsnEnv string
+}
+
+// backends 测试矩阵，PostgreSQL 与 MySQL 需要通过环境变量提供本地实例.
+//
+// 每次运行都会清空数据库，库名必须包含 test，例如 gim_test。
+// CI 通过 make test-stores 启动一次性的 PostgreSQL 与 MySQL 容器并设置环境变量，
+// 本地也可以用同样的方式运行完整矩阵。
+var backends = []backend{
+	{driver: db.DriverSQLite},
+	{driver: db.DriverPostgres, dsnEnv: "GIM_TEST_POSTGRES_DSN"},
+	{driver: db.DriverMySQL, dsnEnv: "GIM_TEST_MYSQL_DSN"},
+}
+
+// openBackend 打开并迁移一个干净的测试数据库，实例不可用时跳过当前用例
+func openBackend(b backend) *gorm.DB {
+	dsn := filepath.Join(GinkgoT().TempDir(), "stores.db")
+	if b.dsnEnv != "" {
+		dsn = os.Getenv(b.dsnEnv)
+		if dsn == "" {
+			Skip(b.dsnEnv + " 未设置，跳过 " + b.driver)
+		}
+	}
+
+	gdb, err := db.Open(&db.Config{Driver: b.driver, DSN: dsn})
+	Expect(err).NotTo(HaveOccurred())
+	DeferCleanup(func() {
+		sqlDB, err := gdb.DB()
+		Expect(err).NotTo(HaveOccurred())
+		Expect(sqlDB.Close()).To(Succeed())
+	})
+
+	// 外部实例在多次运行间共享，迁移前先回滚到空库，只允许对测试库这样做
+	if b.dsnEnv != "" {
+		Expect(currentDatabase(gdb, b.driver)).To(ContainSubstring("test"),
+			b.dsnEnv+" 必须指向库名包含 test 的测试库，测试会清空其中的数据")
+	}
+	m, err := db.NewMigrator(gdb, migrations.All())
+	Expect(err).NotTo(HaveOccurred())
+	_, err = m.Down(len(migrations.All()))
+	Expect(err).NotTo(HaveOccurred())
+	_, err = m.Up()
+	Expect(err).NotTo(HaveOccurred())
+	return gdb
+}
+
+// currentDatabase 返回连接当前使用的库名
+func currentDatabase(gdb *gorm.DB, driver string) string {
+	query := "SELECT current_database()"
+	if driver == db.DriverMySQL {
+		query = "SELECT DATABASE()"
+	}
+	var name string
+	Expect(gdb.Raw(query).Scan(&name).Error).To(Succeed())
+	return name
+}
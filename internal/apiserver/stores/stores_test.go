@@ -0,0 +1,91 @@
+package stores_test
+
+import (
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"gorm.io/gorm"
+
+	"github.com/woxQAQ/gim/internal/apiserver/stores"
+	"github.com/woxQAQ/gim/internal/models"
+)
+
+var _ = Describe("Stores", func() {
+	for _, b := range backends {
+		Context(b.driver, func() {
+			var gdb *gorm.DB
+
+			BeforeEach(func() {
+				gdb = openBackend(b)
+			})
+
+			It("应该能创建并查询用户", func() {
+				store := stores.NewUserStore(gdb)
+				Expect(store.CreateUser(&models.User{
+					ID:       "u1",
+					Username: "alice",
+					Password: "secret",
+					Email:    "alice@example.com",
+				})).To(Succeed())
+
+				user, err := store.GetUserByUsername("alice")
+				Expect(err).NotTo(HaveOccurred())
+				Expect(user.ID).To(Equal("u1"))
+				Expect(user.ValidatePassword("secret")).To(BeTrue())
+
+				exists, err := store.CheckEmailExists("alice@example.com")
+				Expect(err).NotTo(HaveOccurred())
+				Expect(exists).To(BeTrue())
+
+				_, err = store.GetUserByID("missing")
+				Expect(err).To(HaveOccurred())
+			})
+
+			It("应该按时间取出待投递的 webhook 并转入死信", func() {
+				store := stores.NewWebhookStore(gdb)
+				Expect(store.CreateEndpoint(&models.WebhookEndpoint{
+					ID: "ep1", URL: "http://example.com", Secret: "s", Events: "*", Enabled: true,
+				})).To(Succeed())
+
+				now := time.Now()
+				Expect(store.CreateDeliveries([]*models.WebhookDelivery{
+					{ID: "d1", EndpointID: "ep1", Event: "message.created", Payload: "{}", NextAttemptAt: now.Add(-time.Minute)},
+					{ID: "d2", EndpointID: "ep1", Event: "message.created", Payload: "{}", NextAttemptAt: now.Add(time.Hour)},
+				})).To(Succeed())
+
+				due, err := store.GetDueDeliveries(now, 10)
+				Expect(err).NotTo(HaveOccurred())
+				Expect(due).To(HaveLen(1))
+				Expect(due[0].ID).To(Equal("d1"))
+
+				Expect(store.MoveToDeadLetter(due[0])).To(Succeed())
+				letters, err := store.ListDeadLetters("ep1", 10)
+				Expect(err).NotTo(HaveOccurred())
+				Expect(letters).To(HaveLen(1))
+			})
+
+			It("应该只返回用户可见的持久化通知", func() {
+				store := stores.NewNoticeStore(gdb)
+				for _, n := range []*models.SystemNotice{
+					{ID: "n1", Title: "all", Content: "c", TargetType: models.NoticeTargetAll, Persist: true, Status: models.NoticeSent},
+					{ID: "n2", Title: "users", Content: "c", TargetType: models.NoticeTargetUsers, Persist: true, Status: models.NoticeSent},
+					{ID: "n3", Title: "other", Content: "c", TargetType: models.NoticeTargetUsers, Persist: true, Status: models.NoticeSent},
+					{ID: "n4", Title: "transient", Content: "c", TargetType: models.NoticeTargetAll, Status: models.NoticeSent},
+				} {
+					Expect(store.CreateNotice(n)).To(Succeed())
+				}
+				Expect(store.AddRecipients("n2", []string{"u1"})).To(Succeed())
+				Expect(store.AddRecipients("n3", []string{"u2"})).To(Succeed())
+
+				notices, err := store.ListNoticesForUser("u1", 10, "")
+				Expect(err).NotTo(HaveOccurred())
+				ids := make([]string, 0, len(notices))
+				for _, n := range notices {
+					ids = append(ids, n.ID)
+				}
+				Expect(ids).To(Equal([]string{"n2", "n1"}))
+			})
+		})
+	}
+})
@@ -0,0 +1,85 @@
+package stores
+
+import (
+	"context"
+	"errors"
+	"strings"
+
+	"gorm.io/gorm"
+)
+
+// ErrConflict 数据违反唯一约束，ConflictError 可以用 errors.Is 与之比较
+var ErrConflict = errors.New("conflict")
+
+// ConflictError 写入的数据与已有数据在唯一字段上冲突
+type ConflictError struct {
+	Table string
+	Field string
+}
+
+func (e *ConflictError) Error() string {
+	return e.Table + "." + e.Field + " conflict"
+}
+
+// Is 使 errors.Is(err, ErrConflict) 成立
+func (e *ConflictError) Is(target error) bool {
+	return target == ErrConflict
+}
+
+// conflictError 将违反唯一约束的数据库错误转换为 ConflictError，其他错误原样返回。
+// SQLite 的错误信息包含 表名.列名，PostgreSQL 与 MySQL 包含索引名 idx_表名_列名，
+// 据此判断是 fields 中的哪一个字段冲突。
+func conflictError(db *gorm.DB, err error, table string, fields ...string) error {
+	if err == nil {
+		return nil
+	}
+	translator, ok := db.Dialector.(gorm.ErrorTranslator)
+	if !ok || !errors.Is(translator.Translate(err), gorm.ErrDuplicatedKey) {
+		return err
+	}
+	msg := err.Error()
+	for _, field := range fields {
+		if strings.Contains(msg, table+"."+field) || strings.Contains(msg, "idx_"+table+"_"+field) {
+			return &ConflictError{Table: table, Field: field}
+		}
+	}
+	return &ConflictError{Table: table}
+}
+
+// UnitOfWork 在一个数据库事务中执行多个 store 的操作
+type UnitOfWork struct {
+	db *gorm.DB
+}
+
+// NewUnitOfWork 创建UnitOfWork实例
+func NewUnitOfWork(db *gorm.DB) *UnitOfWork {
+	return &UnitOfWork{db: db}
+}
+
+// Tx 一次事务，通过各个 store 的 WithTx 获得绑定到该事务的 store
+type Tx struct {
+	db          *gorm.DB
+	afterCommit []func()
+}
+
+// AfterCommit 注册事务提交后执行的操作，事务回滚时不执行
+func (tx *Tx) AfterCommit(fn func()) {
+	tx.afterCommit = append(tx.afterCommit, fn)
+}
+
+// Do 在事务中执行 fn，fn 返回错误或 panic 时回滚。
+// 事务内修改数据产生的缓存失效推迟到提交之后，避免其他请求在提交前重新读入旧数据。
+func (u *UnitOfWork) Do(ctx context.Context, fn func(tx *Tx) error) error {
+	t := &Tx{}
+	err := u.db.WithContext(ctx).Transaction(func(db *gorm.DB) error {
+		t.db = db
+		return fn(t)
+	})
+	if err != nil {
+		return err
+	}
+	for _, f := range t.afterCommit {
+		f()
+	}
+	return nil
+}
@@ -0,0 +1,82 @@
+package stores_test
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"github.com/woxQAQ/gim/internal/apiserver/stores"
+	"github.com/woxQAQ/gim/internal/models"
+	"github.com/woxQAQ/gim/pkg/cache"
+)
+
+var _ = Describe("UnitOfWork", func() {
+	for _, b := range backends {
+		Context(b.driver, func() {
+			var (
+				uow    *stores.UnitOfWork
+				ustore *stores.UserStore
+				c      *cache.LRU
+			)
+
+			BeforeEach(func() {
+				gdb := openBackend(b)
+				c = cache.NewLRU(100, time.Minute)
+				uow = stores.NewUnitOfWork(gdb)
+				ustore = stores.NewUserStore(gdb).WithCache(c, 0)
+			})
+
+			It("出错时应该回滚事务内的全部写入", func() {
+				err := uow.Do(context.Background(), func(tx *stores.Tx) error {
+					users := ustore.WithTx(tx)
+					Expect(users.CreateUser(&models.User{ID: "u1", Username: "alice", Password: "x"})).To(Succeed())
+					Expect(users.CreateUser(&models.User{ID: "u2", Username: "bob", Password: "x"})).To(Succeed())
+					return errors.New("abort")
+				})
+				Expect(err).To(MatchError("abort"))
+
+				exists, err := ustore.CheckUsernameExists("alice")
+				Expect(err).NotTo(HaveOccurred())
+				Expect(exists).To(BeFalse())
+			})
+
+			It("应该在提交后才删除缓存", func() {
+				Expect(ustore.CreateUser(&models.User{ID: "u1", Username: "alice", Password: "x"})).To(Succeed())
+				_, err := ustore.GetUserByID("u1")
+				Expect(err).NotTo(HaveOccurred())
+
+				err = uow.Do(context.Background(), func(tx *stores.Tx) error {
+					Expect(ustore.WithTx(tx).AnonymizeUser("u1")).To(Succeed())
+					_, ok, _ := c.Get(context.Background(), "user:u1")
+					Expect(ok).To(BeTrue())
+					return nil
+				})
+				Expect(err).NotTo(HaveOccurred())
+
+				u, err := ustore.GetUserByID("u1")
+				Expect(err).NotTo(HaveOccurred())
+				Expect(u.Username).To(Equal("deleted-u1"))
+			})
+
+			It("用户名或邮箱重复时应该返回冲突字段", func() {
+				Expect(ustore.CreateUser(&models.User{ID: "u1", Username: "alice", Password: "x", Email: "a@example.com"})).To(Succeed())
+				// 空邮箱不参与唯一约束
+				Expect(ustore.CreateUser(&models.User{ID: "u2", Username: "bob", Password: "x"})).To(Succeed())
+				Expect(ustore.CreateUser(&models.User{ID: "u3", Username: "carol", Password: "x"})).To(Succeed())
+
+				var conflict *stores.ConflictError
+				err := ustore.CreateUser(&models.User{ID: "u4", Username: "alice", Password: "x"})
+				Expect(err).To(MatchError(stores.ErrConflict))
+				Expect(errors.As(err, &conflict)).To(BeTrue())
+				Expect(conflict.Field).To(Equal("username"))
+
+				err = ustore.CreateUser(&models.User{ID: "u5", Username: "dave", Password: "x", Email: "a@example.com"})
+				Expect(errors.As(err, &conflict)).To(BeTrue())
+				Expect(conflict.Field).To(Equal("email"))
+			})
+		})
+	}
+})
@@ -1,16 +1,24 @@
 package stores
 
 import (
+	"context"
 	"errors"
+	"time"
 
 	"github.com/woxQAQ/gim/internal/models"
+	"github.com/woxQAQ/gim/pkg/auth"
+	"github.com/woxQAQ/gim/pkg/cache"
 
 	"gorm.io/gorm"
 )
 
+// ErrUserNotFound 用户不存在
+var ErrUserNotFound = errors.New("用户不存在")
+
 // UserStore 处理用户相关的数据库操作
 type UserStore struct {
 	db *gorm.DB
+	storeCache
 }
 
 // NewUserStore 创建UserStore实例
@@ -20,13 +28,33 @@ func NewUserStore(db *gorm.DB) *UserStore {
 	}
 }
 
+// WithCache 为按ID读取用户启用缓存，修改用户时删除对应的缓存
+func (s *UserStore) WithCache(c cache.Cache, ttl time.Duration) *UserStore {
+	s.storeCache = storeCache{cache: c, ttl: ttl}
+	return s
+}
+
+// WithTx 返回绑定到事务的UserStore
+func (s *UserStore) WithTx(tx *Tx) *UserStore {
+	return &UserStore{db: tx.db, storeCache: s.storeCache.inTx(tx)}
+}
+
 // GetUserByID 根据用户ID获取用户信息
 func (s *UserStore) GetUserByID(id string) (*models.User, error) {
+	if !s.enabled() {
+		return s.getUserByID(id)
+	}
+	return cache.GetOrLoad(context.Background(), s.cache, userCacheKey(id), s.ttl, func() (*models.User, error) {
+		return s.getUserByID(id)
+	})
+}
+
+func (s *UserStore) getUserByID(id string) (*models.User, error) {
 	var user models.User
 	result := s.db.First(&user, "id = ?", id)
 	if result.Error != nil {
 		if errors.Is(result.Error, gorm.ErrRecordNotFound) {
-			return nil, errors.New("用户不存在")
+			return nil, ErrUserNotFound
 		}
 		return nil, result.Error
 	}
@@ -39,13 +67,69 @@ func (s *UserStore) GetUserByUsername(username string) (*models.User, error) {
 	result := s.db.First(&user, "username = ?", username)
 	if result.Error != nil {
 		if errors.Is(result.Error, gorm.ErrRecordNotFound) {
-			return nil, errors.New("用户不存在")
+			return nil, ErrUserNotFound
 		}
 		return nil, result.Error
 	}
 	return &user, nil
 }
 
+// GetUserByEmail 根据邮箱获取用户
+func (s *UserStore) GetUserByEmail(email string) (*models.User, error) {
+	var user models.User
+	result := s.db.First(&user, "email = ?", email)
+	if result.Error != nil {
+		if errors.Is(result.Error, gorm.ErrRecordNotFound) {
+			return nil, ErrUserNotFound
+		}
+		return nil, result.Error
+	}
+	return &user, nil
+}
+
+// GetUsersByIDs 批量获取用户信息，不存在的用户不会出现在结果中
+func (s *UserStore) GetUsersByIDs(ids []string) ([]*models.User, error) {
+	var users []*models.User
+	if len(ids) == 0 {
+		return users, nil
+	}
+	err := s.db.Where("id IN ?", ids).Find(&users).Error
+	return users, err
+}
+
+// GetUserRole 获取用户当前的角色，用户不存在或已禁用时返回空字符串
+func (s *UserStore) GetUserRole(id string) (string, error) {
+	user, err := s.GetUserByID(id)
+	if errors.Is(err, ErrUserNotFound) {
+		return "", nil
+	}
+	if err != nil {
+		return "", err
+	}
+	if user.Status == models.UserStatusDisabled {
+		return "", nil
+	}
+	return user.Role, nil
+}
+
+// SearchUsers 按用户名、昵称或邮箱模糊查询用户，role 为空时不限角色，按ID倒序分页
+func (s *UserStore) SearchUsers(query, role string, limit int, lastID string) ([]*models.User, error) {
+	var users []*models.User
+	db := s.db.Model(&models.User{})
+	if query != "" {
+		like := "%" + query + "%"
+		db = db.Where("username LIKE ? OR nickname LIKE ? OR email LIKE ?", like, like, like)
+	}
+	if role != "" {
+		db = db.Where("role = ?", role)
+	}
+	if lastID != "" {
+		db = db.Where("id < ?", lastID)
+	}
+	err := db.Order("id desc").Limit(limit).Find(&users).Error
+	return users, err
+}
+
 // CheckUsernameExists 检查用户名是否已存在
 func (s *UserStore) CheckUsernameExists(username string) (bool, error) {
 	var count int64
@@ -66,12 +150,96 @@ func (s *UserStore) CheckEmailExists(email string) (bool, error) {
 	return count > 0, nil
 }
 
-// CreateUser 创建新用户
+// CreateUser 创建新用户，用户名或邮箱已被使用时返回 ConflictError
 func (s *UserStore) CreateUser(user *models.User) error {
-	return s.db.Create(user).Error
+	return userConflict(s.db, s.db.Create(user).Error)
 }
 
-// UpdateUser 更新用户信息
+// UpdateUser 更新用户信息，用户名或邮箱已被使用时返回 ConflictError
 func (s *UserStore) UpdateUser(user *models.User) error {
-	return s.db.Save(user).Error
+	if err := userConflict(s.db, s.db.Save(user).Error); err != nil {
+		return err
+	}
+	s.invalidate(userCacheKey(user.ID))
+	return nil
+}
+
+// UpdateProfile 更新用户资料中的部分字段，邮箱已被使用时返回 ConflictError
+func (s *UserStore) UpdateProfile(id string, fields map[string]interface{}) error {
+	if len(fields) == 0 {
+		return nil
+	}
+	defer s.invalidate(userCacheKey(id))
+	return userConflict(s.db, s.db.Model(&models.User{}).Where("id = ?", id).Updates(fields).Error)
+}
+
+// UpdatePassword 修改用户密码，password 为明文
+func (s *UserStore) UpdatePassword(id, password string) error {
+	hashed, err := auth.HashPassword(password)
+	if err != nil {
+		return err
+	}
+	defer s.invalidate(userCacheKey(id))
+	return s.db.Model(&models.User{}).Where("id = ?", id).Update("password", hashed).Error
+}
+
+// ReplacePassword 用户的密码哈希仍为 oldHash 时修改密码，password 为明文，
+// 密码已被其他请求修改时不修改并返回 false
+func (s *UserStore) ReplacePassword(id, oldHash, password string) (bool, error) {
+	hashed, err := auth.HashPassword(password)
+	if err != nil {
+		return false, err
+	}
+	defer s.invalidate(userCacheKey(id))
+	result := s.db.Model(&models.User{}).Where("id = ? AND password = ?", id, oldHash).Update("password", hashed)
+	return result.RowsAffected > 0, result.Error
+}
+
+// UpdateLastLogin 更新最后登录时间。
+// 不能通过 UpdateUser 保存从数据库读取的用户，否则 BeforeSave 会再次对密码哈希加密
+func (s *UserStore) UpdateLastLogin(id string, t time.Time) error {
+	defer s.invalidate(userCacheKey(id))
+	return s.db.Model(&models.User{}).Where("id = ?", id).Update("last_login", t).Error
+}
+
+// MarkEmailVerified 将用户的邮箱标记为已验证，用户的邮箱已不是 email 时不修改并返回 false
+func (s *UserStore) MarkEmailVerified(id, email string, t time.Time) (bool, error) {
+	defer s.invalidate(userCacheKey(id))
+	result := s.db.Model(&models.User{}).Where("id = ? AND email = ?", id, email).Update("email_verified_at", t)
+	return result.RowsAffected > 0, result.Error
+}
+
+// UpdateRole 修改用户角色
+func (s *UserStore) UpdateRole(id, role string) error {
+	defer s.invalidate(userCacheKey(id))
+	return s.db.Model(&models.User{}).Where("id = ?", id).Update("role", role).Error
+}
+
+// AnonymizeUser 清空用户资料并禁用账号，保留用户ID供其他数据引用
+func (s *UserStore) AnonymizeUser(id string) error {
+	defer s.invalidate(userCacheKey(id))
+	return s.db.Model(&models.User{}).Where("id = ?", id).Updates(map[string]interface{}{
+		"username": "deleted-" + id,
+		"password": "",
+		"nickname": "",
+		"avatar":   "",
+		"gender":   0,
+		"phone":    "",
+		"email":    "",
+		"bio":      "",
+		"status":   0,
+		"role":     models.RoleUser,
+
+		"email_verified_at": nil,
+	}).Error
+}
+
+// DeleteUser 删除用户
+func (s *UserStore) DeleteUser(id string) error {
+	defer s.invalidate(userCacheKey(id))
+	return s.db.Delete(&models.User{}, "id = ?", id).Error
+}
+
+func userConflict(db *gorm.DB, err error) error {
+	return conflictError(db, err, "users", "username", "email")
 }
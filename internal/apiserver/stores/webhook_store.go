@@ -0,0 +1,156 @@
+package stores
+
+import (
+	"errors"
+	"time"
+
+	"gorm.io/gorm"
+
+	"github.com/woxQAQ/gim/internal/models"
+)
+
+// WebhookDeliveryFilter 回调投递记录查询条件
+type WebhookDeliveryFilter struct {
+	EndpointID string
+	Event      string
+	Status     *models.WebhookDeliveryStatus
+	Limit      int
+	LastID     string // 游标，返回ID小于该值的记录
+}
+
+// WebhookStore 处理回调相关的数据库操作
+type WebhookStore struct {
+	db *gorm.DB
+}
+
+// NewWebhookStore 创建WebhookStore实例
+func NewWebhookStore(db *gorm.DB) *WebhookStore {
+	return &WebhookStore{db: db}
+}
+
+// CreateEndpoint 创建回调地址
+func (s *WebhookStore) CreateEndpoint(endpoint *models.WebhookEndpoint) error {
+	return s.db.Create(endpoint).Error
+}
+
+// GetEndpoint 根据ID获取回调地址
+func (s *WebhookStore) GetEndpoint(id string) (*models.WebhookEndpoint, error) {
+	var endpoint models.WebhookEndpoint
+	result := s.db.First(&endpoint, "id = ?", id)
+	if result.Error != nil {
+		if errors.Is(result.Error, gorm.ErrRecordNotFound) {
+			return nil, errors.New("回调地址不存在")
+		}
+		return nil, result.Error
+	}
+	return &endpoint, nil
+}
+
+// ListEndpoints 获取所有回调地址
+func (s *WebhookStore) ListEndpoints() ([]*models.WebhookEndpoint, error) {
+	var endpoints []*models.WebhookEndpoint
+	err := s.db.Order("created_at asc").Find(&endpoints).Error
+	return endpoints, err
+}
+
+// ListEnabledEndpoints 获取所有启用的回调地址
+func (s *WebhookStore) ListEnabledEndpoints() ([]*models.WebhookEndpoint, error) {
+	var endpoints []*models.WebhookEndpoint
+	err := s.db.Where("enabled = ?", true).Find(&endpoints).Error
+	return endpoints, err
+}
+
+// DeleteEndpoint 删除回调地址
+func (s *WebhookStore) DeleteEndpoint(id string) error {
+	return s.db.Delete(&models.WebhookEndpoint{}, "id = ?", id).Error
+}
+
+// CreateDeliveries 批量创建待投递记录
+func (s *WebhookStore) CreateDeliveries(deliveries []*models.WebhookDelivery) error {
+	if len(deliveries) == 0 {
+		return nil
+	}
+	return s.db.Create(deliveries).Error
+}
+
+// GetDueDeliveries 获取已到重试时间的待投递记录
+func (s *WebhookStore) GetDueDeliveries(now time.Time, limit int) ([]*models.WebhookDelivery, error) {
+	var deliveries []*models.WebhookDelivery
+	err := s.db.Where("status = ? AND next_attempt_at <= ?", models.WebhookDeliveryPending, now).
+		Order("next_attempt_at asc").
+		Limit(limit).
+		Find(&deliveries).Error
+	return deliveries, err
+}
+
+// UpdateDelivery 更新投递记录
+func (s *WebhookStore) UpdateDelivery(delivery *models.WebhookDelivery) error {
+	return s.db.Save(delivery).Error
+}
+
+// MoveToDeadLetter 将投递记录标记为死信并写入死信表
+func (s *WebhookStore) MoveToDeadLetter(delivery *models.WebhookDelivery) error {
+	return s.db.Transaction(func(tx *gorm.DB) error {
+		delivery.Status = models.WebhookDeliveryDead
+		if err := tx.Save(delivery).Error; err != nil {
+			return err
+		}
+		return tx.Create(&models.WebhookDeadLetter{
+			ID:         delivery.ID,
+			EndpointID: delivery.EndpointID,
+			Event:      delivery.Event,
+			Payload:    delivery.Payload,
+			Attempts:   delivery.Attempts,
+			LastError:  delivery.LastError,
+		}).Error
+	})
+}
+
+// ListDeliveries 按条件查询投递记录，按ID倒序
+func (s *WebhookStore) ListDeliveries(filter *WebhookDeliveryFilter) ([]*models.WebhookDelivery, error) {
+	var deliveries []*models.WebhookDelivery
+	query := s.db.Model(&models.WebhookDelivery{})
+	if filter.EndpointID != "" {
+		query = query.Where("endpoint_id = ?", filter.EndpointID)
+	}
+	if filter.Event != "" {
+		query = query.Where("event = ?", filter.Event)
+	}
+	if filter.Status != nil {
+		query = query.Where("status = ?", *filter.Status)
+	}
+	if filter.LastID != "" {
+		query = query.Where("id < ?", filter.LastID)
+	}
+	err := query.Order("id desc").Limit(filter.Limit).Find(&deliveries).Error
+	return deliveries, err
+}
+
+// ListDeadLetters 获取死信记录
+func (s *WebhookStore) ListDeadLetters(endpointID string, limit int) ([]*models.WebhookDeadLetter, error) {
+	var letters []*models.WebhookDeadLetter
+	query := s.db.Model(&models.WebhookDeadLetter{})
+	if endpointID != "" {
+		query = query.Where("endpoint_id = ?", endpointID)
+	}
+	err := query.Order("created_at desc").Limit(limit).Find(&letters).Error
+	return letters, err
+}
+
+// RequeueDeadLetter 将死信重新放回投递队列
+func (s *WebhookStore) RequeueDeadLetter(id string, now time.Time) error {
+	return s.db.Transaction(func(tx *gorm.DB) error {
+		result := tx.Delete(&models.WebhookDeadLetter{}, "id = ?", id)
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return errors.New("死信记录不存在")
+		}
+		return tx.Model(&models.WebhookDelivery{}).Where("id = ?", id).Updates(map[string]interface{}{
+			"status":          models.WebhookDeliveryPending,
+			"attempts":        0,
+			"next_attempt_at": now,
+		}).Error
+	})
+}
@@ -0,0 +1,13 @@
+package request
+
+import "time"
+
+// ListAuditLogsRequest 查询审计日志请求
+type ListAuditLogsRequest struct {
+	ActorID   string
+	Action    string
+	Since     *time.Time // 包含
+	Until     *time.Time // 不包含
+	PageSize  int
+	PageToken string
+}
@@ -0,0 +1,13 @@
+package request
+
+// BanUserRequest 封禁用户请求
+type BanUserRequest struct {
+	Reason          string `json:"reason" validate:"required,max=256"`
+	DurationSeconds int64  `json:"duration_seconds" validate:"min=0"` // 封禁时长，为0时永久封禁
+	CreatedBy       string `json:"-"`                                 // 由控制器填写为当前管理员
+}
+
+// LiftBanRequest 解除封禁请求
+type LiftBanRequest struct {
+	LiftedBy string `json:"-"` // 由控制器填写为当前管理员
+}
@@ -0,0 +1,24 @@
+package request
+
+// CreateBotRequest 创建机器人账号请求
+type CreateBotRequest struct {
+	Username  string `json:"username" validate:"required,max=64"`
+	Nickname  string `json:"nickname" validate:"max=64"`
+	Avatar    string `json:"avatar" validate:"max=512"`
+	CreatedBy string `json:"-"` // 由控制器填写为当前管理员
+}
+
+// CreateAPIKeyRequest 为机器人创建 API 密钥请求
+type CreateAPIKeyRequest struct {
+	Name             string   `json:"name" validate:"max=64"`
+	Scopes           []string `json:"scopes" validate:"required,min=1,dive,oneof=messages:send"`
+	ExpiresInSeconds int64    `json:"expires_in_seconds" validate:"min=0"` // 有效期，为0时不过期
+	CreatedBy        string   `json:"-"`                                   // 由控制器填写为当前管理员
+}
+
+// RotateAPIKeyRequest 轮换 API 密钥请求，新密钥沿用旧密钥的名称、权限范围和有效期长度
+type RotateAPIKeyRequest struct {
+	// GracePeriodSeconds 旧密钥继续有效的时间，便于调用方切换，为0时立即吊销
+	GracePeriodSeconds int64  `json:"grace_period_seconds" validate:"min=0,max=604800"`
+	CreatedBy          string `json:"-"` // 由控制器填写为当前管理员
+}
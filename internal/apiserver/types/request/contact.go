@@ -0,0 +1,17 @@
+package request
+
+// SendFriendRequestRequest 发送好友申请请求
+type SendFriendRequestRequest struct {
+	ToID    string `json:"to_id" validate:"required"`
+	Message string `json:"message" validate:"max=256"`
+}
+
+// SetContactRemarkRequest 设置联系人备注请求，为空时清除备注
+type SetContactRemarkRequest struct {
+	Remark string `json:"remark" validate:"max=64"`
+}
+
+// BlockUserRequest 拉黑用户请求
+type BlockUserRequest struct {
+	UserID string `json:"user_id" validate:"required"`
+}
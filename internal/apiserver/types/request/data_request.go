@@ -0,0 +1,9 @@
+package request
+
+// CreateDataRequestRequest 创建用户数据导出或删除任务请求
+type CreateDataRequestRequest struct {
+	UserID      string `json:"-"` // 当前用户，由鉴权中间件提供
+	Kind        string `json:"kind" validate:"required,oneof=export delete"`
+	Mode        string `json:"mode,omitempty"` // 删除方式：anonymize(默认) 或 remove，仅 kind 为 delete 时有效
+	RequestedBy string `json:"-"`              // 提交任务的用户
+}
@@ -1,17 +1,39 @@
 package request
 
-// SendMessageRequest 发送消息请求
+import "time"
+
+// SendMessageRequest 机器人发送消息请求，ReceiverID 与 GroupID 二选一
 type SendMessageRequest struct {
-	ReceiverID string `json:"receiver_id"`
-	Content    string `json:"content"`
-	Type       int32  `json:"type"`
+	ReceiverID string `json:"receiver_id,omitempty" validate:"required_without=GroupID,excluded_with=GroupID"`
+	GroupID    string `json:"group_id,omitempty" validate:"required_without=ReceiverID"`
+	Content    string `json:"content" validate:"required,max=4096"`
+	Type       int32  `json:"type,omitempty" validate:"omitempty,min=3,max=8"` // 业务消息类型，默认为文本
+	SubType    string `json:"sub_type,omitempty" validate:"max=64"`            // 自定义消息的子类型
 }
 
 // GetMessageHistoryRequest 获取消息历史记录请求
 type GetMessageHistoryRequest struct {
-	UserID    string `json:"user_id"`
-	PageSize  int    `json:"page_size"`
-	PageToken string `json:"page_token,omitempty"`
+	UserID         string     `json:"-"`                         // 当前用户，由鉴权中间件提供
+	PeerID         string     `json:"peer_id,omitempty"`         // 与该用户之间的单聊
+	ConversationID string     `json:"conversation_id,omitempty"` // 指定会话，优先于 PeerID
+	Types          []int32    `json:"types,omitempty"`
+	Since          *time.Time `json:"since,omitempty"`
+	Until          *time.Time `json:"until,omitempty"`
+	Before         string     `json:"before,omitempty"` // 返回ID小于该值的消息，按ID倒序
+	After          string     `json:"after,omitempty"`  // 返回ID大于该值的消息，按ID正序
+	PageSize       int        `json:"page_size"`
+}
+
+// SearchMessagesRequest 搜索消息请求
+type SearchMessagesRequest struct {
+	UserID         string     `json:"-"` // 当前用户，由鉴权中间件提供
+	Keyword        string     `json:"q"`
+	ConversationID string     `json:"conversation_id,omitempty"`
+	Types          []int32    `json:"types,omitempty"`
+	Since          *time.Time `json:"since,omitempty"`
+	Until          *time.Time `json:"until,omitempty"`
+	Before         string     `json:"before,omitempty"` // 返回ID小于该值的消息
+	PageSize       int        `json:"page_size"`
 }
 
 // GetUnreadCountRequest 获取未读消息数量请求
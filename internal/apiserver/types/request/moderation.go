@@ -0,0 +1,6 @@
+package request
+
+// ReviewModerationRequest 审核消息请求
+type ReviewModerationRequest struct {
+	Status string `json:"status" validate:"required,oneof=approved removed"`
+}
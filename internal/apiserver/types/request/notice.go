@@ -0,0 +1,16 @@
+package request
+
+import "time"
+
+// CreateNoticeRequest 创建系统通知请求
+type CreateNoticeRequest struct {
+	Title      string     `json:"title" validate:"required"`
+	Content    string     `json:"content" validate:"required"`
+	TargetType string     `json:"target_type" validate:"required,oneof=all users group platform"`
+	UserIDs    []string   `json:"user_ids,omitempty"`    // target_type 为 users 时必填
+	GroupID    string     `json:"group_id,omitempty"`    // target_type 为 group 时必填
+	PlatformID int32      `json:"platform_id,omitempty"` // target_type 为 platform 时必填
+	Persist    bool       `json:"persist"`               // 是否保存供离线用户拉取
+	DeliverAt  *time.Time `json:"deliver_at,omitempty"`  // 定时发送时间，为空时立即发送
+	CreatedBy  string     `json:"-"`                     // 由控制器填写为当前管理员
+}
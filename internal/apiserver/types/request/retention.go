@@ -0,0 +1,10 @@
+package request
+
+// CreateRetentionPolicyRequest 创建或覆盖消息保留策略请求
+type CreateRetentionPolicyRequest struct {
+	ConversationID string `json:"conversation_id,omitempty"` // 为空时匹配所有会话
+	MessageType    int32  `json:"message_type,omitempty"`    // 为0时匹配所有类型
+	TTLSeconds     int64  `json:"ttl_seconds" validate:"min=0"`
+	Action         string `json:"action" validate:"required,oneof=delete archive"`
+	CreatedBy      string `json:"-"` // 由控制器填写为当前管理员
+}
@@ -1,18 +1,64 @@
 package request
 
 type RegisterRequest struct {
-	Email    string `json:"email"`
-	Username string `json:"username" validate:"required"`
-	Password string `json:"password" validate:"required"`
+	Email        string `json:"email"`
+	Username     string `json:"username" validate:"required"`
+	Password     string `json:"password" validate:"required"`
+	CaptchaToken string `json:"captcha_token,omitempty"` // 注册过多返回 captcha_required 后需要提供
 }
 
 type LoginRequest struct {
-	Email    string `json:"email"`
-	UserId   string `json:"userId"`
-	Username string `json:"username"`
+	Username string `json:"username" validate:"required"`
 	Password string `json:"password" validate:"required"`
+	Platform int32  `json:"platform,omitempty"`  // 登录的平台，默认为1
+	DeviceID string `json:"device_id,omitempty"` // 设备标识，便于用户区分登录会话
+	// CaptchaToken 人机验证令牌，登录失败过多返回 captcha_required 后需要提供
+	CaptchaToken string `json:"captcha_token,omitempty"`
+}
+
+// RefreshTokenRequest 刷新访问令牌请求
+type RefreshTokenRequest struct {
+	RefreshToken string `json:"refresh_token" validate:"required"`
+}
+
+// BatchGetUsersRequest 批量获取用户资料请求
+type BatchGetUsersRequest struct {
+	IDs []string `json:"ids" validate:"required,min=1,max=100,dive,required"`
+}
+
+// UpdateProfileRequest 更新用户资料请求，只修改非空的字段。
+// 手机号与邮箱修改后不能清空
+type UpdateProfileRequest struct {
+	Nickname *string `json:"nickname,omitempty" validate:"omitempty,max=64"`
+	Gender   *string `json:"gender,omitempty" validate:"omitempty,oneof=unknown male female"`
+	Phone    *string `json:"phone,omitempty" validate:"omitempty,max=32,e164"`
+	Email    *string `json:"email,omitempty" validate:"omitempty,max=255,email"`
+	Bio      *string `json:"bio,omitempty" validate:"omitempty,max=512"`
+}
+
+// ChangePasswordRequest 修改密码请求，需要提供当前密码
+type ChangePasswordRequest struct {
+	OldPassword string `json:"old_password" validate:"required"`
+	NewPassword string `json:"new_password" validate:"required,min=8,max=72"`
+}
+
+// SetRoleRequest 修改用户角色请求
+type SetRoleRequest struct {
+	Role string `json:"role" validate:"required,oneof=user moderator admin"`
+}
+
+// EmailRequest 按邮箱发送验证邮件或重置密码邮件的请求
+type EmailRequest struct {
+	Email string `json:"email" validate:"required,max=255,email"`
+}
+
+// VerifyEmailRequest 使用验证邮件中的令牌验证邮箱
+type VerifyEmailRequest struct {
+	Token string `json:"token" validate:"required"`
 }
 
-type GetUserInfoRequest struct {
-	ID int64 `query:"userId" validate:"required"`
+// ResetPasswordRequest 使用重置密码邮件中的令牌设置新密码
+type ResetPasswordRequest struct {
+	Token       string `json:"token" validate:"required"`
+	NewPassword string `json:"new_password" validate:"required,min=8,max=72"`
 }
@@ -0,0 +1,8 @@
+package request
+
+// CreateWebhookEndpointRequest 创建回调地址请求
+type CreateWebhookEndpointRequest struct {
+	URL    string   `json:"url" validate:"required,url"`
+	Events []string `json:"events" validate:"required,min=1"`
+	Secret string   `json:"secret,omitempty"` // 为空时自动生成
+}
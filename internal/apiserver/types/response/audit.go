@@ -0,0 +1,34 @@
+package response
+
+import (
+	"encoding/json"
+	"time"
+)
+
+// AuditLogResponse 审计日志响应
+type AuditLogResponse struct {
+	ID        string          `json:"id"`
+	Seq       int64           `json:"seq"`
+	Action    string          `json:"action"`
+	ActorID   string          `json:"actor_id,omitempty"`
+	TargetID  string          `json:"target_id,omitempty"`
+	IP        string          `json:"ip,omitempty"`
+	Detail    json.RawMessage `json:"detail,omitempty"`
+	Hash      string          `json:"hash,omitempty"`
+	CreatedAt time.Time       `json:"created_at"`
+}
+
+// AuditLogListResponse 审计日志列表响应
+type AuditLogListResponse struct {
+	Logs      []*AuditLogResponse `json:"logs"`
+	NextToken string              `json:"next_token,omitempty"`
+}
+
+// AuditVerifyResponse 审计日志哈希链校验结果
+type AuditVerifyResponse struct {
+	Valid     bool   `json:"valid"`
+	Checked   int64  `json:"checked"`              // 校验过的记录数量
+	Unchained int64  `json:"unchained"`            // 未开启哈希链时写入、无法校验的记录数量
+	BrokenSeq int64  `json:"broken_seq,omitempty"` // 第一条校验失败的记录
+	Reason    string `json:"reason,omitempty"`
+}
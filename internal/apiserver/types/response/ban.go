@@ -0,0 +1,16 @@
+package response
+
+import "time"
+
+// BanResponse 用户封禁记录响应
+type BanResponse struct {
+	ID        string     `json:"id"`
+	UserID    string     `json:"user_id"`
+	Reason    string     `json:"reason"`
+	ExpiresAt *time.Time `json:"expires_at,omitempty"` // 为空时永久封禁
+	CreatedBy string     `json:"created_by,omitempty"`
+	LiftedAt  *time.Time `json:"lifted_at,omitempty"`
+	LiftedBy  string     `json:"lifted_by,omitempty"`
+	Active    bool       `json:"active"`
+	CreatedAt time.Time  `json:"created_at"`
+}
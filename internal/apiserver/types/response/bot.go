@@ -0,0 +1,31 @@
+package response
+
+import "time"
+
+// APIKeyResponse 机器人 API 密钥响应，不包含密钥本身
+type APIKeyResponse struct {
+	ID         string     `json:"id"`
+	BotID      string     `json:"bot_id"`
+	Name       string     `json:"name"`
+	Prefix     string     `json:"prefix"`
+	Scopes     []string   `json:"scopes"`
+	CreatedBy  string     `json:"created_by,omitempty"`
+	ExpiresAt  *time.Time `json:"expires_at,omitempty"`
+	RevokedAt  *time.Time `json:"revoked_at,omitempty"`
+	LastUsedAt *time.Time `json:"last_used_at,omitempty"`
+	Active     bool       `json:"active"`
+	CreatedAt  time.Time  `json:"created_at"`
+}
+
+// CreatedAPIKeyResponse 新建或轮换后的 API 密钥，Key 只在此时返回一次
+type CreatedAPIKeyResponse struct {
+	APIKeyResponse
+	Key string `json:"key"`
+}
+
+// SendMessageResponse 机器人发送消息的结果
+type SendMessageResponse struct {
+	ID             string    `json:"id"`
+	ConversationID string    `json:"conversation_id"`
+	CreatedAt      time.Time `json:"created_at"`
+}
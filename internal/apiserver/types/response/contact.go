@@ -0,0 +1,29 @@
+package response
+
+import "time"
+
+// FriendRequestResponse 好友申请响应
+type FriendRequestResponse struct {
+	ID        string     `json:"id"`
+	FromID    string     `json:"from_id"`
+	ToID      string     `json:"to_id"`
+	Message   string     `json:"message,omitempty"`
+	Status    string     `json:"status"`
+	HandledAt *time.Time `json:"handled_at,omitempty"`
+	CreatedAt time.Time  `json:"created_at"`
+}
+
+// ContactResponse 联系人响应，包含对方的资料和在线平台
+type ContactResponse struct {
+	User      *UserResponse `json:"user"`
+	Remark    string        `json:"remark,omitempty"`
+	Online    bool          `json:"online"`
+	Platforms []int32       `json:"platforms,omitempty"` // 在线的平台，网关不可用时为空
+	CreatedAt time.Time     `json:"created_at"`
+}
+
+// BlockResponse 黑名单中的用户
+type BlockResponse struct {
+	User      *UserResponse `json:"user"`
+	CreatedAt time.Time     `json:"created_at"`
+}
@@ -0,0 +1,19 @@
+package response
+
+import "time"
+
+// DataRequestResponse 用户数据任务响应
+type DataRequestResponse struct {
+	ID         string           `json:"id"`
+	UserID     string           `json:"user_id"`
+	Kind       string           `json:"kind"`
+	Mode       string           `json:"mode,omitempty"`
+	Status     string           `json:"status"`
+	FileSize   int64            `json:"file_size,omitempty"`
+	Summary    map[string]int64 `json:"summary,omitempty"`
+	LastError  string           `json:"last_error,omitempty"`
+	StartedAt  *time.Time       `json:"started_at,omitempty"`
+	FinishedAt *time.Time       `json:"finished_at,omitempty"`
+	ExpiresAt  *time.Time       `json:"expires_at,omitempty"`
+	CreatedAt  time.Time        `json:"created_at"`
+}
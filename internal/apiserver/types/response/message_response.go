@@ -6,13 +6,14 @@ import (
 
 // MessageResponse 消息响应
 type MessageResponse struct {
-	ID         string    `json:"id"`
-	SenderID   string    `json:"sender_id"`
-	ReceiverID string    `json:"receiver_id"`
-	Content    string    `json:"content"`
-	Type       int32     `json:"type"`
-	Status     int32     `json:"status"`
-	CreatedAt  time.Time `json:"created_at"`
+	ID             string    `json:"id"`
+	ConversationID string    `json:"conversation_id"`
+	SenderID       string    `json:"sender_id"`
+	ReceiverID     string    `json:"receiver_id"`
+	Content        string    `json:"content"`
+	Type           int32     `json:"type"`
+	Status         int32     `json:"status"`
+	CreatedAt      time.Time `json:"created_at"`
 }
 
 // MessageHistoryResponse 消息历史记录响应
@@ -21,6 +22,18 @@ type MessageHistoryResponse struct {
 	NextToken string             `json:"next_token,omitempty"`
 }
 
+// MessageSearchHit 消息搜索结果
+type MessageSearchHit struct {
+	Message   *MessageResponse `json:"message"`
+	Highlight string           `json:"highlight"` // 命中片段，关键词以 <mark></mark> 包裹
+}
+
+// MessageSearchResponse 消息搜索响应
+type MessageSearchResponse struct {
+	Hits      []*MessageSearchHit `json:"hits"`
+	NextToken string              `json:"next_token,omitempty"`
+}
+
 // UnreadCountResponse 未读消息数量响应
 type UnreadCountResponse struct {
 	Count int `json:"count"`
@@ -0,0 +1,23 @@
+package response
+
+import "time"
+
+// ModerationRecordResponse 待审核消息响应
+type ModerationRecordResponse struct {
+	ID         string     `json:"id"`
+	MessageID  string     `json:"message_id"`
+	FromID     string     `json:"from_id"`
+	ToID       string     `json:"to_id"`
+	Content    string     `json:"content"`
+	Rules      []string   `json:"rules"`
+	Status     string     `json:"status"`
+	ReviewerID string     `json:"reviewer_id,omitempty"`
+	ReviewedAt *time.Time `json:"reviewed_at,omitempty"`
+	CreatedAt  time.Time  `json:"created_at"`
+}
+
+// ModerationRecordListResponse 待审核消息列表响应
+type ModerationRecordListResponse struct {
+	Records   []*ModerationRecordResponse `json:"records"`
+	NextToken string                      `json:"next_token,omitempty"`
+}
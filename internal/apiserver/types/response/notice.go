@@ -0,0 +1,26 @@
+package response
+
+import "time"
+
+// NoticeResponse 系统通知响应
+type NoticeResponse struct {
+	ID         string     `json:"id"`
+	Title      string     `json:"title"`
+	Content    string     `json:"content"`
+	TargetType string     `json:"target_type"`
+	TargetIDs  []string   `json:"target_ids,omitempty"`
+	PlatformID int32      `json:"platform_id,omitempty"`
+	Persist    bool       `json:"persist"`
+	Status     string     `json:"status"`
+	DeliverAt  time.Time  `json:"deliver_at"`
+	SentAt     *time.Time `json:"sent_at,omitempty"`
+	Delivered  int        `json:"delivered"`
+	LastError  string     `json:"last_error,omitempty"`
+	CreatedAt  time.Time  `json:"created_at"`
+}
+
+// NoticeListResponse 系统通知列表响应
+type NoticeListResponse struct {
+	Notices   []*NoticeResponse `json:"notices"`
+	NextToken string            `json:"next_token,omitempty"`
+}
@@ -0,0 +1,32 @@
+package response
+
+import "time"
+
+// RetentionPolicyResponse 消息保留策略响应
+type RetentionPolicyResponse struct {
+	ID             string    `json:"id"`
+	ConversationID string    `json:"conversation_id,omitempty"`
+	MessageType    int32     `json:"message_type,omitempty"`
+	TTLSeconds     int64     `json:"ttl_seconds"`
+	Action         string    `json:"action"`
+	CreatedBy      string    `json:"created_by,omitempty"`
+	CreatedAt      time.Time `json:"created_at"`
+}
+
+// RetentionRunResponse 过期清理执行记录响应
+type RetentionRunResponse struct {
+	ID          string    `json:"id"`
+	StartedAt   time.Time `json:"started_at"`
+	FinishedAt  time.Time `json:"finished_at"`
+	Deleted     int64     `json:"deleted"`
+	Archived    int64     `json:"archived"`
+	Attachments int64     `json:"attachments"`
+	Details     string    `json:"details,omitempty"`
+	LastError   string    `json:"last_error,omitempty"`
+}
+
+// RetentionRunListResponse 过期清理执行记录列表响应
+type RetentionRunListResponse struct {
+	Runs      []*RetentionRunResponse `json:"runs"`
+	NextToken string                  `json:"next_token,omitempty"`
+}
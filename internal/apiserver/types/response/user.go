@@ -1,18 +1,43 @@
 package response
 
+import "time"
+
+// UserResponse 用户资料，联系方式只返回给本人和管理员，其他用户查询时为空
 type UserResponse struct {
 	ID       string `json:"id"`
 	Username string `json:"username"`
 	Nickname string `json:"nickname"`
 	Gender   string `json:"gender"`
-	Email    string `json:"email"`
-	Phone    string `json:"phone"`
 	Avatar   string `json:"avatar"`
 	Bio      string `json:"bio"`
 	Status   string `json:"status"`
+	Role     string `json:"role"`
+	*UserContactResponse
+}
+
+// UserContactResponse 用户的联系方式
+type UserContactResponse struct {
+	Email string `json:"email"`
+	Phone string `json:"phone"`
+	// EmailVerified 邮箱是否已通过验证
+	EmailVerified bool `json:"email_verified"`
+}
+
+// UserListResponse 用户分页列表响应
+type UserListResponse struct {
+	Users     []*UserResponse `json:"users"`
+	NextToken string          `json:"next_token,omitempty"`
+}
+
+// TokenResponse 访问令牌与刷新令牌
+type TokenResponse struct {
+	Token            string    `json:"token"`
+	ExpiresAt        time.Time `json:"expires_at"`
+	RefreshToken     string    `json:"refresh_token"`
+	RefreshExpiresAt time.Time `json:"refresh_expires_at"`
 }
 
 type LoginResponse struct {
-	Token string        `json:"token"`
-	User  *UserResponse `json:"user"`
+	TokenResponse
+	User *UserResponse `json:"user"`
 }
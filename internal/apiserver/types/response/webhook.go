@@ -0,0 +1,44 @@
+package response
+
+import "time"
+
+// WebhookEndpointResponse 回调地址响应
+type WebhookEndpointResponse struct {
+	ID        string    `json:"id"`
+	URL       string    `json:"url"`
+	Events    []string  `json:"events"`
+	Enabled   bool      `json:"enabled"`
+	Secret    string    `json:"secret,omitempty"` // 仅在创建时返回
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// WebhookDeliveryResponse 回调投递记录响应
+type WebhookDeliveryResponse struct {
+	ID            string    `json:"id"`
+	EndpointID    string    `json:"endpoint_id"`
+	Event         string    `json:"event"`
+	Payload       string    `json:"payload"`
+	Status        string    `json:"status"`
+	Attempts      int       `json:"attempts"`
+	NextAttemptAt time.Time `json:"next_attempt_at"`
+	ResponseCode  int       `json:"response_code"`
+	LastError     string    `json:"last_error,omitempty"`
+	CreatedAt     time.Time `json:"created_at"`
+}
+
+// WebhookDeliveryListResponse 回调投递记录列表响应
+type WebhookDeliveryListResponse struct {
+	Deliveries []*WebhookDeliveryResponse `json:"deliveries"`
+	NextToken  string                     `json:"next_token,omitempty"`
+}
+
+// WebhookDeadLetterResponse 死信记录响应
+type WebhookDeadLetterResponse struct {
+	ID         string    `json:"id"`
+	EndpointID string    `json:"endpoint_id"`
+	Event      string    `json:"event"`
+	Payload    string    `json:"payload"`
+	Attempts   int       `json:"attempts"`
+	LastError  string    `json:"last_error,omitempty"`
+	CreatedAt  time.Time `json:"created_at"`
+}
@@ -0,0 +1,585 @@
+// Package archive 将冷数据消息归档到压缩的只追加分段文件中。
+//
+// 目录结构：
+//
+//	segments/<序号>.jsonl.gz  每次归档写入一个新分段，内容为 gzip 压缩的 JSON Lines，除删除用户数据外不再修改
+//	index.jsonl               只追加的索引，每行描述一个分段中某个会话的ID范围、时间范围和参与用户
+//
+// 查询时先用索引筛选分段，再读取分段过滤消息。
+// 同一条消息可能因为归档后删除失败而被重复归档，查询结果会按ID去重。
+package archive
+
+import (
+	"bufio"
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/woxQAQ/gim/internal/models"
+	"github.com/woxQAQ/gim/internal/types"
+)
+
+const (
+	segmentDir = "segments"
+	indexFile  = "index.jsonl"
+)
+
+// ErrInvalidQuery 查询条件不完整或互相冲突
+var ErrInvalidQuery = errors.New("invalid archive query")
+
+// Record 归档文件中的一条消息
+type Record struct {
+	ID             string               `json:"id"`
+	ConversationID string               `json:"conversation_id"`
+	Type           types.MessageType    `json:"type"`
+	Content        string               `json:"content"`
+	FromID         string               `json:"from_id"`
+	ToID           string               `json:"to_id"`
+	Status         models.MessageStatus `json:"status"`
+	Platform       int32                `json:"platform"`
+	CreatedAt      time.Time            `json:"created_at"`
+	UpdatedAt      time.Time            `json:"updated_at"`
+	Attachments    []*Attachment        `json:"attachments,omitempty"`
+}
+
+// Attachment 归档文件中的消息附件
+type Attachment struct {
+	ID       string `json:"id"`
+	Type     string `json:"type"`
+	URL      string `json:"url"`
+	Size     int64  `json:"size"`
+	MimeType string `json:"mime_type,omitempty"`
+	Metadata string `json:"metadata,omitempty"`
+}
+
+// Message 转换为消息模型
+func (r *Record) Message() *models.Message {
+	return &models.Message{
+		ID:             r.ID,
+		ConversationID: r.ConversationID,
+		Type:           r.Type,
+		Content:        r.Content,
+		FromID:         r.FromID,
+		ToID:           r.ToID,
+		Status:         r.Status,
+		Platform:       r.Platform,
+		CreatedAt:      r.CreatedAt,
+		UpdatedAt:      r.UpdatedAt,
+	}
+}
+
+// indexEntry 索引中的一行，对应一个分段中的一个会话
+type indexEntry struct {
+	Segment        string    `json:"segment"`
+	ConversationID string    `json:"conversation_id"`
+	Users          []string  `json:"users"`
+	MinID          string    `json:"min_id"`
+	MaxID          string    `json:"max_id"`
+	MinTime        time.Time `json:"min_time"`
+	MaxTime        time.Time `json:"max_time"`
+	Count          int       `json:"count"`
+}
+
+// Query 归档查询条件，语义与消息表的查询一致：
+// 会话范围三选一：ConversationID；UserID+PeerID；仅 UserID。
+// 未设置游标或使用 Before 时按ID倒序返回，使用 After 时按ID正序返回。
+type Query struct {
+	ConversationID string
+	UserID         string
+	PeerID         string
+
+	Types []types.MessageType
+	Since *time.Time // 包含
+	Until *time.Time // 不包含
+
+	Before string
+	After  string
+	Limit  int
+}
+
+// Archive 消息冷归档
+type Archive struct {
+	dir string
+
+	mutex   sync.RWMutex
+	entries []*indexEntry
+	nextSeq int
+}
+
+// Open 打开归档目录，目录不存在时创建
+func Open(dir string) (*Archive, error) {
+	if err := os.MkdirAll(filepath.Join(dir, segmentDir), 0o755); err != nil {
+		return nil, err
+	}
+	a := &Archive{dir: dir, nextSeq: 1}
+	if err := a.loadIndex(); err != nil {
+		return nil, err
+	}
+	return a, nil
+}
+
+// loadIndex 读取索引，并根据已有分段确定下一个分段序号
+func (a *Archive) loadIndex() error {
+	f, err := os.Open(filepath.Join(a.dir, indexFile))
+	if err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	if err == nil {
+		defer f.Close()
+		scanner := bufio.NewScanner(f)
+		scanner.Buffer(make([]byte, 64*1024), 16*1024*1024)
+		for scanner.Scan() {
+			if len(scanner.Bytes()) == 0 {
+				continue
+			}
+			var e indexEntry
+			// 最后一行可能因为进程退出只写了一半，跳过
+			if err := json.Unmarshal(scanner.Bytes(), &e); err != nil {
+				continue
+			}
+			a.entries = append(a.entries, &e)
+		}
+		if err := scanner.Err(); err != nil {
+			return err
+		}
+	}
+
+	// 没有写入索引的分段也要跳过，避免覆盖
+	segments, err := filepath.Glob(filepath.Join(a.dir, segmentDir, "*.jsonl.gz"))
+	if err != nil {
+		return err
+	}
+	for _, s := range segments {
+		var seq int
+		if _, err := fmt.Sscanf(filepath.Base(s), "%d.jsonl.gz", &seq); err == nil && seq >= a.nextSeq {
+			a.nextSeq = seq + 1
+		}
+	}
+	return nil
+}
+
+// Archive 将消息及其附件写入一个新的分段，并追加索引。
+// 返回后消息已经落盘，可以从消息表中删除。
+func (a *Archive) Archive(_ context.Context, messages []*models.Message, attachments []*models.MessageAttachment) error {
+	if len(messages) == 0 {
+		return nil
+	}
+
+	byMessage := make(map[string][]*Attachment, len(attachments))
+	for _, att := range attachments {
+		byMessage[att.MessageID] = append(byMessage[att.MessageID], &Attachment{
+			ID:       att.ID,
+			Type:     att.Type,
+			URL:      att.URL,
+			Size:     att.Size,
+			MimeType: att.MimeType,
+			Metadata: att.Metadata,
+		})
+	}
+	records := make([]*Record, 0, len(messages))
+	for _, m := range messages {
+		records = append(records, &Record{
+			ID:             m.ID,
+			ConversationID: m.ConversationID,
+			Type:           m.Type,
+			Content:        m.Content,
+			FromID:         m.FromID,
+			ToID:           m.ToID,
+			Status:         m.Status,
+			Platform:       m.Platform,
+			CreatedAt:      m.CreatedAt,
+			UpdatedAt:      m.UpdatedAt,
+			Attachments:    byMessage[m.ID],
+		})
+	}
+	// 分段内按会话、ID排序，便于按会话顺序读取
+	sort.Slice(records, func(i, j int) bool {
+		if records[i].ConversationID != records[j].ConversationID {
+			return records[i].ConversationID < records[j].ConversationID
+		}
+		return records[i].ID < records[j].ID
+	})
+
+	a.mutex.Lock()
+	defer a.mutex.Unlock()
+
+	segment := fmt.Sprintf("%010d.jsonl.gz", a.nextSeq)
+	if err := a.writeSegment(segment, records); err != nil {
+		return err
+	}
+	a.nextSeq++
+
+	entries := buildEntries(segment, records)
+	if err := a.appendIndex(entries); err != nil {
+		return err
+	}
+	a.entries = append(a.entries, entries...)
+	return nil
+}
+
+// writeSegment 先写临时文件再重命名，保证分段文件要么完整要么不存在
+func (a *Archive) writeSegment(name string, records []*Record) error {
+	path := filepath.Join(a.dir, segmentDir, name)
+	tmp, err := os.CreateTemp(filepath.Dir(path), name+".tmp-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	zw := gzip.NewWriter(tmp)
+	enc := json.NewEncoder(zw)
+	for _, r := range records {
+		if err := enc.Encode(r); err != nil {
+			tmp.Close()
+			return err
+		}
+	}
+	if err := zw.Close(); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
+
+func (a *Archive) appendIndex(entries []*indexEntry) error {
+	f, err := os.OpenFile(filepath.Join(a.dir, indexFile), os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0o644)
+	if err != nil {
+		return err
+	}
+	enc := json.NewEncoder(f)
+	for _, e := range entries {
+		if err := enc.Encode(e); err != nil {
+			f.Close()
+			return err
+		}
+	}
+	if err := f.Sync(); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+// buildEntries 为分段中的每个会话生成一行索引，records 需要已按会话排序
+func buildEntries(segment string, records []*Record) []*indexEntry {
+	var entries []*indexEntry
+	var cur *indexEntry
+	users := map[string]struct{}{}
+	flush := func() {
+		if cur == nil {
+			return
+		}
+		for u := range users {
+			cur.Users = append(cur.Users, u)
+		}
+		sort.Strings(cur.Users)
+		entries = append(entries, cur)
+		users = map[string]struct{}{}
+	}
+	for _, r := range records {
+		if cur == nil || cur.ConversationID != r.ConversationID {
+			flush()
+			cur = &indexEntry{
+				Segment:        segment,
+				ConversationID: r.ConversationID,
+				MinID:          r.ID,
+				MinTime:        r.CreatedAt,
+				MaxTime:        r.CreatedAt,
+			}
+		}
+		cur.MaxID = r.ID
+		if r.CreatedAt.Before(cur.MinTime) {
+			cur.MinTime = r.CreatedAt
+		}
+		if r.CreatedAt.After(cur.MaxTime) {
+			cur.MaxTime = r.CreatedAt
+		}
+		cur.Count++
+		users[r.FromID] = struct{}{}
+		users[r.ToID] = struct{}{}
+	}
+	flush()
+	return entries
+}
+
+// ListMessages 按条件查询归档的消息
+func (a *Archive) ListMessages(q *Query) ([]*models.Message, error) {
+	records, err := a.ListRecords(q)
+	if err != nil {
+		return nil, err
+	}
+	messages := make([]*models.Message, 0, len(records))
+	for _, r := range records {
+		messages = append(messages, r.Message())
+	}
+	return messages, nil
+}
+
+// ListRecords 按条件查询归档的消息记录，包含附件
+func (a *Archive) ListRecords(q *Query) ([]*Record, error) {
+	if q.Before != "" && q.After != "" {
+		return nil, ErrInvalidQuery
+	}
+	if q.ConversationID == "" && q.UserID == "" {
+		return nil, ErrInvalidQuery
+	}
+	conversationID := q.ConversationID
+	if conversationID == "" && q.PeerID != "" {
+		conversationID = models.DirectConversationID(q.UserID, q.PeerID)
+	}
+
+	a.mutex.RLock()
+	defer a.mutex.RUnlock()
+
+	var segments []string
+	seen := map[string]struct{}{}
+	for _, e := range a.entries {
+		if !e.matches(q, conversationID) {
+			continue
+		}
+		if _, ok := seen[e.Segment]; !ok {
+			seen[e.Segment] = struct{}{}
+			segments = append(segments, e.Segment)
+		}
+	}
+
+	found := map[string]*Record{}
+	for _, s := range segments {
+		err := a.readSegment(s, func(r *Record) {
+			if r.matches(q, conversationID) {
+				found[r.ID] = r
+			}
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	records := make([]*Record, 0, len(found))
+	for _, r := range found {
+		records = append(records, r)
+	}
+	if q.After != "" {
+		sort.Slice(records, func(i, j int) bool { return records[i].ID < records[j].ID })
+	} else {
+		sort.Slice(records, func(i, j int) bool { return records[i].ID > records[j].ID })
+	}
+	if q.Limit > 0 && len(records) > q.Limit {
+		records = records[:q.Limit]
+	}
+	return records, nil
+}
+
+func (a *Archive) readSegment(name string, fn func(r *Record)) error {
+	f, err := os.Open(filepath.Join(a.dir, segmentDir, name))
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	zr, err := gzip.NewReader(f)
+	if err != nil {
+		return err
+	}
+	defer zr.Close()
+
+	dec := json.NewDecoder(zr)
+	for dec.More() {
+		var r Record
+		if err := dec.Decode(&r); err != nil {
+			return fmt.Errorf("archive segment %s: %w", name, err)
+		}
+		fn(&r)
+	}
+	return nil
+}
+
+// matches 判断索引行对应的消息是否可能满足查询
+func (e *indexEntry) matches(q *Query, conversationID string) bool {
+	if conversationID != "" {
+		if e.ConversationID != conversationID {
+			return false
+		}
+	} else {
+		i := sort.SearchStrings(e.Users, q.UserID)
+		if i == len(e.Users) || e.Users[i] != q.UserID {
+			return false
+		}
+	}
+	if q.Since != nil && e.MaxTime.Before(*q.Since) {
+		return false
+	}
+	if q.Until != nil && !e.MinTime.Before(*q.Until) {
+		return false
+	}
+	if q.Before != "" && e.MinID >= q.Before {
+		return false
+	}
+	if q.After != "" && e.MaxID <= q.After {
+		return false
+	}
+	return true
+}
+
+// matches 判断消息是否满足查询
+func (r *Record) matches(q *Query, conversationID string) bool {
+	if conversationID != "" {
+		if r.ConversationID != conversationID {
+			return false
+		}
+	} else if r.FromID != q.UserID && r.ToID != q.UserID {
+		return false
+	}
+	if len(q.Types) > 0 {
+		ok := false
+		for _, t := range q.Types {
+			if r.Type == t {
+				ok = true
+				break
+			}
+		}
+		if !ok {
+			return false
+		}
+	}
+	if q.Since != nil && r.CreatedAt.Before(*q.Since) {
+		return false
+	}
+	if q.Until != nil && !r.CreatedAt.Before(*q.Until) {
+		return false
+	}
+	if q.Before != "" && r.ID >= q.Before {
+		return false
+	}
+	if q.After != "" && r.ID <= q.After {
+		return false
+	}
+	return true
+}
+
+// Action 删除用户数据时对一条归档消息的处理方式
+type Action int
+
+const (
+	// Keep 保留消息
+	Keep Action = iota
+	// Drop 删除消息
+	Drop
+	// Scrub 清空消息内容和附件，标记为已撤回
+	Scrub
+)
+
+// EraseUser 按 decide 的结果删除或清空与用户相关的归档消息，返回处理的消息数量。
+//
+// 分段写入后原则上不再修改，这是唯一会重写分段的操作，用于满足用户数据删除的要求。
+// 每个分段先写临时文件再替换，最后整体替换索引。
+func (a *Archive) EraseUser(userID string, decide func(r *Record) Action) (int, error) {
+	a.mutex.Lock()
+	defer a.mutex.Unlock()
+
+	var segments []string
+	seen := map[string]struct{}{}
+	for _, e := range a.entries {
+		i := sort.SearchStrings(e.Users, userID)
+		if i == len(e.Users) || e.Users[i] != userID {
+			continue
+		}
+		if _, ok := seen[e.Segment]; !ok {
+			seen[e.Segment] = struct{}{}
+			segments = append(segments, e.Segment)
+		}
+	}
+
+	affected := 0
+	rewritten := map[string][]*indexEntry{}
+	for _, segment := range segments {
+		var records []*Record
+		changed := 0
+		err := a.readSegment(segment, func(r *Record) {
+			switch decide(r) {
+			case Drop:
+				changed++
+				return
+			case Scrub:
+				r.Content = ""
+				r.Attachments = nil
+				r.Status = models.MessageStatusRecalled
+				changed++
+			}
+			records = append(records, r)
+		})
+		if err != nil {
+			return affected, err
+		}
+		if changed == 0 {
+			continue
+		}
+
+		if len(records) == 0 {
+			if err := os.Remove(filepath.Join(a.dir, segmentDir, segment)); err != nil && !os.IsNotExist(err) {
+				return affected, err
+			}
+		} else if err := a.writeSegment(segment, records); err != nil {
+			return affected, err
+		}
+		rewritten[segment] = buildEntries(segment, records)
+		affected += changed
+	}
+	if len(rewritten) == 0 {
+		return 0, nil
+	}
+
+	entries := make([]*indexEntry, 0, len(a.entries))
+	for _, e := range a.entries {
+		if _, ok := rewritten[e.Segment]; !ok {
+			entries = append(entries, e)
+		}
+	}
+	for _, segment := range segments {
+		entries = append(entries, rewritten[segment]...)
+	}
+	if err := a.writeIndex(entries); err != nil {
+		return affected, err
+	}
+	a.entries = entries
+	return affected, nil
+}
+
+// writeIndex 整体替换索引文件
+func (a *Archive) writeIndex(entries []*indexEntry) error {
+	path := filepath.Join(a.dir, indexFile)
+	tmp, err := os.CreateTemp(a.dir, indexFile+".tmp-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	enc := json.NewEncoder(tmp)
+	for _, e := range entries {
+		if err := enc.Encode(e); err != nil {
+			tmp.Close()
+			return err
+		}
+	}
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
@@ -0,0 +1,124 @@
+package archive_test
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"github.com/woxQAQ/gim/internal/archive"
+	"github.com/woxQAQ/gim/internal/models"
+	"github.com/woxQAQ/gim/internal/types"
+)
+
+var _ = Describe("Archive", func() {
+	var (
+		dir  string
+		a    *archive.Archive
+		base time.Time
+	)
+
+	message := func(i int, from, to string, typ types.MessageType) *models.Message {
+		return &models.Message{
+			ID:             fmt.Sprintf("%d", 1000+i),
+			ConversationID: models.DirectConversationID(from, to),
+			Type:           typ,
+			Content:        fmt.Sprintf("msg-%d", i),
+			FromID:         from,
+			ToID:           to,
+			Status:         models.MessageStatusSent,
+			CreatedAt:      base.Add(time.Duration(i) * time.Minute),
+		}
+	}
+
+	ids := func(messages []*models.Message) []string {
+		out := make([]string, 0, len(messages))
+		for _, m := range messages {
+			out = append(out, m.ID)
+		}
+		return out
+	}
+
+	BeforeEach(func() {
+		dir = GinkgoT().TempDir()
+		base = time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+		var err error
+		a, err = archive.Open(dir)
+		Expect(err).NotTo(HaveOccurred())
+
+		// 两次归档写入两个分段
+		Expect(a.Archive(context.Background(), []*models.Message{
+			message(1, "alice", "bob", types.MessageTypeText),
+			message(2, "bob", "alice", types.MessageTypeImage),
+			message(3, "alice", "carol", types.MessageTypeText),
+		}, []*models.MessageAttachment{
+			{ID: "att-1", MessageID: "1002", Type: "image", URL: "a.png"},
+		})).To(Succeed())
+		Expect(a.Archive(context.Background(), []*models.Message{
+			message(4, "alice", "bob", types.MessageTypeText),
+			message(5, "bob", "alice", types.MessageTypeText),
+		}, nil)).To(Succeed())
+	})
+
+	It("应该为每次归档写入独立的压缩分段", func() {
+		segments, err := filepath.Glob(filepath.Join(dir, "segments", "*.jsonl.gz"))
+		Expect(err).NotTo(HaveOccurred())
+		Expect(segments).To(HaveLen(2))
+		Expect(filepath.Join(dir, "index.jsonl")).To(BeAnExistingFile())
+	})
+
+	It("应该按会话和游标倒序翻页", func() {
+		page, err := a.ListMessages(&archive.Query{UserID: "alice", PeerID: "bob", Limit: 2})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(ids(page)).To(Equal([]string{"1005", "1004"}))
+
+		page, err = a.ListMessages(&archive.Query{UserID: "alice", PeerID: "bob", Before: "1004", Limit: 2})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(ids(page)).To(Equal([]string{"1002", "1001"}))
+
+		page, err = a.ListMessages(&archive.Query{UserID: "alice", PeerID: "bob", After: "1002", Limit: 10})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(ids(page)).To(Equal([]string{"1004", "1005"}))
+	})
+
+	It("应该支持用户、类型和时间范围过滤", func() {
+		page, err := a.ListMessages(&archive.Query{UserID: "carol"})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(ids(page)).To(Equal([]string{"1003"}))
+
+		page, err = a.ListMessages(&archive.Query{UserID: "alice", Types: []types.MessageType{types.MessageTypeImage}})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(ids(page)).To(Equal([]string{"1002"}))
+
+		since, until := base.Add(3*time.Minute), base.Add(5*time.Minute)
+		page, err = a.ListMessages(&archive.Query{UserID: "alice", Since: &since, Until: &until})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(ids(page)).To(Equal([]string{"1004", "1003"}))
+	})
+
+	It("重新打开后应该继续追加且重复归档的消息只返回一次", func() {
+		reopened, err := archive.Open(dir)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(reopened.Archive(context.Background(), []*models.Message{
+			message(5, "bob", "alice", types.MessageTypeText),
+			message(6, "alice", "bob", types.MessageTypeText),
+		}, nil)).To(Succeed())
+
+		entries, err := os.ReadDir(filepath.Join(dir, "segments"))
+		Expect(err).NotTo(HaveOccurred())
+		Expect(entries).To(HaveLen(3))
+
+		page, err := reopened.ListMessages(&archive.Query{ConversationID: models.DirectConversationID("alice", "bob")})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(ids(page)).To(Equal([]string{"1006", "1005", "1004", "1002", "1001"}))
+	})
+
+	It("before 与 after 同时使用应该返回错误", func() {
+		_, err := a.ListMessages(&archive.Query{UserID: "alice", Before: "1005", After: "1001"})
+		Expect(err).To(MatchError(archive.ErrInvalidQuery))
+	})
+})
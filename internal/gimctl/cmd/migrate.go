@@ -0,0 +1,127 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"text/tabwriter"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"github.com/woxQAQ/gim/internal/migrations"
+	"github.com/woxQAQ/gim/pkg/db"
+)
+
+// migrateCmd 数据库迁移命令
+var migrateCmd = &cobra.Command{
+	Use:   "migrate",
+	Short: "管理数据库结构版本",
+	Long: `migrate 命令用于执行、回滚和查看数据库迁移。
+
+示例：
+  gimctl migrate status
+  gimctl migrate up
+  gimctl migrate down --steps 2
+  gimctl --db-driver postgres --db-dsn "host=localhost user=gim dbname=gim" migrate up`,
+}
+
+var migrateUpCmd = &cobra.Command{
+	Use:   "up",
+	Short: "执行全部未执行的迁移",
+	Args:  cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		m, err := newMigrator()
+		if err != nil {
+			return err
+		}
+		applied, err := m.Up()
+		for _, mig := range applied {
+			fmt.Printf("已执行 %d_%s\n", mig.Version, mig.Name)
+		}
+		if err != nil {
+			return err
+		}
+		if len(applied) == 0 {
+			fmt.Println("数据库已是最新版本")
+		}
+		return nil
+	},
+}
+
+var migrateDownCmd = &cobra.Command{
+	Use:   "down",
+	Short: "回滚最近执行的迁移",
+	Args:  cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		steps, _ := cmd.Flags().GetInt("steps")
+		if steps <= 0 {
+			return fmt.Errorf("steps must be positive")
+		}
+		m, err := newMigrator()
+		if err != nil {
+			return err
+		}
+		rolled, err := m.Down(steps)
+		for _, mig := range rolled {
+			fmt.Printf("已回滚 %d_%s\n", mig.Version, mig.Name)
+		}
+		if err != nil {
+			return err
+		}
+		if len(rolled) == 0 {
+			fmt.Println("没有可回滚的迁移")
+		}
+		return nil
+	},
+}
+
+var migrateStatusCmd = &cobra.Command{
+	Use:   "status",
+	Short: "查看迁移执行状态",
+	Args:  cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		m, err := newMigrator()
+		if err != nil {
+			return err
+		}
+		statuses, err := m.Status()
+		if err != nil {
+			return err
+		}
+
+		w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(w, "VERSION\tNAME\tSTATUS\tAPPLIED AT")
+		for _, s := range statuses {
+			status, appliedAt := "pending", "-"
+			if s.Applied {
+				status = "applied"
+				appliedAt = s.AppliedAt.Format(time.RFC3339)
+			}
+			if s.Unknown {
+				status = "unknown"
+			}
+			fmt.Fprintf(w, "%d\t%s\t%s\t%s\n", s.Version, s.Name, status, appliedAt)
+		}
+		if err := w.Flush(); err != nil {
+			return err
+		}
+
+		return m.Check()
+	},
+}
+
+// newMigrator 打开数据库并创建迁移执行器
+func newMigrator() (*db.Migrator, error) {
+	gdb, err := openDB()
+	if err != nil {
+		return nil, err
+	}
+	return db.NewMigrator(gdb, migrations.All())
+}
+
+func init() {
+	migrateDownCmd.Flags().Int("steps", 1, "回滚的迁移数量")
+
+	migrateCmd.AddCommand(migrateUpCmd, migrateDownCmd, migrateStatusCmd)
+	rootCmd.AddCommand(migrateCmd)
+}
@@ -0,0 +1,51 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+	"gorm.io/gorm"
+
+	"github.com/woxQAQ/gim/pkg/constants"
+	"github.com/woxQAQ/gim/pkg/db"
+)
+
+// rootCmd gimctl 的根命令
+var rootCmd = &cobra.Command{
+	Use:   "gimctl",
+	Short: "gim 运维命令行工具",
+	// 禁用completion命令
+	CompletionOptions: cobra.CompletionOptions{
+		DisableDefaultCmd: true,
+	},
+	SilenceUsage: true,
+}
+
+func init() {
+	flags := rootCmd.PersistentFlags()
+	flags.String("db-driver", "sqlite", "数据库驱动 (sqlite, postgres, mysql)")
+	flags.String("db-dsn", "gim.db", "数据源名称，SQLite为数据库文件路径")
+	_ = viper.BindPFlag(constants.DBDriver, flags.Lookup("db-driver"))
+	_ = viper.BindPFlag(constants.DBDSN, flags.Lookup("db-dsn"))
+
+	// 与 apiserver 使用相同的环境变量
+	viper.AutomaticEnv()
+}
+
+// openDB 按命令行参数或环境变量打开数据库，不执行迁移
+func openDB() (*gorm.DB, error) {
+	return db.Open(&db.Config{
+		Driver: viper.GetString(constants.DBDriver),
+		DSN:    viper.GetString(constants.DBDSN),
+	})
+}
+
+// Execute 执行根命令
+func Execute() {
+	if err := rootCmd.Execute(); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+}
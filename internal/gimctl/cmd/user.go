@@ -0,0 +1,129 @@
+package cmd
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+
+	"github.com/woxQAQ/gim/internal/apiserver/stores"
+	"github.com/woxQAQ/gim/internal/models"
+	"github.com/woxQAQ/gim/pkg/constants"
+	"github.com/woxQAQ/gim/pkg/snowflake"
+)
+
+// userCmd 用户管理命令
+var userCmd = &cobra.Command{
+	Use:   "user",
+	Short: "管理用户账号和角色",
+	Long: `user 命令直接操作数据库，用于在没有管理员时创建第一个管理员。
+apiserver 启用了用户缓存时，set-role 修改的角色在缓存过期后生效。
+create 的密码从环境变量 GIMCTL_PASSWORD 读取，未设置时从标准输入读取一行，
+避免密码出现在命令行参数和 shell 历史中。
+
+示例：
+  gimctl user create --username root --role admin
+  printf '%s\n' "$ROOT_PASSWORD" | gimctl user create --username root
+  gimctl user set-role alice moderator`,
+}
+
+var userCreateCmd = &cobra.Command{
+	Use:   "create",
+	Short: "创建用户",
+	Args:  cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		username, _ := cmd.Flags().GetString("username")
+		role, _ := cmd.Flags().GetString("role")
+		if username == "" {
+			return errors.New("username is required")
+		}
+		password, err := readPassword(cmd.InOrStdin(), cmd.ErrOrStderr())
+		if err != nil {
+			return err
+		}
+		if password == "" {
+			return errors.New("password is required")
+		}
+		if !models.ValidRole(role) {
+			return fmt.Errorf("unknown role %q", role)
+		}
+		if err := snowflake.InitGenerator(viper.GetInt64(constants.NodeID)); err != nil {
+			return err
+		}
+		userStore, err := newUserStore()
+		if err != nil {
+			return err
+		}
+
+		user := &models.User{
+			ID:       snowflake.GenerateID(),
+			Username: username,
+			Password: password,
+			Role:     role,
+		}
+		if err := userStore.CreateUser(user); err != nil {
+			return err
+		}
+		fmt.Printf("已创建用户 %s (%s)，角色 %s\n", user.Username, user.ID, user.Role)
+		return nil
+	},
+}
+
+var userSetRoleCmd = &cobra.Command{
+	Use:   "set-role USERNAME ROLE",
+	Short: "修改用户角色",
+	Args:  cobra.ExactArgs(2),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		username, role := args[0], args[1]
+		if !models.ValidRole(role) {
+			return fmt.Errorf("unknown role %q", role)
+		}
+		userStore, err := newUserStore()
+		if err != nil {
+			return err
+		}
+		user, err := userStore.GetUserByUsername(username)
+		if err != nil {
+			return err
+		}
+		if err := userStore.UpdateRole(user.ID, role); err != nil {
+			return err
+		}
+		fmt.Printf("用户 %s (%s) 的角色已修改为 %s\n", user.Username, user.ID, role)
+		return nil
+	},
+}
+
+// readPassword 优先使用环境变量中的密码，否则提示并从 in 读取一行
+func readPassword(in io.Reader, prompt io.Writer) (string, error) {
+	if password := viper.GetString(constants.GimctlPassword); password != "" {
+		return password, nil
+	}
+	fmt.Fprint(prompt, "密码: ")
+	line, err := bufio.NewReader(in).ReadString('\n')
+	if err != nil && !errors.Is(err, io.EOF) {
+		return "", err
+	}
+	return strings.TrimRight(line, "\r\n"), nil
+}
+
+// newUserStore 打开数据库并创建用户存储，不使用缓存
+func newUserStore() (*stores.UserStore, error) {
+	gdb, err := openDB()
+	if err != nil {
+		return nil, err
+	}
+	return stores.NewUserStore(gdb), nil
+}
+
+func init() {
+	userCreateCmd.Flags().String("username", "", "用户名")
+	userCreateCmd.Flags().String("role", models.RoleAdmin, "角色 (user, moderator, admin)")
+
+	userCmd.AddCommand(userCreateCmd, userSetRoleCmd)
+	rootCmd.AddCommand(userCmd)
+}
@@ -0,0 +1,31 @@
+// Package migrations 定义数据库结构的版本化迁移。
+//
+// 每个迁移使用各自的结构快照，而不是 internal/models 中的模型，
+// 这样模型后续的变更不会改变已发布迁移的行为。
+// 新增迁移时在 All 的末尾追加，版本号必须递增，已发布的迁移不要修改。
+package migrations
+
+import (
+	"github.com/woxQAQ/gim/pkg/db"
+)
+
+// All 返回全部迁移，按版本号升序
+func All() []db.Migration {
+	return []db.Migration{
+		v1InitialSchema(),
+		v2MessageConversations(),
+		v3MessageSearch(),
+		v4Retention(),
+		v5DataRequests(),
+		v6UserUnique(),
+		v7Sessions(),
+		v8Contacts(),
+		v9Blocks(),
+		v10UserBans(),
+		v11UserRoles(),
+		v12AuditLogs(),
+		v13LoginAttempts(),
+		v14EmailVerification(),
+		v15APIKeys(),
+	}
+}
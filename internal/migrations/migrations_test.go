@@ -0,0 +1,62 @@
+package migrations_test
+
+import (
+	"path/filepath"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"gorm.io/gorm"
+
+	"github.com/woxQAQ/gim/internal/migrations"
+	"github.com/woxQAQ/gim/pkg/db"
+)
+
+var _ = Describe("Migrations", func() {
+	var gdb *gorm.DB
+
+	BeforeEach(func() {
+		var err error
+		gdb, err = db.Open(&db.Config{DSN: filepath.Join(GinkgoT().TempDir(), "migrations.db")})
+		Expect(err).NotTo(HaveOccurred())
+	})
+
+	It("应该能完整执行并回滚全部迁移", func() {
+		m, err := db.NewMigrator(gdb, migrations.All())
+		Expect(err).NotTo(HaveOccurred())
+
+		_, err = m.Up()
+		Expect(err).NotTo(HaveOccurred())
+		Expect(gdb.Migrator().HasTable("messages")).To(BeTrue())
+
+		_, err = m.Down(len(migrations.All()))
+		Expect(err).NotTo(HaveOccurred())
+		Expect(gdb.Migrator().HasTable("messages")).To(BeFalse())
+	})
+
+	It("应该为已有消息回填会话ID", func() {
+		v1, err := db.NewMigrator(gdb, migrations.All()[:1])
+		Expect(err).NotTo(HaveOccurred())
+		_, err = v1.Up()
+		Expect(err).NotTo(HaveOccurred())
+
+		Expect(gdb.Exec(
+			"INSERT INTO messages (id, type, content, from_id, to_id, status, platform) VALUES (?, ?, ?, ?, ?, ?, ?)",
+			"1", 3, "hi", "bob", "alice", 1, 1,
+		).Error).To(Succeed())
+
+		m, err := db.NewMigrator(gdb, migrations.All()[:2])
+		Expect(err).NotTo(HaveOccurred())
+		_, err = m.Up()
+		Expect(err).NotTo(HaveOccurred())
+
+		var conversationID string
+		Expect(gdb.Raw("SELECT conversation_id FROM messages WHERE id = ?", "1").Scan(&conversationID).Error).To(Succeed())
+		Expect(conversationID).To(Equal("d:alice:bob"))
+		Expect(gdb.Migrator().HasIndex("messages", "idx_messages_conversation")).To(BeTrue())
+		Expect(gdb.Migrator().HasIndex("messages", "idx_messages_from_id")).To(BeFalse())
+
+		_, err = m.Down(1)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(gdb.Migrator().HasColumn("messages", "conversation_id")).To(BeFalse())
+	})
+})
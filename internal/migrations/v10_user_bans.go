@@ -0,0 +1,36 @@
+package migrations
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+
+	"github.com/woxQAQ/gim/pkg/db"
+)
+
+// v10UserBans 创建用户封禁记录表
+func v10UserBans() db.Migration {
+	return db.Migration{
+		Version: 10,
+		Name:    "user_bans",
+		Up: func(tx *gorm.DB) error {
+			return tx.AutoMigrate(&v10UserBan{})
+		},
+		Down: func(tx *gorm.DB) error {
+			return tx.Migrator().DropTable(&v10UserBan{})
+		},
+	}
+}
+
+type v10UserBan struct {
+	ID        string `gorm:"primaryKey;type:varchar(64)"`
+	UserID    string `gorm:"type:varchar(64);not null;index"`
+	Reason    string `gorm:"type:varchar(256);not null;default:''"`
+	ExpiresAt *time.Time
+	CreatedBy string `gorm:"type:text"`
+	LiftedAt  *time.Time
+	LiftedBy  string    `gorm:"type:text"`
+	CreatedAt time.Time `gorm:"autoCreateTime"`
+}
+
+func (v10UserBan) TableName() string { return "user_bans" }
@@ -0,0 +1,41 @@
+package migrations
+
+import (
+	"gorm.io/gorm"
+
+	"github.com/woxQAQ/gim/pkg/db"
+)
+
+// v11UserRoles 为用户增加角色，已有用户均为普通用户
+func v11UserRoles() db.Migration {
+	return db.Migration{
+		Version: 11,
+		Name:    "user_roles",
+		Up: func(tx *gorm.DB) error {
+			m := tx.Migrator()
+			if !m.HasColumn(&v11User{}, "Role") {
+				if err := m.AddColumn(&v11User{}, "Role"); err != nil {
+					return err
+				}
+			}
+			return m.CreateIndex(&v11User{}, "idx_users_role")
+		},
+		Down: func(tx *gorm.DB) error {
+			m := tx.Migrator()
+			if m.HasIndex(&v11User{}, "idx_users_role") {
+				if err := m.DropIndex(&v11User{}, "idx_users_role"); err != nil {
+					return err
+				}
+			}
+			// SQLite 的 Migrator.DropColumn 会重建表并丢失 users 上的部分索引
+			return tx.Exec("ALTER TABLE users DROP COLUMN role").Error
+		},
+	}
+}
+
+type v11User struct {
+	ID   string `gorm:"primaryKey;type:varchar(64)"`
+	Role string `gorm:"type:varchar(16);not null;default:'user';index:idx_users_role"`
+}
+
+func (v11User) TableName() string { return "users" }
@@ -0,0 +1,38 @@
+package migrations
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+
+	"github.com/woxQAQ/gim/pkg/db"
+)
+
+// v12AuditLogs 创建审计日志表
+func v12AuditLogs() db.Migration {
+	return db.Migration{
+		Version: 12,
+		Name:    "audit_logs",
+		Up: func(tx *gorm.DB) error {
+			return tx.AutoMigrate(&v12AuditLog{})
+		},
+		Down: func(tx *gorm.DB) error {
+			return tx.Migrator().DropTable(&v12AuditLog{})
+		},
+	}
+}
+
+type v12AuditLog struct {
+	ID        string    `gorm:"primaryKey;type:varchar(64)"`
+	Seq       int64     `gorm:"not null;uniqueIndex:idx_audit_logs_seq"`
+	Action    string    `gorm:"type:varchar(64);not null;index:idx_audit_logs_action,priority:1"`
+	ActorID   string    `gorm:"type:varchar(64);not null;default:'';index:idx_audit_logs_actor,priority:1"`
+	TargetID  string    `gorm:"type:varchar(128);not null;default:''"`
+	IP        string    `gorm:"type:varchar(64);not null;default:''"`
+	Detail    string    `gorm:"type:text"`
+	PrevHash  string    `gorm:"type:varchar(64);not null;default:''"`
+	Hash      string    `gorm:"type:varchar(64);not null;default:''"`
+	CreatedAt time.Time `gorm:"not null;index:idx_audit_logs_action,priority:2;index:idx_audit_logs_actor,priority:2;index"`
+}
+
+func (v12AuditLog) TableName() string { return "audit_logs" }
@@ -0,0 +1,33 @@
+package migrations
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+
+	"github.com/woxQAQ/gim/pkg/db"
+)
+
+// v13LoginAttempts 创建登录和注册的失败尝试计数表
+func v13LoginAttempts() db.Migration {
+	return db.Migration{
+		Version: 13,
+		Name:    "login_attempts",
+		Up: func(tx *gorm.DB) error {
+			return tx.AutoMigrate(&v13LoginAttempt{})
+		},
+		Down: func(tx *gorm.DB) error {
+			return tx.Migrator().DropTable(&v13LoginAttempt{})
+		},
+	}
+}
+
+type v13LoginAttempt struct {
+	ID            string    `gorm:"primaryKey;type:varchar(191)"`
+	Failures      int       `gorm:"not null;default:0"`
+	LastFailureAt time.Time `gorm:"index"`
+	LockedUntil   *time.Time
+	UpdatedAt     time.Time `gorm:"autoUpdateTime"`
+}
+
+func (v13LoginAttempt) TableName() string { return "login_attempts" }
@@ -0,0 +1,35 @@
+package migrations
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+
+	"github.com/woxQAQ/gim/pkg/db"
+)
+
+// v14EmailVerification 记录用户邮箱的验证时间，已有用户的邮箱均为未验证
+func v14EmailVerification() db.Migration {
+	return db.Migration{
+		Version: 14,
+		Name:    "email_verification",
+		Up: func(tx *gorm.DB) error {
+			m := tx.Migrator()
+			if m.HasColumn(&v14User{}, "EmailVerifiedAt") {
+				return nil
+			}
+			return m.AddColumn(&v14User{}, "EmailVerifiedAt")
+		},
+		Down: func(tx *gorm.DB) error {
+			// SQLite 的 Migrator.DropColumn 会重建表并丢失 users 上的部分索引
+			return tx.Exec("ALTER TABLE users DROP COLUMN email_verified_at").Error
+		},
+	}
+}
+
+type v14User struct {
+	ID              string `gorm:"primaryKey;type:varchar(64)"`
+	EmailVerifiedAt *time.Time
+}
+
+func (v14User) TableName() string { return "users" }
@@ -0,0 +1,39 @@
+package migrations
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+
+	"github.com/woxQAQ/gim/pkg/db"
+)
+
+// v15APIKeys 创建机器人账号的 API 密钥表
+func v15APIKeys() db.Migration {
+	return db.Migration{
+		Version: 15,
+		Name:    "api_keys",
+		Up: func(tx *gorm.DB) error {
+			return tx.AutoMigrate(&v15APIKey{})
+		},
+		Down: func(tx *gorm.DB) error {
+			return tx.Migrator().DropTable(&v15APIKey{})
+		},
+	}
+}
+
+type v15APIKey struct {
+	ID         string `gorm:"primaryKey;type:varchar(64)"`
+	UserID     string `gorm:"type:varchar(64);not null;index"`
+	Name       string `gorm:"type:varchar(64);not null;default:''"`
+	Prefix     string `gorm:"type:varchar(16);not null"`
+	Hash       string `gorm:"type:varchar(64);not null;uniqueIndex:idx_api_keys_hash"`
+	Scopes     string `gorm:"type:text;not null"`
+	CreatedBy  string `gorm:"type:text"`
+	ExpiresAt  *time.Time
+	RevokedAt  *time.Time
+	LastUsedAt *time.Time
+	CreatedAt  time.Time `gorm:"autoCreateTime"`
+}
+
+func (v15APIKey) TableName() string { return "api_keys" }
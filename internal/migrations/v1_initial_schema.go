@@ -0,0 +1,189 @@
+package migrations
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+
+	"github.com/woxQAQ/gim/pkg/db"
+)
+
+// v1InitialSchema 初始表结构。
+// 对于此前由 AutoMigrate 创建的数据库，该迁移只会补齐缺失的表和列，可以直接接管。
+func v1InitialSchema() db.Migration {
+	tables := []interface{}{
+		&v1User{},
+		&v1Message{},
+		&v1MessageAttachment{},
+		&v1WebhookEndpoint{},
+		&v1WebhookDelivery{},
+		&v1WebhookDeadLetter{},
+		&v1ModerationRecord{},
+		&v1Group{},
+		&v1GroupMember{},
+		&v1SystemNotice{},
+		&v1SystemNoticeRecipient{},
+	}
+	return db.Migration{
+		Version: 1,
+		Name:    "initial_schema",
+		Up: func(tx *gorm.DB) error {
+			return tx.AutoMigrate(tables...)
+		},
+		Down: func(tx *gorm.DB) error {
+			for i := len(tables) - 1; i >= 0; i-- {
+				if err := tx.Migrator().DropTable(tables[i]); err != nil {
+					return err
+				}
+			}
+			return nil
+		},
+	}
+}
+
+type v1User struct {
+	ID        string    `gorm:"primaryKey;type:varchar(64)"`
+	Username  string    `gorm:"type:text;not null"`
+	Password  string    `gorm:"type:text;not null"`
+	Nickname  string    `gorm:"type:varchar(64);default:''"`
+	Avatar    string    `gorm:"type:varchar(512);default:''"`
+	Gender    int8      `gorm:"type:smallint;default:0"`
+	Phone     string    `gorm:"type:varchar(32);index"`
+	Email     string    `gorm:"type:varchar(255);index"`
+	Status    int8      `gorm:"type:smallint;default:1;index"`
+	Bio       string    `gorm:"type:varchar(512);default:''"`
+	LastLogin time.Time `gorm:"index"`
+	CreatedAt time.Time `gorm:"autoCreateTime"`
+	UpdatedAt time.Time `gorm:"autoUpdateTime"`
+}
+
+func (v1User) TableName() string { return "users" }
+
+type v1Message struct {
+	ID        string    `gorm:"primaryKey;type:varchar(64)"`
+	Type      int       `gorm:"type:smallint;not null;index"`
+	Content   string    `gorm:"type:text;not null"`
+	FromID    string    `gorm:"type:varchar(64);not null;index"`
+	ToID      string    `gorm:"type:varchar(64);not null;index"`
+	Status    int8      `gorm:"type:smallint;not null;default:1;index"`
+	Platform  int32     `gorm:"type:integer;not null;index"`
+	CreatedAt time.Time `gorm:"autoCreateTime;index"`
+	UpdatedAt time.Time `gorm:"autoUpdateTime"`
+}
+
+func (v1Message) TableName() string { return "messages" }
+
+type v1MessageAttachment struct {
+	ID        string    `gorm:"primaryKey;type:varchar(64)"`
+	MessageID string    `gorm:"type:varchar(64);not null;index"`
+	Type      string    `gorm:"type:text;not null"`
+	URL       string    `gorm:"type:text;not null"`
+	Size      int64     `gorm:"type:bigint"`
+	MimeType  string    `gorm:"type:text"`
+	Metadata  string    `gorm:"type:text"`
+	CreatedAt time.Time `gorm:"autoCreateTime"`
+	UpdatedAt time.Time `gorm:"autoUpdateTime"`
+}
+
+func (v1MessageAttachment) TableName() string { return "message_attachments" }
+
+type v1WebhookEndpoint struct {
+	ID        string    `gorm:"primaryKey;type:varchar(64)"`
+	URL       string    `gorm:"type:text;not null"`
+	Secret    string    `gorm:"type:text;not null"`
+	Events    string    `gorm:"type:text;not null"`
+	Enabled   bool      `gorm:"not null;default:true;index"`
+	CreatedAt time.Time `gorm:"autoCreateTime"`
+	UpdatedAt time.Time `gorm:"autoUpdateTime"`
+}
+
+func (v1WebhookEndpoint) TableName() string { return "webhook_endpoints" }
+
+type v1WebhookDelivery struct {
+	ID            string    `gorm:"primaryKey;type:varchar(64)"`
+	EndpointID    string    `gorm:"type:varchar(64);not null;index"`
+	Event         string    `gorm:"type:varchar(64);not null;index"`
+	Payload       string    `gorm:"type:text;not null"`
+	Status        int8      `gorm:"type:smallint;not null;default:0;index:idx_webhook_delivery_due,priority:1"`
+	Attempts      int       `gorm:"type:integer;not null;default:0"`
+	NextAttemptAt time.Time `gorm:"index:idx_webhook_delivery_due,priority:2"`
+	ResponseCode  int       `gorm:"type:integer"`
+	LastError     string    `gorm:"type:text"`
+	CreatedAt     time.Time `gorm:"autoCreateTime;index"`
+	UpdatedAt     time.Time `gorm:"autoUpdateTime"`
+}
+
+func (v1WebhookDelivery) TableName() string { return "webhook_deliveries" }
+
+type v1WebhookDeadLetter struct {
+	ID         string    `gorm:"primaryKey;type:varchar(64)"`
+	EndpointID string    `gorm:"type:varchar(64);not null;index"`
+	Event      string    `gorm:"type:text;not null"`
+	Payload    string    `gorm:"type:text;not null"`
+	Attempts   int       `gorm:"type:integer;not null"`
+	LastError  string    `gorm:"type:text"`
+	CreatedAt  time.Time `gorm:"autoCreateTime;index"`
+}
+
+func (v1WebhookDeadLetter) TableName() string { return "webhook_dead_letters" }
+
+type v1ModerationRecord struct {
+	ID         string `gorm:"primaryKey;type:varchar(64)"`
+	MessageID  string `gorm:"type:varchar(64);not null;index"`
+	FromID     string `gorm:"type:varchar(64);not null;index"`
+	ToID       string `gorm:"type:text;not null"`
+	Content    string `gorm:"type:text;not null"`
+	Rules      string `gorm:"type:text;not null"`
+	Status     int8   `gorm:"type:smallint;not null;default:0;index"`
+	ReviewerID string `gorm:"type:text"`
+	ReviewedAt *time.Time
+	CreatedAt  time.Time `gorm:"autoCreateTime;index"`
+	UpdatedAt  time.Time `gorm:"autoUpdateTime"`
+}
+
+func (v1ModerationRecord) TableName() string { return "moderation_records" }
+
+type v1Group struct {
+	ID        string    `gorm:"primaryKey;type:varchar(64)"`
+	Name      string    `gorm:"type:text;not null"`
+	OwnerID   string    `gorm:"type:varchar(64);not null;index"`
+	CreatedAt time.Time `gorm:"autoCreateTime"`
+	UpdatedAt time.Time `gorm:"autoUpdateTime"`
+}
+
+func (v1Group) TableName() string { return "groups" }
+
+type v1GroupMember struct {
+	GroupID  string    `gorm:"primaryKey;type:varchar(64)"`
+	UserID   string    `gorm:"primaryKey;type:varchar(64);index"`
+	JoinedAt time.Time `gorm:"autoCreateTime"`
+}
+
+func (v1GroupMember) TableName() string { return "group_members" }
+
+type v1SystemNotice struct {
+	ID         string    `gorm:"primaryKey;type:varchar(64)"`
+	Title      string    `gorm:"type:text;not null"`
+	Content    string    `gorm:"type:text;not null"`
+	TargetType string    `gorm:"type:text;not null"`
+	TargetIDs  string    `gorm:"type:text"`
+	PlatformID int32     `gorm:"type:integer"`
+	Persist    bool      `gorm:"not null;default:false"`
+	Status     int8      `gorm:"type:smallint;not null;default:0;index:idx_notice_due,priority:1"`
+	DeliverAt  time.Time `gorm:"index:idx_notice_due,priority:2"`
+	SentAt     *time.Time
+	Delivered  int       `gorm:"type:integer;not null;default:0"`
+	LastError  string    `gorm:"type:text"`
+	CreatedBy  string    `gorm:"type:text"`
+	CreatedAt  time.Time `gorm:"autoCreateTime"`
+	UpdatedAt  time.Time `gorm:"autoUpdateTime"`
+}
+
+func (v1SystemNotice) TableName() string { return "system_notices" }
+
+type v1SystemNoticeRecipient struct {
+	NoticeID string `gorm:"primaryKey;type:varchar(64)"`
+	UserID   string `gorm:"primaryKey;type:varchar(64);index"`
+}
+
+func (v1SystemNoticeRecipient) TableName() string { return "system_notice_recipients" }
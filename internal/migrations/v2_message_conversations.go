@@ -0,0 +1,118 @@
+package migrations
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+
+	"github.com/woxQAQ/gim/pkg/db"
+)
+
+// v2MessageConversations 为消息增加会话ID并回填，
+// 将单列索引替换为与历史查询匹配的联合索引。
+func v2MessageConversations() db.Migration {
+	v1Indexes := []string{
+		"idx_messages_type",
+		"idx_messages_from_id",
+		"idx_messages_to_id",
+		"idx_messages_platform",
+		"idx_messages_created_at",
+	}
+	v2Indexes := []string{
+		"idx_messages_conversation",
+		"idx_messages_conversation_time",
+		"idx_messages_from",
+		"idx_messages_to",
+	}
+
+	return db.Migration{
+		Version: 2,
+		Name:    "message_conversations",
+		Up: func(tx *gorm.DB) error {
+			m := tx.Migrator()
+			if !m.HasColumn(&v2Message{}, "ConversationID") {
+				if err := m.AddColumn(&v2Message{}, "ConversationID"); err != nil {
+					return err
+				}
+			}
+			if err := backfillConversationIDs(tx); err != nil {
+				return err
+			}
+			for _, name := range v1Indexes {
+				if m.HasIndex(&v1Message{}, name) {
+					if err := m.DropIndex(&v1Message{}, name); err != nil {
+						return err
+					}
+				}
+			}
+			for _, name := range v2Indexes {
+				if err := m.CreateIndex(&v2Message{}, name); err != nil {
+					return err
+				}
+			}
+			return nil
+		},
+		Down: func(tx *gorm.DB) error {
+			m := tx.Migrator()
+			for _, name := range v2Indexes {
+				if m.HasIndex(&v2Message{}, name) {
+					if err := m.DropIndex(&v2Message{}, name); err != nil {
+						return err
+					}
+				}
+			}
+			for _, name := range v1Indexes {
+				if err := m.CreateIndex(&v1Message{}, name); err != nil {
+					return err
+				}
+			}
+			return m.DropColumn(&v2Message{}, "ConversationID")
+		},
+	}
+}
+
+// backfillConversationIDs 分批为历史消息回填单聊会话ID，
+// 会话ID在应用层计算以避免不同数据库字符串函数的差异。
+func backfillConversationIDs(tx *gorm.DB) error {
+	const batchSize = 500
+	for {
+		var rows []v2Message
+		err := tx.Select("id", "from_id", "to_id").
+			Where("conversation_id = ?", "").
+			Limit(batchSize).
+			Find(&rows).Error
+		if err != nil {
+			return err
+		}
+		for _, row := range rows {
+			a, b := row.FromID, row.ToID
+			if a > b {
+				a, b = b, a
+			}
+			err := tx.Model(&v2Message{}).
+				Where("id = ?", row.ID).
+				Update("conversation_id", "d:"+a+":"+b).Error
+			if err != nil {
+				return err
+			}
+		}
+		if len(rows) < batchSize {
+			return nil
+		}
+	}
+}
+
+type v2Message struct {
+	ID             string    `gorm:"primaryKey;type:varchar(64);index:idx_messages_conversation,priority:2;index:idx_messages_from,priority:2;index:idx_messages_to,priority:2"`
+	ConversationID string    `gorm:"type:varchar(160);not null;default:'';index:idx_messages_conversation,priority:1;index:idx_messages_conversation_time,priority:1"`
+	Type           int       `gorm:"type:smallint;not null"`
+	Content        string    `gorm:"type:text;not null"`
+	FromID         string    `gorm:"type:varchar(64);not null;index:idx_messages_from,priority:1"`
+	ToID           string    `gorm:"type:varchar(64);not null;index:idx_messages_to,priority:1"`
+	Status         int8      `gorm:"type:smallint;not null;default:1;index"`
+	Platform       int32     `gorm:"type:integer;not null"`
+	CreatedAt      time.Time `gorm:"autoCreateTime;index:idx_messages_conversation_time,priority:2"`
+	UpdatedAt      time.Time `gorm:"autoUpdateTime"`
+}
+
+func (v2Message) TableName() string { return "messages" }
@@ -0,0 +1,116 @@
+package migrations
+
+import (
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+
+	"github.com/woxQAQ/gim/pkg/db"
+)
+
+// v3MessageSearch 创建消息全文检索的索引表并回填已有消息，
+// 同时为消息更新时间建立索引供内存索引增量同步。
+// SQLite 需要以 sqlite_fts5 构建标签编译才能使用 FTS5，否则与 MySQL 一样跳过索引表，
+// 由 apiserver 使用内存索引。
+func v3MessageSearch() db.Migration {
+	return db.Migration{
+		Version: 3,
+		Name:    "message_search",
+		Up: func(tx *gorm.DB) error {
+			if err := tx.Exec("CREATE INDEX idx_messages_updated_at ON messages (updated_at)").Error; err != nil {
+				return err
+			}
+			switch tx.Dialector.Name() {
+			case "sqlite":
+				return v3UpSQLite(tx)
+			case "postgres":
+				return v3UpPostgres(tx)
+			default:
+				return nil
+			}
+		},
+		Down: func(tx *gorm.DB) error {
+			if tx.Dialector.Name() == "sqlite" {
+				for _, stmt := range []string{
+					"DROP TRIGGER IF EXISTS message_search_ai",
+					"DROP TRIGGER IF EXISTS message_search_ad",
+					"DROP TRIGGER IF EXISTS message_search_au",
+					"DROP TABLE IF EXISTS message_search_fts",
+				} {
+					if err := tx.Exec(stmt).Error; err != nil {
+						return err
+					}
+				}
+			}
+			if err := tx.Migrator().DropTable(&v3MessageSearchRow{}); err != nil {
+				return err
+			}
+			return tx.Migrator().DropIndex("messages", "idx_messages_updated_at")
+		},
+	}
+}
+
+func v3UpSQLite(tx *gorm.DB) error {
+	// 没有编译 FTS5 时跳过，不创建任何表
+	err := tx.Exec("CREATE VIRTUAL TABLE message_search_fts USING fts5(content, content='message_search', content_rowid='rowid', tokenize='trigram')").Error
+	if err != nil {
+		if strings.Contains(err.Error(), "no such module") {
+			return nil
+		}
+		return err
+	}
+
+	if err := tx.AutoMigrate(&v3MessageSearchRow{}); err != nil {
+		return err
+	}
+	for _, stmt := range []string{
+		`CREATE TRIGGER message_search_ai AFTER INSERT ON message_search BEGIN
+			INSERT INTO message_search_fts(rowid, content) VALUES (new.rowid, new.content);
+		END`,
+		`CREATE TRIGGER message_search_ad AFTER DELETE ON message_search BEGIN
+			INSERT INTO message_search_fts(message_search_fts, rowid, content) VALUES ('delete', old.rowid, old.content);
+		END`,
+		`CREATE TRIGGER message_search_au AFTER UPDATE ON message_search BEGIN
+			INSERT INTO message_search_fts(message_search_fts, rowid, content) VALUES ('delete', old.rowid, old.content);
+			INSERT INTO message_search_fts(rowid, content) VALUES (new.rowid, new.content);
+		END`,
+	} {
+		if err := tx.Exec(stmt).Error; err != nil {
+			return err
+		}
+	}
+	return v3Backfill(tx)
+}
+
+func v3UpPostgres(tx *gorm.DB) error {
+	if err := tx.AutoMigrate(&v3MessageSearchRow{}); err != nil {
+		return err
+	}
+	for _, stmt := range []string{
+		"ALTER TABLE message_search ADD COLUMN tsv tsvector GENERATED ALWAYS AS (to_tsvector('simple', content)) STORED",
+		"CREATE INDEX idx_message_search_tsv ON message_search USING GIN (tsv)",
+	} {
+		if err := tx.Exec(stmt).Error; err != nil {
+			return err
+		}
+	}
+	return v3Backfill(tx)
+}
+
+func v3Backfill(tx *gorm.DB) error {
+	return tx.Exec(`INSERT INTO message_search (id, conversation_id, from_id, to_id, type, content, created_at)
+		SELECT id, conversation_id, from_id, to_id, type, content, created_at FROM messages`).Error
+}
+
+type v3MessageSearchRow struct {
+	ID             string    `gorm:"primaryKey;type:varchar(64);index:idx_message_search_from,priority:2;index:idx_message_search_to,priority:2"`
+	ConversationID string    `gorm:"type:varchar(160);not null;index"`
+	FromID         string    `gorm:"type:varchar(64);not null;index:idx_message_search_from,priority:1"`
+	ToID           string    `gorm:"type:varchar(64);not null;index:idx_message_search_to,priority:1"`
+	Type           int       `gorm:"type:smallint;not null"`
+	Content        string    `gorm:"type:text;not null"`
+	CreatedAt      time.Time `gorm:"index"`
+}
+
+func (v3MessageSearchRow) TableName() string { return "message_search" }
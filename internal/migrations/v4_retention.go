@@ -0,0 +1,49 @@
+package migrations
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+
+	"github.com/woxQAQ/gim/pkg/db"
+)
+
+// v4Retention 创建消息保留策略和清理记录表
+func v4Retention() db.Migration {
+	return db.Migration{
+		Version: 4,
+		Name:    "retention",
+		Up: func(tx *gorm.DB) error {
+			return tx.AutoMigrate(&v4RetentionPolicy{}, &v4RetentionRun{})
+		},
+		Down: func(tx *gorm.DB) error {
+			return tx.Migrator().DropTable(&v4RetentionRun{}, &v4RetentionPolicy{})
+		},
+	}
+}
+
+type v4RetentionPolicy struct {
+	ID             string    `gorm:"primaryKey;type:varchar(64)"`
+	ConversationID string    `gorm:"type:varchar(160);not null;default:'';uniqueIndex:idx_retention_scope,priority:1"`
+	MessageType    int       `gorm:"type:smallint;not null;default:0;uniqueIndex:idx_retention_scope,priority:2"`
+	TTLSeconds     int64     `gorm:"not null;default:0"`
+	Action         string    `gorm:"type:varchar(16);not null"`
+	CreatedBy      string    `gorm:"type:text"`
+	CreatedAt      time.Time `gorm:"autoCreateTime"`
+	UpdatedAt      time.Time `gorm:"autoUpdateTime"`
+}
+
+func (v4RetentionPolicy) TableName() string { return "retention_policies" }
+
+type v4RetentionRun struct {
+	ID          string    `gorm:"primaryKey;type:varchar(64)"`
+	StartedAt   time.Time `gorm:"not null;index"`
+	FinishedAt  time.Time `gorm:"not null"`
+	Deleted     int64     `gorm:"not null;default:0"`
+	Archived    int64     `gorm:"not null;default:0"`
+	Attachments int64     `gorm:"not null;default:0"`
+	Details     string    `gorm:"type:text"`
+	LastError   string    `gorm:"type:text"`
+}
+
+func (v4RetentionRun) TableName() string { return "retention_runs" }
@@ -0,0 +1,43 @@
+package migrations
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+
+	"github.com/woxQAQ/gim/pkg/db"
+)
+
+// v5DataRequests 创建用户数据导出与删除任务表
+func v5DataRequests() db.Migration {
+	return db.Migration{
+		Version: 5,
+		Name:    "data_requests",
+		Up: func(tx *gorm.DB) error {
+			return tx.AutoMigrate(&v5DataRequest{})
+		},
+		Down: func(tx *gorm.DB) error {
+			return tx.Migrator().DropTable(&v5DataRequest{})
+		},
+	}
+}
+
+type v5DataRequest struct {
+	ID          string `gorm:"primaryKey;type:varchar(64)"`
+	UserID      string `gorm:"type:varchar(64);not null;index"`
+	Kind        string `gorm:"type:varchar(16);not null"`
+	Mode        string `gorm:"type:varchar(16);not null;default:''"`
+	Status      int    `gorm:"type:smallint;not null;default:0;index"`
+	FilePath    string `gorm:"type:text"`
+	FileSize    int64  `gorm:"not null;default:0"`
+	Summary     string `gorm:"type:text"`
+	LastError   string `gorm:"type:text"`
+	RequestedBy string `gorm:"type:text"`
+	StartedAt   *time.Time
+	FinishedAt  *time.Time
+	ExpiresAt   *time.Time `gorm:"index"`
+	CreatedAt   time.Time  `gorm:"autoCreateTime"`
+	UpdatedAt   time.Time  `gorm:"autoUpdateTime"`
+}
+
+func (v5DataRequest) TableName() string { return "data_requests" }
@@ -0,0 +1,87 @@
+package migrations
+
+import (
+	"fmt"
+	"strings"
+
+	"gorm.io/gorm"
+
+	"github.com/woxQAQ/gim/pkg/db"
+)
+
+// v6UserUnique 为用户名和邮箱建立唯一索引，注册时由数据库保证不重复。
+// 邮箱允许为空，只约束非空邮箱：SQLite 与 PostgreSQL 使用部分索引，
+// MySQL 使用函数索引（需要 8.0.13 及以上），空字符串映射为 NULL。
+// 已有重复数据时迁移失败，需要先人工处理。
+func v6UserUnique() db.Migration {
+	return db.Migration{
+		Version: 6,
+		Name:    "user_unique",
+		Up: func(tx *gorm.DB) error {
+			if err := v6CheckDuplicates(tx, "username", "1 = 1"); err != nil {
+				return err
+			}
+			if err := v6CheckDuplicates(tx, "email", "email <> ''"); err != nil {
+				return err
+			}
+
+			// 唯一索引需要定长类型，SQLite 不区分 text 与 varchar
+			if tx.Dialector.Name() != "sqlite" {
+				if err := tx.Migrator().AlterColumn(&v6User{}, "Username"); err != nil {
+					return err
+				}
+			}
+			if err := tx.Exec("CREATE UNIQUE INDEX idx_users_username ON users (username)").Error; err != nil {
+				return err
+			}
+			if err := tx.Migrator().DropIndex("users", "idx_users_email"); err != nil {
+				return err
+			}
+			if tx.Dialector.Name() == "mysql" {
+				return tx.Exec("CREATE UNIQUE INDEX idx_users_email ON users ((NULLIF(email, '')))").Error
+			}
+			return tx.Exec("CREATE UNIQUE INDEX idx_users_email ON users (email) WHERE email <> ''").Error
+		},
+		Down: func(tx *gorm.DB) error {
+			if err := tx.Migrator().DropIndex("users", "idx_users_email"); err != nil {
+				return err
+			}
+			if err := tx.Exec("CREATE INDEX idx_users_email ON users (email)").Error; err != nil {
+				return err
+			}
+			if err := tx.Migrator().DropIndex("users", "idx_users_username"); err != nil {
+				return err
+			}
+			if tx.Dialector.Name() != "sqlite" {
+				return tx.Migrator().AlterColumn(&v1User{}, "Username")
+			}
+			return nil
+		},
+	}
+}
+
+// v6CheckDuplicates 检查 column 在满足 cond 的行中是否有重复值
+func v6CheckDuplicates(tx *gorm.DB, column, cond string) error {
+	var dups []string
+	err := tx.Table("users").
+		Where(cond).
+		Group(column).
+		Having("COUNT(*) > 1").
+		Limit(5).
+		Pluck(column, &dups).Error
+	if err != nil {
+		return err
+	}
+	if len(dups) > 0 {
+		return fmt.Errorf("users.%s has duplicate values, resolve them before migrating: %s",
+			column, strings.Join(dups, ", "))
+	}
+	return nil
+}
+
+type v6User struct {
+	ID       string `gorm:"primaryKey;type:varchar(64)"`
+	Username string `gorm:"type:varchar(64);not null"`
+}
+
+func (v6User) TableName() string { return "users" }
@@ -0,0 +1,38 @@
+package migrations
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+
+	"github.com/woxQAQ/gim/pkg/db"
+)
+
+// v7Sessions 创建登录会话表，保存刷新令牌摘要与吊销状态
+func v7Sessions() db.Migration {
+	return db.Migration{
+		Version: 7,
+		Name:    "sessions",
+		Up: func(tx *gorm.DB) error {
+			return tx.AutoMigrate(&v7Session{})
+		},
+		Down: func(tx *gorm.DB) error {
+			return tx.Migrator().DropTable(&v7Session{})
+		},
+	}
+}
+
+type v7Session struct {
+	ID              string    `gorm:"primaryKey;type:varchar(64)"`
+	UserID          string    `gorm:"type:varchar(64);not null;index"`
+	Platform        int32     `gorm:"type:integer;not null;default:0"`
+	DeviceID        string    `gorm:"type:varchar(128);not null;default:''"`
+	RefreshHash     string    `gorm:"type:varchar(64);not null;uniqueIndex"`
+	PrevRefreshHash string    `gorm:"type:varchar(64);not null;default:'';index"`
+	ExpiresAt       time.Time `gorm:"not null;index"`
+	RevokedAt       *time.Time
+	CreatedAt       time.Time `gorm:"autoCreateTime"`
+	UpdatedAt       time.Time `gorm:"autoUpdateTime"`
+}
+
+func (v7Session) TableName() string { return "user_sessions" }
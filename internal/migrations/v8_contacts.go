@@ -0,0 +1,46 @@
+package migrations
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+
+	"github.com/woxQAQ/gim/pkg/db"
+)
+
+// v8Contacts 创建好友申请表和联系人表
+func v8Contacts() db.Migration {
+	return db.Migration{
+		Version: 8,
+		Name:    "contacts",
+		Up: func(tx *gorm.DB) error {
+			return tx.AutoMigrate(&v8FriendRequest{}, &v8Contact{})
+		},
+		Down: func(tx *gorm.DB) error {
+			return tx.Migrator().DropTable(&v8Contact{}, &v8FriendRequest{})
+		},
+	}
+}
+
+type v8FriendRequest struct {
+	ID        string `gorm:"primaryKey;type:varchar(64)"`
+	FromID    string `gorm:"type:varchar(64);not null;index:idx_friend_requests_from_to"`
+	ToID      string `gorm:"type:varchar(64);not null;index:idx_friend_requests_from_to;index"`
+	Message   string `gorm:"type:varchar(256);not null;default:''"`
+	Status    int8   `gorm:"type:smallint;not null;default:0;index"`
+	HandledAt *time.Time
+	CreatedAt time.Time `gorm:"autoCreateTime"`
+	UpdatedAt time.Time `gorm:"autoUpdateTime"`
+}
+
+func (v8FriendRequest) TableName() string { return "friend_requests" }
+
+type v8Contact struct {
+	UserID    string    `gorm:"primaryKey;type:varchar(64)"`
+	ContactID string    `gorm:"primaryKey;type:varchar(64);index"`
+	Remark    string    `gorm:"type:varchar(64);not null;default:''"`
+	CreatedAt time.Time `gorm:"autoCreateTime"`
+	UpdatedAt time.Time `gorm:"autoUpdateTime"`
+}
+
+func (v8Contact) TableName() string { return "contacts" }
@@ -0,0 +1,31 @@
+package migrations
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+
+	"github.com/woxQAQ/gim/pkg/db"
+)
+
+// v9Blocks 创建用户黑名单表
+func v9Blocks() db.Migration {
+	return db.Migration{
+		Version: 9,
+		Name:    "blocks",
+		Up: func(tx *gorm.DB) error {
+			return tx.AutoMigrate(&v9Block{})
+		},
+		Down: func(tx *gorm.DB) error {
+			return tx.Migrator().DropTable(&v9Block{})
+		},
+	}
+}
+
+type v9Block struct {
+	UserID    string    `gorm:"primaryKey;type:varchar(64)"`
+	BlockedID string    `gorm:"primaryKey;type:varchar(64);index"`
+	CreatedAt time.Time `gorm:"autoCreateTime"`
+}
+
+func (v9Block) TableName() string { return "user_blocks" }
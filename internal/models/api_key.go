@@ -0,0 +1,68 @@
+package models
+
+import (
+	"strings"
+	"time"
+
+	"github.com/woxQAQ/gim/internal/apiserver/types/response"
+)
+
+const (
+	// ScopeMessagesSend 以机器人身份向用户或群发送消息
+	ScopeMessagesSend = "messages:send"
+)
+
+// ValidScope 判断 scope 是否为已知的 API 密钥权限范围
+func ValidScope(scope string) bool {
+	switch scope {
+	case ScopeMessagesSend:
+		return true
+	}
+	return false
+}
+
+// APIKey 机器人账号的 API 密钥，只保存密钥的摘要。
+// 未吊销且未过期的密钥有效，轮换时旧密钥可以保留一段过期时间
+type APIKey struct {
+	ID         string     `gorm:"primaryKey;type:varchar(64)"`
+	UserID     string     `gorm:"type:varchar(64);not null;index"`
+	Name       string     `gorm:"type:varchar(64);not null;default:''"`
+	Prefix     string     `gorm:"type:varchar(16);not null"` // 密钥开头的若干字符，用于识别密钥
+	Hash       string     `gorm:"type:varchar(64);not null;uniqueIndex:idx_api_keys_hash"`
+	Scopes     string     `gorm:"type:text;not null"` // 空格分隔的权限范围
+	CreatedBy  string     `gorm:"type:text"`
+	ExpiresAt  *time.Time // 为空时不过期
+	RevokedAt  *time.Time
+	LastUsedAt *time.Time
+	CreatedAt  time.Time `gorm:"autoCreateTime"`
+}
+
+func (k *APIKey) TableName() string {
+	return "api_keys"
+}
+
+// ScopeList 返回密钥被授予的权限范围
+func (k *APIKey) ScopeList() []string {
+	return strings.Fields(k.Scopes)
+}
+
+// Active 判断密钥在 now 时是否有效
+func (k *APIKey) Active(now time.Time) bool {
+	return k.RevokedAt == nil && (k.ExpiresAt == nil || k.ExpiresAt.After(now))
+}
+
+func (k *APIKey) ToResponse() *response.APIKeyResponse {
+	return &response.APIKeyResponse{
+		ID:         k.ID,
+		BotID:      k.UserID,
+		Name:       k.Name,
+		Prefix:     k.Prefix,
+		Scopes:     k.ScopeList(),
+		CreatedBy:  k.CreatedBy,
+		ExpiresAt:  k.ExpiresAt,
+		RevokedAt:  k.RevokedAt,
+		LastUsedAt: k.LastUsedAt,
+		Active:     k.Active(time.Now()),
+		CreatedAt:  k.CreatedAt,
+	}
+}
@@ -0,0 +1,61 @@
+package models
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"strconv"
+	"time"
+
+	"github.com/woxQAQ/gim/internal/apiserver/types/response"
+)
+
+// AuditLog 审计日志，只追加不修改。
+// 开启哈希链时每条记录的 Hash 覆盖上一条记录的 Hash，修改或删除历史记录会使之后的链校验失败
+type AuditLog struct {
+	ID        string    `gorm:"primaryKey;type:varchar(64)"`
+	Seq       int64     `gorm:"not null;uniqueIndex:idx_audit_logs_seq"` // 从1开始连续递增
+	Action    string    `gorm:"type:varchar(64);not null;index:idx_audit_logs_action,priority:1"`
+	ActorID   string    `gorm:"type:varchar(64);not null;default:'';index:idx_audit_logs_actor,priority:1"` // 为空时为匿名请求或系统
+	TargetID  string    `gorm:"type:varchar(128);not null;default:''"`
+	IP        string    `gorm:"type:varchar(64);not null;default:''"`
+	Detail    string    `gorm:"type:text"` // JSON
+	PrevHash  string    `gorm:"type:varchar(64);not null;default:''"`
+	Hash      string    `gorm:"type:varchar(64);not null;default:''"` // 未开启哈希链时为空
+	CreatedAt time.Time `gorm:"not null;index:idx_audit_logs_action,priority:2;index:idx_audit_logs_actor,priority:2;index"`
+}
+
+func (l *AuditLog) TableName() string {
+	return "audit_logs"
+}
+
+// ComputeHash 计算记录的哈希，包含 PrevHash 和除 ID、Hash 以外的全部字段。
+// 时间按毫秒计算，与各数据库保存的精度一致
+func (l *AuditLog) ComputeHash() string {
+	fields, _ := json.Marshal([]string{
+		l.PrevHash,
+		strconv.FormatInt(l.Seq, 10),
+		l.Action,
+		l.ActorID,
+		l.TargetID,
+		l.IP,
+		l.Detail,
+		strconv.FormatInt(l.CreatedAt.UnixMilli(), 10),
+	})
+	sum := sha256.Sum256(fields)
+	return hex.EncodeToString(sum[:])
+}
+
+func (l *AuditLog) ToResponse() *response.AuditLogResponse {
+	return &response.AuditLogResponse{
+		ID:        l.ID,
+		Seq:       l.Seq,
+		Action:    l.Action,
+		ActorID:   l.ActorID,
+		TargetID:  l.TargetID,
+		IP:        l.IP,
+		Detail:    json.RawMessage(l.Detail),
+		Hash:      l.Hash,
+		CreatedAt: l.CreatedAt,
+	}
+}
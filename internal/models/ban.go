@@ -0,0 +1,42 @@
+package models
+
+import (
+	"time"
+
+	"github.com/woxQAQ/gim/internal/apiserver/types/response"
+)
+
+// UserBan 用户封禁记录，未解除且未过期的记录生效
+type UserBan struct {
+	ID        string     `gorm:"primaryKey;type:varchar(64)"`
+	UserID    string     `gorm:"type:varchar(64);not null;index"`
+	Reason    string     `gorm:"type:varchar(256);not null;default:''"`
+	ExpiresAt *time.Time // 为空时永久封禁
+	CreatedBy string     `gorm:"type:text"`
+	LiftedAt  *time.Time
+	LiftedBy  string    `gorm:"type:text"`
+	CreatedAt time.Time `gorm:"autoCreateTime"`
+}
+
+func (b *UserBan) TableName() string {
+	return "user_bans"
+}
+
+// Active 判断封禁在 now 时是否生效
+func (b *UserBan) Active(now time.Time) bool {
+	return b.LiftedAt == nil && (b.ExpiresAt == nil || b.ExpiresAt.After(now))
+}
+
+func (b *UserBan) ToResponse() *response.BanResponse {
+	return &response.BanResponse{
+		ID:        b.ID,
+		UserID:    b.UserID,
+		Reason:    b.Reason,
+		ExpiresAt: b.ExpiresAt,
+		CreatedBy: b.CreatedBy,
+		LiftedAt:  b.LiftedAt,
+		LiftedBy:  b.LiftedBy,
+		Active:    b.Active(time.Now()),
+		CreatedAt: b.CreatedAt,
+	}
+}
@@ -0,0 +1,84 @@
+package models
+
+import (
+	"time"
+
+	"github.com/woxQAQ/gim/internal/apiserver/types/response"
+)
+
+// FriendRequestStatus 好友申请状态
+type FriendRequestStatus int8
+
+const (
+	// FriendRequestPending 等待对方处理
+	FriendRequestPending FriendRequestStatus = iota
+	// FriendRequestAccepted 已同意，双方成为联系人
+	FriendRequestAccepted
+	// FriendRequestRejected 已拒绝
+	FriendRequestRejected
+)
+
+func (s FriendRequestStatus) String() string {
+	switch s {
+	case FriendRequestPending:
+		return "pending"
+	case FriendRequestAccepted:
+		return "accepted"
+	case FriendRequestRejected:
+		return "rejected"
+	default:
+		return "unknown"
+	}
+}
+
+// FriendRequest 好友申请
+type FriendRequest struct {
+	ID        string              `gorm:"primaryKey;type:varchar(64)"`
+	FromID    string              `gorm:"type:varchar(64);not null;index:idx_friend_requests_from_to"`
+	ToID      string              `gorm:"type:varchar(64);not null;index:idx_friend_requests_from_to;index"`
+	Message   string              `gorm:"type:varchar(256);not null;default:''"` // 申请附言
+	Status    FriendRequestStatus `gorm:"type:smallint;not null;default:0;index"`
+	HandledAt *time.Time
+	CreatedAt time.Time `gorm:"autoCreateTime"`
+	UpdatedAt time.Time `gorm:"autoUpdateTime"`
+}
+
+func (r *FriendRequest) TableName() string {
+	return "friend_requests"
+}
+
+func (r *FriendRequest) ToResponse() *response.FriendRequestResponse {
+	return &response.FriendRequestResponse{
+		ID:        r.ID,
+		FromID:    r.FromID,
+		ToID:      r.ToID,
+		Message:   r.Message,
+		Status:    r.Status.String(),
+		HandledAt: r.HandledAt,
+		CreatedAt: r.CreatedAt,
+	}
+}
+
+// Contact 联系人关系，双方各保存一条记录，备注只对 UserID 可见
+type Contact struct {
+	UserID    string    `gorm:"primaryKey;type:varchar(64)"`
+	ContactID string    `gorm:"primaryKey;type:varchar(64);index"`
+	Remark    string    `gorm:"type:varchar(64);not null;default:''"`
+	CreatedAt time.Time `gorm:"autoCreateTime"`
+	UpdatedAt time.Time `gorm:"autoUpdateTime"`
+}
+
+func (c *Contact) TableName() string {
+	return "contacts"
+}
+
+// Block 用户拉黑记录，UserID 不再接收 BlockedID 的单聊消息
+type Block struct {
+	UserID    string    `gorm:"primaryKey;type:varchar(64)"`
+	BlockedID string    `gorm:"primaryKey;type:varchar(64);index"`
+	CreatedAt time.Time `gorm:"autoCreateTime"`
+}
+
+func (b *Block) TableName() string {
+	return "user_blocks"
+}
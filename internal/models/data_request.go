@@ -0,0 +1,98 @@
+package models
+
+import (
+	"encoding/json"
+	"time"
+
+	"github.com/woxQAQ/gim/internal/apiserver/types/response"
+)
+
+// DataRequestStatus 用户数据任务状态
+type DataRequestStatus int8
+
+const (
+	// DataRequestPending 等待执行
+	DataRequestPending DataRequestStatus = iota
+	// DataRequestRunning 执行中
+	DataRequestRunning
+	// DataRequestCompleted 已完成
+	DataRequestCompleted
+	// DataRequestFailed 执行失败，可以重新提交
+	DataRequestFailed
+	// DataRequestExpired 导出文件已过期删除
+	DataRequestExpired
+)
+
+func (s DataRequestStatus) String() string {
+	switch s {
+	case DataRequestPending:
+		return "pending"
+	case DataRequestRunning:
+		return "running"
+	case DataRequestCompleted:
+		return "completed"
+	case DataRequestFailed:
+		return "failed"
+	case DataRequestExpired:
+		return "expired"
+	default:
+		return "unknown"
+	}
+}
+
+// 用户数据任务类型
+const (
+	DataRequestExport = "export"
+	DataRequestDelete = "delete"
+)
+
+// 删除用户数据的方式
+const (
+	// DeletionAnonymize 保留账号ID，清空资料和发送的消息内容，对方的会话记录保持完整
+	DeletionAnonymize = "anonymize"
+	// DeletionRemove 删除账号以及发送和收到的单聊消息
+	DeletionRemove = "remove"
+)
+
+// DataRequest 用户数据导出或删除任务
+type DataRequest struct {
+	ID          string            `gorm:"primaryKey;type:varchar(64)"`
+	UserID      string            `gorm:"type:varchar(64);not null;index"`
+	Kind        string            `gorm:"type:varchar(16);not null"`
+	Mode        string            `gorm:"type:varchar(16);not null;default:''"` // 删除方式，导出任务为空
+	Status      DataRequestStatus `gorm:"type:smallint;not null;default:0;index"`
+	FilePath    string            `gorm:"type:text"` // 导出文件路径
+	FileSize    int64             `gorm:"not null;default:0"`
+	Summary     string            `gorm:"type:text"` // 各类数据的处理数量(JSON)
+	LastError   string            `gorm:"type:text"`
+	RequestedBy string            `gorm:"type:text"`
+	StartedAt   *time.Time
+	FinishedAt  *time.Time
+	ExpiresAt   *time.Time `gorm:"index"` // 导出文件的过期时间
+	CreatedAt   time.Time  `gorm:"autoCreateTime"`
+	UpdatedAt   time.Time  `gorm:"autoUpdateTime"`
+}
+
+func (r *DataRequest) TableName() string {
+	return "data_requests"
+}
+
+func (r *DataRequest) ToResponse() *response.DataRequestResponse {
+	resp := &response.DataRequestResponse{
+		ID:         r.ID,
+		UserID:     r.UserID,
+		Kind:       r.Kind,
+		Mode:       r.Mode,
+		Status:     r.Status.String(),
+		FileSize:   r.FileSize,
+		LastError:  r.LastError,
+		StartedAt:  r.StartedAt,
+		FinishedAt: r.FinishedAt,
+		ExpiresAt:  r.ExpiresAt,
+		CreatedAt:  r.CreatedAt,
+	}
+	if r.Summary != "" {
+		_ = json.Unmarshal([]byte(r.Summary), &resp.Summary)
+	}
+	return resp
+}
@@ -0,0 +1,27 @@
+package models
+
+import "time"
+
+// Group 群组模型
+type Group struct {
+	ID        string    `gorm:"primaryKey;type:varchar(64)"`
+	Name      string    `gorm:"type:text;not null"`
+	OwnerID   string    `gorm:"type:varchar(64);not null;index"`
+	CreatedAt time.Time `gorm:"autoCreateTime"`
+	UpdatedAt time.Time `gorm:"autoUpdateTime"`
+}
+
+func (g *Group) TableName() string {
+	return "groups"
+}
+
+// GroupMember 群组成员模型
+type GroupMember struct {
+	GroupID  string    `gorm:"primaryKey;type:varchar(64)"`
+	UserID   string    `gorm:"primaryKey;type:varchar(64);index"`
+	JoinedAt time.Time `gorm:"autoCreateTime"`
+}
+
+func (gm *GroupMember) TableName() string {
+	return "group_members"
+}
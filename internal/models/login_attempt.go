@@ -0,0 +1,21 @@
+package models
+
+import "time"
+
+// LoginAttempt 某个账号或客户端地址的失败尝试计数，用于防止暴力破解
+type LoginAttempt struct {
+	ID            string     `gorm:"primaryKey;type:varchar(191)"` // 计数的对象，例如 login:account:alice、login:ip:10.0.0.1
+	Failures      int        `gorm:"not null;default:0"`
+	LastFailureAt time.Time  `gorm:"index"`
+	LockedUntil   *time.Time // 为空或已过去时未锁定
+	UpdatedAt     time.Time  `gorm:"autoUpdateTime"`
+}
+
+func (a *LoginAttempt) TableName() string {
+	return "login_attempts"
+}
+
+// Locked 判断在 now 时是否处于锁定期
+func (a *LoginAttempt) Locked(now time.Time) bool {
+	return a.LockedUntil != nil && a.LockedUntil.After(now)
+}
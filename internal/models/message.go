@@ -24,19 +24,40 @@ const (
 	MessageStatusRead
 	// MessageStatusFailed 发送失败
 	MessageStatusFailed
+	// MessageStatusRecalled 已撤回
+	MessageStatusRecalled
 )
 
 // Message 消息模型
+//
+// 索引与查询方式对应：
+//   - idx_messages_conversation: 会话内按ID游标翻页
+//   - idx_messages_conversation_time: 会话内按时间范围查询
+//   - idx_messages_from / idx_messages_to: 用户全部消息按ID游标翻页
 type Message struct {
-	ID        string            `gorm:"primaryKey;type:text"`
-	Type      types.MessageType `gorm:"type:smallint;not null;index"`
-	Content   string            `gorm:"type:text;not null"`
-	FromID    string            `gorm:"type:text;not null;index"`
-	ToID      string            `gorm:"type:text;not null;index"`
-	Status    MessageStatus     `gorm:"type:smallint;not null;default:1;index"`
-	Platform  int32             `gorm:"type:integer;not null;index"`
-	CreatedAt time.Time         `gorm:"autoCreateTime;index"`
-	UpdatedAt time.Time         `gorm:"autoUpdateTime"`
+	ID             string            `gorm:"primaryKey;type:varchar(64);index:idx_messages_conversation,priority:2;index:idx_messages_from,priority:2;index:idx_messages_to,priority:2"`
+	ConversationID string            `gorm:"type:varchar(160);not null;default:'';index:idx_messages_conversation,priority:1;index:idx_messages_conversation_time,priority:1"`
+	Type           types.MessageType `gorm:"type:smallint;not null"`
+	Content        string            `gorm:"type:text;not null"`
+	FromID         string            `gorm:"type:varchar(64);not null;index:idx_messages_from,priority:1"`
+	ToID           string            `gorm:"type:varchar(64);not null;index:idx_messages_to,priority:1"`
+	Status         MessageStatus     `gorm:"type:smallint;not null;default:1;index"`
+	Platform       int32             `gorm:"type:integer;not null"`
+	CreatedAt      time.Time         `gorm:"autoCreateTime;index:idx_messages_conversation_time,priority:2"`
+	UpdatedAt      time.Time         `gorm:"autoUpdateTime;index"`
+}
+
+// DirectConversationID 返回两个用户之间单聊会话的ID，与参数顺序无关
+func DirectConversationID(a, b string) string {
+	if a > b {
+		a, b = b, a
+	}
+	return "d:" + a + ":" + b
+}
+
+// GroupConversationID 返回群聊会话的ID
+func GroupConversationID(groupID string) string {
+	return "g:" + groupID
 }
 
 func (m *Message) TableName() string {
@@ -52,20 +73,29 @@ func (m *Message) BeforeCreate(tx *gorm.DB) error {
 // ToResponse 将Message转换为MessageResponse
 func (m *Message) ToResponse() *response.MessageResponse {
 	return &response.MessageResponse{
-		ID:         m.ID,
-		SenderID:   m.FromID,
-		ReceiverID: m.ToID,
-		Content:    m.Content,
-		Type:       int32(m.Type),
-		Status:     int32(m.Status),
-		CreatedAt:  m.CreatedAt,
+		ID:             m.ID,
+		ConversationID: m.ConversationID,
+		SenderID:       m.FromID,
+		ReceiverID:     m.ToID,
+		Content:        m.Content,
+		Type:           int32(m.Type),
+		Status:         int32(m.Status),
+		CreatedAt:      m.CreatedAt,
 	}
 }
 
 func (m *Message) FromTypes(msg base.IMessage) {
 	m.ID = msg.GetID()
+	m.Type, _ = msg.GetType().(types.MessageType)
 	m.FromID = msg.GetFrom()
-	m.ToID = msg.GetTo()
+	if groupID := msg.GetGroupID(); groupID != "" {
+		// 群聊消息的接收方为群
+		m.ToID = groupID
+		m.ConversationID = GroupConversationID(groupID)
+	} else {
+		m.ToID = msg.GetTo()
+		m.ConversationID = DirectConversationID(m.FromID, m.ToID)
+	}
 	m.Content = string(msg.GetPayload())
 	m.Platform = msg.GetPlatform()
 	m.CreatedAt = msg.GetTimestamp()
@@ -73,8 +103,8 @@ func (m *Message) FromTypes(msg base.IMessage) {
 
 // MessageAttachment 消息附件模型
 type MessageAttachment struct {
-	ID        string    `gorm:"primaryKey;type:text"`
-	MessageID string    `gorm:"type:text;not null;index"`
+	ID        string    `gorm:"primaryKey;type:varchar(64)"`
+	MessageID string    `gorm:"type:varchar(64);not null;index"`
 	Type      string    `gorm:"type:text;not null"` // 附件类型：image, video, audio, file
 	URL       string    `gorm:"type:text;not null"` // 附件URL
 	Size      int64     `gorm:"type:bigint"`        // 附件大小（字节）
@@ -0,0 +1,67 @@
+package models
+
+import (
+	"strings"
+	"time"
+
+	"github.com/woxQAQ/gim/internal/apiserver/types/response"
+)
+
+// ModerationStatus 审核状态
+type ModerationStatus int8
+
+const (
+	// ModerationPending 待审核
+	ModerationPending ModerationStatus = iota
+	// ModerationApproved 审核通过
+	ModerationApproved
+	// ModerationRemoved 审核不通过，消息已删除
+	ModerationRemoved
+)
+
+func (s ModerationStatus) String() string {
+	switch s {
+	case ModerationPending:
+		return "pending"
+	case ModerationApproved:
+		return "approved"
+	case ModerationRemoved:
+		return "removed"
+	default:
+		return "unknown"
+	}
+}
+
+// ModerationRecord 内容过滤标记的待审核消息
+type ModerationRecord struct {
+	ID         string           `gorm:"primaryKey;type:varchar(64)"`
+	MessageID  string           `gorm:"type:varchar(64);not null;index"`
+	FromID     string           `gorm:"type:varchar(64);not null;index"`
+	ToID       string           `gorm:"type:text;not null"`
+	Content    string           `gorm:"type:text;not null"` // 过滤前的原始内容
+	Rules      string           `gorm:"type:text;not null"` // 命中的规则名，逗号分隔
+	Status     ModerationStatus `gorm:"type:smallint;not null;default:0;index"`
+	ReviewerID string           `gorm:"type:text"`
+	ReviewedAt *time.Time
+	CreatedAt  time.Time `gorm:"autoCreateTime;index"`
+	UpdatedAt  time.Time `gorm:"autoUpdateTime"`
+}
+
+func (r *ModerationRecord) TableName() string {
+	return "moderation_records"
+}
+
+func (r *ModerationRecord) ToResponse() *response.ModerationRecordResponse {
+	return &response.ModerationRecordResponse{
+		ID:         r.ID,
+		MessageID:  r.MessageID,
+		FromID:     r.FromID,
+		ToID:       r.ToID,
+		Content:    r.Content,
+		Rules:      strings.Split(r.Rules, ","),
+		Status:     r.Status.String(),
+		ReviewerID: r.ReviewerID,
+		ReviewedAt: r.ReviewedAt,
+		CreatedAt:  r.CreatedAt,
+	}
+}
@@ -0,0 +1,104 @@
+package models
+
+import (
+	"strings"
+	"time"
+
+	"github.com/woxQAQ/gim/internal/apiserver/types/response"
+)
+
+// NoticeStatus 系统通知状态
+type NoticeStatus int8
+
+const (
+	// NoticeScheduled 等待定时发送
+	NoticeScheduled NoticeStatus = iota
+	// NoticeSent 已发送
+	NoticeSent
+	// NoticeFailed 发送失败
+	NoticeFailed
+	// NoticeCanceled 已取消
+	NoticeCanceled
+)
+
+func (s NoticeStatus) String() string {
+	switch s {
+	case NoticeScheduled:
+		return "scheduled"
+	case NoticeSent:
+		return "sent"
+	case NoticeFailed:
+		return "failed"
+	case NoticeCanceled:
+		return "canceled"
+	default:
+		return "unknown"
+	}
+}
+
+// 系统通知的目标类型，在推送目标之外增加了群组
+const (
+	NoticeTargetAll      = "all"
+	NoticeTargetUsers    = "users"
+	NoticeTargetGroup    = "group"
+	NoticeTargetPlatform = "platform"
+)
+
+// SystemNotice 系统通知模型
+type SystemNotice struct {
+	ID         string       `gorm:"primaryKey;type:varchar(64)"`
+	Title      string       `gorm:"type:text;not null"`
+	Content    string       `gorm:"type:text;not null"`
+	TargetType string       `gorm:"type:text;not null"`
+	TargetIDs  string       `gorm:"type:text"` // users 为逗号分隔的用户ID，group 为群组ID
+	PlatformID int32        `gorm:"type:integer"`
+	Persist    bool         `gorm:"not null;default:false"` // 是否保存供离线用户拉取
+	Status     NoticeStatus `gorm:"type:smallint;not null;default:0;index:idx_notice_due,priority:1"`
+	DeliverAt  time.Time    `gorm:"index:idx_notice_due,priority:2"`
+	SentAt     *time.Time
+	Delivered  int       `gorm:"type:integer;not null;default:0"` // 推送时在线的用户数量
+	LastError  string    `gorm:"type:text"`
+	CreatedBy  string    `gorm:"type:text"`
+	CreatedAt  time.Time `gorm:"autoCreateTime"`
+	UpdatedAt  time.Time `gorm:"autoUpdateTime"`
+}
+
+func (n *SystemNotice) TableName() string {
+	return "system_notices"
+}
+
+// TargetIDList 返回目标ID列表
+func (n *SystemNotice) TargetIDList() []string {
+	if n.TargetIDs == "" {
+		return nil
+	}
+	return strings.Split(n.TargetIDs, ",")
+}
+
+func (n *SystemNotice) ToResponse() *response.NoticeResponse {
+	return &response.NoticeResponse{
+		ID:         n.ID,
+		Title:      n.Title,
+		Content:    n.Content,
+		TargetType: n.TargetType,
+		TargetIDs:  n.TargetIDList(),
+		PlatformID: n.PlatformID,
+		Persist:    n.Persist,
+		Status:     n.Status.String(),
+		DeliverAt:  n.DeliverAt,
+		SentAt:     n.SentAt,
+		Delivered:  n.Delivered,
+		LastError:  n.LastError,
+		CreatedAt:  n.CreatedAt,
+	}
+}
+
+// SystemNoticeRecipient 持久化通知的接收人，用于离线用户拉取
+type SystemNoticeRecipient struct {
+	NoticeID string `gorm:"primaryKey;type:varchar(64)"`
+	UserID   string `gorm:"primaryKey;type:varchar(64);index"`
+}
+
+func (r *SystemNoticeRecipient) TableName() string {
+	return "system_notice_recipients"
+}
@@ -0,0 +1,92 @@
+package models
+
+import (
+	"time"
+
+	"github.com/woxQAQ/gim/internal/apiserver/types/response"
+	"github.com/woxQAQ/gim/internal/types"
+)
+
+// 过期消息的处理方式
+const (
+	RetentionDelete  = "delete"
+	RetentionArchive = "archive"
+)
+
+// RetentionPolicy 消息保留策略
+//
+// ConversationID 与 MessageType 为空（0）时表示匹配任意值，两者都为空即全局策略。
+// 一条消息只使用最具体的策略：会话+类型 > 会话 > 类型 > 全局。
+// TTL 为 0 表示永久保留，可用于让某个会话或类型不受更宽泛策略的影响。
+type RetentionPolicy struct {
+	ID             string            `gorm:"primaryKey;type:varchar(64)"`
+	ConversationID string            `gorm:"type:varchar(160);not null;default:'';uniqueIndex:idx_retention_scope,priority:1"`
+	MessageType    types.MessageType `gorm:"type:smallint;not null;default:0;uniqueIndex:idx_retention_scope,priority:2"`
+	TTLSeconds     int64             `gorm:"not null;default:0"`
+	Action         string            `gorm:"type:varchar(16);not null"`
+	CreatedBy      string            `gorm:"type:text"`
+	CreatedAt      time.Time         `gorm:"autoCreateTime"`
+	UpdatedAt      time.Time         `gorm:"autoUpdateTime"`
+}
+
+func (p *RetentionPolicy) TableName() string {
+	return "retention_policies"
+}
+
+// TTL 返回保留时长
+func (p *RetentionPolicy) TTL() time.Duration {
+	return time.Duration(p.TTLSeconds) * time.Second
+}
+
+// Specificity 返回策略的具体程度，数值越大越优先
+func (p *RetentionPolicy) Specificity() int {
+	s := 0
+	if p.ConversationID != "" {
+		s += 2
+	}
+	if p.MessageType != types.MessageTypeUnknown {
+		s++
+	}
+	return s
+}
+
+func (p *RetentionPolicy) ToResponse() *response.RetentionPolicyResponse {
+	return &response.RetentionPolicyResponse{
+		ID:             p.ID,
+		ConversationID: p.ConversationID,
+		MessageType:    int32(p.MessageType),
+		TTLSeconds:     p.TTLSeconds,
+		Action:         p.Action,
+		CreatedBy:      p.CreatedBy,
+		CreatedAt:      p.CreatedAt,
+	}
+}
+
+// RetentionRun 一次过期清理的执行记录
+type RetentionRun struct {
+	ID          string    `gorm:"primaryKey;type:varchar(64)"`
+	StartedAt   time.Time `gorm:"not null;index"`
+	FinishedAt  time.Time `gorm:"not null"`
+	Deleted     int64     `gorm:"not null;default:0"` // 删除的消息数量
+	Archived    int64     `gorm:"not null;default:0"` // 归档后删除的消息数量
+	Attachments int64     `gorm:"not null;default:0"` // 删除的附件数量
+	Details     string    `gorm:"type:text"`          // 每条策略的处理结果(JSON)
+	LastError   string    `gorm:"type:text"`
+}
+
+func (r *RetentionRun) TableName() string {
+	return "retention_runs"
+}
+
+func (r *RetentionRun) ToResponse() *response.RetentionRunResponse {
+	return &response.RetentionRunResponse{
+		ID:          r.ID,
+		StartedAt:   r.StartedAt,
+		FinishedAt:  r.FinishedAt,
+		Deleted:     r.Deleted,
+		Archived:    r.Archived,
+		Attachments: r.Attachments,
+		Details:     r.Details,
+		LastError:   r.LastError,
+	}
+}
@@ -0,0 +1,30 @@
+package models
+
+import "time"
+
+// Session 用户在一个平台或设备上的登录会话。
+//
+// 会话持有当前有效的刷新令牌，每次刷新都会轮换；只保存令牌的 SHA-256 摘要。
+// 上一个刷新令牌的摘要用于发现令牌被重复使用，此时整个会话会被吊销。
+// 访问令牌携带会话ID，会话吊销后该会话签发的所有访问令牌一并失效。
+type Session struct {
+	ID              string    `gorm:"primaryKey;type:varchar(64)"`
+	UserID          string    `gorm:"type:varchar(64);not null;index"`
+	Platform        int32     `gorm:"type:integer;not null;default:0"`
+	DeviceID        string    `gorm:"type:varchar(128);not null;default:''"`
+	RefreshHash     string    `gorm:"type:varchar(64);not null;uniqueIndex"`
+	PrevRefreshHash string    `gorm:"type:varchar(64);not null;default:'';index"`
+	ExpiresAt       time.Time `gorm:"not null;index"` // 刷新令牌的过期时间
+	RevokedAt       *time.Time
+	CreatedAt       time.Time `gorm:"autoCreateTime"`
+	UpdatedAt       time.Time `gorm:"autoUpdateTime"`
+}
+
+func (s *Session) TableName() string {
+	return "user_sessions"
+}
+
+// Active 会话在 now 时是否仍然有效
+func (s *Session) Active(now time.Time) bool {
+	return s.RevokedAt == nil && now.Before(s.ExpiresAt)
+}
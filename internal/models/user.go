@@ -9,21 +9,50 @@ import (
 	"github.com/woxQAQ/gim/pkg/auth"
 )
 
+const (
+	// UserStatusDisabled 账号已禁用，不能登录和建立连接
+	UserStatusDisabled int8 = 0
+	// UserStatusEnabled 账号正常
+	UserStatusEnabled int8 = 1
+)
+
+const (
+	// RoleUser 普通用户
+	RoleUser = "user"
+	// RoleModerator 审核员，可以审核消息和查看审核记录
+	RoleModerator = "moderator"
+	// RoleAdmin 管理员，可以执行全部管理操作
+	RoleAdmin = "admin"
+	// RoleBot 机器人账号，只能通过 API 密钥调用机器人接口，不能登录，也不能通过修改角色获得
+	RoleBot = "bot"
+)
+
+// ValidRole 判断 role 是否为可以分配给用户的角色
+func ValidRole(role string) bool {
+	switch role {
+	case RoleUser, RoleModerator, RoleAdmin:
+		return true
+	}
+	return false
+}
+
 // User 用户模型
 type User struct {
-	ID        string    `gorm:"primaryKey;type:text"`
-	Username  string    `gorm:"type:text;not null"`
-	Password  string    `gorm:"type:text;not null"`
-	Nickname  string    `gorm:"type:text;default:''"`
-	Avatar    string    `gorm:"type:text;default:''"`
-	Gender    int8      `gorm:"type:smallint;default:0"` // 0: 未知, 1: 男, 2: 女
-	Phone     string    `gorm:"type:text;index"`
-	Email     string    `gorm:"type:text;index"`
-	Status    int8      `gorm:"type:smallint;default:1;index"` // 1: 正常, 0: 禁用
-	Bio       string    `gorm:"type:text;default:''"`
-	LastLogin time.Time `gorm:"index"`
-	CreatedAt time.Time `gorm:"autoCreateTime"`
-	UpdatedAt time.Time `gorm:"autoUpdateTime"`
+	ID              string     `gorm:"primaryKey;type:varchar(64)"`
+	Username        string     `gorm:"type:varchar(64);not null;uniqueIndex:idx_users_username"`
+	Password        string     `gorm:"type:text;not null"`
+	Nickname        string     `gorm:"type:varchar(64);default:''"`
+	Avatar          string     `gorm:"type:varchar(512);default:''"`
+	Gender          int8       `gorm:"type:smallint;default:0"` // 0: 未知, 1: 男, 2: 女
+	Phone           string     `gorm:"type:varchar(32);index"`
+	Email           string     `gorm:"type:varchar(255);index:idx_users_email,unique,where:email <> ''"`
+	EmailVerifiedAt *time.Time // 邮箱通过验证的时间，为空时未验证，修改邮箱后重新置空
+	Status          int8       `gorm:"type:smallint;default:1;index"` // 1: 正常, 0: 禁用
+	Role            string     `gorm:"type:varchar(16);not null;default:'user';index:idx_users_role"`
+	Bio             string     `gorm:"type:varchar(512);default:''"`
+	LastLogin       time.Time  `gorm:"index"`
+	CreatedAt       time.Time  `gorm:"autoCreateTime"`
+	UpdatedAt       time.Time  `gorm:"autoUpdateTime"`
 }
 
 func (u *User) TableName() string {
@@ -43,6 +72,11 @@ func (u *User) BeforeSave(tx *gorm.DB) error {
 	return nil
 }
 
+// EmailVerified 判断用户当前的邮箱是否已经验证
+func (u *User) EmailVerified() bool {
+	return u.Email != "" && u.EmailVerifiedAt != nil
+}
+
 // ValidatePassword 验证密码是否正确
 func (u *User) ValidatePassword(password string) bool {
 	return auth.ValidatePassword(password, u.Password)
@@ -51,9 +85,9 @@ func (u *User) ValidatePassword(password string) bool {
 func (u *User) ToResponse() *response.UserResponse {
 	status := func() string {
 		switch u.Status {
-		case 0:
+		case UserStatusDisabled:
 			return "disable"
-		case 1:
+		case UserStatusEnabled:
 			return "enable"
 		}
 		return ""
@@ -75,9 +109,20 @@ func (u *User) ToResponse() *response.UserResponse {
 		Nickname: u.Nickname,
 		Avatar:   u.Avatar,
 		Gender:   gender(),
-		Phone:    u.Phone,
-		Email:    u.Email,
 		Status:   status(),
+		Role:     u.Role,
 		Bio:      u.Bio,
+		UserContactResponse: &response.UserContactResponse{
+			Phone:         u.Phone,
+			Email:         u.Email,
+			EmailVerified: u.EmailVerified(),
+		},
 	}
 }
+
+// ToPublicResponse 返回不包含联系方式的用户资料，用于其他用户查询
+func (u *User) ToPublicResponse() *response.UserResponse {
+	resp := u.ToResponse()
+	resp.UserContactResponse = nil
+	return resp
+}
@@ -0,0 +1,130 @@
+package models
+
+import (
+	"strings"
+	"time"
+
+	"github.com/woxQAQ/gim/internal/apiserver/types/response"
+)
+
+// WebhookDeliveryStatus 回调投递状态
+type WebhookDeliveryStatus int8
+
+const (
+	// WebhookDeliveryPending 等待投递（包括等待重试）
+	WebhookDeliveryPending WebhookDeliveryStatus = iota
+	// WebhookDeliverySucceeded 投递成功
+	WebhookDeliverySucceeded
+	// WebhookDeliveryDead 重试耗尽，已转入死信表
+	WebhookDeliveryDead
+)
+
+func (s WebhookDeliveryStatus) String() string {
+	switch s {
+	case WebhookDeliveryPending:
+		return "pending"
+	case WebhookDeliverySucceeded:
+		return "succeeded"
+	case WebhookDeliveryDead:
+		return "dead"
+	default:
+		return "unknown"
+	}
+}
+
+// WebhookEndpoint 回调地址配置
+type WebhookEndpoint struct {
+	ID        string    `gorm:"primaryKey;type:varchar(64)"`
+	URL       string    `gorm:"type:text;not null"`
+	Secret    string    `gorm:"type:text;not null"` // HMAC签名密钥
+	Events    string    `gorm:"type:text;not null"` // 订阅的事件，逗号分隔，* 表示全部
+	Enabled   bool      `gorm:"not null;default:true;index"`
+	CreatedAt time.Time `gorm:"autoCreateTime"`
+	UpdatedAt time.Time `gorm:"autoUpdateTime"`
+}
+
+func (e *WebhookEndpoint) TableName() string {
+	return "webhook_endpoints"
+}
+
+// Subscribes 检查回调地址是否订阅了指定事件
+func (e *WebhookEndpoint) Subscribes(event string) bool {
+	for _, ev := range strings.Split(e.Events, ",") {
+		ev = strings.TrimSpace(ev)
+		if ev == "*" || ev == event {
+			return true
+		}
+	}
+	return false
+}
+
+func (e *WebhookEndpoint) ToResponse() *response.WebhookEndpointResponse {
+	return &response.WebhookEndpointResponse{
+		ID:        e.ID,
+		URL:       e.URL,
+		Events:    strings.Split(e.Events, ","),
+		Enabled:   e.Enabled,
+		CreatedAt: e.CreatedAt,
+	}
+}
+
+// WebhookDelivery 回调投递记录，同时作为重试队列
+type WebhookDelivery struct {
+	ID            string                `gorm:"primaryKey;type:varchar(64)"`
+	EndpointID    string                `gorm:"type:varchar(64);not null;index"`
+	Event         string                `gorm:"type:varchar(64);not null;index"`
+	Payload       string                `gorm:"type:text;not null"`
+	Status        WebhookDeliveryStatus `gorm:"type:smallint;not null;default:0;index:idx_webhook_delivery_due,priority:1"`
+	Attempts      int                   `gorm:"type:integer;not null;default:0"`
+	NextAttemptAt time.Time             `gorm:"index:idx_webhook_delivery_due,priority:2"`
+	ResponseCode  int                   `gorm:"type:integer"`
+	LastError     string                `gorm:"type:text"`
+	CreatedAt     time.Time             `gorm:"autoCreateTime;index"`
+	UpdatedAt     time.Time             `gorm:"autoUpdateTime"`
+}
+
+func (d *WebhookDelivery) TableName() string {
+	return "webhook_deliveries"
+}
+
+func (d *WebhookDelivery) ToResponse() *response.WebhookDeliveryResponse {
+	return &response.WebhookDeliveryResponse{
+		ID:            d.ID,
+		EndpointID:    d.EndpointID,
+		Event:         d.Event,
+		Payload:       d.Payload,
+		Status:        d.Status.String(),
+		Attempts:      d.Attempts,
+		NextAttemptAt: d.NextAttemptAt,
+		ResponseCode:  d.ResponseCode,
+		LastError:     d.LastError,
+		CreatedAt:     d.CreatedAt,
+	}
+}
+
+// WebhookDeadLetter 重试耗尽的回调投递
+type WebhookDeadLetter struct {
+	ID         string    `gorm:"primaryKey;type:varchar(64)"` // 与投递记录ID相同
+	EndpointID string    `gorm:"type:varchar(64);not null;index"`
+	Event      string    `gorm:"type:text;not null"`
+	Payload    string    `gorm:"type:text;not null"`
+	Attempts   int       `gorm:"type:integer;not null"`
+	LastError  string    `gorm:"type:text"`
+	CreatedAt  time.Time `gorm:"autoCreateTime;index"`
+}
+
+func (d *WebhookDeadLetter) TableName() string {
+	return "webhook_dead_letters"
+}
+
+func (d *WebhookDeadLetter) ToResponse() *response.WebhookDeadLetterResponse {
+	return &response.WebhookDeadLetterResponse{
+		ID:         d.ID,
+		EndpointID: d.EndpointID,
+		Event:      d.Event,
+		Payload:    d.Payload,
+		Attempts:   d.Attempts,
+		LastError:  d.LastError,
+		CreatedAt:  d.CreatedAt,
+	}
+}
@@ -0,0 +1,155 @@
+package search
+
+import (
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+
+	"github.com/woxQAQ/gim/internal/types"
+)
+
+const (
+	// FTSTableName SQLite FTS5 外部内容表名
+	FTSTableName = "message_search_fts"
+	// trigramMinRunes FTS5 trigram 分词器能够匹配的最短关键词
+	trigramMinRunes = 3
+)
+
+// row 索引表的一行，表结构由迁移维护
+type row struct {
+	ID             string `gorm:"primaryKey"`
+	ConversationID string
+	FromID         string
+	ToID           string
+	Type           types.MessageType
+	Content        string
+	CreatedAt      time.Time
+}
+
+func (row) TableName() string {
+	return TableName
+}
+
+// DBIndex 基于数据库的索引，SQLite 使用 FTS5，PostgreSQL 使用 tsvector
+type DBIndex struct {
+	db      *gorm.DB
+	dialect string
+}
+
+var _ Index = (*DBIndex)(nil)
+
+// New 根据数据库方言创建索引，数据库不支持或索引表不存在时返回 ErrUnsupported
+func New(gdb *gorm.DB) (*DBIndex, error) {
+	dialect := gdb.Dialector.Name()
+	switch dialect {
+	case "sqlite":
+		if !gdb.Migrator().HasTable(FTSTableName) {
+			return nil, ErrUnsupported
+		}
+	case "postgres":
+		if !gdb.Migrator().HasTable(TableName) {
+			return nil, ErrUnsupported
+		}
+	default:
+		return nil, ErrUnsupported
+	}
+	return &DBIndex{db: gdb, dialect: dialect}, nil
+}
+
+// Index 实现 Index 接口
+func (i *DBIndex) Index(doc *Document) error {
+	r := &row{
+		ID:             doc.ID,
+		ConversationID: doc.ConversationID,
+		FromID:         doc.FromID,
+		ToID:           doc.ToID,
+		Type:           doc.Type,
+		Content:        doc.Content,
+		CreatedAt:      doc.CreatedAt,
+	}
+	return i.db.Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "id"}},
+		DoUpdates: clause.AssignmentColumns([]string{"conversation_id", "from_id", "to_id", "type", "content", "created_at"}),
+	}).Create(r).Error
+}
+
+// Delete 实现 Index 接口
+func (i *DBIndex) Delete(id string) error {
+	return i.db.Delete(&row{}, "id = ?", id).Error
+}
+
+// Search 实现 Index 接口
+func (i *DBIndex) Search(q *Query) ([]*Hit, error) {
+	if err := q.Validate(); err != nil {
+		return nil, err
+	}
+	keyword := strings.TrimSpace(q.Keyword)
+
+	query := i.db.Table(TableName + " AS s").Select("s.*")
+	switch i.dialect {
+	case "sqlite":
+		if utf8.RuneCountInString(keyword) >= trigramMinRunes {
+			query = query.Joins("JOIN "+FTSTableName+" f ON f.rowid = s.rowid").
+				Where("f.content MATCH ?", `"`+strings.ReplaceAll(keyword, `"`, `""`)+`"`)
+		} else {
+			// trigram 无法匹配过短的关键词，退化为 LIKE
+			query = query.Where(`s.content LIKE ? ESCAPE '\'`, "%"+escapeLike(keyword)+"%")
+		}
+	case "postgres":
+		query = query.Where("s.tsv @@ plainto_tsquery('simple', ?)", keyword)
+	}
+
+	// 单聊按参与方过滤，群聊只检索用户所在的群
+	direct := i.db.Where("s.conversation_id NOT LIKE ?", groupConversationPrefix+"%").
+		Where(i.db.Where("s.from_id = ?", q.UserID).Or("s.to_id = ?", q.UserID))
+	if groups := q.groupConversations(); len(groups) > 0 {
+		query = query.Where(direct.Or("s.conversation_id IN ?", groups))
+	} else {
+		query = query.Where(direct)
+	}
+	if q.ConversationID != "" {
+		query = query.Where("s.conversation_id = ?", q.ConversationID)
+	}
+	if len(q.Types) > 0 {
+		query = query.Where("s.type IN ?", q.Types)
+	}
+	if q.Since != nil {
+		query = query.Where("s.created_at >= ?", *q.Since)
+	}
+	if q.Until != nil {
+		query = query.Where("s.created_at < ?", *q.Until)
+	}
+	if q.Before != "" {
+		query = query.Where("s.id < ?", q.Before)
+	}
+	query = query.Order("s.id desc")
+	if q.Limit > 0 {
+		query = query.Limit(q.Limit)
+	}
+
+	var rows []*row
+	if err := query.Find(&rows).Error; err != nil {
+		return nil, err
+	}
+	docs := make([]*Document, 0, len(rows))
+	for _, r := range rows {
+		docs = append(docs, &Document{
+			ID:             r.ID,
+			ConversationID: r.ConversationID,
+			FromID:         r.FromID,
+			ToID:           r.ToID,
+			Type:           r.Type,
+			Content:        r.Content,
+			CreatedAt:      r.CreatedAt,
+		})
+	}
+	return toHits(docs, keyword), nil
+}
+
+// escapeLike 转义 LIKE 中的通配符
+func escapeLike(s string) string {
+	return strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`).Replace(s)
+}
@@ -0,0 +1,73 @@
+package search
+
+import (
+	"sort"
+	"strings"
+	"sync"
+)
+
+// MemoryIndex 基于内存的索引，按子串匹配关键词，不区分大小写
+type MemoryIndex struct {
+	mutex sync.RWMutex
+	docs  map[string]*memoryDoc
+}
+
+type memoryDoc struct {
+	doc   *Document
+	lower string
+}
+
+var _ Index = (*MemoryIndex)(nil)
+
+// NewMemoryIndex 创建内存索引
+func NewMemoryIndex() *MemoryIndex {
+	return &MemoryIndex{docs: make(map[string]*memoryDoc)}
+}
+
+// Index 实现 Index 接口
+func (m *MemoryIndex) Index(doc *Document) error {
+	d := *doc
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	m.docs[doc.ID] = &memoryDoc{doc: &d, lower: strings.ToLower(doc.Content)}
+	return nil
+}
+
+// Delete 实现 Index 接口
+func (m *MemoryIndex) Delete(id string) error {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	delete(m.docs, id)
+	return nil
+}
+
+// Search 实现 Index 接口
+func (m *MemoryIndex) Search(q *Query) ([]*Hit, error) {
+	if err := q.Validate(); err != nil {
+		return nil, err
+	}
+	keyword := strings.ToLower(strings.TrimSpace(q.Keyword))
+
+	m.mutex.RLock()
+	var docs []*Document
+	for _, d := range m.docs {
+		if strings.Contains(d.lower, keyword) && q.matches(d.doc) {
+			docs = append(docs, d.doc)
+		}
+	}
+	m.mutex.RUnlock()
+
+	sort.Slice(docs, func(i, j int) bool { return docs[i].ID > docs[j].ID })
+	if q.Limit > 0 && len(docs) > q.Limit {
+		docs = docs[:q.Limit]
+	}
+	return toHits(docs, q.Keyword), nil
+}
+
+func toHits(docs []*Document, keyword string) []*Hit {
+	hits := make([]*Hit, 0, len(docs))
+	for _, d := range docs {
+		hits = append(hits, &Hit{Document: d, Highlight: Highlight(d.Content, keyword)})
+	}
+	return hits
+}
@@ -0,0 +1,206 @@
+// Package search 提供消息全文检索。
+//
+// 支持三种后端：SQLite FTS5、PostgreSQL tsvector 以及内存索引。
+// 数据库后端的索引表由迁移创建，网关在消息存储后写入索引，
+// 内存索引只在单个进程内可见，用于不支持全文检索的数据库和测试。
+package search
+
+import (
+	"errors"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/woxQAQ/gim/internal/models"
+	"github.com/woxQAQ/gim/internal/types"
+)
+
+const (
+	// TableName 数据库索引表名
+	TableName = "message_search"
+
+	highlightOpen  = "<mark>"
+	highlightClose = "</mark>"
+	// snippetRadius 高亮片段中关键词前后保留的字符数
+	snippetRadius = 32
+	// groupConversationPrefix 群聊会话ID的前缀，见 models.GroupConversationID
+	groupConversationPrefix = "g:"
+)
+
+var (
+	// ErrUnsupported 当前数据库不支持全文检索或索引表不存在
+	ErrUnsupported = errors.New("full-text search is not supported by this database")
+	// ErrInvalidQuery 查询条件不完整
+	ErrInvalidQuery = errors.New("invalid search query")
+)
+
+// Document 被索引的消息
+type Document struct {
+	ID             string
+	ConversationID string
+	FromID         string
+	ToID           string
+	Type           types.MessageType
+	Content        string
+	CreatedAt      time.Time
+}
+
+// DocumentFromMessage 将消息模型转换为索引文档
+func DocumentFromMessage(m *models.Message) *Document {
+	return &Document{
+		ID:             m.ID,
+		ConversationID: m.ConversationID,
+		FromID:         m.FromID,
+		ToID:           m.ToID,
+		Type:           m.Type,
+		Content:        m.Content,
+		CreatedAt:      m.CreatedAt,
+	}
+}
+
+// Query 检索条件，UserID 与 Keyword 必填，只返回 UserID 的单聊以及 GroupIDs 中群聊的消息
+type Query struct {
+	UserID         string
+	GroupIDs       []string // UserID 所在的群，由调用方查询
+	Keyword        string
+	ConversationID string
+	Types          []types.MessageType
+	Since          *time.Time // 包含
+	Until          *time.Time // 不包含
+	Before         string     // 消息ID游标，结果按ID倒序
+	Limit          int
+}
+
+// Validate 校验检索条件
+func (q *Query) Validate() error {
+	if q.UserID == "" || strings.TrimSpace(q.Keyword) == "" {
+		return ErrInvalidQuery
+	}
+	return nil
+}
+
+// Hit 一条检索结果
+type Hit struct {
+	Document  *Document
+	Highlight string // 命中关键词的内容片段，关键词以 <mark></mark> 包裹
+}
+
+// Index 消息全文索引
+type Index interface {
+	// Index 新增或覆盖一条消息的索引
+	Index(doc *Document) error
+	// Delete 删除一条消息的索引，消息被撤回时调用
+	Delete(id string) error
+	// Search 检索消息，按ID倒序返回
+	Search(q *Query) ([]*Hit, error)
+}
+
+// Highlight 返回 content 中第一个命中 keyword 的片段，并用 <mark> 标记所有命中，匹配不区分大小写
+func Highlight(content, keyword string) string {
+	keyword = strings.TrimSpace(keyword)
+	lower, lowerKw := strings.ToLower(content), strings.ToLower(keyword)
+	// 大小写转换改变了字节长度时无法按下标对应，退化为原文
+	if keyword == "" || len(lower) != len(content) || len(lowerKw) != len(keyword) {
+		return content
+	}
+	first := strings.Index(lower, lowerKw)
+	if first < 0 {
+		return content
+	}
+
+	start := backRunes(content, first, snippetRadius)
+	end := forwardRunes(content, first+len(keyword), snippetRadius)
+
+	var b strings.Builder
+	if start > 0 {
+		b.WriteString("…")
+	}
+	for i := start; i < end; {
+		j := strings.Index(lower[i:end], lowerKw)
+		if j < 0 || i+j+len(keyword) > end {
+			b.WriteString(content[i:end])
+			break
+		}
+		b.WriteString(content[i : i+j])
+		b.WriteString(highlightOpen)
+		b.WriteString(content[i+j : i+j+len(keyword)])
+		b.WriteString(highlightClose)
+		i += j + len(keyword)
+	}
+	if end < len(content) {
+		b.WriteString("…")
+	}
+	return b.String()
+}
+
+// backRunes 从字节下标 i 向前移动 n 个字符
+func backRunes(s string, i, n int) int {
+	for ; n > 0 && i > 0; n-- {
+		_, size := utf8.DecodeLastRuneInString(s[:i])
+		i -= size
+	}
+	return i
+}
+
+// forwardRunes 从字节下标 i 向后移动 n 个字符
+func forwardRunes(s string, i, n int) int {
+	for ; n > 0 && i < len(s); n-- {
+		_, size := utf8.DecodeRuneInString(s[i:])
+		i += size
+	}
+	return i
+}
+
+// matches 检查文档是否满足除关键词外的过滤条件
+func (q *Query) matches(doc *Document) bool {
+	if !q.canAccess(doc) {
+		return false
+	}
+	if q.ConversationID != "" && doc.ConversationID != q.ConversationID {
+		return false
+	}
+	if len(q.Types) > 0 {
+		found := false
+		for _, t := range q.Types {
+			if t == doc.Type {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+	if q.Since != nil && doc.CreatedAt.Before(*q.Since) {
+		return false
+	}
+	if q.Until != nil && !doc.CreatedAt.Before(*q.Until) {
+		return false
+	}
+	if q.Before != "" && doc.ID >= q.Before {
+		return false
+	}
+	return true
+}
+
+// canAccess 检查 UserID 是否可以访问文档所在的会话
+func (q *Query) canAccess(doc *Document) bool {
+	if !strings.HasPrefix(doc.ConversationID, groupConversationPrefix) {
+		return doc.FromID == q.UserID || doc.ToID == q.UserID
+	}
+	for _, id := range q.GroupIDs {
+		if doc.ConversationID == models.GroupConversationID(id) {
+			return true
+		}
+	}
+	return false
+}
+
+// groupConversations 返回 GroupIDs 对应的会话ID
+func (q *Query) groupConversations() []string {
+	out := make([]string, 0, len(q.GroupIDs))
+	for _, id := range q.GroupIDs {
+		out = append(out, models.GroupConversationID(id))
+	}
+	return out
+}
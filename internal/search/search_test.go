@@ -0,0 +1,206 @@
+package search_test
+
+import (
+	"fmt"
+	"path/filepath"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"github.com/woxQAQ/gim/internal/apiserver/stores"
+	"github.com/woxQAQ/gim/internal/migrations"
+	"github.com/woxQAQ/gim/internal/models"
+	"github.com/woxQAQ/gim/internal/search"
+	"github.com/woxQAQ/gim/internal/types"
+	"github.com/woxQAQ/gim/pkg/db"
+)
+
+var base = time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+
+// seedMessages 1001-1004 为 alice 与 bob 的单聊，1005 为 carol 与 dave 的单聊
+func seedMessages() []*models.Message {
+	seed := []struct {
+		from, to, content string
+		typ               types.MessageType
+	}{
+		{"alice", "bob", "Lunch at the noodle place?", types.MessageTypeText},
+		{"bob", "alice", "Ramen again, sure", types.MessageTypeText},
+		{"alice", "bob", "noodle_menu.pdf", types.MessageTypeFile},
+		{"bob", "alice", "明天一起吃面条吗", types.MessageTypeText},
+		{"carol", "dave", "noodle secrets", types.MessageTypeText},
+	}
+	out := make([]*models.Message, 0, len(seed))
+	for i, s := range seed {
+		out = append(out, &models.Message{
+			ID:             fmt.Sprintf("%d", 1001+i),
+			ConversationID: models.DirectConversationID(s.from, s.to),
+			Type:           s.typ,
+			Content:        s.content,
+			FromID:         s.from,
+			ToID:           s.to,
+			Status:         models.MessageStatusSent,
+			Platform:       1,
+			CreatedAt:      base.Add(time.Duration(i) * time.Hour),
+		})
+	}
+	return out
+}
+
+func hitIDs(hits []*search.Hit) []string {
+	out := make([]string, 0, len(hits))
+	for _, h := range hits {
+		out = append(out, h.Document.ID)
+	}
+	return out
+}
+
+// describeIndex 对所有后端运行相同的检索用例
+func describeIndex(newIndex func() search.Index) {
+	var idx search.Index
+
+	BeforeEach(func() {
+		idx = newIndex()
+		for _, m := range seedMessages() {
+			Expect(idx.Index(search.DocumentFromMessage(m))).To(Succeed())
+		}
+	})
+
+	It("应该只返回用户参与的会话中的消息并高亮", func() {
+		hits, err := idx.Search(&search.Query{UserID: "alice", Keyword: "noodle"})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(hitIDs(hits)).To(Equal([]string{"1003", "1001"}))
+		Expect(hits[1].Highlight).To(Equal("Lunch at the <mark>noodle</mark> place?"))
+	})
+
+	It("应该支持类型、时间范围和游标过滤", func() {
+		hits, err := idx.Search(&search.Query{
+			UserID:  "bob",
+			Keyword: "noodle",
+			Types:   []types.MessageType{types.MessageTypeText},
+		})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(hitIDs(hits)).To(Equal([]string{"1001"}))
+
+		since := base.Add(time.Hour)
+		hits, err = idx.Search(&search.Query{UserID: "bob", Keyword: "noodle", Since: &since})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(hitIDs(hits)).To(Equal([]string{"1003"}))
+
+		hits, err = idx.Search(&search.Query{UserID: "bob", Keyword: "noodle", Before: "1003", Limit: 1})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(hitIDs(hits)).To(Equal([]string{"1001"}))
+	})
+
+	It("应该支持中文关键词", func() {
+		hits, err := idx.Search(&search.Query{UserID: "alice", Keyword: "吃面条"})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(hitIDs(hits)).To(Equal([]string{"1004"}))
+		Expect(hits[0].Highlight).To(Equal("明天一起<mark>吃面条</mark>吗"))
+	})
+
+	It("编辑和撤回后应该更新索引", func() {
+		edited := seedMessages()[0]
+		edited.Content = "Lunch at the pizza place?"
+		Expect(idx.Index(search.DocumentFromMessage(edited))).To(Succeed())
+		Expect(idx.Delete("1003")).To(Succeed())
+
+		hits, err := idx.Search(&search.Query{UserID: "alice", Keyword: "noodle"})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(hits).To(BeEmpty())
+	})
+
+	It("群聊消息只对群成员可见，包括其他成员发送的消息", func() {
+		msg := &models.Message{}
+		msg.FromTypes(&types.Message{
+			Header: types.MessageHeader{
+				ID:        "1006",
+				Type:      types.MessageTypeText,
+				From:      "carol",
+				GroupID:   "g1",
+				Platform:  1,
+				Timestamp: base,
+			},
+			Payload: []byte("noodle party tonight"),
+		})
+		Expect(idx.Index(search.DocumentFromMessage(msg))).To(Succeed())
+
+		hits, err := idx.Search(&search.Query{UserID: "alice", GroupIDs: []string{"g1"}, Keyword: "party"})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(hitIDs(hits)).To(Equal([]string{"1006"}))
+		hits, err = idx.Search(&search.Query{UserID: "alice", GroupIDs: []string{"g1"}, Keyword: "noodle"})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(hitIDs(hits)).To(Equal([]string{"1006", "1003", "1001"}))
+
+		// 已经退出的群，即使是自己发送的消息也不再返回
+		hits, err = idx.Search(&search.Query{UserID: "carol", Keyword: "party"})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(hits).To(BeEmpty())
+	})
+
+	It("缺少用户或关键词时应该返回错误", func() {
+		_, err := idx.Search(&search.Query{UserID: "alice"})
+		Expect(err).To(MatchError(search.ErrInvalidQuery))
+	})
+}
+
+var _ = Describe("Highlight", func() {
+	It("应该截取命中片段并标记所有命中", func() {
+		Expect(search.Highlight("Go go GO", "go")).To(Equal("<mark>Go</mark> <mark>go</mark> <mark>GO</mark>"))
+
+		long := "aaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaa keyword bbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbb"
+		Expect(search.Highlight(long, "keyword")).To(Equal(
+			"…aaaaaaaaaaaaaaaaaaaaaaaaaaaaaaa <mark>keyword</mark> bbbbbbbbbbbbbbbbbbbbbbbbbbbbbbb…"))
+	})
+})
+
+var _ = Describe("MemoryIndex", func() {
+	describeIndex(func() search.Index { return search.NewMemoryIndex() })
+})
+
+var _ = Describe("DBIndex", func() {
+	describeIndex(func() search.Index {
+		gdb, err := db.Open(&db.Config{DSN: filepath.Join(GinkgoT().TempDir(), "search.db")})
+		Expect(err).NotTo(HaveOccurred())
+		m, err := db.NewMigrator(gdb, migrations.All())
+		Expect(err).NotTo(HaveOccurred())
+		_, err = m.Up()
+		Expect(err).NotTo(HaveOccurred())
+
+		idx, err := search.New(gdb)
+		if err != nil {
+			Skip("SQLite 未编译 FTS5，使用 -tags sqlite_fts5 运行")
+		}
+		return idx
+	})
+})
+
+var _ = Describe("SyncedIndex", func() {
+	It("应该从消息表增量同步新增、编辑和撤回", func() {
+		gdb, err := db.Open(&db.Config{DSN: filepath.Join(GinkgoT().TempDir(), "synced.db")})
+		Expect(err).NotTo(HaveOccurred())
+		m, err := db.NewMigrator(gdb, migrations.All())
+		Expect(err).NotTo(HaveOccurred())
+		_, err = m.Up()
+		Expect(err).NotTo(HaveOccurred())
+
+		store := stores.NewMessageStore(gdb)
+		for _, msg := range seedMessages() {
+			Expect(store.CreateMessage(msg)).To(Succeed())
+		}
+		idx := search.NewSyncedIndex(store)
+
+		hits, err := idx.Search(&search.Query{UserID: "alice", Keyword: "noodle"})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(hitIDs(hits)).To(Equal([]string{"1003", "1001"}))
+
+		// 保证更新时间晚于上次同步的水位
+		time.Sleep(10 * time.Millisecond)
+		Expect(store.UpdateMessageContent("1001", "pizza")).To(Succeed())
+		Expect(store.RecallMessage("1003")).To(Succeed())
+
+		hits, err = idx.Search(&search.Query{UserID: "alice", Keyword: "noodle"})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(hits).To(BeEmpty())
+	})
+})
@@ -0,0 +1,75 @@
+package search
+
+import (
+	"sync"
+	"time"
+
+	"github.com/woxQAQ/gim/internal/models"
+)
+
+// syncBatchSize 每次从数据库拉取的消息数量
+const syncBatchSize = 500
+
+// Source 提供增量同步所需的消息
+type Source interface {
+	// ListMessagesUpdatedSince 按更新时间正序返回 since 之后（包含）更新过的消息
+	ListMessagesUpdatedSince(since time.Time, limit int) ([]*models.Message, error)
+}
+
+// SyncedIndex 在每次检索前从数据库增量同步的内存索引。
+// 用于数据库不支持全文检索、写入方（网关）与检索方不在同一进程的部署。
+type SyncedIndex struct {
+	*MemoryIndex
+	source Source
+
+	mutex     sync.Mutex
+	watermark time.Time
+}
+
+var _ Index = (*SyncedIndex)(nil)
+
+// NewSyncedIndex 创建增量同步的内存索引
+func NewSyncedIndex(source Source) *SyncedIndex {
+	return &SyncedIndex{MemoryIndex: NewMemoryIndex(), source: source}
+}
+
+// Search 同步后检索
+func (s *SyncedIndex) Search(q *Query) ([]*Hit, error) {
+	if err := s.Sync(); err != nil {
+		return nil, err
+	}
+	return s.MemoryIndex.Search(q)
+}
+
+// Sync 拉取上次同步之后更新过的消息，撤回的消息从索引中删除
+func (s *SyncedIndex) Sync() error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	for {
+		messages, err := s.source.ListMessagesUpdatedSince(s.watermark, syncBatchSize)
+		if err != nil {
+			return err
+		}
+		for _, m := range messages {
+			if m.Status == models.MessageStatusRecalled {
+				_ = s.MemoryIndex.Delete(m.ID)
+			} else {
+				_ = s.MemoryIndex.Index(DocumentFromMessage(m))
+			}
+		}
+		if len(messages) < syncBatchSize {
+			if len(messages) > 0 {
+				s.watermark = messages[len(messages)-1].UpdatedAt
+			}
+			return nil
+		}
+
+		// 整批消息更新时间相同时无法推进水位，避免死循环
+		last := messages[len(messages)-1].UpdatedAt
+		if !last.After(s.watermark) {
+			return nil
+		}
+		s.watermark = last
+	}
+}
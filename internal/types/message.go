@@ -9,12 +9,14 @@ import (
 
 // MessageHeader 定义消息头部结构
 type MessageHeader struct {
-	ID        string      `json:"id"`        // 消息唯一标识
-	Type      MessageType `json:"type"`      // 消息类型
-	Timestamp time.Time   `json:"timestamp"` // 消息时间戳
-	From      string      `json:"from"`      // 发送者ID
-	To        string      `json:"to"`        // 接收者ID
-	Platform  int32       `json:"platform"`  // 平台标识
+	ID        string      `json:"id"`                 // 消息唯一标识
+	Type      MessageType `json:"type"`               // 消息类型
+	Timestamp time.Time   `json:"timestamp"`          // 消息时间戳
+	From      string      `json:"from"`               // 发送者ID
+	To        string      `json:"to"`                 // 接收者ID
+	GroupID   string      `json:"group_id,omitempty"` // 群ID，不为空时为群聊消息，To 为空
+	Platform  int32       `json:"platform"`           // 平台标识
+	SubType   string      `json:"sub_type,omitempty"` // 自定义消息子类型
 }
 
 // Message 定义新的消息结构
@@ -66,6 +68,10 @@ func (m *Message) GetTo() string {
 	return m.Header.To
 }
 
+func (m *Message) GetGroupID() string {
+	return m.Header.GroupID
+}
+
 func (m *Message) GetPlatform() int32 {
 	return m.Header.Platform
 }
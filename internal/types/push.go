@@ -0,0 +1,62 @@
+package types
+
+// PushTargetType 定义推送目标类型
+type PushTargetType string
+
+const (
+	// PushTargetAll 推送给所有在线用户
+	PushTargetAll PushTargetType = "all"
+	// PushTargetUsers 推送给指定用户
+	PushTargetUsers PushTargetType = "users"
+	// PushTargetPlatform 推送给指定平台上的所有在线用户
+	PushTargetPlatform PushTargetType = "platform"
+)
+
+// PushRequest 定义 apiserver 调用网关推送消息的请求
+type PushRequest struct {
+	Target     PushTargetType `json:"target"`
+	UserIDs    []string       `json:"user_ids,omitempty"`
+	PlatformID int32          `json:"platform_id,omitempty"`
+	Message    *Message       `json:"message"`
+}
+
+// PushResult 定义网关推送结果
+type PushResult struct {
+	Delivered int      `json:"delivered"` // 成功推送的用户数量
+	Errors    []string `json:"errors,omitempty"`
+}
+
+// SendRequest 定义 apiserver 调用网关发送业务消息的请求，
+// 消息与 WebSocket 连接上收到的消息经过相同的校验、投递和存储
+type SendRequest struct {
+	Message *Message `json:"message"`
+}
+
+// SendResult 定义网关发送业务消息的结果
+type SendResult struct {
+	MessageID string `json:"message_id"`
+	Rejected  string `json:"rejected,omitempty"` // 消息被拒绝时的原因，被拒绝的消息不会投递也不会存储
+}
+
+// DisconnectRequest 定义 apiserver 调用网关断开用户连接的请求
+type DisconnectRequest struct {
+	UserIDs    []string `json:"user_ids"`
+	PlatformID int32    `json:"platform_id,omitempty"` // 只断开该平台的连接，为0时断开所有平台
+	Code       string   `json:"code,omitempty"`        // 断开前通知客户端的代码，为空时为 session_terminated
+	Reason     string   `json:"reason,omitempty"`      // 断开前通知客户端的原因
+}
+
+// DisconnectResult 定义网关断开连接的结果
+type DisconnectResult struct {
+	Disconnected int `json:"disconnected"` // 断开的连接数量
+}
+
+// PresenceRequest 定义 apiserver 查询用户在线状态的请求
+type PresenceRequest struct {
+	UserIDs []string `json:"user_ids"`
+}
+
+// PresenceResult 定义用户在线状态，只包含在线的用户
+type PresenceResult struct {
+	Online map[string][]int32 `json:"online"` // 用户ID到在线平台的映射
+}
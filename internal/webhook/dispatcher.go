@@ -0,0 +1,251 @@
+package webhook
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/woxQAQ/gim/internal/apiserver/stores"
+	"github.com/woxQAQ/gim/internal/models"
+	"github.com/woxQAQ/gim/pkg/logger"
+	"github.com/woxQAQ/gim/pkg/snowflake"
+)
+
+// Config 回调分发器配置
+type Config struct {
+	PollInterval time.Duration // 扫描重试队列的间隔
+	BatchSize    int           // 每次扫描处理的最大投递数量
+	MaxAttempts  int           // 最大尝试次数，超过后转入死信表
+	BaseBackoff  time.Duration // 首次重试的等待时间，之后按2的指数增长
+	MaxBackoff   time.Duration // 重试等待时间上限
+	Timeout      time.Duration // 单次请求超时时间
+}
+
+// defaultConfig 默认回调分发器配置
+var defaultConfig = Config{
+	PollInterval: time.Second,
+	BatchSize:    100,
+	MaxAttempts:  8,
+	BaseBackoff:  time.Second,
+	MaxBackoff:   time.Hour,
+	Timeout:      5 * time.Second,
+}
+
+// Dispatcher 负责生成回调投递记录并异步投递.
+//
+// 投递记录持久化在 webhook_deliveries 表中，既是投递日志也是重试队列：
+// 失败的投递按指数退避设置下一次尝试时间，超过最大次数后写入死信表。
+type Dispatcher struct {
+	store  *stores.WebhookStore
+	client *http.Client
+	cfg    Config
+	logger logger.Logger
+
+	notify    chan struct{}
+	cancel    context.CancelFunc
+	done      chan struct{}
+	startOnce sync.Once
+	stopOnce  sync.Once
+}
+
+// NewDispatcher 创建回调分发器，cfg 为空时使用默认配置
+func NewDispatcher(store *stores.WebhookStore, l logger.Logger, cfg *Config) *Dispatcher {
+	c := defaultConfig
+	if cfg != nil {
+		c = *cfg
+	}
+	return &Dispatcher{
+		store:  store,
+		client: &http.Client{Timeout: c.Timeout},
+		cfg:    c,
+		logger: l,
+		notify: make(chan struct{}, 1),
+		done:   make(chan struct{}),
+	}
+}
+
+// Publish 为所有订阅了该事件的回调地址生成投递记录
+func (d *Dispatcher) Publish(event string, data interface{}) error {
+	endpoints, err := d.store.ListEnabledEndpoints()
+	if err != nil {
+		return err
+	}
+
+	now := time.Now()
+	deliveries := make([]*models.WebhookDelivery, 0, len(endpoints))
+	for _, endpoint := range endpoints {
+		if !endpoint.Subscribes(event) {
+			continue
+		}
+		id := snowflake.GenerateID()
+		payload, err := json.Marshal(&Envelope{
+			ID:        id,
+			Event:     event,
+			Timestamp: now,
+			Data:      data,
+		})
+		if err != nil {
+			return err
+		}
+		deliveries = append(deliveries, &models.WebhookDelivery{
+			ID:            id,
+			EndpointID:    endpoint.ID,
+			Event:         event,
+			Payload:       string(payload),
+			Status:        models.WebhookDeliveryPending,
+			NextAttemptAt: now,
+		})
+	}
+
+	if err := d.store.CreateDeliveries(deliveries); err != nil {
+		return err
+	}
+	if len(deliveries) > 0 {
+		d.wakeup()
+	}
+	return nil
+}
+
+// Start 启动后台投递协程
+func (d *Dispatcher) Start(ctx context.Context) {
+	d.startOnce.Do(func() {
+		ctx, d.cancel = context.WithCancel(ctx)
+		go d.run(ctx)
+	})
+}
+
+// Stop 停止后台投递协程并等待其退出
+func (d *Dispatcher) Stop() {
+	d.stopOnce.Do(func() {
+		if d.cancel == nil {
+			return
+		}
+		d.cancel()
+		<-d.done
+	})
+}
+
+// wakeup 通知后台协程立即扫描一次队列
+func (d *Dispatcher) wakeup() {
+	select {
+	case d.notify <- struct{}{}:
+	default:
+	}
+}
+
+func (d *Dispatcher) run(ctx context.Context) {
+	defer close(d.done)
+	ticker := time.NewTicker(d.cfg.PollInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		case <-d.notify:
+		}
+		d.DeliverDue(ctx)
+	}
+}
+
+// DeliverDue 投递所有已到期的记录
+func (d *Dispatcher) DeliverDue(ctx context.Context) {
+	deliveries, err := d.store.GetDueDeliveries(time.Now(), d.cfg.BatchSize)
+	if err != nil {
+		d.logger.Error("获取待投递回调失败", logger.Error(err))
+		return
+	}
+	for _, delivery := range deliveries {
+		if ctx.Err() != nil {
+			return
+		}
+		d.attempt(ctx, delivery)
+	}
+}
+
+// attempt 执行一次投递并根据结果更新记录
+func (d *Dispatcher) attempt(ctx context.Context, delivery *models.WebhookDelivery) {
+	endpoint, err := d.store.GetEndpoint(delivery.EndpointID)
+	if err == nil && !endpoint.Enabled {
+		err = errors.New("回调地址已停用")
+	}
+
+	delivery.Attempts++
+	if err == nil {
+		delivery.ResponseCode, err = d.post(ctx, endpoint, delivery)
+	}
+
+	if err == nil {
+		delivery.Status = models.WebhookDeliverySucceeded
+		delivery.LastError = ""
+		if err := d.store.UpdateDelivery(delivery); err != nil {
+			d.logger.Error("更新回调投递记录失败", logger.String("delivery_id", delivery.ID), logger.Error(err))
+		}
+		return
+	}
+
+	delivery.LastError = err.Error()
+	if delivery.Attempts >= d.cfg.MaxAttempts {
+		d.logger.Warn("回调投递重试耗尽，转入死信",
+			logger.String("delivery_id", delivery.ID),
+			logger.String("endpoint_id", delivery.EndpointID),
+			logger.Error(err))
+		if err := d.store.MoveToDeadLetter(delivery); err != nil {
+			d.logger.Error("写入回调死信失败", logger.String("delivery_id", delivery.ID), logger.Error(err))
+		}
+		return
+	}
+
+	delivery.NextAttemptAt = time.Now().Add(d.backoff(delivery.Attempts))
+	if err := d.store.UpdateDelivery(delivery); err != nil {
+		d.logger.Error("更新回调投递记录失败", logger.String("delivery_id", delivery.ID), logger.Error(err))
+	}
+}
+
+// post 发送带签名的回调请求，返回响应状态码
+func (d *Dispatcher) post(ctx context.Context, endpoint *models.WebhookEndpoint, delivery *models.WebhookDelivery) (int, error) {
+	body := []byte(delivery.Payload)
+	timestamp := time.Now().Unix()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, endpoint.URL, bytes.NewReader(body))
+	if err != nil {
+		return 0, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(HeaderEvent, delivery.Event)
+	req.Header.Set(HeaderDelivery, delivery.ID)
+	req.Header.Set(HeaderTimestamp, strconv.FormatInt(timestamp, 10))
+	req.Header.Set(HeaderSignature, Sign(endpoint.Secret, timestamp, body))
+
+	resp, err := d.client.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+	_, _ = io.Copy(io.Discard, io.LimitReader(resp.Body, 4096))
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return resp.StatusCode, fmt.Errorf("unexpected status code %d", resp.StatusCode)
+	}
+	return resp.StatusCode, nil
+}
+
+// backoff 计算第 attempts 次失败后的等待时间
+func (d *Dispatcher) backoff(attempts int) time.Duration {
+	wait := d.cfg.BaseBackoff
+	for i := 1; i < attempts; i++ {
+		wait *= 2
+		if wait >= d.cfg.MaxBackoff {
+			return d.cfg.MaxBackoff
+		}
+	}
+	return wait
+}
@@ -0,0 +1,117 @@
+package webhook_test
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+	gormLogger "gorm.io/gorm/logger"
+
+	"github.com/woxQAQ/gim/internal/apiserver/stores"
+	"github.com/woxQAQ/gim/internal/models"
+	"github.com/woxQAQ/gim/internal/webhook"
+	"github.com/woxQAQ/gim/pkg/logger"
+)
+
+var _ = Describe("Dispatcher", func() {
+	var (
+		store      *stores.WebhookStore
+		dispatcher *webhook.Dispatcher
+		receiver   *httptest.Server
+		statusCode atomic.Int32
+		mu         sync.Mutex
+		requests   []*http.Request
+		bodies     [][]byte
+	)
+
+	BeforeEach(func() {
+		db, err := gorm.Open(sqlite.Open(filepath.Join(GinkgoT().TempDir(), "webhook.db")), &gorm.Config{
+			Logger: gormLogger.Discard,
+		})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(db.AutoMigrate(&models.WebhookEndpoint{}, &models.WebhookDelivery{}, &models.WebhookDeadLetter{})).To(Succeed())
+		store = stores.NewWebhookStore(db)
+
+		requests, bodies = nil, nil
+		statusCode.Store(http.StatusOK)
+		receiver = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			body, _ := io.ReadAll(r.Body)
+			mu.Lock()
+			requests = append(requests, r)
+			bodies = append(bodies, body)
+			mu.Unlock()
+			w.WriteHeader(int(statusCode.Load()))
+		}))
+
+		Expect(store.CreateEndpoint(&models.WebhookEndpoint{
+			ID:      "ep1",
+			URL:     receiver.URL,
+			Secret:  "secret",
+			Events:  webhook.EventMessageCreated,
+			Enabled: true,
+		})).To(Succeed())
+
+		l, _ := logger.NewLogger(&logger.Config{Level: "error"})
+		l.Disable()
+		dispatcher = webhook.NewDispatcher(store, l, &webhook.Config{
+			PollInterval: time.Hour,
+			BatchSize:    10,
+			MaxAttempts:  2,
+			BaseBackoff:  0,
+			MaxBackoff:   0,
+			Timeout:      time.Second,
+		})
+	})
+
+	AfterEach(func() {
+		receiver.Close()
+	})
+
+	It("应该只投递订阅的事件并附带有效签名", func() {
+		Expect(dispatcher.Publish(webhook.EventUserOnline, &webhook.PresenceData{UserID: "u1"})).To(Succeed())
+		Expect(dispatcher.Publish(webhook.EventMessageCreated, &webhook.MessageData{ID: "m1"})).To(Succeed())
+		dispatcher.DeliverDue(context.Background())
+
+		Expect(requests).To(HaveLen(1))
+		req := requests[0]
+		Expect(req.Header.Get(webhook.HeaderEvent)).To(Equal(webhook.EventMessageCreated))
+		ts, err := strconv.ParseInt(req.Header.Get(webhook.HeaderTimestamp), 10, 64)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(webhook.Verify("secret", ts, bodies[0], req.Header.Get(webhook.HeaderSignature))).To(BeTrue())
+
+		deliveries, err := store.ListDeliveries(&stores.WebhookDeliveryFilter{Limit: 10})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(deliveries).To(HaveLen(1))
+		Expect(deliveries[0].Status).To(Equal(models.WebhookDeliverySucceeded))
+	})
+
+	It("失败的投递应该重试，耗尽后进入死信表", func() {
+		statusCode.Store(http.StatusInternalServerError)
+		Expect(dispatcher.Publish(webhook.EventMessageCreated, &webhook.MessageData{ID: "m1"})).To(Succeed())
+
+		dispatcher.DeliverDue(context.Background())
+		dispatcher.DeliverDue(context.Background())
+		Expect(requests).To(HaveLen(2))
+
+		letters, err := store.ListDeadLetters("ep1", 10)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(letters).To(HaveLen(1))
+		Expect(letters[0].Attempts).To(Equal(2))
+
+		// 死信重新入队后可以再次投递
+		statusCode.Store(http.StatusOK)
+		Expect(store.RequeueDeadLetter(letters[0].ID, time.Now())).To(Succeed())
+		dispatcher.DeliverDue(context.Background())
+		Expect(requests).To(HaveLen(3))
+	})
+})
@@ -0,0 +1,56 @@
+package webhook
+
+import (
+	"time"
+
+	"github.com/woxQAQ/gim/internal/wsgateway/base"
+)
+
+// 支持的回调事件
+const (
+	EventMessageCreated = "message.created"
+	EventUserOnline     = "user.online"
+	EventUserOffline    = "user.offline"
+)
+
+// Envelope 回调请求体
+type Envelope struct {
+	ID        string      `json:"id"`
+	Event     string      `json:"event"`
+	Timestamp time.Time   `json:"timestamp"`
+	Data      interface{} `json:"data"`
+}
+
+// MessageData message.* 事件的数据
+type MessageData struct {
+	ID        string    `json:"id"`
+	Type      string    `json:"type"`
+	SubType   string    `json:"sub_type,omitempty"`
+	From      string    `json:"from"`
+	To        string    `json:"to"`
+	GroupID   string    `json:"group_id,omitempty"`
+	Platform  int32     `json:"platform"`
+	Content   string    `json:"content"`
+	Timestamp time.Time `json:"timestamp"`
+}
+
+// NewMessageData 从消息构造回调数据
+func NewMessageData(msg base.IMessage) *MessageData {
+	return &MessageData{
+		ID:        msg.GetID(),
+		Type:      msg.GetType().String(),
+		From:      msg.GetFrom(),
+		To:        msg.GetTo(),
+		GroupID:   msg.GetGroupID(),
+		Platform:  msg.GetPlatform(),
+		Content:   string(msg.GetPayload()),
+		Timestamp: msg.GetTimestamp(),
+	}
+}
+
+// PresenceData user.online/user.offline 事件的数据
+type PresenceData struct {
+	UserID     string    `json:"user_id"`
+	PlatformID int32     `json:"platform_id"`
+	Timestamp  time.Time `json:"timestamp"`
+}
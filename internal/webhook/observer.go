@@ -0,0 +1,44 @@
+package webhook
+
+import (
+	"time"
+
+	"github.com/woxQAQ/gim/internal/wsgateway/base"
+	"github.com/woxQAQ/gim/internal/wsgateway/user"
+	"github.com/woxQAQ/gim/pkg/logger"
+)
+
+var _ user.StateObserver = (*PresenceObserver)(nil)
+
+// PresenceObserver 将用户上下线状态转换为回调事件
+type PresenceObserver struct {
+	dispatcher *Dispatcher
+	logger     logger.Logger
+}
+
+// NewPresenceObserver 创建在线状态回调观察者
+func NewPresenceObserver(dispatcher *Dispatcher, l logger.Logger) *PresenceObserver {
+	return &PresenceObserver{dispatcher: dispatcher, logger: l}
+}
+
+// OnUserStateChange 实现 user.StateObserver 接口
+func (o *PresenceObserver) OnUserStateChange(userID string, platformID int32, oldState, newState base.ConnectionState, timestamp time.Time) {
+	var event string
+	switch {
+	case newState == base.Connected && oldState != base.Connected:
+		event = EventUserOnline
+	case newState == base.Disconnected && oldState != base.Disconnected:
+		event = EventUserOffline
+	default:
+		return
+	}
+
+	err := o.dispatcher.Publish(event, &PresenceData{
+		UserID:     userID,
+		PlatformID: platformID,
+		Timestamp:  timestamp,
+	})
+	if err != nil {
+		o.logger.Error("发布在线状态回调失败", logger.String("user_id", userID), logger.Error(err))
+	}
+}
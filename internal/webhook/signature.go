@@ -0,0 +1,33 @@
+package webhook
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"strconv"
+)
+
+// 回调请求头
+const (
+	HeaderEvent     = "X-Gim-Event"
+	HeaderDelivery  = "X-Gim-Delivery"
+	HeaderTimestamp = "X-Gim-Timestamp"
+	HeaderSignature = "X-Gim-Signature"
+)
+
+// Sign 计算回调签名.
+//
+// 签名内容为 "<unix秒级时间戳>.<请求体>"，使用 HMAC-SHA256 计算，
+// 结果以 "sha256=<hex>" 的形式放在 X-Gim-Signature 请求头中。
+func Sign(secret string, timestamp int64, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(strconv.FormatInt(timestamp, 10)))
+	mac.Write([]byte("."))
+	mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+// Verify 校验回调签名，供接收方使用
+func Verify(secret string, timestamp int64, body []byte, signature string) bool {
+	return hmac.Equal([]byte(Sign(secret, timestamp, body)), []byte(signature))
+}
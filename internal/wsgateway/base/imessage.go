@@ -19,6 +19,8 @@ type IMessage interface {
 	GetFrom() string
 	// GetTo 获取接收者ID
 	GetTo() string
+	// GetGroupID 获取群ID，单聊消息为空
+	GetGroupID() string
 	// GetPlatform 获取平台标识
 	GetPlatform() int32
 	// GetPayload 获取消息内容
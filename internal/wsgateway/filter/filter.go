@@ -0,0 +1,187 @@
+package filter
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"sync/atomic"
+
+	"github.com/woxQAQ/gim/pkg/ahocorasick"
+)
+
+// Action 规则命中后的处理方式
+type Action string
+
+const (
+	// ActionMask 将命中的内容替换为掩码后放行
+	ActionMask Action = "mask"
+	// ActionReject 拒绝消息
+	ActionReject Action = "reject"
+	// ActionFlag 放行消息并记录待人工审核
+	ActionFlag Action = "flag"
+)
+
+// severity 多个规则同时命中时，按严重程度取最终动作
+func (a Action) severity() int {
+	switch a {
+	case ActionReject:
+		return 3
+	case ActionFlag:
+		return 2
+	case ActionMask:
+		return 1
+	default:
+		return 0
+	}
+}
+
+// Rule 过滤规则
+type Rule struct {
+	Name     string   `mapstructure:"name"`
+	Action   Action   `mapstructure:"action"`
+	Reason   string   `mapstructure:"reason"`   // 拒绝时返回给发送方的原因
+	Words    []string `mapstructure:"words"`    // 关键词，使用 Aho-Corasick 匹配
+	Patterns []string `mapstructure:"patterns"` // 正则表达式
+}
+
+// Config 过滤器配置
+type Config struct {
+	IgnoreCase bool   `mapstructure:"ignore_case"`
+	MaskChar   string `mapstructure:"mask_char"`
+	Rules      []Rule `mapstructure:"rules"`
+}
+
+// Result 过滤结果
+type Result struct {
+	Action  Action   // 最终动作，为空表示未命中
+	Rules   []string // 命中的规则名
+	Reason  string   // 拒绝原因
+	Content string   // 应用掩码后的内容
+}
+
+// Matched 是否命中任意规则
+func (r *Result) Matched() bool {
+	return r.Action != ""
+}
+
+// compiled 编译后的规则集合
+type compiled struct {
+	matcher  *ahocorasick.Matcher
+	wordRule []int // 关键词下标到规则下标的映射
+	regexps  []*regexp.Regexp
+	reRule   []int // 正则下标到规则下标的映射
+	rules    []Rule
+	mask     rune
+}
+
+// Filter 关键词和正则内容过滤器，规则可以在运行时原子替换
+type Filter struct {
+	current atomic.Pointer[compiled]
+}
+
+// New 使用配置创建过滤器
+func New(cfg *Config) (*Filter, error) {
+	f := &Filter{}
+	if err := f.Reload(cfg); err != nil {
+		return nil, err
+	}
+	return f, nil
+}
+
+// Reload 重新编译规则并替换当前规则，编译失败时保留原规则
+func (f *Filter) Reload(cfg *Config) error {
+	c := &compiled{rules: cfg.Rules, mask: '*'}
+	if cfg.MaskChar != "" {
+		c.mask = []rune(cfg.MaskChar)[0]
+	}
+
+	var words []string
+	for i, rule := range cfg.Rules {
+		switch rule.Action {
+		case ActionMask, ActionReject, ActionFlag:
+		default:
+			return fmt.Errorf("rule %q: unknown action %q", rule.Name, rule.Action)
+		}
+		for _, w := range rule.Words {
+			words = append(words, w)
+			c.wordRule = append(c.wordRule, i)
+		}
+		for _, p := range rule.Patterns {
+			if cfg.IgnoreCase {
+				p = "(?i)" + p
+			}
+			re, err := regexp.Compile(p)
+			if err != nil {
+				return fmt.Errorf("rule %q: %w", rule.Name, err)
+			}
+			c.regexps = append(c.regexps, re)
+			c.reRule = append(c.reRule, i)
+		}
+	}
+	c.matcher = ahocorasick.New(words, cfg.IgnoreCase)
+
+	f.current.Store(c)
+	return nil
+}
+
+// Check 检查文本并返回过滤结果
+func (f *Filter) Check(text string) *Result {
+	c := f.current.Load()
+	result := &Result{Content: text}
+
+	hit := make(map[int]bool)
+	var spans [][2]int // 需要掩码的 rune 区间
+	for _, m := range c.matcher.FindAll(text) {
+		ruleIdx := c.wordRule[m.Pattern]
+		hit[ruleIdx] = true
+		if c.rules[ruleIdx].Action == ActionMask {
+			spans = append(spans, [2]int{m.Start, m.End})
+		}
+	}
+	for i, re := range c.regexps {
+		locs := re.FindAllStringIndex(text, -1)
+		if len(locs) == 0 {
+			continue
+		}
+		ruleIdx := c.reRule[i]
+		hit[ruleIdx] = true
+		if c.rules[ruleIdx].Action == ActionMask {
+			for _, loc := range locs {
+				spans = append(spans, [2]int{runeIndex(text, loc[0]), runeIndex(text, loc[1])})
+			}
+		}
+	}
+	if len(hit) == 0 {
+		return result
+	}
+
+	indexes := make([]int, 0, len(hit))
+	for i := range hit {
+		indexes = append(indexes, i)
+	}
+	sort.Ints(indexes)
+	for _, i := range indexes {
+		rule := c.rules[i]
+		result.Rules = append(result.Rules, rule.Name)
+		if rule.Action.severity() > result.Action.severity() {
+			result.Action = rule.Action
+			result.Reason = rule.Reason
+		}
+	}
+
+	if len(spans) > 0 {
+		runes := []rune(text)
+		for _, s := range spans {
+			for i := s[0]; i < s[1]; i++ {
+				runes[i] = c.mask
+			}
+		}
+		result.Content = string(runes)
+	}
+	return result
+}
+
+// runeIndex 将字节下标转换为 rune 下标
+func runeIndex(s string, byteIdx int) int {
+	return len([]rune(s[:byteIdx]))
+}
@@ -0,0 +1,74 @@
+package filter_test
+
+import (
+	"os"
+	"path/filepath"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"github.com/woxQAQ/gim/internal/wsgateway/filter"
+	"github.com/woxQAQ/gim/pkg/logger"
+)
+
+var _ = Describe("Filter", func() {
+	var f *filter.Filter
+
+	BeforeEach(func() {
+		var err error
+		f, err = filter.New(&filter.Config{
+			IgnoreCase: true,
+			Rules: []filter.Rule{
+				{Name: "profanity", Action: filter.ActionMask, Words: []string{"笨蛋", "damn"}},
+				{Name: "phone", Action: filter.ActionFlag, Patterns: []string{`1\d{10}`}},
+				{Name: "fraud", Action: filter.ActionReject, Reason: "疑似诈骗", Words: []string{"转账到"}},
+			},
+		})
+		Expect(err).NotTo(HaveOccurred())
+	})
+
+	It("未命中时应该原样返回", func() {
+		r := f.Check("你好")
+		Expect(r.Matched()).To(BeFalse())
+		Expect(r.Content).To(Equal("你好"))
+	})
+
+	It("应该掩码命中的关键词", func() {
+		r := f.Check("你这个笨蛋, DAMN")
+		Expect(r.Action).To(Equal(filter.ActionMask))
+		Expect(r.Content).To(Equal("你这个**, ****"))
+	})
+
+	It("多个规则命中时应该取最严重的动作", func() {
+		r := f.Check("笨蛋 13800000000")
+		Expect(r.Action).To(Equal(filter.ActionFlag))
+		Expect(r.Rules).To(Equal([]string{"profanity", "phone"}))
+		Expect(r.Content).To(Equal("** 13800000000"))
+
+		r = f.Check("请转账到 13800000000")
+		Expect(r.Action).To(Equal(filter.ActionReject))
+		Expect(r.Reason).To(Equal("疑似诈骗"))
+	})
+
+	It("规则无效时重新加载应该失败并保留旧规则", func() {
+		err := f.Reload(&filter.Config{Rules: []filter.Rule{{Name: "bad", Action: filter.ActionMask, Patterns: []string{"("}}}})
+		Expect(err).To(HaveOccurred())
+		Expect(f.Check("笨蛋").Matched()).To(BeTrue())
+	})
+
+	It("配置文件修改后应该自动重新加载", func() {
+		path := filepath.Join(GinkgoT().TempDir(), "filter.yaml")
+		Expect(os.WriteFile(path, []byte("rules:\n  - name: a\n    action: mask\n    words: [foo]\n"), 0o600)).To(Succeed())
+
+		l, _ := logger.NewLogger(&logger.Config{Level: "error"})
+		l.Disable()
+		loaded, err := filter.LoadFile(path, l)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(loaded.Check("foo bar").Content).To(Equal("*** bar"))
+
+		Expect(os.WriteFile(path, []byte("rules:\n  - name: a\n    action: mask\n    words: [bar]\n"), 0o600)).To(Succeed())
+		Eventually(func() string {
+			return loaded.Check("foo bar").Content
+		}).Should(Equal("foo ***"))
+	})
+})
@@ -0,0 +1,52 @@
+package filter
+
+import (
+	"github.com/fsnotify/fsnotify"
+	"github.com/spf13/viper"
+
+	"github.com/woxQAQ/gim/pkg/logger"
+)
+
+// LoadFile 从配置文件加载过滤器，并在文件变化时自动重新加载.
+//
+// 支持 viper 能识别的格式（yaml、json、toml 等）。
+// 重新加载失败时记录日志并继续使用旧规则。
+func LoadFile(path string, l logger.Logger) (*Filter, error) {
+	v := viper.New()
+	v.SetConfigFile(path)
+	if err := v.ReadInConfig(); err != nil {
+		return nil, err
+	}
+
+	cfg, err := decode(v)
+	if err != nil {
+		return nil, err
+	}
+	f, err := New(cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	v.OnConfigChange(func(e fsnotify.Event) {
+		cfg, err := decode(v)
+		if err == nil {
+			err = f.Reload(cfg)
+		}
+		if err != nil {
+			l.Error("重新加载内容过滤规则失败", logger.String("path", path), logger.Error(err))
+			return
+		}
+		l.Info("内容过滤规则已重新加载", logger.String("path", path), logger.Int("rules", len(cfg.Rules)))
+	})
+	v.WatchConfig()
+
+	return f, nil
+}
+
+func decode(v *viper.Viper) (*Config, error) {
+	cfg := new(Config)
+	if err := v.Unmarshal(cfg); err != nil {
+		return nil, err
+	}
+	return cfg, nil
+}
@@ -2,24 +2,38 @@ package wsgateway
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"net/http"
+	"strings"
 	"sync"
 	"time"
 
 	"github.com/gorilla/websocket"
 
 	"github.com/woxQAQ/gim/internal/apiserver/stores"
+	"github.com/woxQAQ/gim/internal/audit"
+	"github.com/woxQAQ/gim/internal/models"
+	"github.com/woxQAQ/gim/internal/search"
+	"github.com/woxQAQ/gim/internal/types"
+	"github.com/woxQAQ/gim/internal/webhook"
 	"github.com/woxQAQ/gim/internal/wsgateway/base"
 	"github.com/woxQAQ/gim/internal/wsgateway/codec"
+	"github.com/woxQAQ/gim/internal/wsgateway/filter"
 	"github.com/woxQAQ/gim/internal/wsgateway/handler"
+	"github.com/woxQAQ/gim/internal/wsgateway/intercept"
 	"github.com/woxQAQ/gim/internal/wsgateway/user"
+	"github.com/woxQAQ/gim/pkg/auth"
+	"github.com/woxQAQ/gim/pkg/cache"
 	"github.com/woxQAQ/gim/pkg/db"
 	"github.com/woxQAQ/gim/pkg/logger"
 )
 
 var _ Gateway = &WSGateway{}
 
+// ErrSenderMismatch 消息头中的发送方与连接鉴权得到的用户不一致
+var ErrSenderMismatch = errors.New("message sender does not match the connection user")
+
 // Gateway 定义消息网关接口.
 type Gateway interface {
 	// Start 启动网关服务.
@@ -47,6 +61,11 @@ type Gateway interface {
 	GetUserHeartbeatStatus(userID string, platformID int32) (time.Time, error)
 }
 
+// TokenVerifier 校验连接请求中的访问令牌，并检查令牌所属的会话没有被吊销.
+type TokenVerifier interface {
+	Verify(token string) (*auth.Claims, error)
+}
+
 // WSGateway 实现Gateway接口的WebSocket网关.
 type WSGateway struct {
 	// WebSocket配置
@@ -63,8 +82,37 @@ type WSGateway struct {
 	compressor codec.Compressor
 	encoder    codec.Encoder
 
-	// 消息处理链
-	messageChain *handler.Chain
+	// 消息路由
+	router *handler.Router
+
+	// 回调分发器，为空时不发布回调事件
+	webhooks *webhook.Dispatcher
+
+	// 内容过滤器，为空时不过滤
+	contentFilter *filter.Filter
+
+	// 消息全文索引，为空时不建立索引
+	searchIndex search.Index
+
+	// 消息缓存，为空时不需要失效
+	messageCache cache.Cache
+
+	// 审计日志记录器，为空时不记录
+	audit *audit.Recorder
+
+	// 访问令牌校验，为空时信任连接请求中的 user_id
+	tokens TokenVerifier
+
+	// 非联系人之间的单聊策略
+	dmPolicy handler.DMPolicy
+
+	// 握手时检查账号状态和封禁
+	userStore *stores.UserStore
+	banStore  *stores.BanStore
+
+	// 投递前拦截器，为空时不拦截
+	interceptor  intercept.Interceptor
+	interceptCfg handler.InterceptConfig
 
 	// 日志记录器
 	logger logger.Logger
@@ -116,14 +164,50 @@ func NewWSGateway(opts ...Option) (*WSGateway, error) {
 		g.compressor = codec.NewGzipCompressor()
 	}
 
-	ms := stores.NewMessageStore(db.GetDB())
-
-	// 初始化消息处理链
-	g.messageChain = handler.NewMessageChain(g.userManager, ms, g.encoder)
+	ms := stores.NewMessageStore(db.GetDB()).WithCache(g.messageCache, 0)
+	g.userStore = stores.NewUserStore(db.GetDB())
+	g.banStore = stores.NewBanStore(db.GetDB())
+
+	// 初始化消息路由
+	routerCfg := &handler.MessageRouterConfig{
+		UserManager:  g.userManager,
+		MessageStore: ms,
+		Encoder:      g.encoder,
+		SearchIndex:  g.searchIndex,
+		Logger:       g.logger,
+		Audit:        g.audit,
+		Groups:       stores.NewGroupStore(db.GetDB()),
+		Roles:        g.userStore,
+	}
+	// 黑名单始终生效，被拒绝的消息不会转发也不会存储
+	routerCfg.BeforeDeliver = append(routerCfg.BeforeDeliver,
+		handler.NewBlockHandler(stores.NewBlockStore(db.GetDB()), g.encoder))
+	if g.dmPolicy == handler.DMPolicyContacts {
+		routerCfg.BeforeDeliver = append(routerCfg.BeforeDeliver,
+			handler.NewContactPolicyHandler(stores.NewContactStore(db.GetDB()), g.encoder))
+	}
+	if g.contentFilter != nil {
+		routerCfg.BeforeDeliver = append(routerCfg.BeforeDeliver,
+			handler.NewFilterHandler(g.contentFilter, stores.NewModerationStore(db.GetDB()), g.encoder))
+	}
+	if g.interceptor != nil {
+		routerCfg.BeforeDeliver = append(routerCfg.BeforeDeliver,
+			handler.NewInterceptHandler(g.interceptor, g.encoder, g.interceptCfg))
+	}
+	if g.webhooks != nil {
+		routerCfg.AfterStore = append(routerCfg.AfterStore, handler.NewWebhookHandler(g.webhooks, g.encoder))
+		g.userManager.AddObserver(webhook.NewPresenceObserver(g.webhooks, g.logger))
+	}
+	g.router = handler.NewMessageRouter(routerCfg)
 
 	return g, nil
 }
 
+// Router 返回网关的消息路由，用于注册新的消息类型处理链.
+func (g *WSGateway) Router() *handler.Router {
+	return g.router
+}
+
 // Start 实现Gateway接口的Start方法.
 func (g *WSGateway) Start(ctx context.Context) error {
 	g.logger.Info("Starting WebSocket gateway service")
@@ -248,19 +332,81 @@ func (g *WSGateway) GetUserHeartbeatStatus(userID string, platformID int32) (tim
 	return lastPingTime, nil
 }
 
+// authenticate 确定连接请求的用户和平台，失败时已写入错误响应.
+// 浏览器建立 WebSocket 连接时无法设置请求头，访问令牌也可以通过 token 查询参数提供.
+func (g *WSGateway) authenticate(w http.ResponseWriter, r *http.Request) (string, int32, bool) {
+	if g.tokens == nil {
+		userID := r.URL.Query().Get("user_id")
+		if userID == "" {
+			g.logger.Error("Missing user_id in connection request")
+			http.Error(w, "missing user_id", http.StatusBadRequest)
+			return "", 0, false
+		}
+		// 平台ID默认为1
+		return userID, 1, true
+	}
+
+	token := r.URL.Query().Get("token")
+	if scheme, bearer, ok := strings.Cut(r.Header.Get("Authorization"), " "); ok && strings.EqualFold(scheme, "Bearer") {
+		token = bearer
+	}
+	if token == "" {
+		http.Error(w, "missing token", http.StatusUnauthorized)
+		return "", 0, false
+	}
+	claims, err := g.tokens.Verify(token)
+	if err != nil {
+		g.logger.Debug("Rejected connection", logger.Error(err))
+		if errors.Is(err, auth.ErrInvalidToken) || errors.Is(err, auth.ErrTokenRevoked) {
+			http.Error(w, "invalid token", http.StatusUnauthorized)
+		} else {
+			http.Error(w, "internal server error", http.StatusInternalServerError)
+		}
+		return "", 0, false
+	}
+	platformID := claims.Platform
+	if platformID == 0 {
+		platformID = 1
+	}
+	return claims.UserID(), platformID, true
+}
+
+// admit 拒绝已禁用或处于封禁期的账号，数据库中不存在的用户放行.
+func (g *WSGateway) admit(w http.ResponseWriter, userID string) bool {
+	u, err := g.userStore.GetUserByID(userID)
+	switch {
+	case errors.Is(err, stores.ErrUserNotFound):
+		return true
+	case err != nil:
+		g.logger.Error("Failed to get user", logger.String("user_id", userID), logger.Error(err))
+		http.Error(w, "internal server error", http.StatusInternalServerError)
+		return false
+	case u.Status == models.UserStatusDisabled:
+		http.Error(w, "account disabled", http.StatusForbidden)
+		return false
+	}
+
+	ban, err := g.banStore.ActiveBan(userID, time.Now())
+	if errors.Is(err, stores.ErrBanNotFound) {
+		return true
+	}
+	if err != nil {
+		g.logger.Error("Failed to check user ban", logger.String("user_id", userID), logger.Error(err))
+		http.Error(w, "internal server error", http.StatusInternalServerError)
+		return false
+	}
+	g.logger.Debug("Rejected banned user", logger.String("user_id", userID), logger.String("reason", ban.Reason))
+	http.Error(w, "account banned: "+ban.Reason, http.StatusForbidden)
+	return false
+}
+
 // HandleNewConnection 处理新的WebSocket连接.
 func (g *WSGateway) HandleNewConnection(w http.ResponseWriter, r *http.Request) {
-	// 获取用户ID
-	userID := r.URL.Query().Get("user_id")
-	if userID == "" {
-		g.logger.Error("Missing user_id in connection request")
-		http.Error(w, "missing user_id", http.StatusBadRequest)
+	userID, platformID, ok := g.authenticate(w, r)
+	if !ok || !g.admit(w, userID) {
 		return
 	}
 
-	// 获取平台ID，默认为1
-	platformID := int32(1)
-
 	// 升级HTTP连接为WebSocket连接
 	conn, err := g.upgrader.Upgrade(w, r, nil)
 	if err != nil {
@@ -299,11 +445,13 @@ func (g *WSGateway) HandleNewConnection(w http.ResponseWriter, r *http.Request)
 			return
 		}
 
-		// 使用责任链处理其他类型的消息
-		if err := g.messageChain.Process(data); err != nil {
-			g.logger.Error("Failed to process message",
-				logger.String("user_id", userID),
-				logger.Error(err))
+		// 按消息类型路由到对应的处理链
+		if err := g.checkSender(userID, platformID, data); err != nil {
+			g.handleProcessError(userID, err)
+			return
+		}
+		if err := g.router.Process(data); err != nil {
+			g.handleProcessError(userID, err)
 		}
 	})
 
@@ -327,3 +475,53 @@ func (g *WSGateway) HandleNewConnection(w http.ResponseWriter, r *http.Request)
 		logger.Int32("platform_id", platformID),
 	)
 }
+
+// checkSender 拒绝发送方不是当前连接用户的消息，防止冒充他人发送、编辑或撤回消息.
+func (g *WSGateway) checkSender(userID string, platformID int32, data []byte) error {
+	msg := new(types.Message)
+	if err := g.encoder.Decode(data, msg); err != nil {
+		return err
+	}
+	if msg.GetFrom() == userID {
+		return nil
+	}
+	rejected := &handler.RejectedError{
+		MessageID: msg.GetID(),
+		From:      userID,
+		Platform:  platformID,
+		Reason:    "消息的发送方与当前登录的用户不一致",
+	}
+	return fmt.Errorf("%w: %w", rejected, ErrSenderMismatch)
+}
+
+// handleProcessError 处理消息路由返回的错误，被拒绝的消息会通知发送方.
+func (g *WSGateway) handleProcessError(userID string, err error) {
+	var rejected *handler.RejectedError
+	if !errors.As(err, &rejected) {
+		g.logger.Error("Failed to process message",
+			logger.String("user_id", userID),
+			logger.Error(err))
+		return
+	}
+
+	g.logger.Info("Message rejected",
+		logger.String("user_id", userID),
+		logger.String("message_id", rejected.MessageID),
+		logger.String("reason", rejected.Reason))
+
+	payload, encErr := g.encoder.Encode(map[string]string{
+		"code":       "message_rejected",
+		"message_id": rejected.MessageID,
+		"reason":     rejected.Reason,
+	})
+	if encErr != nil {
+		g.logger.Error("Failed to encode reject notice", logger.Error(encErr))
+		return
+	}
+	notice := types.NewMessage(types.MessageTypeSystem, "system", userID, rejected.Platform, payload)
+	if sendErrs := g.userManager.SendMessage(userID, notice); len(sendErrs) > 0 {
+		g.logger.Error("Failed to send reject notice",
+			logger.String("user_id", userID),
+			logger.Error(errors.Join(sendErrs...)))
+	}
+}
@@ -0,0 +1,50 @@
+package handler
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/woxQAQ/gim/internal/types"
+	"github.com/woxQAQ/gim/internal/wsgateway/codec"
+)
+
+// ErrSenderBlocked 接收方已将发送方拉黑
+var ErrSenderBlocked = errors.New("sender is blocked by recipient")
+
+// BlockChecker 判断用户是否在另一个用户的黑名单中
+type BlockChecker interface {
+	IsBlocked(userID, blockedID string) (bool, error)
+}
+
+var _ Handler = (*BlockHandler)(nil)
+
+// BlockHandler 拒绝发给已将发送方拉黑的用户的单聊消息，需放在 ForwardHandler 和 StoreHandler 之前
+type BlockHandler struct {
+	BaseHandler
+	blocks  BlockChecker
+	encoder codec.Encoder
+}
+
+// NewBlockHandler 创建黑名单处理器
+func NewBlockHandler(blocks BlockChecker, encoder codec.Encoder) *BlockHandler {
+	return &BlockHandler{blocks: blocks, encoder: encoder}
+}
+
+// Handle 接收方拉黑了发送方时返回同时匹配 RejectedError 和 ErrSenderBlocked 的错误
+func (h *BlockHandler) Handle(data []byte) (bool, error) {
+	msg := new(types.Message)
+	if err := h.encoder.Decode(data, msg); err != nil {
+		return false, err
+	}
+	if msg.GetTo() == "" {
+		return true, nil
+	}
+	blocked, err := h.blocks.IsBlocked(msg.GetTo(), msg.GetFrom())
+	if err != nil {
+		return false, err
+	}
+	if blocked {
+		return false, fmt.Errorf("%w: %w", newRejectedError(msg, "对方已拒收你的消息"), ErrSenderBlocked)
+	}
+	return true, nil
+}
@@ -0,0 +1,89 @@
+package handler_test
+
+import (
+	"errors"
+	"path/filepath"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"github.com/woxQAQ/gim/internal/apiserver/stores"
+	"github.com/woxQAQ/gim/internal/migrations"
+	"github.com/woxQAQ/gim/internal/models"
+	"github.com/woxQAQ/gim/internal/types"
+	"github.com/woxQAQ/gim/internal/wsgateway/base"
+	"github.com/woxQAQ/gim/internal/wsgateway/codec"
+	"github.com/woxQAQ/gim/internal/wsgateway/handler"
+	"github.com/woxQAQ/gim/internal/wsgateway/user"
+	"github.com/woxQAQ/gim/pkg/db"
+)
+
+// sendRecorder 记录 SendMessage 的接收方，其余方法不应被调用
+type sendRecorder struct {
+	user.IUserManager
+	sent []string
+}
+
+func (m *sendRecorder) SendMessage(userID string, _ base.IMessage) []error {
+	m.sent = append(m.sent, userID)
+	return nil
+}
+
+var _ = Describe("BlockHandler", func() {
+	var (
+		encoder  *codec.JSONEncoder
+		users    *sendRecorder
+		blocks   *stores.BlockStore
+		messages *stores.MessageStore
+		router   *handler.Router
+	)
+
+	send := func(from, to string) error {
+		data, err := encoder.Encode(types.NewMessage(types.MessageTypeText, from, to, 1, []byte("hello")))
+		Expect(err).NotTo(HaveOccurred())
+		return router.Process(data)
+	}
+
+	BeforeEach(func() {
+		gdb, err := db.Open(&db.Config{DSN: filepath.Join(GinkgoT().TempDir(), "handler.db")})
+		Expect(err).NotTo(HaveOccurred())
+		m, err := db.NewMigrator(gdb, migrations.All())
+		Expect(err).NotTo(HaveOccurred())
+		_, err = m.Up()
+		Expect(err).NotTo(HaveOccurred())
+
+		encoder = codec.NewJSONEncoder()
+		users = &sendRecorder{}
+		blocks = stores.NewBlockStore(gdb)
+		messages = stores.NewMessageStore(gdb)
+		router = handler.NewMessageRouter(&handler.MessageRouterConfig{
+			UserManager:   users,
+			MessageStore:  messages,
+			Encoder:       encoder,
+			BeforeDeliver: []handler.Handler{handler.NewBlockHandler(blocks, encoder)},
+		})
+		Expect(blocks.Block("u2", "u1")).To(Succeed())
+	})
+
+	It("被拉黑的发送方的消息既不转发也不存储", func() {
+		err := send("u1", "u2")
+		Expect(err).To(MatchError(handler.ErrSenderBlocked))
+		var rejected *handler.RejectedError
+		Expect(errors.As(err, &rejected)).To(BeTrue())
+		Expect(users.sent).To(BeEmpty())
+
+		stored, err := messages.ListMessages(&stores.MessageQuery{ConversationID: models.DirectConversationID("u1", "u2"), Limit: 10})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(stored).To(BeEmpty())
+	})
+
+	It("拉黑是单向的，拉黑方仍然可以发送消息", func() {
+		Expect(send("u2", "u1")).To(Succeed())
+		Expect(users.sent).To(Equal([]string{"u1"}))
+
+		ok, err := blocks.Unblock("u2", "u1")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(ok).To(BeTrue())
+		Expect(send("u1", "u2")).To(Succeed())
+	})
+})
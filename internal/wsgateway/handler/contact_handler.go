@@ -0,0 +1,68 @@
+package handler
+
+import (
+	"fmt"
+
+	"github.com/woxQAQ/gim/internal/types"
+	"github.com/woxQAQ/gim/internal/wsgateway/codec"
+)
+
+// DMPolicy 非联系人之间的单聊策略
+type DMPolicy string
+
+const (
+	// DMPolicyAllow 任何用户之间都可以单聊
+	DMPolicyAllow DMPolicy = "allow"
+	// DMPolicyContacts 只有联系人之间可以单聊
+	DMPolicyContacts DMPolicy = "contacts"
+)
+
+// ParseDMPolicy 解析单聊策略，为空时为 DMPolicyAllow
+func ParseDMPolicy(s string) (DMPolicy, error) {
+	switch DMPolicy(s) {
+	case "", DMPolicyAllow:
+		return DMPolicyAllow, nil
+	case DMPolicyContacts:
+		return DMPolicyContacts, nil
+	default:
+		return "", fmt.Errorf("unknown dm policy %q", s)
+	}
+}
+
+// ContactChecker 判断两个用户是否为联系人
+type ContactChecker interface {
+	IsContact(userID, contactID string) (bool, error)
+}
+
+var _ Handler = (*ContactPolicyHandler)(nil)
+
+// ContactPolicyHandler 拒绝发给非联系人的单聊消息，需放在 ForwardHandler 之前
+type ContactPolicyHandler struct {
+	BaseHandler
+	contacts ContactChecker
+	encoder  codec.Encoder
+}
+
+// NewContactPolicyHandler 创建联系人单聊策略处理器
+func NewContactPolicyHandler(contacts ContactChecker, encoder codec.Encoder) *ContactPolicyHandler {
+	return &ContactPolicyHandler{contacts: contacts, encoder: encoder}
+}
+
+// Handle 发送方不是接收方的联系人时拒绝消息
+func (h *ContactPolicyHandler) Handle(data []byte) (bool, error) {
+	msg := new(types.Message)
+	if err := h.encoder.Decode(data, msg); err != nil {
+		return false, err
+	}
+	if msg.GetTo() == "" || msg.GetTo() == msg.GetFrom() {
+		return true, nil
+	}
+	ok, err := h.contacts.IsContact(msg.GetTo(), msg.GetFrom())
+	if err != nil {
+		return false, err
+	}
+	if !ok {
+		return false, newRejectedError(msg, "对方不是你的联系人")
+	}
+	return true, nil
+}
@@ -0,0 +1,59 @@
+package handler_test
+
+import (
+	"errors"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"github.com/woxQAQ/gim/internal/types"
+	"github.com/woxQAQ/gim/internal/wsgateway/codec"
+	"github.com/woxQAQ/gim/internal/wsgateway/handler"
+)
+
+// contactSet 以 "userID:contactID" 保存联系人关系
+type contactSet map[string]bool
+
+func (s contactSet) IsContact(userID, contactID string) (bool, error) {
+	return s[userID+":"+contactID], nil
+}
+
+var _ = Describe("ContactPolicyHandler", func() {
+	var (
+		encoder  *codec.JSONEncoder
+		recorder *payloadRecorder
+		contacts contactSet
+	)
+
+	process := func(from, to string) error {
+		data, err := encoder.Encode(types.NewMessage(types.MessageTypeText, from, to, 1, []byte("hello")))
+		Expect(err).NotTo(HaveOccurred())
+		return chainOf(handler.NewContactPolicyHandler(contacts, encoder), recorder).Process(data)
+	}
+
+	BeforeEach(func() {
+		encoder = codec.NewJSONEncoder()
+		recorder = &payloadRecorder{encoder: encoder}
+		contacts = contactSet{"u2:u1": true}
+	})
+
+	It("联系人之间的消息应该放行", func() {
+		Expect(process("u1", "u2")).To(Succeed())
+		Expect(recorder.payloads).To(HaveLen(1))
+	})
+
+	It("发给非联系人的消息应该被拒绝", func() {
+		err := process("u3", "u2")
+		var rejected *handler.RejectedError
+		Expect(errors.As(err, &rejected)).To(BeTrue())
+		Expect(recorder.payloads).To(BeEmpty())
+	})
+
+	It("应该能解析单聊策略", func() {
+		p, err := handler.ParseDMPolicy("")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(p).To(Equal(handler.DMPolicyAllow))
+		_, err = handler.ParseDMPolicy("nobody")
+		Expect(err).To(HaveOccurred())
+	})
+})
@@ -0,0 +1,86 @@
+package handler
+
+import (
+	"strings"
+
+	"github.com/woxQAQ/gim/internal/apiserver/stores"
+	"github.com/woxQAQ/gim/internal/models"
+	"github.com/woxQAQ/gim/internal/types"
+	"github.com/woxQAQ/gim/internal/wsgateway/codec"
+	"github.com/woxQAQ/gim/internal/wsgateway/filter"
+	"github.com/woxQAQ/gim/pkg/snowflake"
+)
+
+var (
+	_ Handler     = (*FilterHandler)(nil)
+	_ Transformer = (*FilterHandler)(nil)
+)
+
+// FilterHandler 文本消息内容过滤处理器，需放在 ForwardHandler 之前
+type FilterHandler struct {
+	BaseHandler
+	filter          *filter.Filter
+	moderationStore *stores.ModerationStore
+	encoder         codec.Encoder
+}
+
+// NewFilterHandler 创建内容过滤处理器
+func NewFilterHandler(f *filter.Filter, moderationStore *stores.ModerationStore, encoder codec.Encoder) *FilterHandler {
+	return &FilterHandler{
+		filter:          f,
+		moderationStore: moderationStore,
+		encoder:         encoder,
+	}
+}
+
+// Handle 实现 Handler 接口，不支持改写时使用
+func (h *FilterHandler) Handle(data []byte) (bool, error) {
+	_, continue_, err := h.Transform(data)
+	return continue_, err
+}
+
+// Transform 检查文本消息，按命中规则的动作掩码、拒绝或标记待审核
+func (h *FilterHandler) Transform(data []byte) ([]byte, bool, error) {
+	msg := new(types.Message)
+	if err := h.encoder.Decode(data, msg); err != nil {
+		return nil, false, err
+	}
+	if msg.Header.Type != types.MessageTypeText {
+		return data, true, nil
+	}
+
+	original := string(msg.Payload)
+	result := h.filter.Check(original)
+	if !result.Matched() {
+		return data, true, nil
+	}
+
+	if result.Action == filter.ActionReject {
+		return nil, false, newRejectedError(msg, result.Reason)
+	}
+
+	if result.Action == filter.ActionFlag {
+		err := h.moderationStore.CreateRecord(&models.ModerationRecord{
+			ID:        snowflake.GenerateID(),
+			MessageID: msg.GetID(),
+			FromID:    msg.GetFrom(),
+			ToID:      msg.GetTo(),
+			Content:   original,
+			Rules:     strings.Join(result.Rules, ","),
+			Status:    models.ModerationPending,
+		})
+		if err != nil {
+			return nil, false, err
+		}
+	}
+
+	if result.Content == original {
+		return data, true, nil
+	}
+	msg.Payload = []byte(result.Content)
+	masked, err := h.encoder.Encode(msg)
+	if err != nil {
+		return nil, false, err
+	}
+	return masked, true, nil
+}
@@ -0,0 +1,74 @@
+package handler
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/woxQAQ/gim/internal/models"
+	"github.com/woxQAQ/gim/internal/types"
+	"github.com/woxQAQ/gim/internal/wsgateway/codec"
+)
+
+var (
+	// ErrNotGroupMember 发送方不是群成员
+	ErrNotGroupMember = errors.New("sender is not a group member")
+	// ErrGroupsUnsupported 网关未配置群成员查询，不能处理群聊消息
+	ErrGroupsUnsupported = errors.New("group messages are not supported")
+)
+
+// GroupMembers 查询群成员
+type GroupMembers interface {
+	IsMember(groupID, userID string) (bool, error)
+	GetMemberIDs(groupID string) ([]string, error)
+}
+
+// RoleResolver 查询用户当前的角色
+type RoleResolver interface {
+	GetUserRole(userID string) (string, error)
+}
+
+var _ Handler = (*GroupHandler)(nil)
+
+// GroupHandler 拒绝非群成员发送的群聊消息，机器人账号可以向任意群发送消息，需放在 ForwardHandler 之前
+type GroupHandler struct {
+	BaseHandler
+	groups  GroupMembers
+	roles   RoleResolver
+	encoder codec.Encoder
+}
+
+// NewGroupHandler 创建群成员校验处理器，groups 为空时拒绝所有群聊消息，roles 为空时机器人账号同样需要是群成员
+func NewGroupHandler(groups GroupMembers, roles RoleResolver, encoder codec.Encoder) *GroupHandler {
+	return &GroupHandler{groups: groups, roles: roles, encoder: encoder}
+}
+
+// Handle 发送方不是群成员时返回同时匹配 RejectedError 和 ErrNotGroupMember 的错误
+func (h *GroupHandler) Handle(data []byte) (bool, error) {
+	msg := new(types.Message)
+	if err := h.encoder.Decode(data, msg); err != nil {
+		return false, err
+	}
+	groupID := msg.GetGroupID()
+	if groupID == "" {
+		return true, nil
+	}
+	if h.groups == nil {
+		return false, ErrGroupsUnsupported
+	}
+	ok, err := h.groups.IsMember(groupID, msg.GetFrom())
+	if err != nil {
+		return false, err
+	}
+	// 只有非成员才需要查询角色
+	if !ok && h.roles != nil {
+		role, err := h.roles.GetUserRole(msg.GetFrom())
+		if err != nil {
+			return false, err
+		}
+		ok = role == models.RoleBot
+	}
+	if !ok {
+		return false, fmt.Errorf("%w: %w", newRejectedError(msg, "你不是该群的成员"), ErrNotGroupMember)
+	}
+	return true, nil
+}
@@ -0,0 +1,114 @@
+package handler_test
+
+import (
+	"encoding/json"
+	"errors"
+	"path/filepath"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"gorm.io/gorm"
+
+	"github.com/woxQAQ/gim/internal/apiserver/stores"
+	"github.com/woxQAQ/gim/internal/migrations"
+	"github.com/woxQAQ/gim/internal/models"
+	"github.com/woxQAQ/gim/internal/types"
+	"github.com/woxQAQ/gim/internal/wsgateway/codec"
+	"github.com/woxQAQ/gim/internal/wsgateway/handler"
+	"github.com/woxQAQ/gim/pkg/db"
+)
+
+var _ = Describe("GroupHandler", func() {
+	var (
+		gdb      *gorm.DB
+		encoder  *codec.JSONEncoder
+		users    *sendRecorder
+		messages *stores.MessageStore
+		router   *handler.Router
+	)
+
+	send := func(from, groupID string) error {
+		msg := types.NewMessage(types.MessageTypeText, from, "", 1, []byte("hello"))
+		msg.Header.GroupID = groupID
+		data, err := encoder.Encode(msg)
+		Expect(err).NotTo(HaveOccurred())
+		return router.Process(data)
+	}
+
+	stored := func() []*models.Message {
+		list, err := messages.ListMessages(&stores.MessageQuery{ConversationID: models.GroupConversationID("g1"), Limit: 10})
+		Expect(err).NotTo(HaveOccurred())
+		return list
+	}
+
+	BeforeEach(func() {
+		var err error
+		gdb, err = db.Open(&db.Config{DSN: filepath.Join(GinkgoT().TempDir(), "handler.db")})
+		Expect(err).NotTo(HaveOccurred())
+		m, err := db.NewMigrator(gdb, migrations.All())
+		Expect(err).NotTo(HaveOccurred())
+		_, err = m.Up()
+		Expect(err).NotTo(HaveOccurred())
+
+		encoder = codec.NewJSONEncoder()
+		users = &sendRecorder{}
+		messages = stores.NewMessageStore(gdb)
+		router = handler.NewMessageRouter(&handler.MessageRouterConfig{
+			UserManager:  users,
+			MessageStore: messages,
+			Encoder:      encoder,
+			Groups:       stores.NewGroupStore(gdb),
+			Roles:        stores.NewUserStore(gdb),
+		})
+		Expect(gdb.Create(&models.Group{ID: "g1", Name: "team", OwnerID: "u1"}).Error).To(Succeed())
+		for _, id := range []string{"u1", "u2", "u3"} {
+			Expect(gdb.Create(&models.GroupMember{GroupID: "g1", UserID: id}).Error).To(Succeed())
+		}
+	})
+
+	It("群成员的消息转发给其他成员，并存储到群会话", func() {
+		Expect(send("u1", "g1")).To(Succeed())
+		Expect(users.sent).To(ConsistOf("u2", "u3"))
+		list := stored()
+		Expect(list).To(HaveLen(1))
+		Expect(list[0].ToID).To(Equal("g1"))
+	})
+
+	It("非群成员的消息既不转发也不存储，机器人账号除外", func() {
+		err := send("u4", "g1")
+		Expect(err).To(MatchError(handler.ErrNotGroupMember))
+		var rejected *handler.RejectedError
+		Expect(errors.As(err, &rejected)).To(BeTrue())
+		Expect(users.sent).To(BeEmpty())
+		Expect(stored()).To(BeEmpty())
+
+		Expect(gdb.Create(&models.User{ID: "bot", Username: "bot", Password: "x", Role: models.RoleBot}).Error).To(Succeed())
+		Expect(send("bot", "g1")).To(Succeed())
+		Expect(users.sent).To(ConsistOf("u1", "u2", "u3"))
+	})
+
+	It("退出群后不能再编辑在群里发送的消息", func() {
+		Expect(send("u1", "g1")).To(Succeed())
+		original := stored()[0].ID
+		Expect(gdb.Delete(&models.GroupMember{}, "group_id = ? AND user_id = ?", "g1", "u1").Error).To(Succeed())
+
+		payload, err := json.Marshal(&handler.MessageMutation{MessageID: original, Content: "edited"})
+		Expect(err).NotTo(HaveOccurred())
+		msg := types.NewMessage(types.MessageTypeCustom, "u1", "", 1, payload)
+		msg.Header.SubType = handler.SubTypeEdit
+		msg.Header.GroupID = "g1"
+		data, err := encoder.Encode(msg)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(router.Process(data)).To(MatchError(handler.ErrNotGroupMember))
+		Expect(stored()[0].Content).To(Equal("hello"))
+	})
+
+	It("群聊消息不能同时指定接收方", func() {
+		msg := types.NewMessage(types.MessageTypeText, "u1", "u2", 1, []byte("hello"))
+		msg.Header.GroupID = "g1"
+		data, err := encoder.Encode(msg)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(router.Process(data)).To(HaveOccurred())
+		Expect(users.sent).To(BeEmpty())
+	})
+})
@@ -10,6 +10,14 @@ type Handler interface {
 	GetNext() Handler
 }
 
+// Transformer 可选接口，实现后处理器可以改写传给后续处理器的消息.
+//
+// Chain 会优先调用 Transform，并把返回的数据交给链上的下一个处理器。
+type Transformer interface {
+	// Transform 处理并返回改写后的消息，第二个返回值表示是否继续处理链
+	Transform(msg []byte) ([]byte, bool, error)
+}
+
 // BaseHandler 处理器基础实现
 type BaseHandler struct {
 	next Handler
@@ -50,21 +58,59 @@ func (c *Chain) AddHandler(handler Handler) {
 
 // Process 处理消息
 func (c *Chain) Process(data []byte) error {
-	if c.head == nil {
-		return nil
-	}
+	_, err := c.Run(data)
+	return err
+}
+
+// Run 处理消息，返回消息是否走完了整条链
+func (c *Chain) Run(data []byte) (bool, error) {
+	_, completed, err := c.Transform(data)
+	return completed, err
+}
 
+// Transform 处理消息，返回经过改写的消息以及消息是否走完了整条链
+func (c *Chain) Transform(data []byte) ([]byte, bool, error) {
 	current := c.head
 	for current != nil {
-		continue_, err := current.Handle(data)
+		var (
+			continue_ bool
+			err       error
+		)
+		if t, ok := current.(Transformer); ok {
+			data, continue_, err = t.Transform(data)
+		} else {
+			continue_, err = current.Handle(data)
+		}
 		if err != nil {
-			return err
+			return nil, false, err
 		}
 		if !continue_ {
-			break
+			return nil, false, nil
 		}
 		current = current.GetNext()
 	}
 
-	return nil
+	return data, true, nil
+}
+
+var (
+	_ Handler     = (*chainHandler)(nil)
+	_ Transformer = (*chainHandler)(nil)
+)
+
+// chainHandler 将一条处理链作为单个处理器加入另一条链，
+// 同一条链可以通过多个 chainHandler 被多条链复用
+type chainHandler struct {
+	BaseHandler
+	chain *Chain
+}
+
+// Handle 实现 Handler 接口
+func (h *chainHandler) Handle(data []byte) (bool, error) {
+	return h.chain.Run(data)
+}
+
+// Transform 实现 Transformer 接口
+func (h *chainHandler) Transform(data []byte) ([]byte, bool, error) {
+	return h.chain.Transform(data)
 }
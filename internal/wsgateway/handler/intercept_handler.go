@@ -0,0 +1,116 @@
+package handler
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/woxQAQ/gim/internal/types"
+	"github.com/woxQAQ/gim/internal/wsgateway/codec"
+	"github.com/woxQAQ/gim/internal/wsgateway/intercept"
+)
+
+// ErrMessageRejected 消息被拦截拒绝
+var ErrMessageRejected = errors.New("message rejected")
+
+// RejectedError 消息被拒绝时返回的错误，携带需要告知发送方的信息
+type RejectedError struct {
+	MessageID string
+	From      string
+	Platform  int32
+	Reason    string
+}
+
+func (e *RejectedError) Error() string {
+	return fmt.Sprintf("message %s rejected: %s", e.MessageID, e.Reason)
+}
+
+// Unwrap 使 errors.Is(err, ErrMessageRejected) 成立
+func (e *RejectedError) Unwrap() error {
+	return ErrMessageRejected
+}
+
+// newRejectedError 根据消息创建拒绝错误
+func newRejectedError(msg *types.Message, reason string) *RejectedError {
+	return &RejectedError{
+		MessageID: msg.GetID(),
+		From:      msg.GetFrom(),
+		Platform:  msg.GetPlatform(),
+		Reason:    reason,
+	}
+}
+
+// InterceptConfig 投递前拦截配置
+type InterceptConfig struct {
+	Timeout  time.Duration // 单条消息的拦截超时时间
+	FailOpen bool          // 拦截器出错或超时时是否放行
+}
+
+var (
+	_ Handler     = (*InterceptHandler)(nil)
+	_ Transformer = (*InterceptHandler)(nil)
+)
+
+// InterceptHandler 投递前拦截处理器，需放在 ForwardHandler 之前
+type InterceptHandler struct {
+	BaseHandler
+	interceptor intercept.Interceptor
+	encoder     codec.Encoder
+	cfg         InterceptConfig
+}
+
+// NewInterceptHandler 创建投递前拦截处理器
+func NewInterceptHandler(interceptor intercept.Interceptor, encoder codec.Encoder, cfg InterceptConfig) *InterceptHandler {
+	return &InterceptHandler{
+		interceptor: interceptor,
+		encoder:     encoder,
+		cfg:         cfg,
+	}
+}
+
+// Handle 实现 Handler 接口，不支持改写时使用
+func (h *InterceptHandler) Handle(data []byte) (bool, error) {
+	_, continue_, err := h.Transform(data)
+	return continue_, err
+}
+
+// Transform 调用拦截器，根据决策放行、拒绝或改写消息
+func (h *InterceptHandler) Transform(data []byte) ([]byte, bool, error) {
+	msg := new(types.Message)
+	if err := h.encoder.Decode(data, msg); err != nil {
+		return nil, false, err
+	}
+
+	ctx := context.Background()
+	if h.cfg.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, h.cfg.Timeout)
+		defer cancel()
+	}
+
+	decision, err := h.interceptor.BeforeDeliver(ctx, msg)
+	if err == nil {
+		err = decision.Validate()
+	}
+	if err != nil {
+		if h.cfg.FailOpen {
+			return data, true, nil
+		}
+		return nil, false, fmt.Errorf("%w: %w", newRejectedError(msg, "消息审核服务不可用"), err)
+	}
+
+	switch decision.Action {
+	case intercept.ActionReject:
+		return nil, false, newRejectedError(msg, decision.Reason)
+	case intercept.ActionRewrite:
+		msg.Payload = decision.Payload
+		rewritten, err := h.encoder.Encode(msg)
+		if err != nil {
+			return nil, false, err
+		}
+		return rewritten, true, nil
+	default:
+		return data, true, nil
+	}
+}
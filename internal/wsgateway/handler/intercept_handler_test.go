@@ -0,0 +1,138 @@
+package handler_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"github.com/woxQAQ/gim/internal/types"
+	"github.com/woxQAQ/gim/internal/wsgateway/codec"
+	"github.com/woxQAQ/gim/internal/wsgateway/handler"
+	"github.com/woxQAQ/gim/internal/wsgateway/intercept"
+)
+
+// payloadRecorder 记录收到的消息内容
+type payloadRecorder struct {
+	handler.BaseHandler
+	encoder  codec.Encoder
+	payloads []string
+}
+
+func (h *payloadRecorder) Handle(data []byte) (bool, error) {
+	msg := new(types.Message)
+	if err := h.encoder.Decode(data, msg); err != nil {
+		return false, err
+	}
+	h.payloads = append(h.payloads, string(msg.Payload))
+	return true, nil
+}
+
+var _ = Describe("InterceptHandler", func() {
+	var (
+		encoder  *codec.JSONEncoder
+		recorder *payloadRecorder
+		data     []byte
+	)
+
+	process := func(i intercept.Interceptor, cfg handler.InterceptConfig) error {
+		return chainOf(handler.NewInterceptHandler(i, encoder, cfg), recorder).Process(data)
+	}
+
+	BeforeEach(func() {
+		encoder = codec.NewJSONEncoder()
+		recorder = &payloadRecorder{encoder: encoder}
+		var err error
+		data, err = encoder.Encode(types.NewMessage(types.MessageTypeText, "u1", "u2", 1, []byte("hello")))
+		Expect(err).NotTo(HaveOccurred())
+	})
+
+	It("放行的消息应该原样传给后续处理器", func() {
+		allow := intercept.InterceptorFunc(func(context.Context, *types.Message) (*intercept.Decision, error) {
+			return intercept.Allow(), nil
+		})
+		Expect(process(allow, handler.InterceptConfig{})).To(Succeed())
+		Expect(recorder.payloads).To(Equal([]string{"hello"}))
+	})
+
+	It("被拒绝的消息应该返回 RejectedError", func() {
+		reject := intercept.InterceptorFunc(func(context.Context, *types.Message) (*intercept.Decision, error) {
+			return intercept.Reject("敏感内容"), nil
+		})
+		err := process(reject, handler.InterceptConfig{})
+		var rejected *handler.RejectedError
+		Expect(errors.As(err, &rejected)).To(BeTrue())
+		Expect(rejected.Reason).To(Equal("敏感内容"))
+		Expect(recorder.payloads).To(BeEmpty())
+	})
+
+	It("改写后的消息应该传给后续处理器", func() {
+		rewrite := intercept.InterceptorFunc(func(context.Context, *types.Message) (*intercept.Decision, error) {
+			return intercept.Rewrite([]byte("***")), nil
+		})
+		Expect(process(rewrite, handler.InterceptConfig{})).To(Succeed())
+		Expect(recorder.payloads).To(Equal([]string{"***"}))
+	})
+
+	Context("拦截器超时", func() {
+		var (
+			slow     *httptest.Server
+			callback *intercept.HTTPInterceptor
+		)
+
+		BeforeEach(func() {
+			slow = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				select {
+				case <-r.Context().Done():
+				case <-time.After(200 * time.Millisecond):
+				}
+				_ = json.NewEncoder(w).Encode(intercept.Allow())
+			}))
+			callback = intercept.NewHTTPInterceptor(slow.URL, "secret", time.Second)
+		})
+
+		AfterEach(func() {
+			slow.Close()
+		})
+
+		It("fail-open 时应该放行", func() {
+			Expect(process(callback, handler.InterceptConfig{Timeout: 20 * time.Millisecond, FailOpen: true})).To(Succeed())
+			Expect(recorder.payloads).To(Equal([]string{"hello"}))
+		})
+
+		It("fail-closed 时应该拒绝", func() {
+			err := process(callback, handler.InterceptConfig{Timeout: 20 * time.Millisecond})
+			Expect(errors.Is(err, handler.ErrMessageRejected)).To(BeTrue())
+			Expect(recorder.payloads).To(BeEmpty())
+		})
+	})
+
+	It("拦截器没有返回决策时按审核服务不可用处理", func() {
+		empty := intercept.InterceptorFunc(func(context.Context, *types.Message) (*intercept.Decision, error) {
+			return nil, nil
+		})
+		err := process(empty, handler.InterceptConfig{})
+		Expect(errors.Is(err, handler.ErrMessageRejected)).To(BeTrue())
+		Expect(recorder.payloads).To(BeEmpty())
+
+		Expect(process(empty, handler.InterceptConfig{FailOpen: true})).To(Succeed())
+		Expect(recorder.payloads).To(Equal([]string{"hello"}))
+	})
+
+	It("HTTP 回调应该能改写消息", func() {
+		srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			msg := new(types.Message)
+			Expect(json.NewDecoder(r.Body).Decode(msg)).To(Succeed())
+			_ = json.NewEncoder(w).Encode(intercept.Rewrite(append(msg.Payload, '!')))
+		}))
+		defer srv.Close()
+
+		Expect(process(intercept.NewHTTPInterceptor(srv.URL, "", time.Second), handler.InterceptConfig{})).To(Succeed())
+		Expect(recorder.payloads).To(Equal([]string{"hello!"}))
+	})
+})
@@ -2,25 +2,33 @@ package handler
 
 import (
 	"errors"
+	"fmt"
 
 	"github.com/woxQAQ/gim/internal/apiserver/stores"
+	"github.com/woxQAQ/gim/internal/audit"
 	"github.com/woxQAQ/gim/internal/models"
+	"github.com/woxQAQ/gim/internal/search"
 	"github.com/woxQAQ/gim/internal/types"
+	"github.com/woxQAQ/gim/internal/webhook"
 	"github.com/woxQAQ/gim/internal/wsgateway/codec"
 	"github.com/woxQAQ/gim/internal/wsgateway/user"
+	"github.com/woxQAQ/gim/pkg/logger"
 )
 
 // ForwardHandler 消息转发处理器
 type ForwardHandler struct {
 	BaseHandler
 	userManager user.IUserManager
+	groups      GroupMembers
 	codec.Encoder
 }
 
-// NewForwardHandler 创建消息转发处理器
-func NewForwardHandler(userManager user.IUserManager) *ForwardHandler {
+// NewForwardHandler 创建消息转发处理器，groups 为空时不能转发群聊消息
+func NewForwardHandler(userManager user.IUserManager, groups GroupMembers, encoder codec.Encoder) *ForwardHandler {
 	return &ForwardHandler{
 		userManager: userManager,
+		groups:      groups,
+		Encoder:     encoder,
 	}
 }
 
@@ -31,18 +39,15 @@ func (h *ForwardHandler) Handle(data []byte) (bool, error) {
 	if err != nil {
 		return false, err
 	}
-	// 根据消息类型和目标进行转发
-	switch msg.GetType() {
-	case types.MessageTypeText, types.MessageTypeImage,
-		types.MessageTypeVideo, types.MessageTypeAudio,
-		types.MessageTypeFile:
-		// 如果消息有特定目标用户，则转发给目标用户
-		if msg.GetTo() != "" {
-			// 不再使用Platform字段，确保消息能够正确转发给目标用户
-			err := h.userManager.SendMessage(msg.GetTo(), msg)
-			if err != nil {
-				return false, errors.Join(err...)
-			}
+	// 消息类型由路由器筛选，这里只负责按目标转发
+	if groupID := msg.GetGroupID(); groupID != "" {
+		return h.forwardGroup(groupID, msg)
+	}
+	if msg.GetTo() != "" {
+		// 不再使用Platform字段，确保消息能够正确转发给目标用户
+		errs := h.userManager.SendMessage(msg.GetTo(), msg)
+		if len(errs) > 0 {
+			return false, errors.Join(errs...)
 		}
 	}
 
@@ -50,6 +55,24 @@ func (h *ForwardHandler) Handle(data []byte) (bool, error) {
 	return true, nil
 }
 
+// forwardGroup 将群聊消息转发给发送方以外的全部成员。
+// 单个成员投递失败不中断处理链，消息存储后成员可以通过历史消息获取
+func (h *ForwardHandler) forwardGroup(groupID string, msg *types.Message) (bool, error) {
+	if h.groups == nil {
+		return false, ErrGroupsUnsupported
+	}
+	members, err := h.groups.GetMemberIDs(groupID)
+	if err != nil {
+		return false, err
+	}
+	for _, memberID := range members {
+		if memberID != msg.GetFrom() {
+			h.userManager.SendMessage(memberID, msg)
+		}
+	}
+	return true, nil
+}
+
 // StoreHandler 消息存储处理器
 type StoreHandler struct {
 	BaseHandler
@@ -88,15 +111,158 @@ func (h *StoreHandler) Handle(data []byte) (bool, error) {
 	return true, nil
 }
 
-// NewMessageChain 创建默认的消息处理链
-func NewMessageChain(userManager user.IUserManager, ms *stores.MessageStore, encoder codec.Encoder) *Chain {
+// EventPublisher 定义业务事件发布接口
+type EventPublisher interface {
+	// Publish 发布事件
+	Publish(event string, data interface{}) error
+}
+
+// WebhookHandler 将消息事件发布到回调系统
+type WebhookHandler struct {
+	BaseHandler
+	publisher EventPublisher
+	encoder   codec.Encoder
+}
+
+// NewWebhookHandler 创建消息回调处理器
+func NewWebhookHandler(publisher EventPublisher, encoder codec.Encoder) *WebhookHandler {
+	return &WebhookHandler{publisher: publisher, encoder: encoder}
+}
+
+// Handle 发布 message.created 事件
+func (h *WebhookHandler) Handle(data []byte) (bool, error) {
+	msg := new(types.Message)
+	if err := h.encoder.Decode(data, msg); err != nil {
+		return false, err
+	}
+	md := webhook.NewMessageData(msg)
+	md.SubType = msg.Header.SubType
+	if err := h.publisher.Publish(webhook.EventMessageCreated, md); err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// ValidateHandler 消息基础校验处理器
+type ValidateHandler struct {
+	BaseHandler
+	encoder codec.Encoder
+}
+
+// NewValidateHandler 创建消息校验处理器
+func NewValidateHandler(encoder codec.Encoder) *ValidateHandler {
+	return &ValidateHandler{encoder: encoder}
+}
+
+// Handle 校验消息头的必填字段
+func (h *ValidateHandler) Handle(data []byte) (bool, error) {
+	msg := new(types.Message)
+	if err := h.encoder.Decode(data, msg); err != nil {
+		return false, err
+	}
+	if msg.GetFrom() == "" {
+		return false, errors.New("message sender is required")
+	}
+	if msg.Header.Type == types.MessageTypeCustom && msg.Header.SubType == "" {
+		return false, errors.New("custom message requires sub_type")
+	}
+	if msg.GetGroupID() != "" && msg.GetTo() != "" {
+		return false, errors.New("group message must not have a receiver")
+	}
+	return true, nil
+}
+
+// ErrUnsupportedType 消息类型不支持由客户端发送
+var ErrUnsupportedType = errors.New("unsupported message type")
+
+// UnsupportedTypeHandler 拒绝没有匹配路由的消息，作为默认路由使用
+type UnsupportedTypeHandler struct {
+	BaseHandler
+	encoder codec.Encoder
+}
+
+// NewUnsupportedTypeHandler 创建未知类型消息处理器
+func NewUnsupportedTypeHandler(encoder codec.Encoder) *UnsupportedTypeHandler {
+	return &UnsupportedTypeHandler{encoder: encoder}
+}
+
+// Handle 返回同时匹配 RejectedError 和 ErrUnsupportedType 的错误，发送方会收到拒绝通知
+func (h *UnsupportedTypeHandler) Handle(data []byte) (bool, error) {
+	msg := new(types.Message)
+	if err := h.encoder.Decode(data, msg); err != nil {
+		return false, err
+	}
+	return false, fmt.Errorf("%w: %w", newRejectedError(msg, "不支持的消息类型"), ErrUnsupportedType)
+}
+
+// MessageRouterConfig 默认消息路由的配置
+type MessageRouterConfig struct {
+	UserManager  user.IUserManager
+	MessageStore *stores.MessageStore
+	Encoder      codec.Encoder
+	// BeforeDeliver 在转发之前执行的处理器，例如投递前拦截
+	BeforeDeliver []Handler
+	// AfterStore 在存储之后执行的处理器，例如回调通知
+	AfterStore []Handler
+	// SearchIndex 消息全文索引，为空时不建立索引
+	SearchIndex search.Index
+	// Logger 记录不影响投递的错误，例如索引失败，为空时不记录
+	Logger logger.Logger
+	// Audit 审计日志记录器，为空时不记录撤回
+	Audit *audit.Recorder
+	// Groups 群成员查询，为空时拒绝群聊消息
+	Groups GroupMembers
+	// Roles 用户角色查询，机器人账号不是群成员时也可以发送群聊消息；为空时不区分机器人
+	Roles RoleResolver
+}
+
+// NewMessageRouter 创建默认的消息路由
+func NewMessageRouter(cfg *MessageRouterConfig) *Router {
+	router := NewRouter(cfg.Encoder)
+
+	// 所有消息共享的前置校验
+	router.Use(NewValidateHandler(cfg.Encoder))
+
+	// 投递前检查：群成员校验、拦截，新消息、编辑和撤回共用
+	checks := NewChain()
+	checks.AddHandler(NewGroupHandler(cfg.Groups, cfg.Roles, cfg.Encoder))
+	for _, h := range cfg.BeforeDeliver {
+		checks.AddHandler(h)
+	}
+
+	// 业务消息：投递前检查、转发、存储、通知
 	chain := NewChain()
+	chain.AddHandler(&chainHandler{chain: checks})
+	chain.AddHandler(NewForwardHandler(cfg.UserManager, cfg.Groups, cfg.Encoder))
+	chain.AddHandler(NewStoreHandler(cfg.MessageStore, cfg.Encoder))
+	for _, h := range cfg.AfterStore {
+		chain.AddHandler(h)
+	}
+	// 索引失败不影响投递和回调，放在最后
+	if cfg.SearchIndex != nil {
+		chain.AddHandler(NewIndexHandler(cfg.SearchIndex, cfg.Encoder, cfg.Logger))
+	}
+	router.Route(chain,
+		types.MessageTypeText, types.MessageTypeImage,
+		types.MessageTypeVideo, types.MessageTypeAudio,
+		types.MessageTypeFile, types.MessageTypeCustom,
+	)
+
+	// 编辑和撤回：同样需要通过投递前检查，更新存储与索引后通知原消息的接收方
+	mutation := NewChain()
+	mutation.AddHandler(NewMutationCheckHandler(cfg.MessageStore, checks, cfg.Encoder))
+	mutation.AddHandler(NewMutationHandler(cfg.MessageStore, cfg.SearchIndex, cfg.Audit, cfg.Encoder))
+	mutation.AddHandler(NewForwardHandler(cfg.UserManager, cfg.Groups, cfg.Encoder))
+	router.RouteCustom(SubTypeEdit, mutation)
+	router.RouteCustom(SubTypeRecall, mutation)
 
-	// 添加消息转发处理器
-	chain.AddHandler(NewForwardHandler(userManager))
+	// 应用层心跳不需要转发和存储
+	router.Route(NewChain(), types.MessageTypeHeartbeat)
 
-	// 添加消息存储处理器
-	chain.AddHandler(NewStoreHandler(ms, encoder))
+	// 其余类型（包括只能由服务端下发的系统消息）直接拒绝
+	fallback := NewChain()
+	fallback.AddHandler(NewUnsupportedTypeHandler(cfg.Encoder))
+	router.SetDefault(fallback)
 
-	return chain
+	return router
 }
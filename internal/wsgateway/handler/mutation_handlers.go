@@ -0,0 +1,231 @@
+package handler
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"github.com/woxQAQ/gim/internal/apiserver/stores"
+	"github.com/woxQAQ/gim/internal/audit"
+	"github.com/woxQAQ/gim/internal/models"
+	"github.com/woxQAQ/gim/internal/search"
+	"github.com/woxQAQ/gim/internal/types"
+	"github.com/woxQAQ/gim/internal/wsgateway/codec"
+	"github.com/woxQAQ/gim/pkg/logger"
+)
+
+// 编辑和撤回以自定义消息发送，sub_type 为以下值，payload 为 MessageMutation 的 JSON
+const (
+	SubTypeEdit   = "edit"
+	SubTypeRecall = "recall"
+)
+
+var (
+	// ErrNotMessageSender 只有消息的发送者可以编辑或撤回消息
+	ErrNotMessageSender = errors.New("only the sender can modify the message")
+	// ErrMessageRecalled 已撤回的消息不能再编辑
+	ErrMessageRecalled = errors.New("message has been recalled")
+)
+
+// MessageMutation 编辑或撤回消息的内容
+type MessageMutation struct {
+	MessageID string `json:"message_id"`
+	Content   string `json:"content,omitempty"` // 编辑后的内容，撤回时忽略
+}
+
+// IndexHandler 将存储后的消息写入全文索引，需放在 StoreHandler 之后
+type IndexHandler struct {
+	BaseHandler
+	index   search.Index
+	encoder codec.Encoder
+	logger  logger.Logger
+}
+
+// NewIndexHandler 创建消息索引处理器，l 为空时不记录索引失败
+func NewIndexHandler(index search.Index, encoder codec.Encoder, l logger.Logger) *IndexHandler {
+	return &IndexHandler{index: index, encoder: encoder, logger: l}
+}
+
+// Handle 实现 Handler 接口。
+// 此时消息已经投递和存储，索引失败只记录日志，避免发送方重试导致重复投递
+func (h *IndexHandler) Handle(data []byte) (bool, error) {
+	msg := new(types.Message)
+	if err := h.encoder.Decode(data, msg); err != nil {
+		return false, err
+	}
+	m := &models.Message{}
+	m.FromTypes(msg)
+	if err := h.index.Index(search.DocumentFromMessage(m)); err != nil && h.logger != nil {
+		h.logger.Error("Failed to index message", logger.String("message_id", m.ID), logger.Error(err))
+	}
+	return true, nil
+}
+
+var (
+	_ Handler     = (*MutationCheckHandler)(nil)
+	_ Transformer = (*MutationCheckHandler)(nil)
+)
+
+// MutationCheckHandler 对编辑和撤回执行与新消息相同的投递前检查，需放在 MutationHandler 之前.
+//
+// 转发目标取自原消息而不是客户端填写的 To 和 GroupID，检查时按原消息的会话构造一条消息交给 checks：
+// 编辑使用原消息的类型和编辑后的内容，内容被改写（例如掩码）时使用改写后的内容；撤回使用撤回消息本身。
+// 被拒绝时编辑或撤回不生效。
+type MutationCheckHandler struct {
+	BaseHandler
+	messageStore *stores.MessageStore
+	checks       *Chain
+	encoder      codec.Encoder
+}
+
+// NewMutationCheckHandler 创建编辑撤回检查处理器
+func NewMutationCheckHandler(messageStore *stores.MessageStore, checks *Chain, encoder codec.Encoder) *MutationCheckHandler {
+	return &MutationCheckHandler{messageStore: messageStore, checks: checks, encoder: encoder}
+}
+
+// Handle 实现 Handler 接口，不支持改写时使用
+func (h *MutationCheckHandler) Handle(data []byte) (bool, error) {
+	_, continue_, err := h.Transform(data)
+	return continue_, err
+}
+
+// Transform 实现 Transformer 接口，返回按原消息会话重建的编辑或撤回消息
+func (h *MutationCheckHandler) Transform(data []byte) ([]byte, bool, error) {
+	msg := new(types.Message)
+	if err := h.encoder.Decode(data, msg); err != nil {
+		return nil, false, err
+	}
+	var mutation MessageMutation
+	if err := json.Unmarshal(msg.Payload, &mutation); err != nil {
+		return nil, false, fmt.Errorf("invalid %s payload: %w", msg.Header.SubType, err)
+	}
+	original, err := h.messageStore.GetMessage(mutation.MessageID)
+	if err != nil {
+		return nil, false, err
+	}
+	// 先校验权限，避免无权修改的请求触发过滤和审核的副作用
+	if err := checkMutation(original, msg); err != nil {
+		return nil, false, err
+	}
+
+	// 重建转发的消息，只保留原消息的会话和编辑内容
+	mutation = MessageMutation{MessageID: original.ID, Content: mutation.Content}
+	if msg.Header.SubType != SubTypeEdit {
+		mutation.Content = ""
+	}
+	forward := types.NewMessage(types.MessageTypeCustom, original.FromID, original.ToID, msg.GetPlatform(), nil)
+	forward.Header.ID = msg.GetID()
+	forward.Header.SubType = msg.Header.SubType
+	if original.ConversationID == models.GroupConversationID(original.ToID) {
+		forward.Header.To = ""
+		forward.Header.GroupID = original.ToID
+	}
+
+	candidate := *forward
+	candidate.Header.ID = original.ID
+	if msg.Header.SubType == SubTypeEdit {
+		candidate.Header.Type = original.Type
+		candidate.Header.SubType = ""
+		candidate.Payload = []byte(mutation.Content)
+	}
+	encoded, err := h.encoder.Encode(&candidate)
+	if err != nil {
+		return nil, false, err
+	}
+	checked, completed, err := h.checks.Transform(encoded)
+	if err != nil || !completed {
+		return nil, false, err
+	}
+	if msg.Header.SubType == SubTypeEdit {
+		if err := h.encoder.Decode(checked, &candidate); err != nil {
+			return nil, false, err
+		}
+		mutation.Content = string(candidate.Payload)
+	}
+
+	if forward.Payload, err = json.Marshal(&mutation); err != nil {
+		return nil, false, err
+	}
+	rewritten, err := h.encoder.Encode(forward)
+	if err != nil {
+		return nil, false, err
+	}
+	return rewritten, true, nil
+}
+
+// MutationHandler 处理消息的编辑和撤回，更新存储和索引后继续转发通知对方
+type MutationHandler struct {
+	BaseHandler
+	messageStore *stores.MessageStore
+	index        search.Index
+	audit        *audit.Recorder
+	encoder      codec.Encoder
+}
+
+// NewMutationHandler 创建编辑撤回处理器，index 为空时不更新索引，recorder 为空时不记录撤回
+func NewMutationHandler(messageStore *stores.MessageStore, index search.Index, recorder *audit.Recorder, encoder codec.Encoder) *MutationHandler {
+	return &MutationHandler{messageStore: messageStore, index: index, audit: recorder, encoder: encoder}
+}
+
+// Handle 实现 Handler 接口
+func (h *MutationHandler) Handle(data []byte) (bool, error) {
+	msg := new(types.Message)
+	if err := h.encoder.Decode(data, msg); err != nil {
+		return false, err
+	}
+	var mutation MessageMutation
+	if err := json.Unmarshal(msg.Payload, &mutation); err != nil {
+		return false, fmt.Errorf("invalid %s payload: %w", msg.Header.SubType, err)
+	}
+
+	original, err := h.messageStore.GetMessage(mutation.MessageID)
+	if err != nil {
+		return false, err
+	}
+	if err := checkMutation(original, msg); err != nil {
+		return false, err
+	}
+
+	switch msg.Header.SubType {
+	case SubTypeEdit:
+		if err := h.messageStore.UpdateMessageContent(original.ID, mutation.Content); err != nil {
+			return false, err
+		}
+		if h.index != nil {
+			original.Content = mutation.Content
+			if err := h.index.Index(search.DocumentFromMessage(original)); err != nil {
+				return false, err
+			}
+		}
+	case SubTypeRecall:
+		if err := h.messageStore.RecallMessage(original.ID); err != nil {
+			return false, err
+		}
+		if h.index != nil {
+			if err := h.index.Delete(original.ID); err != nil {
+				return false, err
+			}
+		}
+		h.audit.Record(context.Background(), &audit.Entry{
+			Action:   audit.ActionMessageRecall,
+			ActorID:  original.FromID,
+			TargetID: original.ID,
+			Detail:   map[string]any{"to": original.ToID, "platform": msg.GetPlatform()},
+		})
+	default:
+		return false, fmt.Errorf("unknown mutation %q", msg.Header.SubType)
+	}
+	return true, nil
+}
+
+// checkMutation 校验 msg 能否修改 original：只有发送者可以修改，已撤回的消息不能再编辑
+func checkMutation(original *models.Message, msg *types.Message) error {
+	if original.FromID != msg.GetFrom() {
+		return ErrNotMessageSender
+	}
+	if msg.Header.SubType == SubTypeEdit && original.Status == models.MessageStatusRecalled {
+		return ErrMessageRecalled
+	}
+	return nil
+}
@@ -0,0 +1,172 @@
+package handler_test
+
+import (
+	"encoding/json"
+	"errors"
+	"path/filepath"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"github.com/woxQAQ/gim/internal/apiserver/stores"
+	"github.com/woxQAQ/gim/internal/migrations"
+	"github.com/woxQAQ/gim/internal/search"
+	"github.com/woxQAQ/gim/internal/types"
+	"github.com/woxQAQ/gim/internal/wsgateway/codec"
+	"github.com/woxQAQ/gim/internal/wsgateway/filter"
+	"github.com/woxQAQ/gim/internal/wsgateway/handler"
+	"github.com/woxQAQ/gim/pkg/db"
+)
+
+var _ = Describe("MutationCheckHandler", func() {
+	var (
+		encoder  *codec.JSONEncoder
+		users    *sendRecorder
+		blocks   *stores.BlockStore
+		messages *stores.MessageStore
+		router   *handler.Router
+		original string
+	)
+
+	mutateTo := func(subType, from, to, content string) error {
+		payload, err := json.Marshal(&handler.MessageMutation{MessageID: original, Content: content})
+		Expect(err).NotTo(HaveOccurred())
+		msg := types.NewMessage(types.MessageTypeCustom, from, to, 1, payload)
+		msg.Header.SubType = subType
+		data, err := encoder.Encode(msg)
+		Expect(err).NotTo(HaveOccurred())
+		return router.Process(data)
+	}
+
+	mutate := func(subType, from, content string) error {
+		return mutateTo(subType, from, "u2", content)
+	}
+
+	edit := func(from, content string) error {
+		return mutate(handler.SubTypeEdit, from, content)
+	}
+
+	content := func() string {
+		m, err := messages.GetMessage(original)
+		Expect(err).NotTo(HaveOccurred())
+		return m.Content
+	}
+
+	BeforeEach(func() {
+		gdb, err := db.Open(&db.Config{DSN: filepath.Join(GinkgoT().TempDir(), "handler.db")})
+		Expect(err).NotTo(HaveOccurred())
+		m, err := db.NewMigrator(gdb, migrations.All())
+		Expect(err).NotTo(HaveOccurred())
+		_, err = m.Up()
+		Expect(err).NotTo(HaveOccurred())
+
+		f, err := filter.New(&filter.Config{MaskChar: "*", Rules: []filter.Rule{
+			{Name: "spam", Action: filter.ActionReject, Reason: "包含违规内容", Words: []string{"spam"}},
+			{Name: "rude", Action: filter.ActionMask, Words: []string{"rude"}},
+		}})
+		Expect(err).NotTo(HaveOccurred())
+
+		encoder = codec.NewJSONEncoder()
+		users = &sendRecorder{}
+		blocks = stores.NewBlockStore(gdb)
+		messages = stores.NewMessageStore(gdb)
+		router = handler.NewMessageRouter(&handler.MessageRouterConfig{
+			UserManager:  users,
+			MessageStore: messages,
+			Encoder:      encoder,
+			BeforeDeliver: []handler.Handler{
+				handler.NewBlockHandler(blocks, encoder),
+				handler.NewFilterHandler(f, stores.NewModerationStore(gdb), encoder),
+			},
+		})
+
+		msg := types.NewMessage(types.MessageTypeText, "u1", "u2", 1, []byte("hello"))
+		original = msg.GetID()
+		data, err := encoder.Encode(msg)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(router.Process(data)).To(Succeed())
+	})
+
+	It("编辑后的内容命中过滤规则时应该被拒绝，原消息不变", func() {
+		err := edit("u1", "buy spam now")
+		var rejected *handler.RejectedError
+		Expect(errors.As(err, &rejected)).To(BeTrue())
+		Expect(rejected.Reason).To(Equal("包含违规内容"))
+		Expect(content()).To(Equal("hello"))
+		Expect(users.sent).To(HaveLen(1))
+	})
+
+	It("编辑后的内容应该按过滤规则掩码后保存", func() {
+		Expect(edit("u1", "so rude")).To(Succeed())
+		Expect(content()).To(Equal("so ****"))
+		Expect(users.sent).To(HaveLen(2))
+	})
+
+	It("只有发送者可以编辑消息", func() {
+		Expect(edit("u3", "hi")).To(MatchError(handler.ErrNotMessageSender))
+		Expect(content()).To(Equal("hello"))
+	})
+
+	It("非发送者的编辑不应该经过过滤规则", func() {
+		Expect(edit("u3", "buy spam now")).To(MatchError(handler.ErrNotMessageSender))
+		Expect(users.sent).To(HaveLen(1))
+	})
+
+	It("已撤回的消息不能再编辑", func() {
+		Expect(mutate(handler.SubTypeRecall, "u1", "")).To(Succeed())
+		Expect(edit("u1", "hello again")).To(MatchError(handler.ErrMessageRecalled))
+		Expect(content()).To(BeEmpty())
+	})
+
+	It("编辑和撤回只通知原消息的接收方，忽略客户端填写的目标", func() {
+		Expect(mutateTo(handler.SubTypeEdit, "u1", "u3", "hi")).To(Succeed())
+		Expect(mutateTo(handler.SubTypeRecall, "u1", "u3", "")).To(Succeed())
+		Expect(users.sent).To(Equal([]string{"u2", "u2", "u2"}))
+	})
+
+	It("被对方拉黑后不能再编辑或撤回发给对方的消息", func() {
+		Expect(blocks.Block("u2", "u1")).To(Succeed())
+		Expect(mutateTo(handler.SubTypeEdit, "u1", "u3", "hi")).To(MatchError(handler.ErrSenderBlocked))
+		Expect(mutateTo(handler.SubTypeRecall, "u1", "u3", "")).To(MatchError(handler.ErrSenderBlocked))
+		Expect(content()).To(Equal("hello"))
+		Expect(users.sent).To(HaveLen(1))
+	})
+})
+
+// failingIndex 写入总是失败的索引
+type failingIndex struct {
+	*search.MemoryIndex
+}
+
+func (failingIndex) Index(*search.Document) error {
+	return errors.New("index unavailable")
+}
+
+var _ = Describe("IndexHandler", func() {
+	It("索引失败不影响已经投递和存储的消息", func() {
+		gdb, err := db.Open(&db.Config{DSN: filepath.Join(GinkgoT().TempDir(), "handler.db")})
+		Expect(err).NotTo(HaveOccurred())
+		m, err := db.NewMigrator(gdb, migrations.All())
+		Expect(err).NotTo(HaveOccurred())
+		_, err = m.Up()
+		Expect(err).NotTo(HaveOccurred())
+
+		encoder := codec.NewJSONEncoder()
+		users := &sendRecorder{}
+		messages := stores.NewMessageStore(gdb)
+		router := handler.NewMessageRouter(&handler.MessageRouterConfig{
+			UserManager:  users,
+			MessageStore: messages,
+			Encoder:      encoder,
+			SearchIndex:  failingIndex{search.NewMemoryIndex()},
+		})
+
+		msg := types.NewMessage(types.MessageTypeText, "u1", "u2", 1, []byte("hello"))
+		data, err := encoder.Encode(msg)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(router.Process(data)).To(Succeed())
+		Expect(users.sent).To(Equal([]string{"u2"}))
+		_, err = messages.GetMessage(msg.GetID())
+		Expect(err).NotTo(HaveOccurred())
+	})
+})
@@ -0,0 +1,126 @@
+package handler
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+
+	"github.com/woxQAQ/gim/internal/types"
+	"github.com/woxQAQ/gim/internal/wsgateway/codec"
+)
+
+// ErrNoRoute 消息类型没有匹配的路由且未设置默认路由
+var ErrNoRoute = errors.New("no route for message type")
+
+// Processor 定义消息处理入口
+type Processor interface {
+	// Process 处理一条原始消息
+	Process(data []byte) error
+}
+
+var (
+	_ Processor = (*Chain)(nil)
+	_ Processor = (*Router)(nil)
+)
+
+// Router 按消息类型将消息分发到不同的处理链.
+//
+// 每条消息先经过共享的前置链（鉴权、校验、限流等横切逻辑），
+// 前置链全部放行后再根据 MessageType 选择业务处理链。
+// 自定义消息（MessageTypeCustom）会优先按 SubType 匹配，
+// 都未命中时交给默认路由处理。
+//
+// 注意：Handler 通过 SetNext 串联，同一个 Handler 实例只能属于一条链，
+// 需要在多个类型间复用时应复用整条 Chain 而不是 Handler。
+type Router struct {
+	encoder      codec.Encoder
+	prefix       *Chain
+	routes       map[types.MessageType]*Chain
+	customRoutes map[string]*Chain
+	defaultRoute *Chain
+	mutex        sync.RWMutex
+}
+
+// NewRouter 创建新的消息路由器
+func NewRouter(encoder codec.Encoder) *Router {
+	return &Router{
+		encoder:      encoder,
+		prefix:       NewChain(),
+		routes:       make(map[types.MessageType]*Chain),
+		customRoutes: make(map[string]*Chain),
+	}
+}
+
+// Use 添加处理器到共享前置链
+func (r *Router) Use(handlers ...Handler) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	for _, h := range handlers {
+		r.prefix.AddHandler(h)
+	}
+}
+
+// Route 将指定的消息类型路由到处理链
+func (r *Router) Route(chain *Chain, msgTypes ...types.MessageType) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	for _, t := range msgTypes {
+		r.routes[t] = chain
+	}
+}
+
+// RouteCustom 将指定子类型的自定义消息路由到处理链
+func (r *Router) RouteCustom(subType string, chain *Chain) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	r.customRoutes[subType] = chain
+}
+
+// SetDefault 设置未匹配到路由时使用的处理链
+func (r *Router) SetDefault(chain *Chain) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	r.defaultRoute = chain
+}
+
+// Process 实现 Processor 接口
+func (r *Router) Process(data []byte) error {
+	header := new(routeHeader)
+	if err := r.encoder.Decode(data, header); err != nil {
+		return err
+	}
+
+	r.mutex.RLock()
+	prefix := r.prefix
+	chain := r.match(header.Header.Type, header.Header.SubType)
+	r.mutex.RUnlock()
+
+	// 前置链中断时不再分发
+	completed, err := prefix.Run(data)
+	if err != nil || !completed {
+		return err
+	}
+
+	if chain == nil {
+		return fmt.Errorf("%w: %s", ErrNoRoute, header.Header.Type)
+	}
+	return chain.Process(data)
+}
+
+// match 查找消息对应的处理链，调用方需持有读锁
+func (r *Router) match(msgType types.MessageType, subType string) *Chain {
+	if msgType == types.MessageTypeCustom && subType != "" {
+		if chain, ok := r.customRoutes[subType]; ok {
+			return chain
+		}
+	}
+	if chain, ok := r.routes[msgType]; ok {
+		return chain
+	}
+	return r.defaultRoute
+}
+
+// routeHeader 路由时只需要解析消息头
+type routeHeader struct {
+	Header types.MessageHeader `json:"header"`
+}
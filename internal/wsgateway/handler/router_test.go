@@ -0,0 +1,114 @@
+package handler_test
+
+import (
+	"errors"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"github.com/woxQAQ/gim/internal/types"
+	"github.com/woxQAQ/gim/internal/wsgateway/codec"
+	"github.com/woxQAQ/gim/internal/wsgateway/handler"
+)
+
+// recordHandler 记录调用次数的测试处理器
+type recordHandler struct {
+	handler.BaseHandler
+	calls     int
+	continue_ bool
+}
+
+func (h *recordHandler) Handle([]byte) (bool, error) {
+	h.calls++
+	return h.continue_, nil
+}
+
+func newRecord(continue_ bool) *recordHandler {
+	return &recordHandler{continue_: continue_}
+}
+
+func chainOf(handlers ...handler.Handler) *handler.Chain {
+	c := handler.NewChain()
+	for _, h := range handlers {
+		c.AddHandler(h)
+	}
+	return c
+}
+
+var _ = Describe("Router", func() {
+	var (
+		encoder *codec.JSONEncoder
+		router  *handler.Router
+	)
+
+	encode := func(msgType types.MessageType, subType string) []byte {
+		data, err := encoder.Encode(&types.Message{Header: types.MessageHeader{
+			Type:    msgType,
+			From:    "u1",
+			SubType: subType,
+		}})
+		Expect(err).NotTo(HaveOccurred())
+		return data
+	}
+
+	BeforeEach(func() {
+		encoder = codec.NewJSONEncoder()
+		router = handler.NewRouter(encoder)
+	})
+
+	It("应该按消息类型分发到对应的处理链", func() {
+		text, image := newRecord(true), newRecord(true)
+		router.Route(chainOf(text), types.MessageTypeText)
+		router.Route(chainOf(image), types.MessageTypeImage)
+
+		Expect(router.Process(encode(types.MessageTypeText, ""))).To(Succeed())
+		Expect(text.calls).To(Equal(1))
+		Expect(image.calls).To(Equal(0))
+	})
+
+	It("自定义消息应该优先按子类型分发", func() {
+		custom, typing := newRecord(true), newRecord(true)
+		router.Route(chainOf(custom), types.MessageTypeCustom)
+		router.RouteCustom("typing", chainOf(typing))
+
+		Expect(router.Process(encode(types.MessageTypeCustom, "typing"))).To(Succeed())
+		Expect(router.Process(encode(types.MessageTypeCustom, "other"))).To(Succeed())
+		Expect(typing.calls).To(Equal(1))
+		Expect(custom.calls).To(Equal(1))
+	})
+
+	It("未匹配的类型应该走默认路由", func() {
+		Expect(errors.Is(router.Process(encode(types.MessageTypeVideo, "")), handler.ErrNoRoute)).To(BeTrue())
+
+		fallback := newRecord(true)
+		router.SetDefault(chainOf(fallback))
+		Expect(router.Process(encode(types.MessageTypeVideo, ""))).To(Succeed())
+		Expect(fallback.calls).To(Equal(1))
+	})
+
+	It("前置链中断时不应该分发", func() {
+		text := newRecord(true)
+		router.Use(newRecord(false))
+		router.Route(chainOf(text), types.MessageTypeText)
+
+		Expect(router.Process(encode(types.MessageTypeText, ""))).To(Succeed())
+		Expect(text.calls).To(Equal(0))
+	})
+})
+
+var _ = Describe("NewMessageRouter", func() {
+	It("未知类型的消息应该被默认路由拒绝", func() {
+		encoder := codec.NewJSONEncoder()
+		router := handler.NewMessageRouter(&handler.MessageRouterConfig{Encoder: encoder})
+		for _, msgType := range []types.MessageType{types.MessageTypeSystem, types.MessageType(99)} {
+			data, err := encoder.Encode(types.NewMessage(msgType, "u1", "u2", 1, []byte("hello")))
+			Expect(err).NotTo(HaveOccurred())
+
+			err = router.Process(data)
+			Expect(err).To(MatchError(handler.ErrUnsupportedType))
+			var rejected *handler.RejectedError
+			Expect(errors.As(err, &rejected)).To(BeTrue())
+			Expect(rejected.From).To(Equal("u1"))
+		}
+	})
+})
@@ -0,0 +1,72 @@
+package intercept
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/woxQAQ/gim/internal/types"
+	"github.com/woxQAQ/gim/internal/webhook"
+)
+
+var _ Interceptor = (*HTTPInterceptor)(nil)
+
+// HTTPInterceptor 通过HTTP回调业务方决定是否投递消息.
+//
+// 请求体为 JSON 编码的消息，签名方式与 webhook 相同；
+// 业务方需要在超时时间内返回 JSON 编码的 Decision。
+type HTTPInterceptor struct {
+	url    string
+	secret string
+	client *http.Client
+}
+
+// NewHTTPInterceptor 创建HTTP回调拦截器
+func NewHTTPInterceptor(url, secret string, timeout time.Duration) *HTTPInterceptor {
+	return &HTTPInterceptor{
+		url:    url,
+		secret: secret,
+		client: &http.Client{Timeout: timeout},
+	}
+}
+
+// BeforeDeliver 实现 Interceptor 接口
+func (h *HTTPInterceptor) BeforeDeliver(ctx context.Context, msg *types.Message) (*Decision, error) {
+	body, err := json.Marshal(msg)
+	if err != nil {
+		return nil, err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, h.url, bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	timestamp := time.Now().Unix()
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(webhook.HeaderEvent, "message.before_deliver")
+	req.Header.Set(webhook.HeaderTimestamp, strconv.FormatInt(timestamp, 10))
+	if h.secret != "" {
+		req.Header.Set(webhook.HeaderSignature, webhook.Sign(h.secret, timestamp, body))
+	}
+
+	resp, err := h.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("intercept callback returned status %d", resp.StatusCode)
+	}
+
+	decision := new(Decision)
+	if err := json.NewDecoder(io.LimitReader(resp.Body, 1<<20)).Decode(decision); err != nil {
+		return nil, err
+	}
+	return decision, nil
+}
@@ -0,0 +1,108 @@
+package intercept
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/woxQAQ/gim/internal/types"
+)
+
+// Action 拦截结果
+type Action string
+
+const (
+	// ActionAllow 放行消息
+	ActionAllow Action = "allow"
+	// ActionReject 拒绝消息，不再投递和存储
+	ActionReject Action = "reject"
+	// ActionRewrite 使用新的内容替换消息后放行
+	ActionRewrite Action = "rewrite"
+)
+
+// Decision 拦截决策
+type Decision struct {
+	Action  Action `json:"action"`
+	Reason  string `json:"reason,omitempty"`  // 拒绝原因，会返回给发送方
+	Payload []byte `json:"payload,omitempty"` // 改写后的消息内容，仅 ActionRewrite 使用
+}
+
+// Allow 放行决策
+func Allow() *Decision {
+	return &Decision{Action: ActionAllow}
+}
+
+// Reject 拒绝决策
+func Reject(reason string) *Decision {
+	return &Decision{Action: ActionReject, Reason: reason}
+}
+
+// Rewrite 改写决策
+func Rewrite(payload []byte) *Decision {
+	return &Decision{Action: ActionRewrite, Payload: payload}
+}
+
+// Validate 检查决策是否合法，拦截器没有返回决策时同样不合法
+func (d *Decision) Validate() error {
+	if d == nil {
+		return errors.New("missing intercept decision")
+	}
+	switch d.Action {
+	case ActionAllow, ActionReject:
+		return nil
+	case ActionRewrite:
+		if d.Payload == nil {
+			return errors.New("rewrite decision requires payload")
+		}
+		return nil
+	default:
+		return fmt.Errorf("unknown intercept action %q", d.Action)
+	}
+}
+
+// Interceptor 定义消息投递前的拦截接口.
+//
+// 拦截器在消息转发之前同步执行，可以放行、拒绝或改写消息。
+// 实现需要遵守 ctx 的超时设置。
+type Interceptor interface {
+	// BeforeDeliver 在消息投递前调用
+	BeforeDeliver(ctx context.Context, msg *types.Message) (*Decision, error)
+}
+
+// InterceptorFunc 函数形式的拦截器
+type InterceptorFunc func(ctx context.Context, msg *types.Message) (*Decision, error)
+
+// BeforeDeliver 实现 Interceptor 接口
+func (f InterceptorFunc) BeforeDeliver(ctx context.Context, msg *types.Message) (*Decision, error) {
+	return f(ctx, msg)
+}
+
+// Chain 依次执行多个拦截器.
+//
+// 任意拦截器拒绝时立即返回；改写会作用于后续拦截器看到的消息。
+type Chain []Interceptor
+
+// BeforeDeliver 实现 Interceptor 接口
+func (c Chain) BeforeDeliver(ctx context.Context, msg *types.Message) (*Decision, error) {
+	rewritten := false
+	for _, i := range c {
+		d, err := i.BeforeDeliver(ctx, msg)
+		if err != nil {
+			return nil, err
+		}
+		if err := d.Validate(); err != nil {
+			return nil, err
+		}
+		switch d.Action {
+		case ActionReject:
+			return d, nil
+		case ActionRewrite:
+			msg.Payload = d.Payload
+			rewritten = true
+		}
+	}
+	if rewritten {
+		return Rewrite(msg.Payload), nil
+	}
+	return Allow(), nil
+}
@@ -0,0 +1,286 @@
+package wsgateway
+
+import (
+	"crypto/subtle"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"slices"
+
+	"github.com/woxQAQ/gim/internal/types"
+	"github.com/woxQAQ/gim/internal/wsgateway/base"
+	"github.com/woxQAQ/gim/internal/wsgateway/handler"
+	"github.com/woxQAQ/gim/pkg/logger"
+)
+
+// InternalTokenHeader 内部接口鉴权请求头
+const InternalTokenHeader = "X-Internal-Token"
+
+// InternalHandler 返回供 apiserver 调用的内部接口.
+//
+// 内部接口使用共享令牌鉴权，token 为空时所有请求都会被拒绝。
+func (g *WSGateway) InternalHandler(token string) http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("POST /internal/push", g.handlePush)
+	mux.HandleFunc("POST /internal/send", g.handleSend)
+	mux.HandleFunc("POST /internal/disconnect", g.handleDisconnect)
+	mux.HandleFunc("POST /internal/presence", g.handlePresence)
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		provided := r.Header.Get(InternalTokenHeader)
+		if token == "" || subtle.ConstantTimeCompare([]byte(provided), []byte(token)) != 1 {
+			http.Error(w, "unauthorized", http.StatusUnauthorized)
+			return
+		}
+		mux.ServeHTTP(w, r)
+	})
+}
+
+// handlePush 处理推送请求
+func (g *WSGateway) handlePush(w http.ResponseWriter, r *http.Request) {
+	req := new(types.PushRequest)
+	if err := json.NewDecoder(r.Body).Decode(req); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	result, err := g.Push(req)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(result); err != nil {
+		g.logger.Error("Failed to write push result", logger.Error(err))
+	}
+}
+
+// Push 按推送目标向在线用户发送消息.
+func (g *WSGateway) Push(req *types.PushRequest) (*types.PushResult, error) {
+	if req.Message == nil {
+		return nil, errors.New("message is required")
+	}
+
+	result := &types.PushResult{}
+	collect := func(errs ...error) {
+		for _, err := range errs {
+			if err != nil {
+				result.Errors = append(result.Errors, err.Error())
+			}
+		}
+	}
+
+	switch req.Target {
+	case types.PushTargetAll:
+		states, err := g.userManager.GetAll()
+		if err != nil {
+			return nil, err
+		}
+		collect(g.Broadcast(req.Message)...)
+		result.Delivered = len(states)
+	case types.PushTargetUsers:
+		for _, userID := range req.UserIDs {
+			if !g.userManager.IsOnline(userID) {
+				continue
+			}
+			msg := *req.Message
+			msg.Header.To = userID
+			errs := g.SendToAllPlatforms(userID, &msg)
+			collect(errs...)
+			if len(errs) == 0 {
+				result.Delivered++
+			}
+		}
+	case types.PushTargetPlatform:
+		states, err := g.userManager.GetAll()
+		if err != nil {
+			return nil, err
+		}
+		for _, state := range states {
+			for _, platformID := range state.OnlinePlatform {
+				if platformID != req.PlatformID {
+					continue
+				}
+				msg := *req.Message
+				msg.Header.To = state.Id
+				err := g.SendToPlatform(state.Id, platformID, &msg)
+				collect(err)
+				if err == nil {
+					result.Delivered++
+				}
+			}
+		}
+	default:
+		return nil, errors.New("unknown push target")
+	}
+
+	return result, nil
+}
+
+// handleSend 处理发送业务消息请求
+func (g *WSGateway) handleSend(w http.ResponseWriter, r *http.Request) {
+	req := new(types.SendRequest)
+	if err := json.NewDecoder(r.Body).Decode(req); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	result, err := g.Send(req.Message)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(result); err != nil {
+		g.logger.Error("Failed to write send result", logger.Error(err))
+	}
+}
+
+// Send 将消息交给消息路由处理，与连接上收到的消息经过相同的校验、投递和存储.
+// 消息被拒绝时不返回错误，拒绝原因记录在结果中.
+func (g *WSGateway) Send(msg *types.Message) (*types.SendResult, error) {
+	if msg == nil {
+		return nil, errors.New("message is required")
+	}
+	if msg.Header.ID == "" {
+		return nil, errors.New("message id is required")
+	}
+	data, err := g.encoder.Encode(msg)
+	if err != nil {
+		return nil, err
+	}
+
+	result := &types.SendResult{MessageID: msg.GetID()}
+	err = g.router.Process(data)
+	var rejected *handler.RejectedError
+	if errors.As(err, &rejected) {
+		g.logger.Info("Message rejected",
+			logger.String("user_id", msg.GetFrom()),
+			logger.String("message_id", rejected.MessageID),
+			logger.String("reason", rejected.Reason))
+		result.Rejected = rejected.Reason
+		return result, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// handleDisconnect 处理断开用户连接请求
+func (g *WSGateway) handleDisconnect(w http.ResponseWriter, r *http.Request) {
+	req := new(types.DisconnectRequest)
+	if err := json.NewDecoder(r.Body).Decode(req); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	result := &types.DisconnectResult{}
+	for _, userID := range req.UserIDs {
+		result.Disconnected += g.disconnect(userID, req.PlatformID, req.Code, req.Reason)
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(result); err != nil {
+		g.logger.Error("Failed to write disconnect result", logger.Error(err))
+	}
+}
+
+// handlePresence 处理查询在线状态请求
+func (g *WSGateway) handlePresence(w http.ResponseWriter, r *http.Request) {
+	req := new(types.PresenceRequest)
+	if err := json.NewDecoder(r.Body).Decode(req); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(g.Presence(req.UserIDs)); err != nil {
+		g.logger.Error("Failed to write presence result", logger.Error(err))
+	}
+}
+
+// Presence 返回用户在线的平台，不在线的用户不出现在结果中.
+func (g *WSGateway) Presence(userIDs []string) *types.PresenceResult {
+	result := &types.PresenceResult{Online: make(map[string][]int32)}
+	for _, userID := range userIDs {
+		state, err := g.userManager.GetState(userID)
+		if err != nil || len(state.OnlinePlatform) == 0 {
+			continue
+		}
+		result.Online[userID] = state.OnlinePlatform
+	}
+	return result
+}
+
+// DisconnectUser 通知并断开用户在所有平台上的连接，返回断开的连接数量.
+func (g *WSGateway) DisconnectUser(userID, reason string) int {
+	return g.DisconnectPlatform(userID, 0, reason)
+}
+
+// DisconnectPlatform 通知并断开用户在指定平台上的连接，platformID 为0时断开所有平台，返回断开的连接数量.
+func (g *WSGateway) DisconnectPlatform(userID string, platformID int32, reason string) int {
+	return g.disconnect(userID, platformID, "", reason)
+}
+
+// disconnect 以 code 通知客户端后断开连接，code 为空时为 session_terminated.
+func (g *WSGateway) disconnect(userID string, platformID int32, code, reason string) int {
+	if code == "" {
+		code = "session_terminated"
+	}
+	state, err := g.userManager.GetState(userID)
+	if err != nil {
+		g.logger.Error("Failed to get user state", logger.String("user_id", userID), logger.Error(err))
+		return 0
+	}
+
+	platforms := append(state.OnlinePlatform, state.OfflinePlatform...)
+	if platformID != 0 {
+		platforms = slices.DeleteFunc(platforms, func(p int32) bool { return p != platformID })
+	}
+	disconnected := 0
+	for _, p := range platforms {
+		conn, err := g.userManager.GetConn(userID, p)
+		if err != nil || conn == nil {
+			continue
+		}
+		if conn.State() == base.Connected {
+			g.notifyDisconnect(userID, p, code, reason)
+			disconnected++
+		}
+		if err := conn.Disconnect(errors.New("disconnected by server: " + reason)); err != nil {
+			g.logger.Error("Failed to disconnect user",
+				logger.String("user_id", userID),
+				logger.Int32("platform_id", p),
+				logger.Error(err))
+		}
+		if err := g.userManager.RemoveConn(userID, p); err != nil {
+			g.logger.Error("Failed to remove connection", logger.String("user_id", userID), logger.Error(err))
+		}
+	}
+
+	g.logger.Info("User disconnected by server",
+		logger.String("user_id", userID),
+		logger.Int32("platform_id", platformID),
+		logger.String("code", code),
+		logger.String("reason", reason),
+		logger.Int("connections", disconnected))
+	return disconnected
+}
+
+// notifyDisconnect 断开前通知客户端，失败时只记录日志.
+func (g *WSGateway) notifyDisconnect(userID string, platformID int32, code, reason string) {
+	payload, err := g.encoder.Encode(map[string]string{
+		"code":   code,
+		"reason": reason,
+	})
+	if err != nil {
+		g.logger.Error("Failed to encode disconnect notice", logger.Error(err))
+		return
+	}
+	notice := types.NewMessage(types.MessageTypeSystem, "system", userID, platformID, payload)
+	if err := g.userManager.SendPlatformMessage(userID, platformID, notice); err != nil {
+		g.logger.Warn("Failed to send disconnect notice", logger.String("user_id", userID), logger.Error(err))
+	}
+}
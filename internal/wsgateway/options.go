@@ -3,7 +3,14 @@ package wsgateway
 import (
 	"time"
 
+	"github.com/woxQAQ/gim/internal/audit"
+	"github.com/woxQAQ/gim/internal/search"
+	"github.com/woxQAQ/gim/internal/webhook"
 	"github.com/woxQAQ/gim/internal/wsgateway/codec"
+	"github.com/woxQAQ/gim/internal/wsgateway/filter"
+	"github.com/woxQAQ/gim/internal/wsgateway/handler"
+	"github.com/woxQAQ/gim/internal/wsgateway/intercept"
+	"github.com/woxQAQ/gim/pkg/cache"
 	"github.com/woxQAQ/gim/pkg/logger"
 )
 
@@ -37,3 +44,61 @@ func WithHeartbeat(interval, timeout time.Duration) Option {
 		g.heartbeatTimeout = timeout
 	}
 }
+
+// WithWebhookDispatcher 设置回调分发器，消息和在线状态事件会发布到该分发器.
+func WithWebhookDispatcher(d *webhook.Dispatcher) Option {
+	return func(g *WSGateway) {
+		g.webhooks = d
+	}
+}
+
+// WithInterceptor 设置投递前拦截器，可以是进程内插件或 HTTP 回调.
+func WithInterceptor(i intercept.Interceptor, cfg handler.InterceptConfig) Option {
+	return func(g *WSGateway) {
+		g.interceptor = i
+		g.interceptCfg = cfg
+	}
+}
+
+// WithDMPolicy 设置非联系人之间的单聊策略，默认允许任何用户之间单聊.
+func WithDMPolicy(p handler.DMPolicy) Option {
+	return func(g *WSGateway) {
+		g.dmPolicy = p
+	}
+}
+
+// WithContentFilter 设置文本消息的内容过滤器.
+func WithContentFilter(f *filter.Filter) Option {
+	return func(g *WSGateway) {
+		g.contentFilter = f
+	}
+}
+
+// WithSearchIndex 设置消息全文索引，消息存储、编辑和撤回时同步更新.
+func WithSearchIndex(i search.Index) Option {
+	return func(g *WSGateway) {
+		g.searchIndex = i
+	}
+}
+
+// WithMessageCache 设置 apiserver 使用的消息缓存，网关写入消息时删除对应会话的缓存.
+func WithMessageCache(c cache.Cache) Option {
+	return func(g *WSGateway) {
+		g.messageCache = c
+	}
+}
+
+// WithAuditRecorder 设置审计日志记录器，记录消息撤回等操作.
+func WithAuditRecorder(r *audit.Recorder) Option {
+	return func(g *WSGateway) {
+		g.audit = r
+	}
+}
+
+// WithTokenVerifier 要求建立连接时提供访问令牌，用户和平台以令牌为准.
+// 未设置时沿用 user_id 查询参数，仅适合内网或测试环境.
+func WithTokenVerifier(v TokenVerifier) Option {
+	return func(g *WSGateway) {
+		g.tokens = v
+	}
+}
@@ -31,6 +31,10 @@ type IUserManager interface {
 	SendMessage(userID string, msg base.IMessage) []error
 	// SendPlatformMessage 向指定用户的指定平台发送消息
 	SendPlatformMessage(userID string, platformID int32, msg base.IMessage) error
+	// AddObserver 添加状态观察者
+	AddObserver(observer StateObserver)
+	// RemoveObserver 移除状态观察者
+	RemoveObserver(observer StateObserver)
 }
 
 var _ IUserManager = &Manager{}
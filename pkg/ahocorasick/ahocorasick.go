@@ -0,0 +1,126 @@
+package ahocorasick
+
+import "unicode"
+
+// Match 一次匹配结果，Start 和 End 为 rune 下标，区间左闭右开
+type Match struct {
+	Pattern int // 命中的模式串下标
+	Start   int
+	End     int
+}
+
+// node 自动机节点
+type node struct {
+	children map[rune]int
+	fail     int
+	outputs  []int // 以该节点结尾的模式串下标（包含 fail 链上的输出）
+	depth    int
+}
+
+// Matcher 基于 Aho-Corasick 自动机的多模式匹配器，构建后只读，可并发使用
+type Matcher struct {
+	nodes      []node
+	lengths    []int
+	ignoreCase bool
+}
+
+// New 使用给定的模式串构建匹配器，空串会被忽略
+func New(patterns []string, ignoreCase bool) *Matcher {
+	m := &Matcher{
+		nodes:      []node{{children: make(map[rune]int)}},
+		lengths:    make([]int, len(patterns)),
+		ignoreCase: ignoreCase,
+	}
+	for i, p := range patterns {
+		m.insert(i, []rune(p))
+	}
+	m.build()
+	return m
+}
+
+// insert 将模式串插入字典树
+func (m *Matcher) insert(index int, pattern []rune) {
+	m.lengths[index] = len(pattern)
+	if len(pattern) == 0 {
+		return
+	}
+	cur := 0
+	for _, r := range pattern {
+		r = m.fold(r)
+		next, ok := m.nodes[cur].children[r]
+		if !ok {
+			next = len(m.nodes)
+			m.nodes = append(m.nodes, node{
+				children: make(map[rune]int),
+				depth:    m.nodes[cur].depth + 1,
+			})
+			m.nodes[cur].children[r] = next
+		}
+		cur = next
+	}
+	m.nodes[cur].outputs = append(m.nodes[cur].outputs, index)
+}
+
+// build 按层序计算 fail 指针
+func (m *Matcher) build() {
+	queue := make([]int, 0, len(m.nodes))
+	for _, child := range m.nodes[0].children {
+		queue = append(queue, child)
+	}
+	for len(queue) > 0 {
+		cur := queue[0]
+		queue = queue[1:]
+		for r, child := range m.nodes[cur].children {
+			fail := m.nodes[cur].fail
+			for fail != 0 {
+				if _, ok := m.nodes[fail].children[r]; ok {
+					break
+				}
+				fail = m.nodes[fail].fail
+			}
+			if next, ok := m.nodes[fail].children[r]; ok && next != child {
+				m.nodes[child].fail = next
+			}
+			m.nodes[child].outputs = append(m.nodes[child].outputs, m.nodes[m.nodes[child].fail].outputs...)
+			queue = append(queue, child)
+		}
+	}
+}
+
+// FindAll 返回文本中所有命中的模式串，允许重叠
+func (m *Matcher) FindAll(text string) []Match {
+	var matches []Match
+	cur := 0
+	for i, r := range []rune(text) {
+		r = m.fold(r)
+		for cur != 0 {
+			if _, ok := m.nodes[cur].children[r]; ok {
+				break
+			}
+			cur = m.nodes[cur].fail
+		}
+		if next, ok := m.nodes[cur].children[r]; ok {
+			cur = next
+		}
+		for _, p := range m.nodes[cur].outputs {
+			matches = append(matches, Match{
+				Pattern: p,
+				Start:   i + 1 - m.lengths[p],
+				End:     i + 1,
+			})
+		}
+	}
+	return matches
+}
+
+// Contains 检查文本是否命中任意模式串
+func (m *Matcher) Contains(text string) bool {
+	return len(m.FindAll(text)) > 0
+}
+
+func (m *Matcher) fold(r rune) rune {
+	if m.ignoreCase {
+		return unicode.ToLower(r)
+	}
+	return r
+}
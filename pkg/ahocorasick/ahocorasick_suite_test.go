@@ -0,0 +1,13 @@
+package ahocorasick_test
+
+import (
+	"testing"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+func TestAhocorasick(t *testing.T) {
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "Ahocorasick Suite")
+}
@@ -0,0 +1,35 @@
+package ahocorasick_test
+
+import (
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"github.com/woxQAQ/gim/pkg/ahocorasick"
+)
+
+var _ = Describe("Matcher", func() {
+	It("应该找出所有重叠的匹配", func() {
+		m := ahocorasick.New([]string{"he", "she", "his", "hers"}, false)
+		Expect(m.FindAll("ushers")).To(ConsistOf(
+			ahocorasick.Match{Pattern: 1, Start: 1, End: 4},
+			ahocorasick.Match{Pattern: 0, Start: 2, End: 4},
+			ahocorasick.Match{Pattern: 3, Start: 2, End: 6},
+		))
+	})
+
+	It("应该按 rune 计算中文的位置", func() {
+		m := ahocorasick.New([]string{"敏感词"}, false)
+		Expect(m.FindAll("这是敏感词吗")).To(Equal([]ahocorasick.Match{{Pattern: 0, Start: 2, End: 5}}))
+	})
+
+	It("应该支持忽略大小写", func() {
+		Expect(ahocorasick.New([]string{"Spam"}, true).Contains("no SPAM here")).To(BeTrue())
+		Expect(ahocorasick.New([]string{"Spam"}, false).Contains("no SPAM here")).To(BeFalse())
+	})
+
+	It("空模式串不应该产生匹配", func() {
+		m := ahocorasick.New([]string{"", "a"}, false)
+		Expect(m.FindAll("aa")).To(HaveLen(2))
+		Expect(ahocorasick.New(nil, false).Contains("anything")).To(BeFalse())
+	})
+})
@@ -0,0 +1,80 @@
+package auth
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"fmt"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// ActionClaims 邮件验证、重置密码等操作令牌中的声明，Subject 为用户ID，Audience 为令牌用途
+type ActionClaims struct {
+	jwt.RegisteredClaims
+	// Binding 签发时绑定值的摘要，绑定值变化后令牌失效
+	Binding string `json:"bnd"`
+}
+
+// Bound 判断令牌是否仍然绑定在 binding 上
+func (c *ActionClaims) Bound(binding string) bool {
+	return subtle.ConstantTimeCompare([]byte(c.Binding), []byte(bindingDigest(binding))) == 1
+}
+
+// ActionTokens 签发和校验发送给用户的操作令牌。
+// 令牌绑定用户的某个当前值（例如邮箱或密码哈希），该值变化后令牌自动失效，不需要在服务端保存
+type ActionTokens struct {
+	key []byte
+	now func() time.Time
+}
+
+// NewActionTokens 使用 HS256 共享密钥创建ActionTokens实例，密钥至少 32 字节。
+// 签名密钥由 secret 派生，与访问令牌共用 secret 时两种令牌也不能互相冒用
+func NewActionTokens(secret string) (*ActionTokens, error) {
+	if len(secret) < minSecretLength {
+		return nil, fmt.Errorf("action token secret must be at least %d bytes", minSecretLength)
+	}
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte("gim action token"))
+	return &ActionTokens{key: mac.Sum(nil), now: time.Now}, nil
+}
+
+// Issue 为用户签发用途为 purpose、在 ttl 后过期的令牌
+func (t *ActionTokens) Issue(purpose, userID, binding string, ttl time.Duration) (string, error) {
+	now := t.now()
+	claims := &ActionClaims{
+		RegisteredClaims: jwt.RegisteredClaims{
+			Subject:   userID,
+			Audience:  jwt.ClaimStrings{purpose},
+			IssuedAt:  jwt.NewNumericDate(now),
+			ExpiresAt: jwt.NewNumericDate(now.Add(ttl)),
+		},
+		Binding: bindingDigest(binding),
+	}
+	return jwt.NewWithClaims(jwt.SigningMethodHS256, claims).SignedString(t.key)
+}
+
+// Verify 校验令牌的签名、有效期和用途，调用方还需要通过 ActionClaims.Bound 检查绑定值
+func (t *ActionTokens) Verify(token, purpose string) (*ActionClaims, error) {
+	claims := new(ActionClaims)
+	_, err := jwt.ParseWithClaims(token, claims, func(*jwt.Token) (interface{}, error) {
+		return t.key, nil
+	},
+		jwt.WithValidMethods([]string{jwt.SigningMethodHS256.Alg()}),
+		jwt.WithAudience(purpose),
+		jwt.WithExpirationRequired(),
+		jwt.WithTimeFunc(t.now),
+	)
+	if err != nil || claims.Subject == "" {
+		return nil, ErrInvalidToken
+	}
+	return claims, nil
+}
+
+// bindingDigest 令牌中只保存绑定值的摘要，避免泄露邮箱或密码哈希
+func bindingDigest(binding string) string {
+	sum := sha256.Sum256([]byte(binding))
+	return base64.RawURLEncoding.EncodeToString(sum[:16])
+}
@@ -0,0 +1,50 @@
+package auth_test
+
+import (
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"github.com/woxQAQ/gim/pkg/auth"
+)
+
+var _ = Describe("ActionTokens", func() {
+	const secret = "0123456789abcdef0123456789abcdef"
+
+	It("应该校验令牌的用途和绑定值", func() {
+		tokens, err := auth.NewActionTokens(secret)
+		Expect(err).NotTo(HaveOccurred())
+
+		token, err := tokens.Issue("verify_email", "u1", "a@example.com", time.Hour)
+		Expect(err).NotTo(HaveOccurred())
+
+		claims, err := tokens.Verify(token, "verify_email")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(claims.Subject).To(Equal("u1"))
+		Expect(claims.Bound("a@example.com")).To(BeTrue())
+		Expect(claims.Bound("b@example.com")).To(BeFalse())
+
+		_, err = tokens.Verify(token, "reset_password")
+		Expect(err).To(MatchError(auth.ErrInvalidToken))
+	})
+
+	It("应该拒绝过期的令牌和访问令牌", func() {
+		tokens, err := auth.NewActionTokens(secret)
+		Expect(err).NotTo(HaveOccurred())
+		token, err := tokens.Issue("verify_email", "u1", "", time.Second)
+		Expect(err).NotTo(HaveOccurred())
+		Eventually(func() error {
+			_, err := tokens.Verify(token, "verify_email")
+			return err
+		}, 3*time.Second, 100*time.Millisecond).Should(MatchError(auth.ErrInvalidToken))
+
+		// 与访问令牌共用密钥时，访问令牌不能当作操作令牌使用
+		j, err := auth.NewJWT(&auth.JWTConfig{Secret: secret, TTL: time.Hour})
+		Expect(err).NotTo(HaveOccurred())
+		access, _, err := j.Issue("u1", "s1", 1)
+		Expect(err).NotTo(HaveOccurred())
+		_, err = tokens.Verify(access, "")
+		Expect(err).To(MatchError(auth.ErrInvalidToken))
+	})
+})
@@ -0,0 +1,67 @@
+package auth
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"slices"
+	"strings"
+)
+
+// APIKeyPrefix API 密钥的固定前缀，便于在日志和代码仓库中识别泄露的密钥
+const APIKeyPrefix = "gim_"
+
+// apiKeyDisplayLength 用于展示和识别密钥的前缀长度
+const apiKeyDisplayLength = len(APIKeyPrefix) + 8
+
+// GenerateAPIKey 生成随机的 API 密钥，返回密钥和用于展示的前缀。
+// 密钥只在创建时返回一次，服务端只保存 HashAPIKey 的结果
+func GenerateAPIKey() (key, prefix string, err error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", "", err
+	}
+	key = APIKeyPrefix + base64.RawURLEncoding.EncodeToString(b)
+	return key, key[:apiKeyDisplayLength], nil
+}
+
+// HashAPIKey 返回 API 密钥的摘要。密钥本身有足够的随机性，不需要加盐和慢哈希
+func HashAPIKey(key string) string {
+	sum := sha256.Sum256([]byte(key))
+	return hex.EncodeToString(sum[:])
+}
+
+// LooksLikeAPIKey 判断 token 是否具有 API 密钥的格式
+func LooksLikeAPIKey(token string) bool {
+	return strings.HasPrefix(token, APIKeyPrefix) && len(token) > apiKeyDisplayLength
+}
+
+// APIKeyPrincipal 通过 API 密钥鉴权的调用方
+type APIKeyPrincipal struct {
+	// KeyID 使用的密钥ID
+	KeyID string
+	// UserID 密钥所属的机器人账号
+	UserID string
+	// Scopes 密钥被授予的权限范围
+	Scopes []string
+}
+
+// HasScope 判断密钥是否被授予了 scope
+func (p *APIKeyPrincipal) HasScope(scope string) bool {
+	return slices.Contains(p.Scopes, scope)
+}
+
+type apiKeyKey struct{}
+
+// WithAPIKey 将已校验的 API 密钥调用方放入上下文
+func WithAPIKey(ctx context.Context, p *APIKeyPrincipal) context.Context {
+	return context.WithValue(ctx, apiKeyKey{}, p)
+}
+
+// APIKeyFromContext 取出上下文中的 API 密钥调用方，未经过 API 密钥鉴权时返回 false
+func APIKeyFromContext(ctx context.Context) (*APIKeyPrincipal, bool) {
+	p, ok := ctx.Value(apiKeyKey{}).(*APIKeyPrincipal)
+	return p, ok
+}
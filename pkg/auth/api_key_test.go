@@ -0,0 +1,28 @@
+package auth_test
+
+import (
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"github.com/woxQAQ/gim/pkg/auth"
+)
+
+var _ = Describe("APIKey", func() {
+	It("应该生成带前缀的随机密钥，摘要不包含密钥本身", func() {
+		key, prefix, err := auth.GenerateAPIKey()
+		Expect(err).NotTo(HaveOccurred())
+		Expect(key).To(HavePrefix(prefix))
+		Expect(prefix).To(HavePrefix(auth.APIKeyPrefix))
+		Expect(auth.LooksLikeAPIKey(key)).To(BeTrue())
+		Expect(auth.LooksLikeAPIKey("eyJhbGciOiJIUzI1NiJ9.e30.sig")).To(BeFalse())
+
+		other, _, err := auth.GenerateAPIKey()
+		Expect(err).NotTo(HaveOccurred())
+		Expect(other).NotTo(Equal(key))
+
+		hash := auth.HashAPIKey(key)
+		Expect(hash).To(HaveLen(64))
+		Expect(hash).To(Equal(auth.HashAPIKey(key)))
+		Expect(hash).NotTo(ContainSubstring(key[len(prefix):]))
+	})
+})
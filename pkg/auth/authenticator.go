@@ -0,0 +1,41 @@
+package auth
+
+import "errors"
+
+// ErrTokenRevoked 令牌所属的登录会话已吊销
+var ErrTokenRevoked = errors.New("token revoked")
+
+// RevocationList 查询登录会话是否已吊销
+type RevocationList interface {
+	IsRevoked(sessionID string) (bool, error)
+}
+
+// Authenticator 校验访问令牌，并检查令牌所属的登录会话没有被吊销
+type Authenticator struct {
+	tokens  *JWT
+	revoked RevocationList
+}
+
+// NewAuthenticator 创建Authenticator实例
+func NewAuthenticator(tokens *JWT, revoked RevocationList) *Authenticator {
+	return &Authenticator{tokens: tokens, revoked: revoked}
+}
+
+// Verify 校验令牌，会话已吊销或令牌没有会话ID时返回 ErrTokenRevoked
+func (a *Authenticator) Verify(token string) (*Claims, error) {
+	claims, err := a.tokens.Verify(token)
+	if err != nil {
+		return nil, err
+	}
+	if claims.SessionID == "" {
+		return nil, ErrTokenRevoked
+	}
+	revoked, err := a.revoked.IsRevoked(claims.SessionID)
+	if err != nil {
+		return nil, err
+	}
+	if revoked {
+		return nil, ErrTokenRevoked
+	}
+	return claims, nil
+}
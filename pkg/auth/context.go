@@ -0,0 +1,24 @@
+package auth
+
+import "context"
+
+type claimsKey struct{}
+
+// WithClaims 将已校验的令牌声明放入上下文
+func WithClaims(ctx context.Context, claims *Claims) context.Context {
+	return context.WithValue(ctx, claimsKey{}, claims)
+}
+
+// ClaimsFromContext 取出上下文中的令牌声明，未经过鉴权时返回 false
+func ClaimsFromContext(ctx context.Context) (*Claims, bool) {
+	claims, ok := ctx.Value(claimsKey{}).(*Claims)
+	return claims, ok
+}
+
+// UserIDFromContext 返回当前请求的用户ID，未经过鉴权时返回空字符串
+func UserIDFromContext(ctx context.Context) string {
+	if claims, ok := ClaimsFromContext(ctx); ok {
+		return claims.UserID()
+	}
+	return ""
+}
@@ -0,0 +1,165 @@
+package auth
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// minSecretLength HS256 密钥的最小长度
+const minSecretLength = 32
+
+// ErrInvalidToken 令牌格式错误、签名不匹配或已过期
+var ErrInvalidToken = errors.New("invalid token")
+
+// JWTConfig 令牌签发配置，Secret 与密钥对二选一，同时配置时优先使用密钥对
+type JWTConfig struct {
+	// Secret 使用 HS256 签名的共享密钥，至少 32 字节
+	Secret string
+	// PrivateKeyFile 与 PublicKeyFile 为 PEM 格式的 RSA 或 Ed25519 密钥对，
+	// 分别使用 RS256 与 EdDSA 签名。只配置公钥时只能校验令牌
+	PrivateKeyFile string
+	PublicKeyFile  string
+
+	Issuer string
+	TTL    time.Duration
+}
+
+// Claims 访问令牌中的声明，Subject 为用户ID
+type Claims struct {
+	jwt.RegisteredClaims
+	// SessionID 签发该令牌的登录会话，会话吊销后令牌失效
+	SessionID string `json:"sid"`
+	// Platform 登录的平台
+	Platform int32 `json:"plat,omitempty"`
+}
+
+// UserID 返回令牌所属的用户ID
+func (c *Claims) UserID() string {
+	return c.Subject
+}
+
+// JWT 签发和校验访问令牌
+type JWT struct {
+	method    jwt.SigningMethod
+	signKey   interface{}
+	verifyKey interface{}
+	issuer    string
+	ttl       time.Duration
+	now       func() time.Time
+}
+
+// NewJWT 根据配置创建JWT实例
+func NewJWT(cfg *JWTConfig) (*JWT, error) {
+	j := &JWT{issuer: cfg.Issuer, ttl: cfg.TTL, now: time.Now}
+	if j.ttl <= 0 {
+		j.ttl = 24 * time.Hour
+	}
+
+	if cfg.PrivateKeyFile != "" || cfg.PublicKeyFile != "" {
+		if err := j.loadKeyPair(cfg.PrivateKeyFile, cfg.PublicKeyFile); err != nil {
+			return nil, err
+		}
+		return j, nil
+	}
+
+	if len(cfg.Secret) < minSecretLength {
+		return nil, fmt.Errorf("jwt secret must be at least %d bytes", minSecretLength)
+	}
+	j.method = jwt.SigningMethodHS256
+	j.signKey = []byte(cfg.Secret)
+	j.verifyKey = j.signKey
+	return j, nil
+}
+
+func (j *JWT) loadKeyPair(privateFile, publicFile string) error {
+	if publicFile == "" {
+		return errors.New("jwt public key file is required")
+	}
+	pubPEM, err := os.ReadFile(publicFile)
+	if err != nil {
+		return fmt.Errorf("read jwt public key: %w", err)
+	}
+	if key, err := jwt.ParseRSAPublicKeyFromPEM(pubPEM); err == nil {
+		j.method, j.verifyKey = jwt.SigningMethodRS256, key
+	} else if key, err := jwt.ParseEdPublicKeyFromPEM(pubPEM); err == nil {
+		j.method, j.verifyKey = jwt.SigningMethodEdDSA, key
+	} else {
+		return errors.New("jwt public key must be an RSA or Ed25519 PEM key")
+	}
+
+	if privateFile == "" {
+		return nil
+	}
+	privPEM, err := os.ReadFile(privateFile)
+	if err != nil {
+		return fmt.Errorf("read jwt private key: %w", err)
+	}
+	switch j.verifyKey.(type) {
+	case *rsa.PublicKey:
+		j.signKey, err = jwt.ParseRSAPrivateKeyFromPEM(privPEM)
+	case ed25519.PublicKey:
+		j.signKey, err = jwt.ParseEdPrivateKeyFromPEM(privPEM)
+	}
+	if err != nil {
+		return fmt.Errorf("parse jwt private key: %w", err)
+	}
+	return nil
+}
+
+// Issue 为用户的登录会话签发访问令牌，返回令牌及其声明
+func (j *JWT) Issue(userID, sessionID string, platform int32) (string, *Claims, error) {
+	if j.signKey == nil {
+		return "", nil, errors.New("jwt private key is not configured")
+	}
+	id := make([]byte, 16)
+	if _, err := rand.Read(id); err != nil {
+		return "", nil, err
+	}
+	now := j.now()
+	claims := &Claims{RegisteredClaims: jwt.RegisteredClaims{
+		ID:        hex.EncodeToString(id),
+		Subject:   userID,
+		Issuer:    j.issuer,
+		IssuedAt:  jwt.NewNumericDate(now),
+		NotBefore: jwt.NewNumericDate(now),
+		ExpiresAt: jwt.NewNumericDate(now.Add(j.ttl)),
+	}, SessionID: sessionID, Platform: platform}
+	token, err := jwt.NewWithClaims(j.method, claims).SignedString(j.signKey)
+	if err != nil {
+		return "", nil, err
+	}
+	return token, claims, nil
+}
+
+// TTL 返回访问令牌的有效期
+func (j *JWT) TTL() time.Duration {
+	return j.ttl
+}
+
+// Verify 校验令牌的签名、签发者和有效期，返回其中的声明
+func (j *JWT) Verify(token string) (*Claims, error) {
+	opts := []jwt.ParserOption{
+		jwt.WithValidMethods([]string{j.method.Alg()}),
+		jwt.WithExpirationRequired(),
+		jwt.WithTimeFunc(j.now),
+	}
+	if j.issuer != "" {
+		opts = append(opts, jwt.WithIssuer(j.issuer))
+	}
+	claims := &Claims{}
+	_, err := jwt.ParseWithClaims(token, claims, func(*jwt.Token) (interface{}, error) {
+		return j.verifyKey, nil
+	}, opts...)
+	if err != nil || claims.Subject == "" {
+		return nil, ErrInvalidToken
+	}
+	return claims, nil
+}
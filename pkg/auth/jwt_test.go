@@ -0,0 +1,103 @@
+package auth_test
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/x509"
+	"encoding/pem"
+	"os"
+	"path/filepath"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"github.com/woxQAQ/gim/pkg/auth"
+)
+
+var _ = Describe("JWT", func() {
+	const secret = "0123456789abcdef0123456789abcdef"
+
+	It("应该校验自己签发的令牌", func() {
+		j, err := auth.NewJWT(&auth.JWTConfig{Secret: secret, Issuer: "gim", TTL: time.Hour})
+		Expect(err).NotTo(HaveOccurred())
+
+		token, issued, err := j.Issue("u1", "s1", 1)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(issued.ExpiresAt.Time).To(BeTemporally("~", time.Now().Add(time.Hour), time.Second))
+
+		claims, err := j.Verify(token)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(claims.UserID()).To(Equal("u1"))
+		Expect(claims.ID).To(Equal(issued.ID))
+		Expect(claims.SessionID).To(Equal("s1"))
+		Expect(claims.Platform).To(BeEquivalentTo(1))
+	})
+
+	It("应该拒绝其他密钥签发、签发者不同或已过期的令牌", func() {
+		j, err := auth.NewJWT(&auth.JWTConfig{Secret: secret, Issuer: "gim", TTL: time.Hour})
+		Expect(err).NotTo(HaveOccurred())
+
+		other, err := auth.NewJWT(&auth.JWTConfig{Secret: secret + "x", Issuer: "gim"})
+		Expect(err).NotTo(HaveOccurred())
+		token, _, err := other.Issue("u1", "s1", 1)
+		Expect(err).NotTo(HaveOccurred())
+		_, err = j.Verify(token)
+		Expect(err).To(MatchError(auth.ErrInvalidToken))
+
+		issuer, err := auth.NewJWT(&auth.JWTConfig{Secret: secret, Issuer: "other"})
+		Expect(err).NotTo(HaveOccurred())
+		token, _, err = issuer.Issue("u1", "s1", 1)
+		Expect(err).NotTo(HaveOccurred())
+		_, err = j.Verify(token)
+		Expect(err).To(MatchError(auth.ErrInvalidToken))
+
+		short, err := auth.NewJWT(&auth.JWTConfig{Secret: secret, Issuer: "gim", TTL: time.Second})
+		Expect(err).NotTo(HaveOccurred())
+		token, _, err = short.Issue("u1", "s1", 1)
+		Expect(err).NotTo(HaveOccurred())
+		Eventually(func() error {
+			_, err := j.Verify(token)
+			return err
+		}, 3*time.Second, 100*time.Millisecond).Should(MatchError(auth.ErrInvalidToken))
+
+		_, err = j.Verify("not-a-token")
+		Expect(err).To(MatchError(auth.ErrInvalidToken))
+	})
+
+	It("密钥过短时应该拒绝启动", func() {
+		_, err := auth.NewJWT(&auth.JWTConfig{Secret: "short"})
+		Expect(err).To(HaveOccurred())
+	})
+
+	It("应该支持 Ed25519 密钥对，只有公钥时只能校验", func() {
+		pub, priv, err := ed25519.GenerateKey(rand.Reader)
+		Expect(err).NotTo(HaveOccurred())
+		dir := GinkgoT().TempDir()
+		writePEM := func(name, typ string, der []byte) string {
+			path := filepath.Join(dir, name)
+			Expect(os.WriteFile(path, pem.EncodeToMemory(&pem.Block{Type: typ, Bytes: der}), 0o600)).To(Succeed())
+			return path
+		}
+		privDER, err := x509.MarshalPKCS8PrivateKey(priv)
+		Expect(err).NotTo(HaveOccurred())
+		pubDER, err := x509.MarshalPKIXPublicKey(pub)
+		Expect(err).NotTo(HaveOccurred())
+		privFile := writePEM("jwt.key", "PRIVATE KEY", privDER)
+		pubFile := writePEM("jwt.pub", "PUBLIC KEY", pubDER)
+
+		signer, err := auth.NewJWT(&auth.JWTConfig{PrivateKeyFile: privFile, PublicKeyFile: pubFile})
+		Expect(err).NotTo(HaveOccurred())
+		verifier, err := auth.NewJWT(&auth.JWTConfig{PublicKeyFile: pubFile})
+		Expect(err).NotTo(HaveOccurred())
+
+		token, _, err := signer.Issue("u1", "s1", 1)
+		Expect(err).NotTo(HaveOccurred())
+		claims, err := verifier.Verify(token)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(claims.UserID()).To(Equal("u1"))
+
+		_, _, err = verifier.Issue("u1", "s1", 1)
+		Expect(err).To(HaveOccurred())
+	})
+})
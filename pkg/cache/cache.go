@@ -0,0 +1,67 @@
+// Package cache 提供读穿缓存抽象，以及进程内 LRU 和 Redis 两种实现。
+//
+// 缓存只是数据库的副本：读取失败按未命中处理，写入失败不影响业务，
+// 数据变更时由写入方删除对应的键。
+package cache
+
+import (
+	"context"
+	"encoding/json"
+	"time"
+
+	"golang.org/x/sync/singleflight"
+)
+
+// Cache 键值缓存
+type Cache interface {
+	// Get 读取缓存，未命中时返回 false
+	Get(ctx context.Context, key string) ([]byte, bool, error)
+	// Set 写入缓存，ttl 为 0 时使用实现的默认过期时间
+	Set(ctx context.Context, key string, value []byte, ttl time.Duration) error
+	// Delete 删除缓存
+	Delete(ctx context.Context, keys ...string) error
+}
+
+// Nop 不缓存任何数据
+type Nop struct{}
+
+var _ Cache = Nop{}
+
+// Get 实现 Cache 接口
+func (Nop) Get(context.Context, string) ([]byte, bool, error) { return nil, false, nil }
+
+// Set 实现 Cache 接口
+func (Nop) Set(context.Context, string, []byte, time.Duration) error { return nil }
+
+// Delete 实现 Cache 接口
+func (Nop) Delete(context.Context, ...string) error { return nil }
+
+// loads 合并同一个键的并发加载，避免缓存失效时大量请求同时访问数据库
+var loads singleflight.Group
+
+// GetOrLoad 读穿缓存：命中时解码返回，未命中时调用 load 并写入缓存。
+// load 返回错误时不写入缓存；缓存本身的错误按未命中处理。
+func GetOrLoad[T any](ctx context.Context, c Cache, key string, ttl time.Duration, load func() (T, error)) (T, error) {
+	if data, ok, err := c.Get(ctx, key); err == nil && ok {
+		var v T
+		if err := json.Unmarshal(data, &v); err == nil {
+			return v, nil
+		}
+	}
+
+	v, err, _ := loads.Do(key, func() (interface{}, error) {
+		v, err := load()
+		if err != nil {
+			return v, err
+		}
+		if data, err := json.Marshal(v); err == nil {
+			_ = c.Set(ctx, key, data, ttl)
+		}
+		return v, nil
+	})
+	if err != nil {
+		var zero T
+		return zero, err
+	}
+	return v.(T), nil
+}
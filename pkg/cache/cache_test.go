@@ -0,0 +1,157 @@
+package cache_test
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"github.com/redis/go-redis/v9"
+
+	"github.com/woxQAQ/gim/pkg/cache"
+)
+
+type profile struct {
+	ID   string
+	Name string
+}
+
+var _ = Describe("Cache", func() {
+	ctx := context.Background()
+
+	// 两种实现共用同一组用例，expire 让缓存中的时间前进
+	implementations := []struct {
+		name string
+		open func() (cache.Cache, func(time.Duration))
+	}{
+		{"LRU", func() (cache.Cache, func(time.Duration)) {
+			return cache.NewLRU(100, time.Minute), func(d time.Duration) { time.Sleep(d) }
+		}},
+		{"Redis", func() (cache.Cache, func(time.Duration)) {
+			mr := miniredis.RunT(GinkgoT())
+			client := redis.NewClient(&redis.Options{Addr: mr.Addr()})
+			DeferCleanup(client.Close)
+			return cache.NewRedis(client, "test:", time.Minute), mr.FastForward
+		}},
+	}
+
+	for _, impl := range implementations {
+		Context(impl.name, func() {
+			var (
+				c      cache.Cache
+				expire func(time.Duration)
+			)
+
+			BeforeEach(func() {
+				c, expire = impl.open()
+			})
+
+			It("应该读取写入的值并在删除后未命中", func() {
+				Expect(c.Set(ctx, "a", []byte("1"), 0)).To(Succeed())
+				data, ok, err := c.Get(ctx, "a")
+				Expect(err).NotTo(HaveOccurred())
+				Expect(ok).To(BeTrue())
+				Expect(string(data)).To(Equal("1"))
+
+				Expect(c.Delete(ctx, "a", "missing")).To(Succeed())
+				_, ok, err = c.Get(ctx, "a")
+				Expect(err).NotTo(HaveOccurred())
+				Expect(ok).To(BeFalse())
+			})
+
+			It("过期后应该未命中", func() {
+				Expect(c.Set(ctx, "a", []byte("1"), 50*time.Millisecond)).To(Succeed())
+				expire(100 * time.Millisecond)
+				_, ok, err := c.Get(ctx, "a")
+				Expect(err).NotTo(HaveOccurred())
+				Expect(ok).To(BeFalse())
+			})
+
+			It("GetOrLoad 应该只在未命中时加载", func() {
+				loads := 0
+				load := func() (*profile, error) {
+					loads++
+					return &profile{ID: "1", Name: "alice"}, nil
+				}
+				for i := 0; i < 3; i++ {
+					p, err := cache.GetOrLoad(ctx, c, "user:1", 0, load)
+					Expect(err).NotTo(HaveOccurred())
+					Expect(p).To(Equal(&profile{ID: "1", Name: "alice"}))
+				}
+				Expect(loads).To(Equal(1))
+
+				Expect(c.Delete(ctx, "user:1")).To(Succeed())
+				_, err := cache.GetOrLoad(ctx, c, "user:1", 0, load)
+				Expect(err).NotTo(HaveOccurred())
+				Expect(loads).To(Equal(2))
+			})
+
+			It("加载失败时不应该缓存", func() {
+				_, err := cache.GetOrLoad(ctx, c, "user:2", 0, func() (*profile, error) {
+					return nil, errors.New("not found")
+				})
+				Expect(err).To(MatchError("not found"))
+				_, ok, err := c.Get(ctx, "user:2")
+				Expect(err).NotTo(HaveOccurred())
+				Expect(ok).To(BeFalse())
+			})
+		})
+	}
+
+	It("LRU 超过容量时应该淘汰最久未访问的键", func() {
+		c := cache.NewLRU(2, 0)
+		Expect(c.Set(ctx, "a", []byte("1"), 0)).To(Succeed())
+		Expect(c.Set(ctx, "b", []byte("2"), 0)).To(Succeed())
+		_, _, _ = c.Get(ctx, "a")
+		Expect(c.Set(ctx, "c", []byte("3"), 0)).To(Succeed())
+
+		Expect(c.Len()).To(Equal(2))
+		_, ok, _ := c.Get(ctx, "b")
+		Expect(ok).To(BeFalse())
+		_, ok, _ = c.Get(ctx, "a")
+		Expect(ok).To(BeTrue())
+	})
+
+	It("Redis 不可用时 GetOrLoad 应该直接加载", func() {
+		mr := miniredis.RunT(GinkgoT())
+		client := redis.NewClient(&redis.Options{Addr: mr.Addr(), MaxRetries: -1})
+		DeferCleanup(client.Close)
+		c := cache.NewRedis(client, "test:", time.Minute)
+		mr.Close()
+
+		p, err := cache.GetOrLoad(ctx, c, "user:1", 0, func() (*profile, error) {
+			return &profile{ID: "1"}, nil
+		})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(p.ID).To(Equal("1"))
+	})
+
+	It("并发未命中时应该只加载一次", func() {
+		c := cache.NewLRU(10, time.Minute)
+		var loads atomic.Int32
+		release := make(chan struct{})
+		var wg sync.WaitGroup
+		for i := 0; i < 10; i++ {
+			wg.Add(1)
+			go func() {
+				defer GinkgoRecover()
+				defer wg.Done()
+				_, err := cache.GetOrLoad(ctx, c, "hot", 0, func() (string, error) {
+					loads.Add(1)
+					<-release
+					return "v", nil
+				})
+				Expect(err).NotTo(HaveOccurred())
+			}()
+		}
+		Eventually(loads.Load).Should(Equal(int32(1)))
+		time.Sleep(20 * time.Millisecond)
+		close(release)
+		wg.Wait()
+		Expect(loads.Load()).To(Equal(int32(1)))
+	})
+})
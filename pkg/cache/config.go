@@ -0,0 +1,56 @@
+package cache
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// 缓存驱动
+const (
+	DriverNone   = ""
+	DriverMemory = "memory"
+	DriverRedis  = "redis"
+)
+
+// Config 缓存配置
+type Config struct {
+	Driver string
+	TTL    time.Duration
+
+	// Size 进程内缓存最多保存的键数量
+	Size int
+
+	RedisAddr     string
+	RedisPassword string
+	RedisDB       int
+	Prefix        string
+}
+
+// New 根据配置创建缓存，未配置驱动时返回 Nop。
+// 使用 Redis 时会先检查连接是否可用。
+func New(cfg *Config) (Cache, error) {
+	switch cfg.Driver {
+	case DriverNone:
+		return Nop{}, nil
+	case DriverMemory:
+		return NewLRU(cfg.Size, cfg.TTL), nil
+	case DriverRedis:
+		client := redis.NewClient(&redis.Options{
+			Addr:     cfg.RedisAddr,
+			Password: cfg.RedisPassword,
+			DB:       cfg.RedisDB,
+		})
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		if err := client.Ping(ctx).Err(); err != nil {
+			client.Close()
+			return nil, fmt.Errorf("connect redis %s: %w", cfg.RedisAddr, err)
+		}
+		return NewRedis(client, cfg.Prefix, cfg.TTL), nil
+	default:
+		return nil, fmt.Errorf("unsupported cache driver %q", cfg.Driver)
+	}
+}
@@ -5,8 +5,78 @@ const (
 	OpenapiRoute      = "OPENAPI_ROUTE"
 	EnableOpenapiSpec = "ENABLE_OPENAPI_SPEC"
 	ApiPath           = "API_PATH"
-	DBPath            = "DB_PATH"
+	NodeID            = "NODE_ID"
+
+	DBDriver          = "DB_DRIVER"
+	DBDSN             = "DB_DSN"
+	DBMaxOpenConns    = "DB_MAX_OPEN_CONNS"
+	DBMaxIdleConns    = "DB_MAX_IDLE_CONNS"
+	DBConnMaxLifetime = "DB_CONN_MAX_LIFETIME"
+	DBConnMaxIdleTime = "DB_CONN_MAX_IDLE_TIME"
+	DBAutoMigrate     = "DB_AUTO_MIGRATE"
 
 	LogLevel    = "LOG_LEVEL"
 	LogFilePath = "LOG_FILE_PATH"
+
+	JWTSecret         = "JWT_SECRET"
+	JWTPrivateKeyFile = "JWT_PRIVATE_KEY_FILE"
+	JWTPublicKeyFile  = "JWT_PUBLIC_KEY_FILE"
+	JWTIssuer         = "JWT_ISSUER"
+	JWTTTL            = "JWT_TTL"
+
+	RefreshTokenTTL        = "REFRESH_TOKEN_TTL"
+	SessionCleanupInterval = "SESSION_CLEANUP_INTERVAL"
+
+	AuditHashChain = "AUDIT_HASH_CHAIN"
+
+	LoginMaxFailures     = "LOGIN_MAX_FAILURES"
+	LoginIPMaxFailures   = "LOGIN_IP_MAX_FAILURES"
+	LoginFailureWindow   = "LOGIN_FAILURE_WINDOW"
+	LoginLockoutDuration = "LOGIN_LOCKOUT_DURATION"
+	LoginDelayBase       = "LOGIN_DELAY_BASE"
+	LoginMaxDelay        = "LOGIN_MAX_DELAY"
+	LoginCleanupInterval = "LOGIN_CLEANUP_INTERVAL"
+	RegisterPerIP        = "REGISTER_PER_IP"
+	CaptchaAfterFailures = "CAPTCHA_AFTER_FAILURES"
+	CaptchaStaticToken   = "CAPTCHA_STATIC_TOKEN"
+
+	MailDriver           = "MAIL_DRIVER"
+	MailFrom             = "MAIL_FROM"
+	MailDir              = "MAIL_DIR"
+	SMTPAddr             = "SMTP_ADDR"
+	SMTPUsername         = "SMTP_USERNAME"
+	SMTPPassword         = "SMTP_PASSWORD"
+	MailLinkBaseURL      = "MAIL_LINK_BASE_URL"
+	EmailTokenSecret     = "EMAIL_TOKEN_SECRET"
+	EmailVerifyTTL       = "EMAIL_VERIFY_TTL"
+	PasswordResetTTL     = "PASSWORD_RESET_TTL"
+	RequireVerifiedEmail = "REQUIRE_VERIFIED_EMAIL"
+
+	GatewayURL           = "GATEWAY_URL"
+	GatewayInternalToken = "GATEWAY_INTERNAL_TOKEN"
+	GatewayTimeout       = "GATEWAY_TIMEOUT"
+
+	NoticeSchedulerInterval = "NOTICE_SCHEDULER_INTERVAL"
+
+	RetentionInterval   = "RETENTION_INTERVAL"
+	RetentionBatchSize  = "RETENTION_BATCH_SIZE"
+	RetentionBatchPause = "RETENTION_BATCH_PAUSE"
+
+	ArchiveDir    = "ARCHIVE_DIR"
+	AttachmentDir = "ATTACHMENT_DIR"
+
+	DataRequestInterval = "DATA_REQUEST_INTERVAL"
+	DataExportDir       = "DATA_EXPORT_DIR"
+	DataExportTTL       = "DATA_EXPORT_TTL"
+
+	CacheDriver        = "CACHE_DRIVER"
+	CacheTTL           = "CACHE_TTL"
+	CacheSize          = "CACHE_SIZE"
+	CacheRedisAddr     = "CACHE_REDIS_ADDR"
+	CacheRedisPassword = "CACHE_REDIS_PASSWORD"
+	CacheRedisDB       = "CACHE_REDIS_DB"
+	CachePrefix        = "CACHE_PREFIX"
+
+	// GimctlPassword gimctl user create 使用的密码，未设置时从标准输入读取
+	GimctlPassword = "GIMCTL_PASSWORD"
 )
@@ -1,13 +1,24 @@
 package db
 
 import (
+	"fmt"
 	"sync"
+	"time"
 
-	"github.com/woxQAQ/gim/internal/models"
-	"github.com/woxQAQ/gim/pkg/logger"
-
+	"gorm.io/driver/mysql"
+	"gorm.io/driver/postgres"
 	"gorm.io/driver/sqlite"
 	"gorm.io/gorm"
+	gormLogger "gorm.io/gorm/logger"
+
+	"github.com/woxQAQ/gim/pkg/logger"
+)
+
+// 支持的数据库驱动
+const (
+	DriverSQLite   = "sqlite"
+	DriverPostgres = "postgres"
+	DriverMySQL    = "mysql"
 )
 
 var (
@@ -17,49 +28,121 @@ var (
 
 // Config 数据库配置
 type Config struct {
-	Logger       logger.Logger
-	DatabasePath string // SQLite数据库文件路径
+	Logger logger.Logger
+	Driver string // 数据库驱动：sqlite, postgres, mysql，默认 sqlite
+	// DSN 数据源名称：
+	//   sqlite:   文件路径，例如 gim.db 或 :memory:
+	//   postgres: host=localhost user=gim password=gim dbname=gim port=5432 sslmode=disable
+	//   mysql:    gim:gim@tcp(127.0.0.1:3306)/gim?charset=utf8mb4&parseTime=True&loc=Local
+	DSN string
+
+	// 连接池配置，为零时使用 database/sql 的默认值
+	MaxOpenConns    int
+	MaxIdleConns    int
+	ConnMaxLifetime time.Duration
+	ConnMaxIdleTime time.Duration
+
+	// Migrations 版本化迁移列表
+	Migrations []Migration
+	// AutoMigrate 启动时自动执行未执行的迁移，关闭时存在未执行迁移会拒绝启动
+	AutoMigrate bool
 }
 
-// Init 初始化数据库连接并执行迁移
+// Init 初始化数据库连接并检查结构版本
 func Init(cfg *Config) error {
 	var err error
 	once.Do(func() {
-		// 连接SQLite数据库
-		gormLogger := NewGormLogger(cfg.Logger.With(logger.String("domain", "database")))
-		instance, err = gorm.Open(sqlite.Open(cfg.DatabasePath), &gorm.Config{
-			Logger: gormLogger,
-		})
+		instance, err = Open(cfg)
 		if err != nil {
 			return
 		}
 
-		// 自动迁移数据库表
-		if err = migrateDB(); err != nil {
-			return
-		}
+		err = prepareSchema(instance, cfg)
 	})
 
 	return err
 }
 
-// migrateDB 执行数据库迁移
-func migrateDB() error {
-	// 在这里添加需要迁移的模型
-	models := []interface{}{
-		&models.User{},
-		&models.Message{},
-		&models.MessageAttachment{},
-		// 在此处添加其他需要迁移的模型
+// Open 按配置打开数据库连接并设置连接池，不执行迁移
+func Open(cfg *Config) (*gorm.DB, error) {
+	dialector, err := dialectorFor(cfg)
+	if err != nil {
+		return nil, err
 	}
 
-	// 执行迁移
-	for _, model := range models {
-		if err := instance.AutoMigrate(model); err != nil {
-			return err
-		}
+	gcfg := &gorm.Config{Logger: gormLogger.Discard}
+	if cfg.Logger != nil {
+		gcfg.Logger = NewGormLogger(cfg.Logger.With(logger.String("domain", "database")))
+	}
+	gdb, err := gorm.Open(dialector, gcfg)
+	if err != nil {
+		return nil, err
 	}
 
+	sqlDB, err := gdb.DB()
+	if err != nil {
+		return nil, err
+	}
+	maxOpen := cfg.MaxOpenConns
+	if isMemorySQLite(cfg) {
+		// 内存数据库的每个连接都是独立的库，只能使用单连接
+		maxOpen = 1
+	}
+	if maxOpen > 0 {
+		sqlDB.SetMaxOpenConns(maxOpen)
+	}
+	if cfg.MaxIdleConns > 0 {
+		sqlDB.SetMaxIdleConns(cfg.MaxIdleConns)
+	}
+	if cfg.ConnMaxLifetime > 0 {
+		sqlDB.SetConnMaxLifetime(cfg.ConnMaxLifetime)
+	}
+	if cfg.ConnMaxIdleTime > 0 {
+		sqlDB.SetConnMaxIdleTime(cfg.ConnMaxIdleTime)
+	}
+
+	return gdb, nil
+}
+
+// dialectorFor 根据驱动名称创建 GORM 方言
+func dialectorFor(cfg *Config) (gorm.Dialector, error) {
+	switch cfg.Driver {
+	case "", DriverSQLite:
+		return sqlite.Open(cfg.DSN), nil
+	case DriverPostgres:
+		return postgres.Open(cfg.DSN), nil
+	case DriverMySQL:
+		return mysql.Open(cfg.DSN), nil
+	default:
+		return nil, fmt.Errorf("unsupported database driver %q", cfg.Driver)
+	}
+}
+
+func isMemorySQLite(cfg *Config) bool {
+	return (cfg.Driver == "" || cfg.Driver == DriverSQLite) && cfg.DSN == ":memory:"
+}
+
+// prepareSchema 检查数据库结构版本，按配置执行未执行的迁移
+func prepareSchema(gdb *gorm.DB, cfg *Config) error {
+	m, err := NewMigrator(gdb, cfg.Migrations)
+	if err != nil {
+		return err
+	}
+	if err := m.Check(); err != nil {
+		return err
+	}
+
+	if cfg.AutoMigrate {
+		_, err = m.Up()
+		return err
+	}
+	pending, err := m.Pending()
+	if err != nil {
+		return err
+	}
+	if len(pending) > 0 {
+		return fmt.Errorf("%w: %d migration(s) not applied, run `gimctl migrate up`", ErrPendingMigrations, len(pending))
+	}
 	return nil
 }
 
@@ -0,0 +1,238 @@
+package db
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+var (
+	// ErrSchemaAhead 数据库中的结构版本高于当前程序已知的最新版本
+	ErrSchemaAhead = errors.New("database schema is ahead of this binary")
+	// ErrPendingMigrations 存在未执行的迁移且未开启自动迁移
+	ErrPendingMigrations = errors.New("database has pending migrations")
+)
+
+// Migration 一个版本化的迁移步骤
+type Migration struct {
+	Version int64  // 版本号，必须唯一且递增
+	Name    string // 迁移名称，仅用于展示
+	Up      func(tx *gorm.DB) error
+	Down    func(tx *gorm.DB) error
+}
+
+// SchemaVersion 记录已执行的迁移
+type SchemaVersion struct {
+	Version   int64     `gorm:"primaryKey;autoIncrement:false"`
+	Name      string    `gorm:"type:varchar(255);not null"`
+	AppliedAt time.Time `gorm:"not null"`
+}
+
+func (SchemaVersion) TableName() string {
+	return "schema_versions"
+}
+
+// MigrationStatus 迁移的执行状态
+type MigrationStatus struct {
+	Version   int64
+	Name      string
+	Applied   bool
+	AppliedAt *time.Time
+	// Unknown 表示数据库中存在但程序中没有定义的版本
+	Unknown bool
+}
+
+// Migrator 按版本顺序执行迁移
+type Migrator struct {
+	db         *gorm.DB
+	migrations []Migration
+}
+
+// NewMigrator 创建Migrator实例，迁移会按版本号排序
+func NewMigrator(gdb *gorm.DB, migrations []Migration) (*Migrator, error) {
+	sorted := make([]Migration, len(migrations))
+	copy(sorted, migrations)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i].Version < sorted[j].Version })
+
+	for i, m := range sorted {
+		if m.Version <= 0 {
+			return nil, fmt.Errorf("migration %q: version must be positive", m.Name)
+		}
+		if m.Up == nil {
+			return nil, fmt.Errorf("migration %d: missing up step", m.Version)
+		}
+		if i > 0 && sorted[i-1].Version == m.Version {
+			return nil, fmt.Errorf("migration %d: duplicate version", m.Version)
+		}
+	}
+	return &Migrator{db: gdb, migrations: sorted}, nil
+}
+
+// Latest 返回程序已知的最新版本
+func (m *Migrator) Latest() int64 {
+	if len(m.migrations) == 0 {
+		return 0
+	}
+	return m.migrations[len(m.migrations)-1].Version
+}
+
+// Current 返回数据库当前的结构版本
+func (m *Migrator) Current() (int64, error) {
+	applied, err := m.applied()
+	if err != nil {
+		return 0, err
+	}
+	if len(applied) == 0 {
+		return 0, nil
+	}
+	return applied[len(applied)-1].Version, nil
+}
+
+// Check 检查数据库结构是否比程序更新
+func (m *Migrator) Check() error {
+	current, err := m.Current()
+	if err != nil {
+		return err
+	}
+	if current > m.Latest() {
+		return fmt.Errorf("%w: database at version %d, binary supports up to %d", ErrSchemaAhead, current, m.Latest())
+	}
+	return nil
+}
+
+// Pending 返回尚未执行的迁移
+func (m *Migrator) Pending() ([]Migration, error) {
+	applied, err := m.appliedSet()
+	if err != nil {
+		return nil, err
+	}
+	var pending []Migration
+	for _, mig := range m.migrations {
+		if _, ok := applied[mig.Version]; !ok {
+			pending = append(pending, mig)
+		}
+	}
+	return pending, nil
+}
+
+// Status 返回所有迁移的执行状态，包括数据库中未知的版本
+func (m *Migrator) Status() ([]MigrationStatus, error) {
+	applied, err := m.appliedSet()
+	if err != nil {
+		return nil, err
+	}
+
+	statuses := make([]MigrationStatus, 0, len(m.migrations))
+	for _, mig := range m.migrations {
+		s := MigrationStatus{Version: mig.Version, Name: mig.Name}
+		if v, ok := applied[mig.Version]; ok {
+			s.Applied = true
+			s.AppliedAt = &v.AppliedAt
+			delete(applied, mig.Version)
+		}
+		statuses = append(statuses, s)
+	}
+	for _, v := range applied {
+		appliedAt := v.AppliedAt
+		statuses = append(statuses, MigrationStatus{
+			Version:   v.Version,
+			Name:      v.Name,
+			Applied:   true,
+			AppliedAt: &appliedAt,
+			Unknown:   true,
+		})
+	}
+	sort.Slice(statuses, func(i, j int) bool { return statuses[i].Version < statuses[j].Version })
+	return statuses, nil
+}
+
+// Up 按顺序执行所有未执行的迁移，返回本次执行的迁移
+func (m *Migrator) Up() ([]Migration, error) {
+	if err := m.Check(); err != nil {
+		return nil, err
+	}
+	pending, err := m.Pending()
+	if err != nil {
+		return nil, err
+	}
+
+	for i, mig := range pending {
+		err := m.db.Transaction(func(tx *gorm.DB) error {
+			if err := mig.Up(tx); err != nil {
+				return err
+			}
+			return tx.Create(&SchemaVersion{
+				Version:   mig.Version,
+				Name:      mig.Name,
+				AppliedAt: time.Now(),
+			}).Error
+		})
+		if err != nil {
+			return pending[:i], fmt.Errorf("migration %d (%s) up: %w", mig.Version, mig.Name, err)
+		}
+	}
+	return pending, nil
+}
+
+// Down 按倒序回滚最近执行的 steps 个迁移，返回本次回滚的迁移
+func (m *Migrator) Down(steps int) ([]Migration, error) {
+	if err := m.Check(); err != nil {
+		return nil, err
+	}
+	applied, err := m.applied()
+	if err != nil {
+		return nil, err
+	}
+
+	byVersion := make(map[int64]Migration, len(m.migrations))
+	for _, mig := range m.migrations {
+		byVersion[mig.Version] = mig
+	}
+
+	var rolled []Migration
+	for i := len(applied) - 1; i >= 0 && len(rolled) < steps; i-- {
+		mig, ok := byVersion[applied[i].Version]
+		if !ok {
+			return rolled, fmt.Errorf("migration %d is not known to this binary", applied[i].Version)
+		}
+		if mig.Down == nil {
+			return rolled, fmt.Errorf("migration %d (%s) is irreversible", mig.Version, mig.Name)
+		}
+		err := m.db.Transaction(func(tx *gorm.DB) error {
+			if err := mig.Down(tx); err != nil {
+				return err
+			}
+			return tx.Delete(&SchemaVersion{}, "version = ?", mig.Version).Error
+		})
+		if err != nil {
+			return rolled, fmt.Errorf("migration %d (%s) down: %w", mig.Version, mig.Name, err)
+		}
+		rolled = append(rolled, mig)
+	}
+	return rolled, nil
+}
+
+// applied 返回数据库中已执行的版本，按版本号升序
+func (m *Migrator) applied() ([]SchemaVersion, error) {
+	if err := m.db.AutoMigrate(&SchemaVersion{}); err != nil {
+		return nil, err
+	}
+	var versions []SchemaVersion
+	err := m.db.Order("version asc").Find(&versions).Error
+	return versions, err
+}
+
+func (m *Migrator) appliedSet() (map[int64]SchemaVersion, error) {
+	versions, err := m.applied()
+	if err != nil {
+		return nil, err
+	}
+	set := make(map[int64]SchemaVersion, len(versions))
+	for _, v := range versions {
+		set[v.Version] = v
+	}
+	return set, nil
+}
@@ -0,0 +1,133 @@
+package db_test
+
+import (
+	"errors"
+	"path/filepath"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"gorm.io/gorm"
+
+	"github.com/woxQAQ/gim/pkg/db"
+)
+
+type widget struct {
+	ID   string `gorm:"primaryKey;type:varchar(64)"`
+	Name string `gorm:"type:text"`
+}
+
+var _ = Describe("Migrator", func() {
+	var (
+		gdb        *gorm.DB
+		migrations []db.Migration
+	)
+
+	BeforeEach(func() {
+		var err error
+		gdb, err = db.Open(&db.Config{DSN: filepath.Join(GinkgoT().TempDir(), "migrate.db")})
+		Expect(err).NotTo(HaveOccurred())
+
+		migrations = []db.Migration{
+			{
+				Version: 2,
+				Name:    "add_widget_color",
+				Up: func(tx *gorm.DB) error {
+					return tx.Exec("ALTER TABLE widgets ADD COLUMN color text").Error
+				},
+				Down: func(tx *gorm.DB) error {
+					return tx.Exec("ALTER TABLE widgets DROP COLUMN color").Error
+				},
+			},
+			{
+				Version: 1,
+				Name:    "create_widgets",
+				Up: func(tx *gorm.DB) error {
+					return tx.AutoMigrate(&widget{})
+				},
+				Down: func(tx *gorm.DB) error {
+					return tx.Migrator().DropTable(&widget{})
+				},
+			},
+		}
+	})
+
+	It("应该按版本顺序执行并记录迁移", func() {
+		m, err := db.NewMigrator(gdb, migrations)
+		Expect(err).NotTo(HaveOccurred())
+
+		applied, err := m.Up()
+		Expect(err).NotTo(HaveOccurred())
+		Expect(applied).To(HaveLen(2))
+		Expect(applied[0].Version).To(Equal(int64(1)))
+		Expect(gdb.Migrator().HasColumn("widgets", "color")).To(BeTrue())
+
+		current, err := m.Current()
+		Expect(err).NotTo(HaveOccurred())
+		Expect(current).To(Equal(int64(2)))
+
+		applied, err = m.Up()
+		Expect(err).NotTo(HaveOccurred())
+		Expect(applied).To(BeEmpty())
+	})
+
+	It("应该按倒序回滚指定数量的迁移", func() {
+		m, err := db.NewMigrator(gdb, migrations)
+		Expect(err).NotTo(HaveOccurred())
+		_, err = m.Up()
+		Expect(err).NotTo(HaveOccurred())
+
+		rolled, err := m.Down(1)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(rolled).To(HaveLen(1))
+		Expect(rolled[0].Version).To(Equal(int64(2)))
+		Expect(gdb.Migrator().HasColumn("widgets", "color")).To(BeFalse())
+
+		statuses, err := m.Status()
+		Expect(err).NotTo(HaveOccurred())
+		Expect(statuses).To(HaveLen(2))
+		Expect(statuses[0].Applied).To(BeTrue())
+		Expect(statuses[1].Applied).To(BeFalse())
+	})
+
+	It("迁移失败时应该回滚事务且不记录版本", func() {
+		migrations = append(migrations, db.Migration{
+			Version: 3,
+			Name:    "broken",
+			Up: func(tx *gorm.DB) error {
+				return errors.New("boom")
+			},
+		})
+		m, err := db.NewMigrator(gdb, migrations)
+		Expect(err).NotTo(HaveOccurred())
+
+		applied, err := m.Up()
+		Expect(err).To(MatchError(ContainSubstring("boom")))
+		Expect(applied).To(HaveLen(2))
+
+		current, err := m.Current()
+		Expect(err).NotTo(HaveOccurred())
+		Expect(current).To(Equal(int64(2)))
+	})
+
+	It("数据库版本高于程序时应该拒绝执行", func() {
+		m, err := db.NewMigrator(gdb, migrations)
+		Expect(err).NotTo(HaveOccurred())
+		_, err = m.Up()
+		Expect(err).NotTo(HaveOccurred())
+
+		older, err := db.NewMigrator(gdb, migrations[1:])
+		Expect(err).NotTo(HaveOccurred())
+		Expect(older.Check()).To(MatchError(db.ErrSchemaAhead))
+		_, err = older.Up()
+		Expect(err).To(MatchError(db.ErrSchemaAhead))
+
+		statuses, err := older.Status()
+		Expect(err).NotTo(HaveOccurred())
+		Expect(statuses[1].Unknown).To(BeTrue())
+	})
+
+	It("应该拒绝重复的版本号", func() {
+		_, err := db.NewMigrator(gdb, append(migrations, migrations[0]))
+		Expect(err).To(HaveOccurred())
+	})
+})
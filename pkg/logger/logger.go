@@ -57,15 +57,16 @@ type Field = zap.Field
 
 // 提供创建Field的便捷方法.
 var (
-	String  = zap.String
-	Int     = zap.Int
-	Int32   = zap.Int32
-	Int64   = zap.Int64
-	Time    = zap.Time
-	Float64 = zap.Float64
-	Bool    = zap.Bool
-	Any     = zap.Any
-	Error   = zap.Error
+	String   = zap.String
+	Int      = zap.Int
+	Int32    = zap.Int32
+	Int64    = zap.Int64
+	Time     = zap.Time
+	Float64  = zap.Float64
+	Bool     = zap.Bool
+	Duration = zap.Duration
+	Any      = zap.Any
+	Error    = zap.Error
 )
 
 // logger 实现Logger接口.
@@ -0,0 +1,83 @@
+package mailer
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+// File 把邮件保存为目录中的 .eml 文件，用于本地开发时查看发出的邮件
+type File struct {
+	dir  string
+	from string
+	mu   sync.Mutex
+	seq  int
+}
+
+var _ Mailer = (*File)(nil)
+
+// NewFile 创建File实例，目录不存在时创建
+func NewFile(dir, from string) (*File, error) {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, err
+	}
+	return &File{dir: dir, from: from}, nil
+}
+
+// Send 实现 Mailer 接口
+func (f *File) Send(_ context.Context, msg *Message) error {
+	now := time.Now()
+	data, err := encode(f.from, msg, now)
+	if err != nil {
+		return err
+	}
+	f.mu.Lock()
+	f.seq++
+	name := fmt.Sprintf("%s-%04d.eml", now.Format("20060102T150405"), f.seq)
+	f.mu.Unlock()
+	return os.WriteFile(filepath.Join(f.dir, name), data, 0o600)
+}
+
+// Memory 把邮件保存在内存中，用于测试
+type Memory struct {
+	mu       sync.Mutex
+	messages []*Message
+}
+
+var _ Mailer = (*Memory)(nil)
+
+// NewMemory 创建Memory实例
+func NewMemory() *Memory {
+	return &Memory{}
+}
+
+// Send 实现 Mailer 接口
+func (m *Memory) Send(_ context.Context, msg *Message) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	copied := *msg
+	m.messages = append(m.messages, &copied)
+	return nil
+}
+
+// Messages 返回已发送的全部邮件
+func (m *Memory) Messages() []*Message {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return append([]*Message(nil), m.messages...)
+}
+
+// Last 返回发送给 to 的最后一封邮件，没有时返回 nil
+func (m *Memory) Last(to string) *Message {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for i := len(m.messages) - 1; i >= 0; i-- {
+		if m.messages[i].To == to {
+			return m.messages[i]
+		}
+	}
+	return nil
+}
@@ -0,0 +1,99 @@
+// Package mailer 提供发送邮件的抽象，以及 SMTP、本地文件和内存三种实现。
+//
+// SMTP 用于生产环境，文件和内存实现把邮件保存在本地，便于开发调试和测试时查看邮件内容。
+package mailer
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"mime"
+	"mime/quotedprintable"
+	"net/mail"
+	"time"
+)
+
+// 邮件驱动
+const (
+	DriverNone   = ""
+	DriverSMTP   = "smtp"
+	DriverFile   = "file"
+	DriverMemory = "memory"
+)
+
+// Message 一封纯文本邮件
+type Message struct {
+	To      string
+	Subject string
+	Body    string
+}
+
+// Mailer 发送邮件
+type Mailer interface {
+	Send(ctx context.Context, msg *Message) error
+}
+
+// Config 邮件配置
+type Config struct {
+	Driver string
+	// From 发件人地址，可以带显示名称，例如 "gim <noreply@example.com>"
+	From string
+
+	SMTPAddr     string // host:port
+	SMTPUsername string
+	SMTPPassword string
+
+	// Dir 文件驱动保存邮件的目录
+	Dir string
+}
+
+// New 根据配置创建 Mailer，未配置驱动时返回 nil
+func New(cfg *Config) (Mailer, error) {
+	switch cfg.Driver {
+	case DriverNone:
+		return nil, nil
+	case DriverSMTP:
+		if cfg.SMTPAddr == "" {
+			return nil, fmt.Errorf("smtp address is required")
+		}
+		return NewSMTP(cfg.SMTPAddr, cfg.SMTPUsername, cfg.SMTPPassword, cfg.From)
+	case DriverFile:
+		if cfg.Dir == "" {
+			return nil, fmt.Errorf("mail directory is required")
+		}
+		return NewFile(cfg.Dir, cfg.From)
+	case DriverMemory:
+		return NewMemory(), nil
+	default:
+		return nil, fmt.Errorf("unsupported mail driver %q", cfg.Driver)
+	}
+}
+
+// encode 将邮件编码为 RFC 5322 格式，正文使用 quoted-printable 编码
+func encode(from string, msg *Message, now time.Time) ([]byte, error) {
+	var buf bytes.Buffer
+	fmt.Fprintf(&buf, "From: %s\r\n", from)
+	fmt.Fprintf(&buf, "To: %s\r\n", msg.To)
+	fmt.Fprintf(&buf, "Subject: %s\r\n", mime.QEncoding.Encode("utf-8", msg.Subject))
+	fmt.Fprintf(&buf, "Date: %s\r\n", now.Format(time.RFC1123Z))
+	buf.WriteString("MIME-Version: 1.0\r\n")
+	buf.WriteString("Content-Type: text/plain; charset=utf-8\r\n")
+	buf.WriteString("Content-Transfer-Encoding: quoted-printable\r\n\r\n")
+	w := quotedprintable.NewWriter(&buf)
+	if _, err := w.Write([]byte(msg.Body)); err != nil {
+		return nil, err
+	}
+	if err := w.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// address 解析地址，只返回邮箱部分
+func address(addr string) (string, error) {
+	a, err := mail.ParseAddress(addr)
+	if err != nil {
+		return "", fmt.Errorf("invalid mail address %q: %w", addr, err)
+	}
+	return a.Address, nil
+}
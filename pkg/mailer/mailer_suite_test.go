@@ -0,0 +1,13 @@
+package mailer_test
+
+import (
+	"testing"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+func TestMailer(t *testing.T) {
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "Mailer Suite")
+}
@@ -0,0 +1,63 @@
+package mailer_test
+
+import (
+	"context"
+	"io"
+	"mime"
+	"mime/quotedprintable"
+	"net/mail"
+	"os"
+	"path/filepath"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"github.com/woxQAQ/gim/pkg/mailer"
+)
+
+var _ = Describe("Mailer", func() {
+	ctx := context.Background()
+
+	It("未配置驱动时不发送邮件，未知驱动返回错误", func() {
+		m, err := mailer.New(&mailer.Config{})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(m).To(BeNil())
+
+		_, err = mailer.New(&mailer.Config{Driver: "pigeon"})
+		Expect(err).To(HaveOccurred())
+	})
+
+	It("文件驱动应该把邮件保存为可解析的 .eml 文件", func() {
+		dir := GinkgoT().TempDir()
+		m, err := mailer.New(&mailer.Config{Driver: mailer.DriverFile, Dir: dir, From: "gim <noreply@example.com>"})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(m.Send(ctx, &mailer.Message{To: "alice@example.com", Subject: "验证邮箱", Body: "打开链接完成验证"})).To(Succeed())
+
+		files, err := filepath.Glob(filepath.Join(dir, "*.eml"))
+		Expect(err).NotTo(HaveOccurred())
+		Expect(files).To(HaveLen(1))
+		f, err := os.Open(files[0])
+		Expect(err).NotTo(HaveOccurred())
+		defer f.Close()
+
+		msg, err := mail.ReadMessage(f)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(msg.Header.Get("To")).To(Equal("alice@example.com"))
+		subject, err := new(mime.WordDecoder).DecodeHeader(msg.Header.Get("Subject"))
+		Expect(err).NotTo(HaveOccurred())
+		Expect(subject).To(Equal("验证邮箱"))
+		body, err := io.ReadAll(quotedprintable.NewReader(msg.Body))
+		Expect(err).NotTo(HaveOccurred())
+		Expect(string(body)).To(Equal("打开链接完成验证"))
+	})
+
+	It("内存驱动应该保存发送的邮件", func() {
+		m := mailer.NewMemory()
+		Expect(m.Send(ctx, &mailer.Message{To: "a@example.com", Subject: "1"})).To(Succeed())
+		Expect(m.Send(ctx, &mailer.Message{To: "b@example.com", Subject: "2"})).To(Succeed())
+		Expect(m.Send(ctx, &mailer.Message{To: "a@example.com", Subject: "3"})).To(Succeed())
+		Expect(m.Messages()).To(HaveLen(3))
+		Expect(m.Last("a@example.com").Subject).To(Equal("3"))
+		Expect(m.Last("c@example.com")).To(BeNil())
+	})
+})
@@ -0,0 +1,52 @@
+package mailer
+
+import (
+	"context"
+	"net"
+	"net/smtp"
+	"time"
+)
+
+// SMTP 通过 SMTP 服务器发送邮件，服务器支持时使用 STARTTLS
+type SMTP struct {
+	addr string
+	auth smtp.Auth
+	from string
+	// sender 信封发件人，只包含邮箱地址
+	sender string
+}
+
+var _ Mailer = (*SMTP)(nil)
+
+// NewSMTP 创建SMTP实例，username 为空时不进行认证
+func NewSMTP(addr, username, password, from string) (*SMTP, error) {
+	sender, err := address(from)
+	if err != nil {
+		return nil, err
+	}
+	host, _, err := net.SplitHostPort(addr)
+	if err != nil {
+		return nil, err
+	}
+	s := &SMTP{addr: addr, from: from, sender: sender}
+	if username != "" {
+		s.auth = smtp.PlainAuth("", username, password, host)
+	}
+	return s, nil
+}
+
+// Send 实现 Mailer 接口。net/smtp 不支持取消，ctx 只用于在发送前检查是否已经结束
+func (s *SMTP) Send(ctx context.Context, msg *Message) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	to, err := address(msg.To)
+	if err != nil {
+		return err
+	}
+	data, err := encode(s.from, msg, time.Now())
+	if err != nil {
+		return err
+	}
+	return smtp.SendMail(s.addr, s.auth, s.sender, []string{to}, data)
+}
@@ -0,0 +1,49 @@
+package middleware
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/woxQAQ/gim/pkg/auth"
+)
+
+// APIKeyHeader API 密钥请求头，也可以通过 "Authorization: Bearer <key>" 提供
+const APIKeyHeader = "X-API-Key"
+
+// APIKeyVerifier 校验 API 密钥
+type APIKeyVerifier interface {
+	VerifyAPIKey(key string) (*auth.APIKeyPrincipal, error)
+}
+
+// APIKeyAuth 创建一个校验 API 密钥的中间件，密钥需要被授予 scope，
+// 校验通过后将调用方放入请求上下文
+func APIKeyAuth(v APIKeyVerifier, scope string) func(next http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			key := r.Header.Get(APIKeyHeader)
+			if key == "" {
+				if token, ok := bearerToken(r); ok && auth.LooksLikeAPIKey(token) {
+					key = token
+				}
+			}
+			if key == "" {
+				unauthorized(w, "missing api key")
+				return
+			}
+			principal, err := v.VerifyAPIKey(key)
+			switch {
+			case errors.Is(err, auth.ErrInvalidToken):
+				unauthorized(w, "invalid api key")
+				return
+			case err != nil:
+				http.Error(w, "internal server error", http.StatusInternalServerError)
+				return
+			}
+			if !principal.HasScope(scope) {
+				http.Error(w, "api key lacks scope "+scope, http.StatusForbidden)
+				return
+			}
+			next.ServeHTTP(w, r.WithContext(auth.WithAPIKey(r.Context(), principal)))
+		})
+	}
+}
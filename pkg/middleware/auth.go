@@ -0,0 +1,56 @@
+package middleware
+
+import (
+	"errors"
+	"net/http"
+	"strings"
+
+	"github.com/woxQAQ/gim/pkg/auth"
+)
+
+// AuthorizationHeader 访问令牌请求头，格式为 "Bearer <token>"
+const AuthorizationHeader = "Authorization"
+
+// TokenVerifier 校验访问令牌
+type TokenVerifier interface {
+	Verify(token string) (*auth.Claims, error)
+}
+
+// JWTAuth 创建一个校验访问令牌的中间件，校验通过后将令牌声明放入请求上下文
+func JWTAuth(v TokenVerifier) func(next http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			token, ok := bearerToken(r)
+			if !ok {
+				unauthorized(w, "missing bearer token")
+				return
+			}
+			claims, err := v.Verify(token)
+			switch {
+			case errors.Is(err, auth.ErrTokenRevoked):
+				unauthorized(w, "token revoked")
+				return
+			case errors.Is(err, auth.ErrInvalidToken):
+				unauthorized(w, "invalid token")
+				return
+			case err != nil:
+				http.Error(w, "internal server error", http.StatusInternalServerError)
+				return
+			}
+			next.ServeHTTP(w, r.WithContext(auth.WithClaims(r.Context(), claims)))
+		})
+	}
+}
+
+func bearerToken(r *http.Request) (string, bool) {
+	scheme, token, ok := strings.Cut(r.Header.Get(AuthorizationHeader), " ")
+	if !ok || !strings.EqualFold(scheme, "Bearer") || token == "" {
+		return "", false
+	}
+	return strings.TrimSpace(token), true
+}
+
+func unauthorized(w http.ResponseWriter, msg string) {
+	w.Header().Set("WWW-Authenticate", `Bearer realm="gim"`)
+	http.Error(w, msg, http.StatusUnauthorized)
+}
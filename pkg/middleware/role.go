@@ -0,0 +1,37 @@
+package middleware
+
+import (
+	"net/http"
+	"slices"
+
+	"github.com/woxQAQ/gim/pkg/auth"
+)
+
+// RoleResolver 查询用户当前的角色，用户不存在时返回空字符串
+type RoleResolver interface {
+	GetUserRole(userID string) (string, error)
+}
+
+// RequireRole 创建一个校验调用方角色的中间件，需要挂载在 JWTAuth 之后。
+// 角色在每次请求时查询，修改角色后不需要重新登录即可生效
+func RequireRole(resolver RoleResolver, roles ...string) func(next http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			userID := auth.UserIDFromContext(r.Context())
+			if userID == "" {
+				unauthorized(w, "missing bearer token")
+				return
+			}
+			role, err := resolver.GetUserRole(userID)
+			if err != nil {
+				http.Error(w, "internal server error", http.StatusInternalServerError)
+				return
+			}
+			if !slices.Contains(roles, role) {
+				http.Error(w, "forbidden", http.StatusForbidden)
+				return
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}